@@ -91,8 +91,8 @@ func (o *allowArtifactOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err := kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("artifact %s was allow listed in environment: %s", o.payload.Fingerprint, o.environmentName)
 	}
 	return err
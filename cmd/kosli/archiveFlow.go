@@ -46,8 +46,8 @@ func newArchiveFlowCmd(out io.Writer) *cobra.Command {
 				DryRun: global.DryRun,
 				Token:  global.ApiToken,
 			}
-			_, err := kosliClient.Do(reqParams)
-			if err == nil && !global.DryRun {
+			res, err := kosliClient.Do(reqParams)
+			if requestReported(res, err) {
 				logger.Info("flow %s was archived", args[0])
 			}
 			return err
@@ -65,6 +65,7 @@ func newAttachPolicyCmd(out io.Writer) *cobra.Command {
 
 func (o *attachPolicyOptions) run(args []string) error {
 	var err error
+	var res *requests.HTTPResponse
 	for _, env := range o.environments {
 		url := fmt.Sprintf("%s/api/v2/environments/%s/%s/policies", global.Host, global.Org, env)
 		o.payload.PolicyNames = []string{args[0]}
@@ -75,12 +76,12 @@ func (o *attachPolicyOptions) run(args []string) error {
 			DryRun:  global.DryRun,
 			Token:   global.ApiToken,
 		}
-		_, err = kosliClient.Do(reqParams)
+		res, err = kosliClient.Do(reqParams)
 		if err != nil {
 			break
 		}
 	}
-	if err == nil && !global.DryRun {
+	if requestReported(res, err) {
 		logger.Info("policy '%s' is attached to environments: %s", args[0], o.environments)
 	}
 	return err
@@ -20,7 +20,10 @@ func newAttestCmd(out io.Writer) *cobra.Command {
 		newAttestArtifactCmd(out),
 		newAttestGenericCmd(out),
 		newAttestSnykCmd(out),
+		newAttestSarifCmd(out),
+		newAttestSbomCmd(out),
 		newAttestJunitCmd(out),
+		newAttestCoverageCmd(out),
 		newAttestJiraCmd(out),
 		newAttestPRCmd(out),
 		newAttestSonarCmd(out),
@@ -18,6 +18,7 @@ type attestArtifactOptions struct {
 	redactedCommitInfo   []string
 	srcRepoRoot          string
 	displayName          string
+	platformAliases      bool
 	payload              AttestArtifactPayload
 	externalFingerprints map[string]string
 	externalURLs         map[string]string
@@ -26,6 +27,7 @@ type attestArtifactOptions struct {
 
 type AttestArtifactPayload struct {
 	Fingerprint   string                   `json:"fingerprint"`
+	Aliases       []string                 `json:"fingerprint_aliases,omitempty"`
 	Filename      string                   `json:"filename"`
 	GitCommit     string                   `json:"git_commit"`
 	GitCommitInfo *gitview.BasicCommitInfo `json:"git_commit_info"`
@@ -59,6 +61,19 @@ kosli attest artifact FILE.tgz \
 	--org yourOrgName
 
 
+# Attest that a multi-arch image has been created, and record the digests of all its platforms (requires a Kosli server which accepts fingerprint aliases)
+kosli attest artifact yourImageName:yourImageTag \
+	--artifact-type oci \
+	--platform-aliases \
+	--build-url https://exampleci.com \
+	--commit-url https://github.com/YourOrg/YourProject/commit/yourCommitShaThatThisArtifactWasBuiltFrom \
+	--commit yourCommitShaThatThisArtifactWasBuiltFrom \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--name yourTemplateArtifactName \
+	--api-token yourApiToken \
+	--org yourOrgName
+
 # Attest that an artifact has been created and provide its fingerprint (sha256) 
 kosli attest artifact ANOTHER_FILE.txt \
 	--build-url https://exampleci.com \
@@ -116,6 +131,16 @@ func newAttestArtifactCmd(out io.Writer) *cobra.Command {
 			if err != nil {
 				return ErrorBeforePrintingUsage(cmd, err.Error())
 			}
+
+			err = MuXRequiredFlags(cmd, []string{"fingerprint", "platform-aliases"}, false)
+			if err != nil {
+				return err
+			}
+
+			artifactType := o.fingerprintOptions.artifactType
+			if o.platformAliases && artifactType != "oci" && artifactType != "oci-dir" && artifactType != "oci-archive" {
+				return ErrorBeforePrintingUsage(cmd, "--platform-aliases is only applicable when --artifact-type is 'oci', 'oci-dir' or 'oci-archive'")
+			}
 			return ValidateRegistryFlags(cmd, o.fingerprintOptions)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -137,6 +162,7 @@ func newAttestArtifactCmd(out io.Writer) *cobra.Command {
 	cmd.Flags().StringToStringVar(&o.externalFingerprints, "external-fingerprint", map[string]string{}, externalFingerprintFlag)
 	cmd.Flags().StringToStringVar(&o.externalURLs, "external-url", map[string]string{}, externalURLFlag)
 	cmd.Flags().StringToStringVar(&o.annotations, "annotate", map[string]string{}, annotationFlag)
+	cmd.Flags().BoolVar(&o.platformAliases, "platform-aliases", false, platformAliasesFlag)
 	addFingerprintFlags(cmd, o.fingerprintOptions)
 
 	addDryRunFlag(cmd)
@@ -172,7 +198,14 @@ func (o *attestArtifactOptions) run(args []string) error {
 		return err
 	}
 
-	if o.payload.Fingerprint == "" {
+	if o.platformAliases {
+		digests, err := GetImageDigests(args[0], o.fingerprintOptions, logger)
+		if err != nil {
+			return err
+		}
+		o.payload.Fingerprint = digests.Digest
+		o.payload.Aliases = digests.Aliases()
+	} else if o.payload.Fingerprint == "" {
 		o.payload.Fingerprint, err = GetSha256Digest(args[0], o.fingerprintOptions, logger)
 		if err != nil {
 			return err
@@ -205,8 +238,8 @@ func (o *attestArtifactOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("artifact %s was attested with fingerprint: %s", o.payload.Filename, o.payload.Fingerprint)
 	}
 	return err
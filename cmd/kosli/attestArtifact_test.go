@@ -109,6 +109,24 @@ func (suite *AttestArtifactCommandTestSuite) TestAttestArtifactCmd() {
 			cmd:       fmt.Sprintf("attest artifact testdata/file1 --artifact-type file --redact-commit-info author,bar --name cli --commit HEAD --build-url http://www.example.com --commit-url http://www.example.com  %s", suite.defaultKosliArguments),
 			golden:    "Error: bar is not an allowed value for --redact-commit-info\n",
 		},
+		{
+			wantError: true,
+			name:      "fails when --platform-aliases is used for a file artifact",
+			cmd:       fmt.Sprintf("attest artifact testdata/file1 --artifact-type file --platform-aliases --name cli --commit HEAD --build-url http://www.example.com --commit-url http://www.example.com  %s", suite.defaultKosliArguments),
+			golden:    "Error: --platform-aliases is only applicable when --artifact-type is 'oci', 'oci-dir' or 'oci-archive'\nUsage: kosli attest artifact {IMAGE-NAME | FILE-PATH | DIR-PATH} [flags]\n",
+		},
+		{
+			wantError: true,
+			name:      "fails when --platform-aliases is used with --fingerprint",
+			cmd:       fmt.Sprintf("attest artifact ../../internal/digest/testdata/oci/oci-archive-multiarch.tar --artifact-type oci-archive --platform-aliases --fingerprint 4618160c5fe217365eb0236d8cc8f5a002cf715fb4e32f6eacd937e81409c685 --name cli --commit HEAD --build-url http://www.example.com --commit-url http://www.example.com  %s", suite.defaultKosliArguments),
+			golden:    "Error: only one of --fingerprint, --platform-aliases is allowed\n",
+		},
+		{
+			name: "can attest a multi-arch image with its platform digests as aliases",
+			cmd:  fmt.Sprintf("attest artifact ../../internal/digest/testdata/oci/oci-archive-multiarch.tar --artifact-type oci-archive --platform-aliases --dry-run --name cli --commit HEAD --build-url http://www.example.com --commit-url http://www.example.com  %s", suite.defaultKosliArguments),
+			goldenRegex: `(?s)"fingerprint": "4618160c5fe217365eb0236d8cc8f5a002cf715fb4e32f6eacd937e81409c685",\s+"fingerprint_aliases": \[\s+` +
+				`"b9e3f255ceb7dca22bd790f0f97524bc38b306eb6d5e2715f4c3bedc36c5de41",\s+"f1500da1532d875b3ac366ee57e40c1a2a48183fa5d3bc218aa1b09192fbaa6e"\s+\]`,
+		},
 	}
 
 	runTestCmd(suite.T(), tests)
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/kosli-dev/cli/internal/coverage"
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/spf13/cobra"
+)
+
+type CoverageAttestationPayload struct {
+	*CommonAttestationPayload
+	Compliant  *bool                  `json:"is_compliant,omitempty"`
+	Coverage   *coverage.CoverageData `json:"coverage"`
+	Thresholds *coverage.Evaluation   `json:"thresholds,omitempty"`
+}
+
+type attestCoverageOptions struct {
+	*CommonAttestationOptions
+	coverageReports   []string
+	format            string
+	uploadResultsFile bool
+	thresholds        coverage.Thresholds
+	payload           CoverageAttestationPayload
+}
+
+const attestCoverageShortDesc = `Report a code coverage attestation to an artifact or a trail in a Kosli flow.  `
+
+const attestCoverageLongDesc = attestCoverageShortDesc + `
+The ^--coverage-reports^ can be Cobertura XML (e.g. from coverage.py, gcovr or coverlet), LCOV (e.g. from c8, istanbul or
+cargo-llvm-cov), JaCoCo XML or Go cover profiles (^go test -coverprofile^). The format of each report is detected
+from its content unless ^--format^ is set.
+
+The coverage reports are combined into a summary which is reported to Kosli: the line and branch rates of all
+the reports, of each report, of each package and of each file. A line or a branch covered in any of the reports is covered.
+The lines of Go cover profiles are statements, and Go cover profiles have no branches.
+
+The compliance of the attestation is decided by the combined coverage when ^--min-line-coverage^ or ^--min-branch-coverage^
+are set: the attestation is non-compliant when the coverage is below one of the minimums. Otherwise, Kosli decides
+the compliance of the attestation.
+
+By default, the ^--coverage-reports^ are also uploaded to Kosli's evidence vault.
+You can disable that by setting ^--upload-results=false^
+` + attestationBindingDesc + `
+
+` + commitDescription
+
+const attestCoverageExample = `
+# report a coverage attestation about a pre-built docker artifact (kosli calculates the fingerprint):
+kosli attest coverage yourDockerImageName \
+	--artifact-type docker \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--coverage-reports yourCoverageReport \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report a coverage attestation about a pre-built docker artifact (you provide the fingerprint):
+kosli attest coverage \
+	--fingerprint yourDockerImageFingerprint \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--coverage-reports yourCoverageReport \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report a coverage attestation about a trail from the coverage reports of unit and integration tests:
+kosli attest coverage \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--coverage-reports unit-coverage.out,integration-coverage.out \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report a coverage attestation about a trail which is non-compliant below 80% line coverage or 60% branch coverage:
+kosli attest coverage \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--coverage-reports coverage.xml \
+	--min-line-coverage 80 \
+	--min-branch-coverage 60 \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report a coverage attestation about a trail without uploading the coverage reports:
+kosli attest coverage \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--coverage-reports lcov.info \
+	--upload-results=false \
+	--api-token yourAPIToken \
+	--org yourOrgName
+`
+
+func newAttestCoverageCmd(out io.Writer) *cobra.Command {
+	o := &attestCoverageOptions{
+		CommonAttestationOptions: &CommonAttestationOptions{
+			fingerprintOptions: &fingerprintOptions{},
+		},
+		payload: CoverageAttestationPayload{
+			CommonAttestationPayload: &CommonAttestationPayload{},
+		},
+	}
+	cmd := &cobra.Command{
+		// Args:    cobra.MaximumNArgs(1),  // See CustomMaximumNArgs() below
+		Use:     "coverage [IMAGE-NAME | FILE-PATH | DIR-PATH]",
+		Short:   attestCoverageShortDesc,
+		Long:    attestCoverageLongDesc,
+		Example: attestCoverageExample,
+		Hidden:  true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+
+			err := CustomMaximumNArgs(1, args)
+			if err != nil {
+				return err
+			}
+
+			err = RequireGlobalFlags(global, []string{"Org", "ApiToken"})
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+
+			err = MuXRequiredFlags(cmd, []string{"fingerprint", "artifact-type"}, false)
+			if err != nil {
+				return err
+			}
+
+			err = ValidateSliceValues(o.redactedCommitInfo, allowedCommitRedactionValues)
+			if err != nil {
+				return fmt.Errorf("%s for --redact-commit-info", err.Error())
+			}
+
+			err = coverage.ValidateFormat(o.format)
+			if err != nil {
+				return err
+			}
+
+			err = o.thresholds.Validate()
+			if err != nil {
+				return err
+			}
+
+			err = ValidateAttestationArtifactArg(args, o.fingerprintOptions.artifactType, o.payload.ArtifactFingerprint)
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+
+			return ValidateRegistryFlags(cmd, o.fingerprintOptions)
+
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(args)
+		},
+	}
+
+	ci := WhichCI()
+	addAttestationFlags(cmd, o.CommonAttestationOptions, o.payload.CommonAttestationPayload, ci)
+	cmd.Flags().StringSliceVarP(&o.coverageReports, "coverage-reports", "R", []string{}, coverageReportsFlag)
+	cmd.Flags().StringVar(&o.format, "format", "", coverageFormatFlag)
+	cmd.Flags().BoolVar(&o.uploadResultsFile, "upload-results", true, uploadCoverageResultsFlag)
+	cmd.Flags().Float64Var(&o.thresholds.MinLineCoverage, "min-line-coverage", 0, minLineCoverageFlag)
+	cmd.Flags().Float64Var(&o.thresholds.MinBranchCoverage, "min-branch-coverage", 0, minBranchCoverageFlag)
+
+	err := RequireFlags(cmd, []string{"flow", "trail", "name", "coverage-reports"})
+	if err != nil {
+		logger.Error("failed to configure required flags: %v", err)
+	}
+
+	return cmd
+}
+
+func (o *attestCoverageOptions) run(args []string) error {
+	url := fmt.Sprintf("%s/api/v2/attestations/%s/%s/trail/%s/coverage", global.Host, global.Org, o.flowName, o.trailName)
+
+	err := o.CommonAttestationOptions.run(args, o.payload.CommonAttestationPayload)
+	if err != nil {
+		return err
+	}
+
+	o.payload.Coverage, err = coverage.ProcessCoverageFiles(o.coverageReports, o.format)
+	if err != nil {
+		return err
+	}
+
+	if !o.thresholds.IsEmpty() {
+		o.payload.Thresholds = o.thresholds.Evaluate(o.payload.Coverage)
+		o.payload.Compliant = &o.payload.Thresholds.Compliant
+		if !o.payload.Thresholds.Compliant {
+			logger.Info("the attestation is non-compliant: %s", strings.Join(o.payload.Thresholds.Violations, ", "))
+		}
+	}
+
+	if o.uploadResultsFile {
+		o.attachments = append(o.attachments, o.coverageReports...)
+	}
+
+	form, cleanupNeeded, evidencePath, err := prepareAttestationForm(o.payload, o.attachments)
+	if err != nil {
+		return err
+	}
+	// if we created a tar package, remove it after uploading it
+	if cleanupNeeded {
+		defer os.Remove(evidencePath)
+	}
+
+	reqParams := &requests.RequestParams{
+		Method: http.MethodPost,
+		URL:    url,
+		Form:   form,
+		DryRun: global.DryRun,
+		Token:  global.ApiToken,
+	}
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
+		logger.Info("coverage attestation '%s' is reported to trail: %s", o.payload.AttestationName, o.trailName)
+	}
+	return wrapAttestationError(err)
+}
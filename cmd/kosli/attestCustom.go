@@ -178,8 +178,8 @@ func (o *attestCustomOptions) run(args []string) error {
 		DryRun: global.DryRun,
 		Token:  global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("custom:%s attestation '%s' is reported to trail: %s", o.payload.TypeName, o.payload.AttestationName, o.trailName)
 	}
 	return wrapAttestationError(err)
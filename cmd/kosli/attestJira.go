@@ -274,8 +274,8 @@ func (o *attestJiraOptions) run(args []string) error {
 		DryRun: global.DryRun,
 		Token:  global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("jira attestation '%s' is reported to trail: %s", o.payload.AttestationName, o.trailName)
 	}
 
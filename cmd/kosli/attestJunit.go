@@ -182,8 +182,8 @@ func (o *attestJunitOptions) run(args []string) error {
 		DryRun: global.DryRun,
 		Token:  global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("junit attestation '%s' is reported to trail: %s", o.payload.AttestationName, o.trailName)
 	}
 	return wrapAttestationError(err)
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/kosli-dev/cli/internal/sarif"
+	"github.com/kosli-dev/cli/internal/scanpolicy"
+	"github.com/spf13/cobra"
+)
+
+type SarifAttestationPayload struct {
+	*CommonAttestationPayload
+	Compliant    bool                   `json:"is_compliant"`
+	SarifResults *sarif.SarifData       `json:"sarif_results"`
+	Policy       *scanpolicy.Evaluation `json:"policy"`
+}
+
+type attestSarifOptions struct {
+	*CommonAttestationOptions
+	*scanPolicyOptions
+	sarifFilePath     string
+	uploadResultsFile bool
+	payload           SarifAttestationPayload
+}
+
+const attestSarifShortDesc = `Report a SARIF attestation to an artifact or a trail in a Kosli flow.  `
+
+const attestSarifLongDesc = attestSarifShortDesc + `
+Any SARIF 2.1.0 output is accepted, e.g. from CodeQL, Semgrep, Trivy, Checkov or Snyk.
+
+The ^--scan-results^ file is analyzed and a summary of the results of each tool run is reported to Kosli:
+the number of results per severity, per rule and per location, and the results themselves.
+Results with a suppression which is not rejected (e.g. a ^// nosemgrep^ comment) and results of another kind
+than ^fail^ (e.g. ^pass^) are only counted, as suppressed and not failing results, and do not affect compliance.
+The severity of a result is taken from, in order:
+  - the ^security-severity^ (CVSS score) property of the result or of its rule: critical from 9.0, high from 7.0,
+    medium from 4.0 and low below that.
+  - the level of the result: high for ^error^, medium for ^warning^, low for ^note^ and info for ^none^.
+  - the ^problem.severity^ property of its rule, with ^info^ as info.
+  - the default level of its rule.
+and defaults to medium.
+
+The attestation is non-compliant when any result has a severity at or above ^--fail-on^ (defaults to high),
+or when there are more results of a severity than its maximum in ^--max-findings^ (e.g. ^--max-findings medium=10,low=50^).
+Use ^--fail-on none^ and no ^--max-findings^ to always report a compliant attestation.
+` + scanSuppressionDesc + `
+
+By default, the ^--scan-results^ file is also uploaded to Kosli's evidence vault.
+You can disable that by setting ^--upload-results=false^
+` + attestationBindingDesc + `
+
+` + commitDescription
+
+const attestSarifExample = `
+# report a SARIF attestation about a pre-built docker artifact (kosli calculates the fingerprint):
+kosli attest sarif yourDockerImageName \
+	--artifact-type docker \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--scan-results yourSARIFScanResults \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report a SARIF attestation about a pre-built docker artifact (you provide the fingerprint):
+kosli attest sarif \
+	--fingerprint yourDockerImageFingerprint \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--scan-results yourSARIFScanResults \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report a SARIF attestation about a trail:
+kosli attest sarif \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--scan-results yourSARIFScanResults \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report a SARIF attestation about a trail which is only non-compliant if there are critical results:
+kosli attest sarif \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--scan-results yourSARIFScanResults \
+	--fail-on critical \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report a SARIF attestation about a trail which is non-compliant if there are high, critical or more than 10 medium results (ignoring those in a baseline file):
+kosli attest sarif \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--scan-results yourSARIFScanResults \
+	--max-findings medium=10 \
+	--baseline-file yourBaselineFile \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report a SARIF attestation about a trail without uploading the SARIF results file:
+kosli attest sarif \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--scan-results yourSARIFScanResults \
+	--upload-results=false \
+	--api-token yourAPIToken \
+	--org yourOrgName
+`
+
+func newAttestSarifCmd(out io.Writer) *cobra.Command {
+	o := &attestSarifOptions{
+		CommonAttestationOptions: &CommonAttestationOptions{
+			fingerprintOptions: &fingerprintOptions{},
+		},
+		scanPolicyOptions: &scanPolicyOptions{},
+		payload: SarifAttestationPayload{
+			CommonAttestationPayload: &CommonAttestationPayload{},
+		},
+	}
+	cmd := &cobra.Command{
+		// Args:    cobra.MaximumNArgs(1),  // See CustomMaximumNArgs() below
+		Use:     "sarif [IMAGE-NAME | FILE-PATH | DIR-PATH]",
+		Short:   attestSarifShortDesc,
+		Long:    attestSarifLongDesc,
+		Example: attestSarifExample,
+		Hidden:  true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+
+			err := CustomMaximumNArgs(1, args)
+			if err != nil {
+				return err
+			}
+
+			err = RequireGlobalFlags(global, []string{"Org", "ApiToken"})
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+
+			err = MuXRequiredFlags(cmd, []string{"fingerprint", "artifact-type"}, false)
+			if err != nil {
+				return err
+			}
+
+			err = ValidateSliceValues(o.redactedCommitInfo, allowedCommitRedactionValues)
+			if err != nil {
+				return fmt.Errorf("%s for --redact-commit-info", err.Error())
+			}
+
+			err = o.validate()
+			if err != nil {
+				return err
+			}
+
+			err = ValidateAttestationArtifactArg(args, o.fingerprintOptions.artifactType, o.payload.ArtifactFingerprint)
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+
+			return ValidateRegistryFlags(cmd, o.fingerprintOptions)
+
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(args)
+		},
+	}
+
+	ci := WhichCI()
+	addAttestationFlags(cmd, o.CommonAttestationOptions, o.payload.CommonAttestationPayload, ci)
+	cmd.Flags().StringVarP(&o.sarifFilePath, "scan-results", "R", "", sarifResultsFileFlag)
+	cmd.Flags().BoolVar(&o.uploadResultsFile, "upload-results", true, uploadSarifResultsFlag)
+	addScanPolicyFlags(cmd, o.scanPolicyOptions, sarif.SeverityHigh, sarifFailOnFlag)
+
+	err := RequireFlags(cmd, []string{"flow", "trail", "name", "scan-results"})
+	if err != nil {
+		logger.Error("failed to configure required flags: %v", err)
+	}
+
+	return cmd
+}
+
+func (o *attestSarifOptions) run(args []string) error {
+	url := fmt.Sprintf("%s/api/v2/attestations/%s/%s/trail/%s/sarif", global.Host, global.Org, o.flowName, o.trailName)
+
+	err := o.CommonAttestationOptions.run(args, o.payload.CommonAttestationPayload)
+	if err != nil {
+		return err
+	}
+
+	policy, err := o.loadPolicy()
+	if err != nil {
+		return err
+	}
+
+	o.payload.SarifResults, err = sarif.ProcessSarifFile(o.sarifFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse sarif results file [%s]: %s", o.sarifFilePath, err)
+	}
+	o.payload.Policy = evaluateScanPolicy(policy, scanpolicy.SarifFindings(o.payload.SarifResults))
+	o.payload.Compliant = o.payload.Policy.Compliant
+
+	if o.uploadResultsFile {
+		o.attachments = append(o.attachments, o.sarifFilePath)
+	}
+
+	form, cleanupNeeded, evidencePath, err := prepareAttestationForm(o.payload, o.attachments)
+	if err != nil {
+		return err
+	}
+	// if we created a tar package, remove it after uploading it
+	if cleanupNeeded {
+		defer os.Remove(evidencePath)
+	}
+
+	reqParams := &requests.RequestParams{
+		Method: http.MethodPost,
+		URL:    url,
+		Form:   form,
+		DryRun: global.DryRun,
+		Token:  global.ApiToken,
+	}
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
+		logger.Info("sarif attestation '%s' is reported to trail: %s", o.payload.AttestationName, o.trailName)
+	}
+	return wrapAttestationError(err)
+}
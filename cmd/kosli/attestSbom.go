@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/kosli-dev/cli/internal/sbom"
+	"github.com/spf13/cobra"
+)
+
+type SbomAttestationPayload struct {
+	*CommonAttestationPayload
+	Compliant             *bool                   `json:"is_compliant,omitempty"`
+	Sbom                  *sbom.SbomData          `json:"sbom"`
+	SubjectDigestMatched  bool                    `json:"subject_digest_matched"`
+	SubjectDigestMismatch bool                    `json:"subject_digest_mismatch"`
+	LicensePolicy         *sbom.LicenseEvaluation `json:"license_policy,omitempty"`
+}
+
+type attestSbomOptions struct {
+	*CommonAttestationOptions
+	sbomFilePath    string
+	allowedLicenses []string
+	deniedLicenses  []string
+	payload         SbomAttestationPayload
+}
+
+const attestSbomShortDesc = `Report an SBOM attestation to an artifact or a trail in a Kosli flow.  `
+
+const attestSbomLongDesc = attestSbomShortDesc + `
+The ^--sbom-file^ can be a CycloneDX (1.2 to 1.6, JSON or XML) or an SPDX (2.1 to 2.3, JSON or tag-value) document,
+e.g. generated by syft, trivy or cdxgen. It is validated and a summary of its components is reported to Kosli:
+the number of components, and the number of components per license and per ecosystem (the type of their package URL).
+The SBOM file is also uploaded to Kosli's evidence vault.
+
+When the attestation is about an artifact, the SHA-256 digest of the subject of the SBOM (the component in its metadata
+for CycloneDX, the package it describes for SPDX) is checked against the fingerprint of the artifact. The digests of the
+subject are its SHA-256 hashes (checksums for SPDX) and the digest in the version of its package URL
+(e.g. ^pkg:oci/app@sha256%3A...^), not its version: syft sets it to the image ID, which is not the fingerprint of the image.
+The attestation is non-compliant when none of the digests of the subject matches the fingerprint of the artifact.
+SBOMs without a SHA-256 digest of their subject are reported with a warning.
+
+The compliance of the attestation is also decided by the licenses of the components when ^--allowed-licenses^ or
+^--denied-licenses^ are set: the attestation is non-compliant when a component has a denied license, or when
+^--allowed-licenses^ is set and a component has another license or no license. The licenses of a component can be
+SPDX license expressions, which comply when one side of each ^OR^ complies. Otherwise, unless the subject does not match, Kosli decides the compliance of the attestation.
+` + attestationBindingDesc + `
+
+` + commitDescription
+
+const attestSbomExample = `
+# report an SBOM attestation about a pre-built docker artifact (kosli calculates the fingerprint):
+kosli attest sbom yourDockerImageName \
+	--artifact-type docker \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--sbom-file yourSBOMFile \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report an SBOM attestation about a pre-built docker artifact (you provide the fingerprint):
+kosli attest sbom \
+	--fingerprint yourDockerImageFingerprint \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--sbom-file yourSBOMFile \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report an SBOM attestation about a trail:
+kosli attest sbom \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--sbom-file yourSBOMFile \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report an SBOM attestation about a trail which is non-compliant if a component has a GPL or AGPL license:
+kosli attest sbom \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--sbom-file yourSBOMFile \
+	--denied-licenses GPL-2.0-only,GPL-3.0-only,AGPL-3.0-only \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report an SBOM attestation about a trail which is non-compliant unless all components have permissive licenses:
+kosli attest sbom \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--sbom-file yourSBOMFile \
+	--allowed-licenses MIT,Apache-2.0,BSD-2-Clause,BSD-3-Clause,ISC \
+	--api-token yourAPIToken \
+	--org yourOrgName
+`
+
+func newAttestSbomCmd(out io.Writer) *cobra.Command {
+	o := &attestSbomOptions{
+		CommonAttestationOptions: &CommonAttestationOptions{
+			fingerprintOptions: &fingerprintOptions{},
+		},
+		payload: SbomAttestationPayload{
+			CommonAttestationPayload: &CommonAttestationPayload{},
+		},
+	}
+	cmd := &cobra.Command{
+		// Args:    cobra.MaximumNArgs(1),  // See CustomMaximumNArgs() below
+		Use:     "sbom [IMAGE-NAME | FILE-PATH | DIR-PATH]",
+		Short:   attestSbomShortDesc,
+		Long:    attestSbomLongDesc,
+		Example: attestSbomExample,
+		Hidden:  true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+
+			err := CustomMaximumNArgs(1, args)
+			if err != nil {
+				return err
+			}
+
+			err = RequireGlobalFlags(global, []string{"Org", "ApiToken"})
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+
+			err = MuXRequiredFlags(cmd, []string{"fingerprint", "artifact-type"}, false)
+			if err != nil {
+				return err
+			}
+
+			err = ValidateSliceValues(o.redactedCommitInfo, allowedCommitRedactionValues)
+			if err != nil {
+				return fmt.Errorf("%s for --redact-commit-info", err.Error())
+			}
+
+			err = ValidateAttestationArtifactArg(args, o.fingerprintOptions.artifactType, o.payload.ArtifactFingerprint)
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+
+			return ValidateRegistryFlags(cmd, o.fingerprintOptions)
+
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(args)
+		},
+	}
+
+	ci := WhichCI()
+	addAttestationFlags(cmd, o.CommonAttestationOptions, o.payload.CommonAttestationPayload, ci)
+	cmd.Flags().StringVar(&o.sbomFilePath, "sbom-file", "", sbomFileFlag)
+	cmd.Flags().StringSliceVar(&o.allowedLicenses, "allowed-licenses", []string{}, sbomAllowedLicensesFlag)
+	cmd.Flags().StringSliceVar(&o.deniedLicenses, "denied-licenses", []string{}, sbomDeniedLicensesFlag)
+
+	err := RequireFlags(cmd, []string{"flow", "trail", "name", "sbom-file"})
+	if err != nil {
+		logger.Error("failed to configure required flags: %v", err)
+	}
+
+	return cmd
+}
+
+func (o *attestSbomOptions) run(args []string) error {
+	url := fmt.Sprintf("%s/api/v2/attestations/%s/%s/trail/%s/sbom", global.Host, global.Org, o.flowName, o.trailName)
+
+	err := o.CommonAttestationOptions.run(args, o.payload.CommonAttestationPayload)
+	if err != nil {
+		return err
+	}
+
+	o.payload.Sbom, err = sbom.ProcessSbomFile(o.sbomFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse SBOM file [%s]: %s", o.sbomFilePath, err)
+	}
+
+	nonCompliantReasons := []string{}
+	if o.payload.ArtifactFingerprint != "" {
+		subject := o.payload.Sbom.Subject
+		if subject == nil || len(subject.Digests) == 0 {
+			logger.Warning("the SBOM file [%s] has no SHA-256 digest of its subject to check against the artifact fingerprint", o.sbomFilePath)
+		} else if !o.payload.Sbom.HasSubjectDigest(o.payload.ArtifactFingerprint) {
+			o.payload.SubjectDigestMismatch = true
+			nonCompliantReasons = append(nonCompliantReasons, fmt.Sprintf("the SHA-256 digest of the subject of the SBOM (%s) does not match the artifact fingerprint %s",
+				strings.Join(subject.Digests, ", "), o.payload.ArtifactFingerprint))
+		} else {
+			o.payload.SubjectDigestMatched = true
+		}
+	}
+
+	policy := &sbom.LicensePolicy{Allowed: o.allowedLicenses, Denied: o.deniedLicenses}
+	if !policy.IsEmpty() {
+		o.payload.LicensePolicy = policy.Evaluate(o.payload.Sbom.Components)
+		if !o.payload.LicensePolicy.Compliant {
+			nonCompliantReasons = append(nonCompliantReasons, o.payload.LicensePolicy.Summary()...)
+		}
+	}
+	if !policy.IsEmpty() || o.payload.SubjectDigestMismatch {
+		compliant := len(nonCompliantReasons) == 0
+		o.payload.Compliant = &compliant
+		if !compliant {
+			logger.Info("the attestation is non-compliant: %s", strings.Join(nonCompliantReasons, ", "))
+		}
+	}
+
+	o.attachments = append(o.attachments, o.sbomFilePath)
+
+	form, cleanupNeeded, evidencePath, err := prepareAttestationForm(o.payload, o.attachments)
+	if err != nil {
+		return err
+	}
+	// if we created a tar package, remove it after uploading it
+	if cleanupNeeded {
+		defer os.Remove(evidencePath)
+	}
+
+	reqParams := &requests.RequestParams{
+		Method: http.MethodPost,
+		URL:    url,
+		Form:   form,
+		DryRun: global.DryRun,
+		Token:  global.ApiToken,
+	}
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
+		logger.Info("sbom attestation '%s' is reported to trail: %s", o.payload.AttestationName, o.trailName)
+	}
+	return wrapAttestationError(err)
+}
@@ -7,17 +7,21 @@ import (
 	"os"
 
 	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/kosli-dev/cli/internal/scanpolicy"
 	"github.com/kosli-dev/cli/internal/snyk"
 	"github.com/spf13/cobra"
 )
 
 type SnykAttestationPayload struct {
 	*CommonAttestationPayload
-	SnykResults *snyk.SnykData `json:"snyk_results"`
+	SnykResults *snyk.SnykData         `json:"snyk_results"`
+	Compliant   *bool                  `json:"is_compliant,omitempty"`
+	Policy      *scanpolicy.Evaluation `json:"policy,omitempty"`
 }
 
 type attestSnykOptions struct {
 	*CommonAttestationOptions
+	*scanPolicyOptions
 	snykSarifFilePath string
 	uploadResultsFile bool
 	payload           SnykAttestationPayload
@@ -26,10 +30,14 @@ type attestSnykOptions struct {
 const attestSnykShortDesc = `Report a snyk attestation to an artifact or a trail in a Kosli flow.  `
 
 const attestSnykLongDesc = attestSnykShortDesc + `
-Only SARIF snyk output is accepted. 
-Snyk output can be for "snyk code test", "snyk container test", or "snyk iac test".
+Snyk SARIF and JSON output are accepted.
+SARIF output can be for "snyk code test", "snyk container test", or "snyk iac test".
+JSON output can be for "snyk test" (also with ^--all-projects^) or "snyk container test".
 
 The ^--scan-results^ .json file is analyzed and a summary of the scan results are reported to Kosli.
+Snyk SARIF results are counted by the level of the result, or the ^problem.severity^ of its rule if it has no level:
+high for ^error^, medium for ^warning^ and low for ^note^ (as in the Snyk JSON to SARIF mapping).
+For Snyk JSON results, each vulnerability is reported once per project.
 
 By default, the ^--scan-results^ .json file is also uploaded to Kosli's evidence vault.
 You can disable that by setting ^--upload-results=false^
@@ -77,6 +85,15 @@ kosli attest snyk \
 	--api-token yourAPIToken \
 	--org yourOrgName
 
+# report a snyk attestation about a trail from the JSON output of snyk test --all-projects:
+kosli attest snyk \
+	--name yourAttestationName \
+	--flow yourFlowName \
+	--trail yourTrailName \
+	--scan-results yourSnykJSONScanResults \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
 # report a snyk attestation about a trail with an attachment:
 kosli attest snyk \
 	--name yourAttestationName \
@@ -103,6 +120,7 @@ func newAttestSnykCmd(out io.Writer) *cobra.Command {
 		CommonAttestationOptions: &CommonAttestationOptions{
 			fingerprintOptions: &fingerprintOptions{},
 		},
+		scanPolicyOptions: &scanPolicyOptions{},
 		payload: SnykAttestationPayload{
 			CommonAttestationPayload: &CommonAttestationPayload{},
 		},
@@ -135,6 +153,11 @@ func newAttestSnykCmd(out io.Writer) *cobra.Command {
 				return fmt.Errorf("%s for --redact-commit-info", err.Error())
 			}
 
+			err = o.validate()
+			if err != nil {
+				return err
+			}
+
 			err = ValidateAttestationArtifactArg(args, o.fingerprintOptions.artifactType, o.payload.ArtifactFingerprint)
 			if err != nil {
 				return ErrorBeforePrintingUsage(cmd, err.Error())
@@ -150,8 +173,15 @@ func newAttestSnykCmd(out io.Writer) *cobra.Command {
 
 	ci := WhichCI()
 	addAttestationFlags(cmd, o.CommonAttestationOptions, o.payload.CommonAttestationPayload, ci)
-	cmd.Flags().StringVarP(&o.snykSarifFilePath, "scan-results", "R", "", snykSarifResultsFileFlag)
+	cmd.Flags().StringVarP(&o.snykSarifFilePath, "scan-results", "R", "", snykResultsFileFlag)
 	cmd.Flags().BoolVar(&o.uploadResultsFile, "upload-results", true, uploadSnykResultsFlag)
+	addScanPolicyFlags(cmd, o.scanPolicyOptions, "", snykFailOnFlag)
+	// the compliance policy flags are hidden until the snyk attestation endpoint accepts is_compliant and policy
+	for _, name := range []string{"fail-on", "max-findings", "scan-ignore-file", "baseline-file"} {
+		if err := cmd.Flags().MarkHidden(name); err != nil {
+			logger.Error("failed to hide flag %s: %v", name, err)
+		}
+	}
 
 	err := RequireFlags(cmd, []string{"flow", "trail", "name", "scan-results"})
 	if err != nil {
@@ -169,9 +199,18 @@ func (o *attestSnykOptions) run(args []string) error {
 		return err
 	}
 
+	policy, err := o.loadPolicy()
+	if err != nil {
+		return err
+	}
+
 	o.payload.SnykResults, err = snyk.ProcessSnykResultFile(o.snykSarifFilePath)
 	if err != nil {
-		return fmt.Errorf("failed to parse Snyk sarif results file [%s]: %s", o.snykSarifFilePath, err)
+		return fmt.Errorf("failed to parse Snyk results file [%s]: %s", o.snykSarifFilePath, err)
+	}
+	if policy.HasThresholds() {
+		o.payload.Policy = evaluateScanPolicy(policy, scanpolicy.SnykFindings(o.payload.SnykResults))
+		o.payload.Compliant = &o.payload.Policy.Compliant
 	}
 
 	if o.uploadResultsFile {
@@ -194,8 +233,8 @@ func (o *attestSnykOptions) run(args []string) error {
 		DryRun: global.DryRun,
 		Token:  global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("snyk attestation '%s' is reported to trail: %s", o.payload.AttestationName, o.trailName)
 	}
 	return wrapAttestationError(err)
@@ -124,6 +124,39 @@ func (suite *AttestSnykCommandTestSuite) TestAttestSnykCmd() {
 				--scan-results testdata/snyk_sarif.json %s`, suite.defaultKosliArguments),
 			golden: "Error: --annotate flag should be in the format key=value. Invalid key: 'foo.baz'. Key can only contain [A-Za-z0-9_].\n",
 		},
+		{
+			name:   "can attest snyk JSON results against a trail",
+			cmd:    fmt.Sprintf("attest snyk --name bar --commit HEAD --origin-url https://example.com --scan-results testdata/snyk_scan_example.json %s", suite.defaultKosliArguments),
+			golden: "snyk attestation 'bar' is reported to trail: test-123\n",
+		},
+		{
+			wantError:   true,
+			name:        "fails when --scan-results is neither Snyk SARIF nor JSON results",
+			cmd:         fmt.Sprintf("attest snyk --name bar --commit HEAD --origin-url https://example.com --scan-results testdata/valid_template.yml %s", suite.defaultKosliArguments),
+			goldenRegex: "^Error: failed to parse Snyk results file \\[testdata/valid_template.yml\\]: .*\n",
+		},
+		{
+			wantError: true,
+			name:      "fails when --fail-on is not a severity",
+			cmd:       fmt.Sprintf("attest snyk --name bar --commit HEAD --origin-url https://example.com --scan-results testdata/snyk_sarif.json --fail-on error %s", suite.defaultKosliArguments),
+			golden:    "Error: invalid severity \"error\", the allowed severities are: critical, high, medium, low, info, none for --fail-on\n",
+		},
+		{
+			name:        "can attest snyk with results at or above --fail-on against a trail",
+			cmd:         fmt.Sprintf("attest snyk --name bar --commit HEAD --origin-url https://example.com --scan-results testdata/snyk_sarif.json --fail-on medium --dry-run %s", suite.defaultKosliArguments),
+			goldenRegex: `(?s)^the attestation is non-compliant: 9 findings have a severity at or above medium\n.*/api/v2/attestations/docs-cmd-test-user/attest-snyk/trail/test-123/snyk\n.*"attestation_name": "bar"`,
+		},
+		{
+			wantError: true,
+			name:      "fails when --scan-ignore-file is used without --fail-on or --max-findings",
+			cmd:       fmt.Sprintf("attest snyk --name bar --commit HEAD --origin-url https://example.com --scan-results testdata/snyk_scan_example.json --scan-ignore-file testdata/snyk_ignore.json %s", suite.defaultKosliArguments),
+			golden:    "Error: --scan-ignore-file and --baseline-file can only be used with --fail-on or --max-findings\n",
+		},
+		{
+			name:        "can attest snyk JSON results with an expired ignore rule against a trail",
+			cmd:         fmt.Sprintf("attest snyk --name bar --commit HEAD --origin-url https://example.com --scan-results testdata/snyk_scan_example.json --scan-ignore-file testdata/snyk_ignore.json --fail-on critical --dry-run %s", suite.defaultKosliArguments),
+			goldenRegex: `(?s)^\[warning\] the ignore rule of SNYK-ALPINE312-APKTOOLS-1246338 expired on 2024-06-30 and is not applied\nthe attestation is non-compliant: 6 findings have a severity at or above critical\n.*/api/v2/attestations/docs-cmd-test-user/attest-snyk/trail/test-123/snyk\n.*"attestation_name": "bar"`,
+		},
 	}
 
 	runTestCmd(suite.T(), tests)
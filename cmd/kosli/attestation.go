@@ -7,8 +7,36 @@ import (
 
 	"github.com/kosli-dev/cli/internal/gitview"
 	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/kosli-dev/cli/internal/scanpolicy"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
 )
 
+const scanSuppressionDesc = `
+Findings can be suppressed so that they do not count for compliance, with:
+  - an ignore file (^--scan-ignore-file^) listing the rule IDs, vulnerability IDs or CVEs to ignore, with the reason
+    and optionally an expiry date from which they are not ignored anymore.
+  - a baseline file (^--baseline-file^) listing the previously accepted findings by ID and optionally by file.
+The ignore and baseline files can only be used with ^--fail-on^ or ^--max-findings^.
+The suppressed findings and the reasons of their suppression are reported in the attestation.
+
+This is an example YAML ignore file:
+` + "```yaml\n" + `version: 1
+ignore:
+  - id: CVE-2020-8203
+    reason: the vulnerable function is not called
+    expires: 2025-06-30
+  - id: js/unused-local-variable
+    reason: not a security issue` + "\n```" + `
+
+This is an example YAML baseline file:
+` + "```yaml\n" + `version: 1
+findings:
+  - id: SNYK-JS-MINIMIST-559764
+    location: package-lock.json
+    reason: accepted in the security review of 2024-Q4` + "\n```" + `
+`
+
 const commitDescription = `You can optionally associate the attestation to a git commit using ^--commit^ (requires access to a git repo). And you  
 can optionally redact some of the git commit data sent to Kosli using ^--redact-commit-info^. 
 Note that when the attestation is reported for an artifact that does not yet exist in Kosli, ^--commit^ becomes required to facilitate 
@@ -95,6 +123,75 @@ func (o *CommonAttestationOptions) run(args []string, payload *CommonAttestation
 	return err
 }
 
+// scanPolicyOptions are the options of the compliance policy of security scan attestations
+type scanPolicyOptions struct {
+	failOn       string
+	maxFindings  map[string]int
+	ignoreFile   string
+	baselineFile string
+}
+
+// policy returns the compliance policy of the options, without the ignore and baseline files
+func (o *scanPolicyOptions) policy() *scanpolicy.Policy {
+	return &scanpolicy.Policy{
+		FailOn:      o.failOn,
+		MaxFindings: o.maxFindings,
+	}
+}
+
+// validate checks the severities of the policy, and that the ignore and baseline files are only used
+// when the policy decides compliance, since they would have no effect otherwise
+func (o *scanPolicyOptions) validate() error {
+	policy := o.policy()
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if !policy.HasThresholds() && (o.ignoreFile != "" || o.baselineFile != "") {
+		return fmt.Errorf("--scan-ignore-file and --baseline-file can only be used with --fail-on or --max-findings")
+	}
+	return nil
+}
+
+// loadPolicy returns the compliance policy of the options with the content of the ignore and baseline files
+func (o *scanPolicyOptions) loadPolicy() (*scanpolicy.Policy, error) {
+	policy := o.policy()
+	// the expiry dates of ignore files are strings in JSON files
+	decodeDates := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		mapstructure.StringToTimeHookFunc("2006-01-02"),
+	))
+	if o.ignoreFile != "" {
+		policy.Ignore = new(scanpolicy.IgnoreSpec)
+		if err := processSpecFile(o.ignoreFile, "ignore", policy.Ignore, decodeDates); err != nil {
+			return nil, err
+		}
+	}
+	if o.baselineFile != "" {
+		policy.Baseline = new(scanpolicy.BaselineSpec)
+		if err := processSpecFile(o.baselineFile, "baseline", policy.Baseline); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// evaluateScanPolicy evaluates the findings of a security scan against a compliance policy and logs why
+// findings were suppressed and why the scan is non-compliant
+func evaluateScanPolicy(policy *scanpolicy.Policy, findings []scanpolicy.Finding) *scanpolicy.Evaluation {
+	evaluation := policy.Evaluate(findings)
+	for _, rule := range evaluation.ExpiredIgnores {
+		logger.Warning("the ignore rule of %s expired on %s and is not applied", rule.ID, rule.Expires.Format("2006-01-02"))
+	}
+	if len(evaluation.Suppressed) > 0 {
+		logger.Info("%d findings are suppressed by the ignore and baseline files", len(evaluation.Suppressed))
+	}
+	if !evaluation.Compliant {
+		logger.Info("the attestation is non-compliant: %s", strings.Join(evaluation.Violations, ", "))
+	}
+	return evaluation
+}
+
 func processAnnotations(annotations map[string]string) (map[string]string, error) {
 	for label := range annotations {
 		if !regexp.MustCompile(`^[A-Za-z0-9_]+$`).MatchString(label) {
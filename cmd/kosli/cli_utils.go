@@ -18,6 +18,7 @@ import (
 	"github.com/kosli-dev/cli/internal/digest"
 	"github.com/kosli-dev/cli/internal/gitview"
 	log "github.com/kosli-dev/cli/internal/logger"
+	"github.com/kosli-dev/cli/internal/requests"
 	"github.com/kosli-dev/cli/internal/utils"
 	cp "github.com/otiai10/copy"
 	"github.com/spf13/cobra"
@@ -310,7 +311,7 @@ func GetFlagFromVarName(varName string) string {
 }
 
 // GetSha256Digest calculates the sha256 digest of an artifact.
-// Supported artifact types are: dir, file, docker
+// Supported artifact types are: dir, file, oci, docker, oci-dir, oci-archive
 func GetSha256Digest(artifactName string, o *fingerprintOptions, logger *log.Logger) (string, error) {
 	var err error
 	var fingerprint string
@@ -318,9 +319,13 @@ func GetSha256Digest(artifactName string, o *fingerprintOptions, logger *log.Log
 	case "file":
 		fingerprint, err = digest.FileSha256(artifactName)
 	case "dir":
-		fingerprint, err = digest.DirSha256(artifactName, o.excludePaths, logger)
-	case "oci":
-		fingerprint, err = digest.OciSha256(artifactName, o.registryUsername, o.registryPassword)
+		fingerprint, err = digest.DirSha256(artifactName, o.excludePaths, o.ignoreFile, logger)
+	case "oci", "oci-dir", "oci-archive":
+		var digests *digest.ImageDigests
+		digests, err = GetImageDigests(artifactName, o, logger)
+		if err == nil {
+			fingerprint = digests.Digest
+		}
 	case "docker":
 		if o.registryUsername != "" {
 			fingerprint, err = digest.OciSha256(artifactName, o.registryUsername, o.registryPassword)
@@ -335,6 +340,34 @@ func GetSha256Digest(artifactName string, o *fingerprintOptions, logger *log.Log
 	return fingerprint, err
 }
 
+// GetImageDigests gets the digests of a container image artifact, including the digests of the
+// platform manifests of a multi-arch image. The fingerprint is the digest of the image index,
+// unless a platform is selected with --platform.
+// Supported artifact types are: oci, oci-dir, oci-archive
+func GetImageDigests(artifactName string, o *fingerprintOptions, logger *log.Logger) (*digest.ImageDigests, error) {
+	var err error
+	var digests *digest.ImageDigests
+	switch o.artifactType {
+	case "oci":
+		digests, err = digest.OciImageDigests(artifactName, o.registryUsername, o.registryPassword, o.platform)
+	case "oci-dir":
+		digests, err = digest.OciLayoutImageDigests(artifactName, o.platform)
+	case "oci-archive":
+		digests, err = digest.OciArchiveImageDigests(artifactName, o.platform)
+	default:
+		return nil, fmt.Errorf("%s is not a container image artifact type", o.artifactType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if digests.IndexDigest != "" {
+		logger.Debug("artifact: %s is a multi-arch image with index digest: %s and platform digests: %v",
+			artifactName, digests.IndexDigest, digests.PlatformDigests)
+	}
+	return digests, nil
+}
+
 // LoadJsonData loads json data from a file
 func LoadJsonData(filepath string) (interface{}, error) {
 	var err error
@@ -423,6 +456,9 @@ func ValidateRegistryFlags(cmd *cobra.Command, o *fingerprintOptions) error {
 	if (o.registryPassword == "" && o.registryUsername != "") || (o.registryPassword != "" && o.registryUsername == "") {
 		return ErrorBeforePrintingUsage(cmd, "--registry-username and registry-password must both be set")
 	}
+	if o.platform != "" && o.artifactType != "oci" && o.artifactType != "oci-dir" && o.artifactType != "oci-archive" {
+		return ErrorBeforePrintingUsage(cmd, "--platform is only applicable when --artifact-type is 'oci', 'oci-dir' or 'oci-archive'")
+	}
 	return nil
 }
 
@@ -445,6 +481,20 @@ func ErrorBeforePrintingUsage(cmd *cobra.Command, errMsg string) error {
 	)
 }
 
+// requestReported returns true if a request sent with kosliClient.Do was delivered to Kosli,
+// so that the calling command can log what was reported. A request queued in the outbox
+// was not delivered yet, which is logged instead.
+func requestReported(res *requests.HTTPResponse, err error) bool {
+	if err != nil || global.DryRun {
+		return false
+	}
+	if res != nil && res.Queued {
+		logger.Info("nothing was reported to Kosli yet, the request will be sent when the outbox is replayed")
+		return false
+	}
+	return true
+}
+
 // tabFormattedPrint prints data in a tabular format. Takes header titles in a string slice
 // and rows as a slice of strings
 func tabFormattedPrint(out io.Writer, header []string, rows []string) {
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	log "github.com/kosli-dev/cli/internal/logger"
+	"github.com/kosli-dev/cli/internal/requests"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -1081,3 +1083,45 @@ func TestValidateSliceValues(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestReported(t *testing.T) {
+	global = &GlobalOpts{}
+	defer func() { logger = log.NewStandardLogger() }()
+
+	tests := []struct {
+		name    string
+		res     *requests.HTTPResponse
+		err     error
+		dryRun  bool
+		want    bool
+		wantLog string
+	}{
+		{
+			name: "a delivered request is reported",
+			res:  &requests.HTTPResponse{},
+			want: true,
+		},
+		{
+			name: "a failed request is not reported",
+			err:  fmt.Errorf("failed"),
+		},
+		{
+			name:   "a dry run request is not reported",
+			dryRun: true,
+		},
+		{
+			name:    "a request queued in the outbox is not reported",
+			res:     &requests.HTTPResponse{Queued: true},
+			wantLog: "nothing was reported to Kosli yet, the request will be sent when the outbox is replayed\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			logger = log.NewLogger(out, out, false)
+			global.DryRun = tt.dryRun
+			require.Equal(t, tt.want, requestReported(tt.res, tt.err))
+			require.Equal(t, tt.wantLog, out.String())
+		})
+	}
+}
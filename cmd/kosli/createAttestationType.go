@@ -109,8 +109,8 @@ func (o *createAttestationTypeOptions) run(args []string) error {
 		DryRun: global.DryRun,
 		Token:  global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("attestation-type %s was created", o.payload.TypeName)
 	}
 	return err
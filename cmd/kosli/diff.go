@@ -18,6 +18,7 @@ func newDiffCmd(out io.Writer) *cobra.Command {
 	// Add subcommands
 	cmd.AddCommand(
 		newDiffSnapshotsCmd(out),
+		newDiffFingerprintsCmd(out),
 	)
 	return cmd
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+const diffFingerprintsShortDesc = `Compare the fingerprints of two directories and list the entries that differ.`
+
+const diffFingerprintsLongDesc = diffFingerprintsShortDesc + `
+Each argument can be a directory or a manifest saved with ^kosli fingerprint --artifact-type dir --explain --output json^.
+Directories are fingerprinted using ^--exclude^, ^--ignore-file^ and their ^.kosli_ignore^ files.
+An entry is reported as added, removed or modified when it differs between the two, and as excluded or included
+when it is only excluded on one side. Entries inside directories excluded on either side are not compared.`
+
+const diffFingerprintsExample = `
+# compare two directories
+kosli diff fingerprints build-1/dist build-2/dist
+
+# compare a saved manifest with a directory
+kosli fingerprint --artifact-type dir --explain --output json dist > manifest.json
+kosli diff fingerprints manifest.json dist
+
+# compare two directories while excluding paths ^logs^
+kosli diff fingerprints build-1/dist build-2/dist --exclude logs
+`
+
+type diffFingerprintsOptions struct {
+	excludePaths []string
+	ignoreFile   string
+	output       string
+}
+
+// fingerprintsDiff is the output of comparing two directory fingerprints
+type fingerprintsDiff struct {
+	OldFingerprint string                   `json:"old_fingerprint"`
+	NewFingerprint string                   `json:"new_fingerprint"`
+	Changes        []*digest.ManifestChange `json:"changes"`
+}
+
+func newDiffFingerprintsCmd(out io.Writer) *cobra.Command {
+	o := new(diffFingerprintsOptions)
+	cmd := &cobra.Command{
+		Use:     "fingerprints {DIR-PATH | MANIFEST-FILE} {DIR-PATH | MANIFEST-FILE}",
+		Short:   diffFingerprintsShortDesc,
+		Long:    diffFingerprintsLongDesc,
+		Example: diffFingerprintsExample,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(args, out)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&o.excludePaths, "exclude", "x", []string{}, excludePathsFlag)
+	cmd.Flags().StringVar(&o.ignoreFile, "ignore-file", "", ignoreFileFlag)
+	cmd.Flags().StringVarP(&o.output, "output", "o", "table", outputFlag)
+
+	return cmd
+}
+
+func (o *diffFingerprintsOptions) run(args []string, out io.Writer) error {
+	oldManifest, err := o.loadManifest(args[0])
+	if err != nil {
+		return err
+	}
+	newManifest, err := o.loadManifest(args[1])
+	if err != nil {
+		return err
+	}
+
+	diff := &fingerprintsDiff{
+		OldFingerprint: oldManifest.Fingerprint,
+		NewFingerprint: newManifest.Fingerprint,
+		Changes:        digest.DiffDirManifests(oldManifest, newManifest),
+	}
+	raw, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	return output.FormattedPrint(string(raw), o.output, out, 0,
+		map[string]output.FormatOutputFunc{
+			"table": printFingerprintsDiffAsTable,
+			"json":  output.PrintJson,
+		})
+}
+
+// loadManifest fingerprints a directory or loads a saved manifest file
+func (o *diffFingerprintsOptions) loadManifest(path string) (*digest.DirManifest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return digest.DirManifestSha256(path, o.excludePaths, o.ignoreFile, logger)
+	}
+	return digest.LoadDirManifest(path)
+}
+
+func printFingerprintsDiffAsTable(raw string, out io.Writer, page int) error {
+	var diff fingerprintsDiff
+	err := json.Unmarshal([]byte(raw), &diff)
+	if err != nil {
+		return err
+	}
+
+	if len(diff.Changes) == 0 {
+		logger.Info("No differences were found. Fingerprint: %s", diff.NewFingerprint)
+		return nil
+	}
+
+	header := []string{"CHANGE", "PATH", "OLD", "NEW"}
+	rows := []string{}
+	for _, change := range diff.Changes {
+		rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%s", change.Change, change.Path,
+			describeManifestEntry(change.Old), describeManifestEntry(change.New)))
+	}
+	tabFormattedPrint(out, header, rows)
+	fmt.Fprintf(out, "\nOld fingerprint: %s\nNew fingerprint: %s\n", diff.OldFingerprint, diff.NewFingerprint)
+	return nil
+}
+
+// describeManifestEntry returns a short description of a manifest entry for table output
+func describeManifestEntry(entry *digest.ManifestEntry) string {
+	switch {
+	case entry == nil:
+		return "-"
+	case entry.ExcludedBy != "":
+		return fmt.Sprintf("excluded by %s", entry.ExcludedBy)
+	case entry.ContentDigest != "":
+		return fmt.Sprintf("%s %s", entry.Type, entry.ContentDigest)
+	default:
+		return entry.Type
+	}
+}
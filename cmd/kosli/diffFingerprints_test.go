@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type DiffFingerprintsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *DiffFingerprintsTestSuite) TestDiffFingerprintsCmd() {
+	tests := []cmdTestCase{
+		{
+			name:   "identical dirs have no differences",
+			cmd:    "diff fingerprints testdata/folder1 testdata/folder1",
+			golden: "No differences were found. Fingerprint: c43808cb04c6e66c4c6fc1f972dd67c3b9b71c81e0a0c78730da3699922d17be\n",
+		},
+		{
+			name: "dirs with differences list the changed entries",
+			cmd:  "diff fingerprints testdata/folder1 testdata/folder1-with-ignore",
+			golden: "CHANGE    PATH           OLD  NEW\n" +
+				"added     .kosli_ignore  -    file a5fb76b68afc913eaa97a94c25b01d301a2527face29b296cd6b6c4b8d73b491\n" +
+				"excluded  folder2        dir  excluded by ignore-file\n" +
+				"\nOld fingerprint: c43808cb04c6e66c4c6fc1f972dd67c3b9b71c81e0a0c78730da3699922d17be\n" +
+				"New fingerprint: 038897ea5334462098d65125380d58a493671fb3b8bdbbee1e75ec8bd4a65c23\n",
+		},
+		{
+			name:        "dirs with differences in json",
+			cmd:         "diff fingerprints testdata/folder1 testdata/folder1-with-ignore --output json",
+			goldenRegex: `(?s)"old_fingerprint": "c43808cb04c6e66c4c6fc1f972dd67c3b9b71c81e0a0c78730da3699922d17be",\s+"new_fingerprint": "038897ea5334462098d65125380d58a493671fb3b8bdbbee1e75ec8bd4a65c23",\s+"changes": \[.*"path": "folder2",\s+"change": "excluded"`,
+		},
+		{
+			name:   "excluded paths apply to both dirs",
+			cmd:    "diff fingerprints testdata/folder1 testdata/folder1-with-ignore --exclude folder2,.kosli_ignore",
+			golden: "No differences were found. Fingerprint: 773fd3300860454a2b065c5912c03008adb11e6a6dcf7c1c64c094ceab8f430a\n",
+		},
+		{
+			wantError: true,
+			name:      "fails if a file is not a valid manifest",
+			cmd:       "diff fingerprints testdata/file1 testdata/folder1",
+			golden:    "Error: testdata/file1 is not a valid fingerprint manifest: invalid character 'h' looking for beginning of value\n",
+		},
+		{
+			wantError: true,
+			name:      "fails if a path does not exist",
+			cmd:       "diff fingerprints testdata/does-not-exist testdata/folder1",
+			golden:    "Error: stat testdata/does-not-exist: no such file or directory\n",
+		},
+		{
+			wantError: true,
+			name:      "fails if only one argument is provided",
+			cmd:       "diff fingerprints testdata/folder1",
+			golden:    "Error: accepts 2 arg(s), received 1\n",
+		},
+	}
+
+	runTestCmd(suite.T(), tests)
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestDiffFingerprintsTestSuite(t *testing.T) {
+	suite.Run(t, new(DiffFingerprintsTestSuite))
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/output"
 	"github.com/spf13/cobra"
 )
 
@@ -21,12 +25,29 @@ plus the ability to use recursive globs "**"
 const fingerprintLongDesc = fingerprintShortDesc + `
 Requires ^--artifact-type^ flag to be set.
 Artifact type can be one of: "file" for files, "dir" for directories, "oci" for container
-images in registries or "docker" for local docker images.
+images in registries, "docker" for local docker images, "oci-dir" for container images in OCI image
+layout directories or "oci-archive" for tarballs of OCI image layouts.
 
 Fingerprinting container images can be done using the local docker daemon or the fingerprint can be fetched
 from a remote registry.
+Images built with buildkit, kaniko or skopeo can also be fingerprinted offline from an OCI image layout, either as
+a directory ("oci-dir") or a (optionally gzipped) tarball ("oci-archive"), such as the output of
+^docker buildx build --output type=oci^ or ^docker save^ (docker 25 or later). The fingerprint is the digest of the
+image manifest (or image index), which is the digest the registry reports once the image is pushed.
+Tarballs created by ^docker save^ before docker 25 have no OCI image layout and cannot be fingerprinted, because
+the digest of their ^manifest.json^ is not the digest the registry reports once the image is pushed.
+If the layout contains several images, select one by appending ^:REFERENCE^ (the ^org.opencontainers.image.ref.name^
+annotation in ^index.json^) to the path.
 
-` + fingerprintDirSynopsis
+The fingerprint of a multi-arch image ("oci", "oci-dir" or "oci-archive") is the digest of its image index, which is
+what the registry reports for the image tag. Use ^--platform^ (e.g. ^linux/arm64^) to get the digest of the manifest
+for one platform instead, which is what a node running that platform reports when it pulls the image.
+
+` + fingerprintDirSynopsis + `
+
+Use ^--explain^ to print the manifest a 'dir' fingerprint is calculated from: the relative path, type and content
+digest of each entry, and whether it is excluded by ^--exclude^ or a ^.kosli_ignore^ file. Save the manifest with
+^--explain --output json^ and compare it with ^kosli diff fingerprints^ to find out why two fingerprints differ.`
 
 const fingerprintExamples = `
 # fingerprint a file
@@ -45,6 +66,15 @@ kosli fingerprint --artifact-type dir  --exclude **/*.pyc mydir
 echo bar/file.txt > mydir/.kosli_ignore
 kosli fingerprint --artifact-type dir mydir
 
+# fingerprint a dir while excluding paths in an ignore file outside the dir
+kosli fingerprint --artifact-type dir --ignore-file path/to/ignore-file mydir
+
+# explain which entries a dir fingerprint is calculated from
+kosli fingerprint --artifact-type dir --explain mydir
+
+# save the manifest of a dir fingerprint to compare it later with ^kosli diff fingerprints^
+kosli fingerprint --artifact-type dir --explain --output json mydir > manifest.json
+
 # fingerprint a locally available docker image (requires docker daemon running)
 kosli fingerprint --artifact-type docker nginx:latest
 
@@ -53,6 +83,19 @@ kosli fingerprint --artifact-type oci nginx:latest
 
 # fingerprint a private image from a remote registry
 kosli fingerprint --artifact-type oci private:latest --registry-username YourUsername --registry-password YourPassword
+
+# fingerprint an image in an OCI image layout directory
+kosli fingerprint --artifact-type oci-dir build/image
+
+# fingerprint an image in a tarball of an OCI image layout (no docker daemon or registry needed)
+docker buildx build --output type=oci,dest=image.tar .
+kosli fingerprint --artifact-type oci-archive image.tar
+
+# fingerprint the image with reference 'v1' in a tarball of an OCI image layout containing several images
+kosli fingerprint --artifact-type oci-archive images.tar:v1
+
+# fingerprint the linux/arm64 image of a multi-arch image in a remote registry
+kosli fingerprint --artifact-type oci nginx:latest --platform linux/arm64
 `
 
 type fingerprintOptions struct {
@@ -61,10 +104,18 @@ type fingerprintOptions struct {
 	registryUsername string
 	registryPassword string
 	excludePaths     []string
+	ignoreFile       string
+	platform         string
+}
+
+type fingerprintCmdOptions struct {
+	fingerprintOptions
+	explain bool
+	output  string
 }
 
 func newFingerprintCmd(out io.Writer) *cobra.Command {
-	o := new(fingerprintOptions)
+	o := new(fingerprintCmdOptions)
 	cmd := &cobra.Command{
 		Use:     "fingerprint {IMAGE-NAME | FILE-PATH | DIR-PATH}",
 		Short:   fingerprintShortDesc,
@@ -72,15 +123,23 @@ func newFingerprintCmd(out io.Writer) *cobra.Command {
 		Example: fingerprintExamples,
 		Args:    cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			return ValidateRegistryFlags(cmd, o)
+			if o.explain && o.artifactType != "dir" {
+				return fmt.Errorf("--explain is only supported for --artifact-type dir")
+			}
+			if cmd.Flags().Changed("output") && !o.explain {
+				return fmt.Errorf("--output is only allowed when --explain is set")
+			}
+			return ValidateRegistryFlags(cmd, &o.fingerprintOptions)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return o.run(args, out)
 		},
 	}
 
-	addFingerprintFlags(cmd, o)
+	addFingerprintFlags(cmd, &o.fingerprintOptions)
 	cmd.Flags().StringSliceVarP(&o.excludePaths, "e", "e", []string{}, excludePathsFlag)
+	cmd.Flags().BoolVar(&o.explain, "explain", false, fingerprintExplainFlag)
+	cmd.Flags().StringVarP(&o.output, "output", "o", "table", outputFlag)
 	err := RequireFlags(cmd, []string{"artifact-type"})
 	if err != nil {
 		logger.Error("failed to configure required flags: %v", err)
@@ -97,11 +156,57 @@ func newFingerprintCmd(out io.Writer) *cobra.Command {
 	return cmd
 }
 
-func (o *fingerprintOptions) run(args []string, out io.Writer) error {
-	fingerprint, err := GetSha256Digest(args[0], o, logger)
+func (o *fingerprintCmdOptions) run(args []string, out io.Writer) error {
+	if o.explain {
+		return o.explainDir(args[0], out)
+	}
+	fingerprint, err := GetSha256Digest(args[0], &o.fingerprintOptions, logger)
 	if err != nil {
 		return err
 	}
 	logger.Info(fingerprint)
 	return nil
 }
+
+// explainDir prints the manifest a dir fingerprint is calculated from
+func (o *fingerprintCmdOptions) explainDir(dirPath string, out io.Writer) error {
+	manifest, err := digest.DirManifestSha256(dirPath, o.excludePaths, o.ignoreFile, logger)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return output.FormattedPrint(string(raw), o.output, out, 0,
+		map[string]output.FormatOutputFunc{
+			"table": printDirManifestAsTable,
+			"json":  output.PrintJson,
+		})
+}
+
+func printDirManifestAsTable(raw string, out io.Writer, page int) error {
+	var manifest digest.DirManifest
+	err := json.Unmarshal([]byte(raw), &manifest)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"PATH", "TYPE", "CONTENT DIGEST", "EXCLUDED BY"}
+	rows := []string{}
+	for _, entry := range manifest.Entries {
+		rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%s", entry.Path, entry.Type,
+			valueOrDash(entry.ContentDigest), valueOrDash(entry.ExcludedBy)))
+	}
+	tabFormattedPrint(out, header, rows)
+	fmt.Fprintf(out, "\nFingerprint: %s\n", manifest.Fingerprint)
+	return nil
+}
+
+// valueOrDash returns the value, or a dash if the value is empty
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
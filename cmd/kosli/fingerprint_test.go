@@ -44,6 +44,86 @@ func (suite *FingerprintTestSuite) TestFingerprintCmd() {
 			cmd:    "fingerprint --artifact-type dir testdata/folder1-with-ignore",
 			golden: "038897ea5334462098d65125380d58a493671fb3b8bdbbee1e75ec8bd4a65c23\n",
 		},
+		{
+			name:   "dir fingerprint with external ignore file",
+			cmd:    "fingerprint --artifact-type dir testdata/folder1 --ignore-file testdata/ignore-files/folder2-ignore",
+			golden: "773fd3300860454a2b065c5912c03008adb11e6a6dcf7c1c64c094ceab8f430a\n",
+		},
+		{
+			wantError: true,
+			name:      "dir fingerprint fails when the ignore file does not exist",
+			cmd:       "fingerprint --artifact-type dir testdata/folder1 --ignore-file testdata/ignore-files/non-existing",
+			golden:    "Error: failed to read ignore file: stat testdata/ignore-files/non-existing: no such file or directory\n",
+		},
+		{
+			wantError: true,
+			name:      "an argument named diff is fingerprinted as a path",
+			cmd:       "fingerprint --artifact-type dir diff",
+			golden:    "Error: stat diff: no such file or directory\n",
+		},
+		{
+			name:   "oci-dir fingerprint",
+			cmd:    "fingerprint --artifact-type oci-dir ../../internal/digest/testdata/oci/oci-layout",
+			golden: "60ee031e6e5138163841c02de14960015eba5e31c9a9d6047a913637b7068574\n",
+		},
+		{
+			name:   "oci-archive fingerprint with reference",
+			cmd:    "fingerprint --artifact-type oci-archive ../../internal/digest/testdata/oci/oci-archive.tar:latest",
+			golden: "60ee031e6e5138163841c02de14960015eba5e31c9a9d6047a913637b7068574\n",
+		},
+		{
+			wantError:   true,
+			name:        "oci-archive fingerprint of a legacy docker save tarball fails",
+			cmd:         "fingerprint --artifact-type oci-archive ../../internal/digest/testdata/oci/docker-save-legacy.tar",
+			goldenRegex: "^Error: .*index.json not found, the path is not an OCI image layout \\(e.g. it was created by 'docker save' before docker 25\\)",
+		},
+		{
+			name:   "oci-archive fingerprint of a multi-arch image is the index digest",
+			cmd:    "fingerprint --artifact-type oci-archive ../../internal/digest/testdata/oci/oci-archive-multiarch.tar",
+			golden: "4618160c5fe217365eb0236d8cc8f5a002cf715fb4e32f6eacd937e81409c685\n",
+		},
+		{
+			name:   "oci-archive fingerprint of a multi-arch image with --platform",
+			cmd:    "fingerprint --artifact-type oci-archive ../../internal/digest/testdata/oci/oci-archive-multiarch.tar --platform linux/arm64",
+			golden: "b9e3f255ceb7dca22bd790f0f97524bc38b306eb6d5e2715f4c3bedc36c5de41\n",
+		},
+		{
+			wantError: true,
+			name:      "oci-dir fingerprint of a single-platform image fails with another --platform",
+			cmd:       "fingerprint --artifact-type oci-dir ../../internal/digest/testdata/oci/oci-layout --platform linux/arm64",
+			golden:    "Error: image ../../internal/digest/testdata/oci/oci-layout is a single-platform image for linux/amd64, it has no manifest for platform linux/arm64\n",
+		},
+		{
+			wantError: true,
+			name:      "fingerprint fails when --platform is used for a dir",
+			cmd:       "fingerprint --artifact-type dir testdata/folder1 --platform linux/arm64",
+			golden:    "Error: --platform is only applicable when --artifact-type is 'oci', 'oci-dir' or 'oci-archive'\nUsage: kosli fingerprint {IMAGE-NAME | FILE-PATH | DIR-PATH} [flags]\n",
+		},
+		{
+			name: "dir fingerprint with explain",
+			cmd:  "fingerprint --artifact-type dir testdata/folder1 -x folder2 --explain",
+			golden: "PATH       TYPE  CONTENT DIGEST                                                    EXCLUDED BY\n" +
+				"folder2    dir   -                                                                 exclude\n" +
+				"hello.txt  file  fcf33337634c2577a5d86fd7ecb0a25a7c1bb5d89c14fd236f546a5759252c02  -\n" +
+				"\nFingerprint: 773fd3300860454a2b065c5912c03008adb11e6a6dcf7c1c64c094ceab8f430a\n",
+		},
+		{
+			name:        "dir fingerprint with explain in json",
+			cmd:         "fingerprint --artifact-type dir testdata/folder1-with-ignore --explain --output json",
+			goldenRegex: `(?s)"fingerprint": "038897ea5334462098d65125380d58a493671fb3b8bdbbee1e75ec8bd4a65c23".*"path": "folder2",\s+"type": "dir",\s+"excluded_by": "ignore-file"`,
+		},
+		{
+			name:      "fails if --explain is used with a non dir artifact type",
+			cmd:       "fingerprint --artifact-type file testdata/file1 --explain",
+			wantError: true,
+			golden:    "Error: --explain is only supported for --artifact-type dir\n",
+		},
+		{
+			name:      "fails if --output is used without --explain",
+			cmd:       "fingerprint --artifact-type dir testdata/folder1 --output json",
+			wantError: true,
+			golden:    "Error: --output is only allowed when --explain is set\n",
+		},
 		{
 			name:      "fails if type is directory but the argument is not a dir",
 			cmd:       "fingerprint --artifact-type dir testdata/file1",
@@ -23,6 +23,8 @@ func addFingerprintFlags(cmd *cobra.Command, o *fingerprintOptions) {
 	cmd.Flags().StringVar(&o.registryUsername, "registry-username", "", registryUsernameFlag)
 	cmd.Flags().StringVar(&o.registryPassword, "registry-password", "", registryPasswordFlag)
 	cmd.Flags().StringSliceVarP(&o.excludePaths, "exclude", "x", []string{}, excludePathsFlag)
+	cmd.Flags().StringVar(&o.ignoreFile, "ignore-file", "", ignoreFileFlag)
+	cmd.Flags().StringVar(&o.platform, "platform", "", platformFlag)
 
 	err := DeprecateFlags(cmd, map[string]string{
 		"registry-provider": "no longer used",
@@ -109,6 +111,13 @@ func addListFlags(cmd *cobra.Command, o *listOptions) {
 	cmd.Flags().IntVarP(&o.pageLimit, "page-limit", "n", 15, pageLimitFlag)
 }
 
+func addScanPolicyFlags(cmd *cobra.Command, o *scanPolicyOptions, defaultFailOn, failOnDesc string) {
+	cmd.Flags().StringVar(&o.failOn, "fail-on", defaultFailOn, failOnDesc)
+	cmd.Flags().StringToIntVar(&o.maxFindings, "max-findings", map[string]int{}, scanMaxFindingsFlag)
+	cmd.Flags().StringVar(&o.ignoreFile, "scan-ignore-file", "", scanIgnoreFileFlag)
+	cmd.Flags().StringVar(&o.baselineFile, "baseline-file", "", scanBaselineFileFlag)
+}
+
 func addAttestationFlags(cmd *cobra.Command, o *CommonAttestationOptions, payload *CommonAttestationPayload, ci string) {
 	commitFlagDesc := attestationCommitFlag
 	if _, ok := cmd.Annotations["pr"]; ok {
@@ -58,8 +58,8 @@ func newJoinEnvironmentCmd(out io.Writer) *cobra.Command {
 				DryRun:  global.DryRun,
 				Token:   global.ApiToken,
 			}
-			_, err := kosliClient.Do(reqParams)
-			if err == nil && !global.DryRun {
+			res, err := kosliClient.Do(reqParams)
+			if requestReported(res, err) {
 				logger.Info("environment '%s' was joined to '%s'", o.payload.Physical, o.logical)
 			}
 			return err
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+const outboxDesc = `All Kosli outbox commands.`
+
+const outboxLongDesc = outboxDesc + `
+When the global ^--outbox-dir^ flag is set, POST/PUT requests (e.g. attestations and snapshots)
+that cannot reach the Kosli host after ^--max-api-retries^ are queued in that directory instead of failing.
+The outbox commands allow you to inspect, replay, drop or purge the queued requests.`
+
+func newOutboxCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "outbox",
+		Short: outboxDesc,
+		Long:  outboxLongDesc,
+	}
+
+	// Add subcommands
+	cmd.AddCommand(
+		newOutboxListCmd(out),
+		newOutboxReplayCmd(out),
+		newOutboxDropCmd(out),
+		newOutboxPurgeCmd(out),
+	)
+
+	return cmd
+}
+
+// requireOutboxDir returns an error if the global --outbox-dir flag is not set
+func requireOutboxDir() error {
+	if global.OutboxDir == "" {
+		return fmt.Errorf("--outbox-dir is not set")
+	}
+	return nil
+}
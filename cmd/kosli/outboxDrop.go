@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/spf13/cobra"
+)
+
+const outboxDropShortDesc = `Delete requests queued in the outbox by their IDs without sending them.`
+
+const outboxDropLongDesc = outboxDropShortDesc + `
+Use it to skip a request which stops ^kosli outbox replay^ because it can never be sent,
+e.g. because Kosli rejects it. The IDs of the queued requests are listed by ^kosli outbox list^.
+Dropped requests are lost and will never be sent to Kosli.`
+
+const outboxDropExample = `
+# delete a queued request from an outbox directory:
+kosli outbox drop 01729252263123456789-1a2b3c4d \
+	--outbox-dir /path/to/outbox
+`
+
+func newOutboxDropCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "drop ID...",
+		Short:   outboxDropShortDesc,
+		Long:    outboxDropLongDesc,
+		Example: outboxDropExample,
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			err := requireOutboxDir()
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outbox := requests.NewOutbox(global.OutboxDir)
+			for _, id := range args {
+				err := outbox.Drop(id)
+				if err != nil {
+					return err
+				}
+				logger.Info("outbox entry %s was dropped from the outbox", id)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
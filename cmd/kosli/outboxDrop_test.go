@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type OutboxDropCommandTestSuite struct {
+	suite.Suite
+	outboxDir string
+	entryID   string
+}
+
+func (suite *OutboxDropCommandTestSuite) SetupTest() {
+	var err error
+	suite.outboxDir, err = os.MkdirTemp("", "outbox")
+	require.NoError(suite.T(), err)
+	global = &GlobalOpts{}
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:8001/api/v2/attestations/org/flow/trail/generic", nil)
+	require.NoError(suite.T(), err)
+	entry, err := requests.NewOutbox(suite.outboxDir).Enqueue(req, nil)
+	require.NoError(suite.T(), err)
+	suite.entryID = entry.ID
+}
+
+func (suite *OutboxDropCommandTestSuite) TearDownTest() {
+	os.RemoveAll(suite.outboxDir)
+}
+
+func (suite *OutboxDropCommandTestSuite) TestOutboxDropCmd() {
+	tests := []cmdTestCase{
+		{
+			name:   "dropping a queued request deletes it",
+			cmd:    fmt.Sprintf(`outbox drop %s --outbox-dir %s`, suite.entryID, suite.outboxDir),
+			golden: fmt.Sprintf("outbox entry %s was dropped from the outbox\n", suite.entryID),
+		},
+		{
+			wantError: true,
+			name:      "dropping a request which is not queued fails",
+			cmd:       fmt.Sprintf(`outbox drop %s --outbox-dir %s`, suite.entryID, suite.outboxDir),
+			golden:    fmt.Sprintf("Error: outbox entry %s does not exist in the outbox [%s]\n", suite.entryID, suite.outboxDir),
+		},
+		{
+			wantError: true,
+			name:      "dropping fails when no ID is provided",
+			cmd:       fmt.Sprintf(`outbox drop --outbox-dir %s`, suite.outboxDir),
+			golden:    "Error: requires at least 1 arg(s), only received 0\n",
+		},
+		{
+			wantError: true,
+			name:      "dropping fails when --outbox-dir is not set",
+			cmd:       fmt.Sprintf(`outbox drop %s`, suite.entryID),
+			golden:    "Error: --outbox-dir is not set\nUsage: kosli outbox drop ID... [flags]\n",
+		},
+	}
+
+	runTestCmd(suite.T(), tests)
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestOutboxDropCommandTestSuite(t *testing.T) {
+	suite.Run(t, new(OutboxDropCommandTestSuite))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type OutboxListCommandTestSuite struct {
+	suite.Suite
+	outboxDir string
+}
+
+func (suite *OutboxListCommandTestSuite) SetupTest() {
+	var err error
+	suite.outboxDir, err = os.MkdirTemp("", "outbox")
+	require.NoError(suite.T(), err)
+	global = &GlobalOpts{}
+}
+
+func (suite *OutboxListCommandTestSuite) TearDownTest() {
+	os.RemoveAll(suite.outboxDir)
+}
+
+func (suite *OutboxListCommandTestSuite) TestOutboxListCmd() {
+	emptyDir, err := os.MkdirTemp("", "empty-outbox")
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(emptyDir)
+
+	req, err := http.NewRequest(http.MethodPut, "http://localhost:8001/api/v2/environments/org/env/report/K8S", nil)
+	require.NoError(suite.T(), err)
+	_, err = requests.NewOutbox(suite.outboxDir).Enqueue(req, nil)
+	require.NoError(suite.T(), err)
+
+	tests := []cmdTestCase{
+		{
+			name:   "listing an empty outbox works",
+			cmd:    fmt.Sprintf(`outbox list --outbox-dir %s`, emptyDir),
+			golden: "No queued requests were found.\n",
+		},
+		{
+			name:        "listing an outbox with entries works",
+			cmd:         fmt.Sprintf(`outbox list --outbox-dir %s`, suite.outboxDir),
+			goldenRegex: `ID\s+QUEUED AT\s+METHOD\s+URL\s+SIZE\n\d{20}-[0-9a-f]{8}\s+.*\s+PUT\s+http://localhost:8001/api/v2/environments/org/env/report/K8S\s+0 bytes`,
+		},
+		{
+			name:        "listing an outbox with entries works with json output",
+			cmd:         fmt.Sprintf(`outbox list --outbox-dir %s --output json`, suite.outboxDir),
+			goldenRegex: `"method": "PUT"`,
+		},
+		{
+			wantError: true,
+			name:      "listing fails when --outbox-dir is not set",
+			cmd:       `outbox list`,
+			golden:    "Error: --outbox-dir is not set\nUsage: kosli outbox list [flags]\n",
+		},
+		{
+			wantError: true,
+			name:      "listing fails when an argument is provided",
+			cmd:       fmt.Sprintf(`outbox list xxx --outbox-dir %s`, suite.outboxDir),
+			golden:    "Error: unknown command \"xxx\" for \"kosli outbox list\"\n",
+		},
+	}
+
+	runTestCmd(suite.T(), tests)
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestOutboxListCommandTestSuite(t *testing.T) {
+	suite.Run(t, new(OutboxListCommandTestSuite))
+}
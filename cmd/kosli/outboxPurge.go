@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/spf13/cobra"
+)
+
+const outboxPurgeShortDesc = `Delete all requests queued in the outbox without sending them.`
+
+const outboxPurgeLongDesc = outboxPurgeShortDesc + `
+Purged requests are lost and will never be sent to Kosli.`
+
+const outboxPurgeExample = `
+# delete all queued requests in an outbox directory:
+kosli outbox purge \
+	--outbox-dir /path/to/outbox
+`
+
+func newOutboxPurgeCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "purge",
+		Short:   outboxPurgeShortDesc,
+		Long:    outboxPurgeLongDesc,
+		Example: outboxPurgeExample,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			err := requireOutboxDir()
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			purged, err := requests.NewOutbox(global.OutboxDir).Purge()
+			if err == nil {
+				logger.Info("[%d] queued requests were purged from the outbox", purged)
+			}
+			return err
+		},
+	}
+	return cmd
+}
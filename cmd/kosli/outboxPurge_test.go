@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type OutboxPurgeCommandTestSuite struct {
+	suite.Suite
+	outboxDir string
+}
+
+func (suite *OutboxPurgeCommandTestSuite) SetupTest() {
+	var err error
+	suite.outboxDir, err = os.MkdirTemp("", "outbox")
+	require.NoError(suite.T(), err)
+	global = &GlobalOpts{}
+
+	outbox := requests.NewOutbox(suite.outboxDir)
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodPost, "http://localhost:8001/api/v2/attestations/org/flow/trail/generic", nil)
+		require.NoError(suite.T(), err)
+		_, err = outbox.Enqueue(req, nil)
+		require.NoError(suite.T(), err)
+	}
+}
+
+func (suite *OutboxPurgeCommandTestSuite) TearDownTest() {
+	os.RemoveAll(suite.outboxDir)
+}
+
+func (suite *OutboxPurgeCommandTestSuite) TestOutboxPurgeCmd() {
+	tests := []cmdTestCase{
+		{
+			name:   "purging an outbox deletes all queued requests",
+			cmd:    fmt.Sprintf(`outbox purge --outbox-dir %s`, suite.outboxDir),
+			golden: "[2] queued requests were purged from the outbox\n",
+		},
+		{
+			name:   "purging an empty outbox works",
+			cmd:    fmt.Sprintf(`outbox purge --outbox-dir %s`, suite.outboxDir),
+			golden: "[0] queued requests were purged from the outbox\n",
+		},
+		{
+			wantError: true,
+			name:      "purging fails when --outbox-dir is not set",
+			cmd:       `outbox purge`,
+			golden:    "Error: --outbox-dir is not set\nUsage: kosli outbox purge [flags]\n",
+		},
+	}
+
+	runTestCmd(suite.T(), tests)
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestOutboxPurgeCommandTestSuite(t *testing.T) {
+	suite.Run(t, new(OutboxPurgeCommandTestSuite))
+}
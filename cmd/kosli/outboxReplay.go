@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+const outboxReplayShortDesc = `Send the requests queued in the outbox to Kosli.`
+
+const outboxReplayLongDesc = outboxReplayShortDesc + `
+Queued requests are sent in the order they were queued, using the current ^--api-token^.
+Replaying stops at the first request that fails, so that the original order is preserved.
+A request which can never be sent (e.g. because Kosli rejects it) can be deleted with ^kosli outbox drop^.
+Successfully sent requests are removed from the outbox.`
+
+const outboxReplayExample = `
+# send all queued requests in an outbox directory:
+kosli outbox replay \
+	--outbox-dir /path/to/outbox \
+	--api-token yourAPIToken
+`
+
+func newOutboxReplayCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "replay",
+		Short:   outboxReplayShortDesc,
+		Long:    outboxReplayLongDesc,
+		Example: outboxReplayExample,
+		Args:    cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			err := requireOutboxDir()
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+			err = RequireGlobalFlags(global, []string{"ApiToken"})
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOutboxReplay()
+		},
+	}
+	addDryRunFlag(cmd)
+	return cmd
+}
+
+func runOutboxReplay() error {
+	sent, err := kosliClient.ReplayOutbox(global.ApiToken, global.DryRun)
+	if !global.DryRun {
+		logger.Info("[%d] queued requests were sent from the outbox", sent)
+	}
+	return err
+}
@@ -54,8 +54,8 @@ func newRenameEnvironmentCmd(out io.Writer) *cobra.Command {
 				DryRun:  global.DryRun,
 				Token:   global.ApiToken,
 			}
-			_, err := kosliClient.Do(reqParams)
-			if err == nil && !global.DryRun {
+			res, err := kosliClient.Do(reqParams)
+			if requestReported(res, err) {
 				logger.Info("environment %s was renamed to %s", args[0], payload.NewName)
 			}
 			return err
@@ -53,8 +53,8 @@ func newRenameFlowCmd(out io.Writer) *cobra.Command {
 				DryRun:  global.DryRun,
 				Token:   global.ApiToken,
 			}
-			_, err := kosliClient.Do(reqParams)
-			if err == nil && !global.DryRun {
+			res, err := kosliClient.Do(reqParams)
+			if requestReported(res, err) {
 				logger.Info("flow %s was renamed to %s", args[0], payload.NewName)
 			}
 			return err
@@ -215,8 +215,8 @@ func (o *reportApprovalOptions) run(args []string, request bool) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("approval created for artifact: %s", o.payload.ArtifactFingerprint)
 	}
 	return err
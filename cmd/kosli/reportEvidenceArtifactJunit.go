@@ -152,8 +152,8 @@ func (o *reportEvidenceArtifactJunitOptions) run(args []string) error {
 		DryRun: global.DryRun,
 		Token:  global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("junit test evidence is reported to artifact: %s", o.payload.ArtifactFingerprint)
 	}
 	return err
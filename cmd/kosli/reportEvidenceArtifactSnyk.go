@@ -122,7 +122,7 @@ func (o *reportEvidenceArtifactSnykOptions) run(args []string) error {
 		return err
 	}
 
-	o.payload.SnykSarifResults, err = snyk.ProcessSnykResultFile(o.snykJsonFilePath)
+	o.payload.SnykSarifResults, err = snyk.ProcessSnykSarifFile(o.snykJsonFilePath)
 	if err != nil {
 		sarifErr := err
 		o.payload.SnykResults, err = LoadJsonData(o.snykJsonFilePath)
@@ -153,8 +153,8 @@ func (o *reportEvidenceArtifactSnykOptions) run(args []string) error {
 		DryRun: global.DryRun,
 		Token:  global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("snyk scan evidence is reported to artifact: %s", o.payload.ArtifactFingerprint)
 	}
 	return err
@@ -108,8 +108,8 @@ func (o *reportEvidenceCommitGenericOptions) run(args []string) error {
 		Token:  global.ApiToken,
 	}
 
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("generic evidence '%s' is reported to commit: %s", o.payload.EvidenceName, o.payload.CommitSHA)
 	}
 	return err
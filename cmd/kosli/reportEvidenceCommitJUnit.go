@@ -116,8 +116,8 @@ func (o *reportEvidenceCommitJunitOptions) run(args []string) error {
 		DryRun: global.DryRun,
 		Token:  global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("junit test evidence is reported to commit: %s", o.payload.CommitSHA)
 	}
 	return err
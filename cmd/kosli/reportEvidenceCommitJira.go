@@ -216,8 +216,8 @@ func (o *reportEvidenceCommitJiraOptions) run(args []string) error {
 		Token:  global.ApiToken,
 	}
 
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("Jira evidence is reported to commit: %s", o.payload.CommitSHA)
 		logger.Info("  Issues references reported: %s", issueLog)
 	}
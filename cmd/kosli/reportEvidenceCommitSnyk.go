@@ -99,7 +99,7 @@ func (o *reportEvidenceCommitSnykOptions) run(args []string) error {
 		return err
 	}
 
-	o.payload.SnykSarifResults, err = snyk.ProcessSnykResultFile(o.snykJsonFile)
+	o.payload.SnykSarifResults, err = snyk.ProcessSnykSarifFile(o.snykJsonFile)
 	if err != nil {
 		sarifErr := err
 		o.payload.SnykResults, err = LoadJsonData(o.snykJsonFile)
@@ -130,8 +130,8 @@ func (o *reportEvidenceCommitSnykOptions) run(args []string) error {
 		DryRun: global.DryRun,
 		Token:  global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("snyk scan evidence is reported to commit: %s", o.payload.CommitSHA)
 	}
 	return err
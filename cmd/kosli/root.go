@@ -45,7 +45,9 @@ The artifact fingerprint can be provided directly with the ^--fingerprint^ flag,
 calculated based on ^--artifact-type^ flag.
 
 Artifact type can be one of: "file" for files, "dir" for directories, "oci" for container
-images in registries or "docker" for local docker images.
+images in registries, "docker" for local docker images, "oci-dir" for container images in OCI image
+layout directories or "oci-archive" for tarballs of OCI image layouts.
+The fingerprint of a multi-arch image is the digest of its image index, unless a platform is selected with ^--platform^.
 
 `
 
@@ -76,7 +78,12 @@ The service principal needs to have the following permissions:
 
 	`
 	kosliIgnoreDesc = `To specify paths in a directory artifact that should always be excluded from the SHA256 calculation, you can add a ^.kosli_ignore^ file to the root of the artifact.
-Each line should specify a relative path or path glob to be ignored. You can include comments in this file, using ^#^.
+^.kosli_ignore^ files follow the gitignore pattern format (https://git-scm.com/docs/gitignore#_pattern_format): patterns without a slash
+match at any depth (e.g. ^*.log^), a leading slash anchors a pattern to the directory of the ignore file (e.g. ^/build^), a trailing slash
+only matches directories (e.g. ^logs/^) and a leading ^!^ re-includes a path excluded by a previous pattern (e.g. ^!keep.log^).
+^.kosli_ignore^ files in subdirectories apply to the paths under their own directory.
+You can include comments in this file, using ^#^ at the start of a line or after whitespace at the end of a pattern.
+You can also use ^--ignore-file^ to provide an ignore file stored outside the artifact; its patterns are relative to the root of the artifact.
 The ^.kosli_ignore^ will be treated as part of the artifact like any other file,unless it is explicitly ignored itself.`
 
 	// flags
@@ -90,13 +97,15 @@ The ^.kosli_ignore^ will be treated as part of the artifact like any other file,
 	maxAPIRetryFlag                      = "[defaulted] How many times should API calls be retried when the API host is not reachable."
 	configFileFlag                       = "[optional] The Kosli config file path."
 	debugFlag                            = "[optional] Print debug logs to stdout. A boolean flag https://docs.kosli.com/faq/#boolean-flags (default false)"
-	artifactTypeFlag                     = "The type of the artifact to calculate its SHA256 fingerprint. One of: [oci, docker, file, dir]. Only required if you want Kosli to calculate the fingerprint for you (i.e. when you don't specify '--fingerprint' on commands that allow it)."
+	outboxDirFlag                        = "[optional] The path to a local outbox directory. When set, POST/PUT requests that cannot reach the Kosli host after --max-api-retries are queued there instead of failing, and can be sent later with 'kosli outbox replay'."
+	artifactTypeFlag                     = "The type of the artifact to calculate its SHA256 fingerprint. One of: [oci, docker, file, dir, oci-dir, oci-archive]. Only required if you want Kosli to calculate the fingerprint for you (i.e. when you don't specify '--fingerprint' on commands that allow it)."
 	flowNameFlag                         = "The Kosli flow name."
 	trailNameFlag                        = "The Kosli trail name."
 	trailNameFlagOptional                = "[optional] The Kosli trail name."
 	templateArtifactName                 = "The name of the artifact in the yml template file."
 	flowNamesFlag                        = "[defaulted] The comma separated list of Kosli flows. Defaults to all flows of the org."
 	outputFlag                           = "[defaulted] The format of the output. Valid formats are: [table, json]."
+	fingerprintExplainFlag               = "[optional] Print the manifest the fingerprint of a dir artifact is calculated from instead of the fingerprint only. A boolean flag https://docs.kosli.com/faq/#boolean-flags (default false)"
 	environmentNameFlag                  = "The environment name."
 	approvalEnvironmentNameFlag          = "[defaulted] The environment the artifact is approved for. (defaults to all environments)"
 	pageNumberFlag                       = "[defaulted] The page number of a response."
@@ -149,7 +158,11 @@ The ^.kosli_ignore^ will be treated as part of the artifact like any other file,
 	azureClientSecretFlag                = "Azure client secret."
 	azureTenantIdFlag                    = "Azure tenant ID."
 	azureSubscriptionIdFlag              = "Azure subscription ID."
-	azureResourceGroupNameFlag           = "Azure resource group name."
+	azureResourceGroupNameFlag           = "[optional] The comma-separated list of Azure resource group names to report apps from. Defaults to all resource groups in the subscription. Can't be used together with --exclude-resource-groups or --exclude-resource-groups-regex."
+	azureResourceGroupsRegexFlag         = "[optional] The comma-separated list of Azure resource group name regex patterns to report apps from. Can't be used together with --exclude-resource-groups or --exclude-resource-groups-regex."
+	azureExcludeResourceGroupsFlag       = "[optional] The comma-separated list of Azure resource group names to exclude. Can't be used together with --azure-resource-group-name or --azure-resource-groups-regex."
+	azureExcludeResourceGroupsRegexFlag  = "[optional] The comma-separated list of Azure resource group name regex patterns to exclude. Can't be used together with --azure-resource-group-name or --azure-resource-groups-regex."
+	azureIncludeSlotsFlag                = "[defaulted] Also report the deployment slots of the apps, each as a separate app named app-name/slot-name. Requires a Kosli server which accepts the slots of Azure apps."
 	azureDigestsSourceFlag               = "[defaulted] Where to get the digests from. Valid values are 'acr' and 'logs'."
 	githubTokenFlag                      = "Github token."
 	githubOrgFlag                        = "Github organization. (defaulted if you are running in GitHub Actions: https://docs.kosli.com/ci-defaults )."
@@ -162,32 +175,73 @@ The ^.kosli_ignore^ will be treated as part of the artifact like any other file,
 	registryPasswordFlag                 = "[conditional] The container registry password or access token. Only required if you want to read container image SHA256 digest from a remote container registry."
 	resultsDirFlag                       = "[defaulted] The path to a directory with JUnit test results. By default, the directory will be uploaded to Kosli's evidence vault."
 	snykJsonResultsFileFlag              = "The path to Snyk SARIF or JSON scan results file from 'snyk test' and 'snyk container test'. By default, the Snyk results will be uploaded to Kosli's evidence vault."
-	snykSarifResultsFileFlag             = "The path to Snyk scan SARIF results file from 'snyk test' and 'snyk container test'. By default, the Snyk results will be uploaded to Kosli's evidence vault."
+	snykResultsFileFlag                  = "The path to Snyk SARIF or JSON scan results file from 'snyk test', 'snyk container test', 'snyk code test' or 'snyk iac test'. By default, the Snyk results will be uploaded to Kosli's evidence vault."
 	ecsClusterFlag                       = "The name of the ECS cluster."
+	gcpProjectsFlag                      = "The comma-separated list of Google Cloud project IDs to snapshot Cloud Run services from."
+	gcpRegionsFlag                       = "[optional] The comma-separated list of Google Cloud regions to snapshot Cloud Run services from. Defaults to all regions."
+	gcpCredentialsFileFlag               = "[optional] The path to the JSON key file of a Google Cloud service account. Defaults to the application default credentials."
+	cloudRunServicesFlag                 = "[optional] The comma-separated list of Cloud Run service names to snapshot. Can't be used together with --exclude or --exclude-regex."
+	cloudRunServicesRegexFlag            = "[optional] The comma-separated list of Cloud Run service name regex patterns to snapshot. Can't be used together with --exclude or --exclude-regex."
+	cloudRunExcludeServicesFlag          = "[optional] The comma-separated list of Cloud Run service names to exclude. Can't be used together with --services or --services-regex."
+	cloudRunExcludeServicesRegexFlag     = "[optional] The comma-separated list of Cloud Run service name regex patterns to exclude. Can't be used together with --services or --services-regex."
+	nomadAddressFlag                     = "[defaulted] The address of the Nomad HTTP API. Defaults to the NOMAD_ADDR env variable if it is set."
+	nomadTokenFlag                       = "[optional] The Nomad ACL token. Only required if ACLs are enabled in the Nomad cluster. Defaults to the NOMAD_TOKEN env variable."
+	nomadCACertFlag                      = "[optional] The path to the CA certificate used to verify the certificate of the Nomad HTTP API. Defaults to the NOMAD_CACERT env variable."
+	nomadClientCertFlag                  = "[optional] The path to the client certificate used when the Nomad agents verify HTTPS clients. Requires --nomad-client-key. Defaults to the NOMAD_CLIENT_CERT env variable."
+	nomadClientKeyFlag                   = "[optional] The path to the key of the client certificate. Requires --nomad-client-cert. Defaults to the NOMAD_CLIENT_KEY env variable."
+	nomadNamespacesFlag                  = "[optional] The comma-separated list of Nomad namespaces to report allocations from. Can't be used together with --exclude-namespaces or --exclude-namespaces-regex."
+	nomadNamespacesRegexFlag             = "[optional] The comma-separated list of Nomad namespace regex patterns to report allocations from. Can't be used together with --exclude-namespaces or --exclude-namespaces-regex."
+	nomadExcludeNamespacesFlag           = "[optional] The comma-separated list of Nomad namespaces to exclude. Can't be used together with --namespaces or --namespaces-regex."
+	nomadExcludeNamespacesRegexFlag      = "[optional] The comma-separated list of Nomad namespace regex patterns to exclude. Can't be used together with --namespaces or --namespaces-regex."
+	nomadJobsFlag                        = "[optional] The comma-separated list of Nomad job IDs to report allocations of. Can't be used together with --exclude-jobs or --exclude-jobs-regex."
+	nomadJobsRegexFlag                   = "[optional] The comma-separated list of Nomad job ID regex patterns to report allocations of. Can't be used together with --exclude-jobs or --exclude-jobs-regex."
+	nomadExcludeJobsFlag                 = "[optional] The comma-separated list of Nomad job IDs to exclude. Can't be used together with --jobs or --jobs-regex."
+	nomadExcludeJobsRegexFlag            = "[optional] The comma-separated list of Nomad job ID regex patterns to exclude. Can't be used together with --jobs or --jobs-regex."
+	nomadResolveMissingDigestsFlag       = "[optional] Look up the digests of task images referenced by tag only in their registries. A boolean flag https://docs.kosli.com/faq/#boolean-flags (default false)"
+	containerRuntimeFlag                 = "[defaulted] The container runtime to read the running containers from. One of: auto, docker, podman, containerd."
+	containerSelectorFlag                = "[optional] The label selector of the containers to report (e.g. com.docker.compose.project=shop,tier!=test). Defaults to all running containers."
+	includeServicesFlag                  = "[defaulted] Also report the docker compose project and service and the swarm stack and service of the containers, taken from their labels. Requires a Kosli server which accepts the services of docker artifacts."
+	containerRuntimeEndpointFlag         = "[optional] The socket path or address of the container runtime API. Defaults to the default socket of the runtime."
+	containerdNamespaceFlag              = "[optional] The containerd namespace to read the running containers from with the containerd API instead of the CRI API, e.g. default for the containers started with ctr or nerdctl. Only for the containerd runtime."
 	ecsClustersFlag                      = "[optional] The comma-separated list of ECS cluster names to snapshot. Can't be used together with --exclude or --exclude-regex."
 	ecsClustersRegexFlag                 = "[optional] The comma-separated list of ECS cluster name regex patterns to snapshot. Can't be used together with --exclude or --exclude-regex."
 	ecsExcludeClustersFlag               = "[optional] The comma-separated list of ECS cluster names to exclude. Can't be used together with --exclude or --exclude-regex."
 	ecsExcludeClustersRegexFlag          = "[optional] The comma-separated list of ECS cluster name regex patterns to exclude. Can't be used together with --clusters or --clusters-regex."
-	ecsServiceFlag                       = "[optional] The name of the ECS service."
+	ecsServiceFlag                       = "[optional] The comma-separated list of ECS service names to snapshot. Only the tasks of these services are reported. Fails if a service is found in none of the clusters."
 	kubeconfigFlag                       = "[defaulted] The kubeconfig path for the target cluster."
 	namespacesFlag                       = "[optional] The comma separated list of namespaces names to report artifacts info from. Can't be used together with --exclude-namespaces or --exclude-namespaces-regex."
 	excludeNamespacesFlag                = "[optional] The comma separated list of namespaces names to exclude from reporting artifacts info from. Requires cluster-wide read permissions for pods and namespaces. Can't be used together with --namespaces or --namespaces-regex."
 	namespacesRegexFlag                  = "[optional] The comma separated list of namespaces regex patterns to report artifacts info from. Requires cluster-wide read permissions for pods and namespaces. Can't be used together with --exclude-namespaces --exclude-namespaces-regex."
 	excludeNamespacesRegexFlag           = "[optional] The comma separated list of namespaces regex patterns to exclude from reporting artifacts info from. Requires cluster-wide read permissions for pods and namespaces. Can't be used together with --namespaces or --namespaces-regex."
+	k8sWatchFlag                         = "[optional] Keep running and report a new snapshot whenever the set of running image digests changes. A boolean flag https://docs.kosli.com/faq/#boolean-flags (default false)"
+	k8sDebounceFlag                      = "[defaulted] How long to wait after the last pod change before checking for changes to report. Only applicable with --watch."
+	k8sMaxDelayFlag                      = "[defaulted] The longest time to wait after a pod change before checking for changes to report, even if pods keep changing. Only applicable with --watch."
+	k8sResyncIntervalFlag                = "[defaulted] How often to report a full snapshot regardless of pod changes. Only applicable with --watch."
+	ecsResolveMissingDigestsFlag         = "[optional] Look up the digests of container images that have no digest in the task description in ECR or in their registries. A boolean flag https://docs.kosli.com/faq/#boolean-flags (default false)"
+	k8sResolveMissingDigestsFlag         = "[optional] Look up the digests of container images that cannot be found in the pod status in their registries. A boolean flag https://docs.kosli.com/faq/#boolean-flags (default false)"
+	k8sContextFlag                       = "[optional] The kubeconfig context to use. Defaults to the current context of the kubeconfig. Cannot be used together with --clusters-file ."
+	k8sClustersFileFlag                  = "[optional] The path to a clusters file in YAML/JSON/TOML format mapping kubeconfig contexts to Kosli environments. When set, the environment name argument must not be provided."
 	functionNameFlag                     = "[optional] The name of the AWS Lambda function."
 	functionNamesFlag                    = "[optional] The comma-separated list of AWS Lambda function names to be reported. Cannot be used together with --exclude or --exclude-regex."
 	functionNamesRegexFlag               = "[optional] The comma-separated list of AWS Lambda function names regex patterns to be reported. Cannot be used together with --exclude or --exclude-regex."
 	excludeFlag                          = "[optional] The comma-separated list of AWS Lambda function names to be excluded. Cannot be used together with --function-names"
 	excludeRegexFlag                     = "[optional] The comma-separated list of name regex patterns for AWS Lambda functions to be excluded. Cannot be used together with --function-names. Allowed regex patterns are described in https://github.com/google/re2/wiki/Syntax"
 	functionVersionFlag                  = "[optional] The version of the AWS Lambda function."
+	includeAliasesFlag                   = "[defaulted] Also report the versions of the AWS Lambda functions that their aliases route traffic to, with the aliases and their routing weights."
 	awsKeyIdFlag                         = "The AWS access key ID."
 	awsSecretKeyFlag                     = "The AWS secret access key."
 	awsRegionFlag                        = "The AWS region."
 	bucketNameFlag                       = "The name of the S3 bucket."
 	bucketPathsFlag                      = "[optional] The comma separated list of file and/or directory paths in the S3 bucket to include when fingerprinting. Cannot be used together with --exclude."
 	excludeBucketPathsFlag               = "[optional] The comma separated list of file and/or directory paths in the S3 bucket to exclude when fingerprinting. Cannot be used together with --include."
+	s3PrefixesFileFlag                   = "[optional] The path to a prefixes file in YAML/JSON/TOML format specifying the artifacts in the S3 bucket to report. Cannot be used together with --include or --exclude."
+	useS3ChecksumsFlag                   = "[defaulted] Use the SHA256 checksums of the S3 objects that have one instead of downloading them. The fingerprint is the same."
 	pathsFlag                            = "The comma separated list of absolute or relative paths of artifact directories or files. Can take glob patterns, but be aware that each matching path will be reported as an artifact."
 	excludePathsFlag                     = "[optional] The comma separated list of directories and files to exclude from fingerprinting. Can take glob patterns. Only applicable for --artifact-type dir."
+	platformFlag                         = "[optional] The platform (os/arch[/variant], e.g. linux/arm64) of the manifest to fingerprint in a multi-arch image. Defaults to the digest of the image index. Only applicable for --artifact-type oci, oci-dir or oci-archive."
+	platformAliasesFlag                  = "[optional] Record the digests of the image index and of all the platform manifests of a multi-arch image as aliases of the artifact fingerprint, so the artifact matches whichever platform runs it. Only applicable for --artifact-type oci, oci-dir or oci-archive. Can't be used together with --fingerprint. Requires a Kosli server which accepts fingerprint aliases."
+	ignoreFileFlag                       = "[optional] The path to an ignore file, in .kosli_ignore format, listing paths to exclude from fingerprinting. Patterns are relative to the root of the artifact. Only applicable for --artifact-type dir."
+	serverIgnoreFileFlag                 = "[optional] The path to an ignore file, in .kosli_ignore format, listing paths to exclude from fingerprinting. Patterns are relative to the root of each directory artifact."
 	serverExcludePathsFlag               = "[optional] The comma separated list of directories and files to exclude from fingerprinting. Can take glob patterns."
 	shortFlag                            = "[optional] Print only the Kosli CLI version number."
 	reverseFlag                          = "[defaulted] Reverse the order of output list."
@@ -210,6 +264,21 @@ The ^.kosli_ignore^ will be treated as part of the artifact like any other file,
 	attestationCustomDataFileFlag        = "The filepath of a json file containing the custom attestation data."
 	uploadJunitResultsFlag               = "[defaulted] Whether to upload the provided Junit results directory as an attachment to Kosli or not."
 	uploadSnykResultsFlag                = "[defaulted] Whether to upload the provided Snyk results file as an attachment to Kosli or not."
+	snykFailOnFlag                       = "[optional] The lowest severity (critical, high, medium, low, info or none) of Snyk results which makes the attestation non-compliant. If neither --fail-on nor --max-findings is set, Kosli decides the compliance of the attestation."
+	sarifResultsFileFlag                 = "The path to the SARIF 2.1.0 results file of a scan. By default, the SARIF results will be uploaded to Kosli's evidence vault."
+	uploadSarifResultsFlag               = "[defaulted] Whether to upload the provided SARIF results file as an attachment to Kosli or not."
+	sarifFailOnFlag                      = "[defaulted] The lowest severity (critical, high, medium, low, info or none) of SARIF results which makes the attestation non-compliant. Use none to always report a compliant attestation."
+	scanMaxFindingsFlag                  = "[optional] The maximum numbers of findings per severity (critical, high, medium, low or info) for the attestation to be compliant, e.g. high=0,medium=10."
+	scanIgnoreFileFlag                   = "[optional] The path to a YAML or JSON file of the rule IDs, vulnerability IDs or CVEs whose findings are ignored, with the reasons and optional expiry dates."
+	scanBaselineFileFlag                 = "[optional] The path to a YAML or JSON file of the previously accepted findings, which are not counted for compliance."
+	sbomFileFlag                         = "The path to the SBOM file, a CycloneDX (JSON or XML) or SPDX (JSON or tag-value) document. The SBOM file is uploaded to Kosli's evidence vault."
+	sbomAllowedLicensesFlag              = "[optional] The comma-separated list of the licenses (SPDX license IDs or license names) which components are allowed to have. Components with other licenses or without a license make the attestation non-compliant."
+	sbomDeniedLicensesFlag               = "[optional] The comma-separated list of the licenses (SPDX license IDs or license names) which components are not allowed to have. Components with a denied license make the attestation non-compliant."
+	coverageReportsFlag                  = "The comma-separated list of paths to the coverage reports, in Cobertura, LCOV, JaCoCo or Go cover profile format. By default, the coverage reports will be uploaded to Kosli's evidence vault."
+	coverageFormatFlag                   = "[optional] The format of the coverage reports (cobertura, lcov, jacoco or go). If not set, the format of each report is detected from its content."
+	uploadCoverageResultsFlag            = "[defaulted] Whether to upload the provided coverage reports as attachments to Kosli or not."
+	minLineCoverageFlag                  = "[optional] The minimum line coverage, in percent, for the attestation to be compliant. If neither --min-line-coverage nor --min-branch-coverage is set, Kosli decides the compliance of the attestation."
+	minBranchCoverageFlag                = "[optional] The minimum branch coverage, in percent, for the attestation to be compliant. Coverage reports without branches, e.g. Go cover profiles, do not meet any minimum branch coverage."
 	attestationAssertFlag                = "[optional] Exit with non-zero code if the attestation is non-compliant"
 	beginTrailCommitFlag                 = "[defaulted] The git commit from which the trail is begun. (defaulted in some CIs: https://docs.kosli.com/ci-defaults, otherwise defaults to HEAD )."
 	attachmentsFlag                      = "[optional] The comma-separated list of paths of attachments for the reported attestation. Attachments can be files or directories. All attachments are compressed and uploaded to Kosli's evidence vault."
@@ -257,6 +326,7 @@ type GlobalOpts struct {
 	MaxAPIRetries int
 	ConfigFile    string
 	Debug         bool
+	OutboxDir     string
 }
 
 // ConfigGetter defines an interface for getting the default config file path
@@ -339,6 +409,7 @@ func newRootCmd(out, errOut io.Writer, args []string) (*cobra.Command, error) {
 	cmd.PersistentFlags().IntVarP(&global.MaxAPIRetries, "max-api-retries", "r", defaultMaxAPIRetries, maxAPIRetryFlag)
 	cmd.PersistentFlags().StringVarP(&global.ConfigFile, "config-file", "c", getConfigFileFlagDefault(), configFileFlag)
 	cmd.PersistentFlags().BoolVar(&global.Debug, "debug", false, debugFlag)
+	cmd.PersistentFlags().StringVar(&global.OutboxDir, "outbox-dir", "", outboxDirFlag)
 
 	// Add subcommands
 	cmd.AddCommand(
@@ -374,6 +445,7 @@ func newRootCmd(out, errOut io.Writer, args []string) (*cobra.Command, error) {
 		newConfigCmd(out),
 		newAttachPolicyCmd(out),
 		newDetachPolicyCmd(out),
+		newOutboxCmd(out),
 	)
 
 	cobra.AddTemplateFunc("isBeta", isBeta)
@@ -450,6 +522,9 @@ func initialize(cmd *cobra.Command, out, errOut io.Writer) error {
 	if err != nil {
 		return err
 	}
+	if global.OutboxDir != "" {
+		kosliClient.Outbox = requests.NewOutbox(global.OutboxDir)
+	}
 
 	return nil
 }
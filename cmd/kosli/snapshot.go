@@ -24,6 +24,8 @@ func newSnapshotCmd(out io.Writer) *cobra.Command {
 		newSnapshotLambdaCmd(out),
 		newSnapshotS3Cmd(out),
 		newSnapshotAzureAppsCmd(out),
+		newSnapshotCloudRunCmd(out),
+		newSnapshotNomadCmd(out),
 		newSnapshotPathsCmd(out),
 		newSnapshotPathCmd(out),
 	)
@@ -6,14 +6,27 @@ import (
 	"net/http"
 
 	"github.com/kosli-dev/cli/internal/azure"
+	"github.com/kosli-dev/cli/internal/filters"
 	"github.com/kosli-dev/cli/internal/requests"
 	"github.com/spf13/cobra"
 )
 
-const snapshotAzureAppsShortDesc = `Report a snapshot of running Azure web apps and function apps in an Azure resource group to Kosli.  `
+const snapshotAzureAppsShortDesc = `Report a snapshot of running Azure web apps and function apps in Azure resource groups to Kosli.  `
 
 const snapshotAzureAppsLongDesc = snapshotAzureAppsShortDesc + `
-The reported data includes Azure app names, container image digests and creation timestamps.` + azureAuthDesc
+The reported data includes Azure app names, container image digests and creation timestamps.
+
+Skip ^--azure-resource-group-name^ and ^--azure-resource-groups-regex^ to report the apps in all the resource groups
+of the subscription. Or use ^--exclude-resource-groups^ and/or ^--exclude-resource-groups-regex^ to report the apps
+in all resource groups excluding some.
+
+Use ^--include-slots^ to also report the deployment slots (e.g. staging) of the apps. Each slot is reported as a
+separate app named ^app-name/slot-name^. This requires a Kosli server which accepts the slots of Azure apps.
+
+Apps which are not running docker images are fingerprinted from their content, downloaded as a zip package from
+their Kudu site. Function apps running from a package URL (the ^WEBSITE_RUN_FROM_PACKAGE^ application setting)
+are fingerprinted from the content of that package instead. In both cases, the fingerprint is the same as the
+fingerprint of a directory with the content of the package.` + azureAuthDesc
 
 const snapshotAzureAppsExample = `
 # Use Azure Container Registry to get the digests for artifacts in a snapshot
@@ -47,15 +60,39 @@ kosli snapshot azure yourEnvironmentName \
 	--azure-resource-group-name yourAzureResourceGroupName \
 	--api-token yourAPIToken \
 	--org yourOrgName
+
+# Report the apps and their deployment slots in several resource groups
+kosli snapshot azure yourEnvironmentName \
+	--azure-client-id yourAzureClientID \
+	--azure-client-secret yourAzureClientSecret \
+	--azure-tenant-id yourAzureTenantID \
+	--azure-subscription-id yourAzureSubscriptionID \
+	--azure-resource-group-name yourFirstResourceGroupName,yourSecondResourceGroupName \
+	--include-slots \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# Report the apps in all resource groups of a subscription, excluding some
+kosli snapshot azure yourEnvironmentName \
+	--azure-client-id yourAzureClientID \
+	--azure-client-secret yourAzureClientSecret \
+	--azure-tenant-id yourAzureTenantID \
+	--azure-subscription-id yourAzureSubscriptionID \
+	--exclude-resource-groups-regex "^sandbox-.*" \
+	--api-token yourAPIToken \
+	--org yourOrgName
 `
 
 type snapshotAzureAppsOptions struct {
 	azureStaticCredentials *azure.AzureStaticCredentials
+	resourceGroups         *filters.ResourceFilterOptions
+	includeSlots           bool
 }
 
 func newSnapshotAzureAppsCmd(out io.Writer) *cobra.Command {
 	o := new(snapshotAzureAppsOptions)
 	o.azureStaticCredentials = new(azure.AzureStaticCredentials)
+	o.resourceGroups = new(filters.ResourceFilterOptions)
 	cmd := &cobra.Command{
 		Use:     "azure ENVIRONMENT-NAME",
 		Short:   snapshotAzureAppsShortDesc,
@@ -68,6 +105,26 @@ func newSnapshotAzureAppsCmd(out io.Writer) *cobra.Command {
 				return ErrorBeforePrintingUsage(cmd, err.Error())
 			}
 
+			err = MuXRequiredFlags(cmd, []string{"azure-resource-group-name", "exclude-resource-groups"}, false)
+			if err != nil {
+				return err
+			}
+
+			err = MuXRequiredFlags(cmd, []string{"azure-resource-groups-regex", "exclude-resource-groups"}, false)
+			if err != nil {
+				return err
+			}
+
+			err = MuXRequiredFlags(cmd, []string{"azure-resource-group-name", "exclude-resource-groups-regex"}, false)
+			if err != nil {
+				return err
+			}
+
+			err = MuXRequiredFlags(cmd, []string{"azure-resource-groups-regex", "exclude-resource-groups-regex"}, false)
+			if err != nil {
+				return err
+			}
+
 			if o.azureStaticCredentials.DigestsSource != "acr" && o.azureStaticCredentials.DigestsSource != "logs" {
 				return fmt.Errorf("invalid value for --digests-source flag. Valid values are 'acr' and 'logs'")
 			}
@@ -83,14 +140,18 @@ func newSnapshotAzureAppsCmd(out io.Writer) *cobra.Command {
 	cmd.Flags().StringVar(&o.azureStaticCredentials.ClientSecret, "azure-client-secret", "", azureClientSecretFlag)
 	cmd.Flags().StringVar(&o.azureStaticCredentials.TenantId, "azure-tenant-id", "", azureTenantIdFlag)
 	cmd.Flags().StringVar(&o.azureStaticCredentials.SubscriptionId, "azure-subscription-id", "", azureSubscriptionIdFlag)
-	cmd.Flags().StringVar(&o.azureStaticCredentials.ResourceGroupName, "azure-resource-group-name", "", azureResourceGroupNameFlag)
+	cmd.Flags().StringSliceVar(&o.resourceGroups.IncludeNames, "azure-resource-group-name", []string{}, azureResourceGroupNameFlag)
+	cmd.Flags().StringSliceVar(&o.resourceGroups.IncludeNamesRegex, "azure-resource-groups-regex", []string{}, azureResourceGroupsRegexFlag)
+	cmd.Flags().StringSliceVar(&o.resourceGroups.ExcludeNames, "exclude-resource-groups", []string{}, azureExcludeResourceGroupsFlag)
+	cmd.Flags().StringSliceVar(&o.resourceGroups.ExcludeNamesRegex, "exclude-resource-groups-regex", []string{}, azureExcludeResourceGroupsRegexFlag)
+	cmd.Flags().BoolVar(&o.includeSlots, "include-slots", false, azureIncludeSlotsFlag)
 	cmd.Flags().BoolVar(&o.azureStaticCredentials.DownloadLogsAsZip, "zip", false, "Download logs from Azure as zip files")
 	cmd.Flags().StringVar(&o.azureStaticCredentials.DigestsSource, "digests-source", "acr", azureDigestsSourceFlag)
 	addDryRunFlag(cmd)
 
 	err := RequireFlags(cmd, []string{
 		"azure-client-id", "azure-client-secret",
-		"azure-tenant-id", "azure-subscription-id", "azure-resource-group-name",
+		"azure-tenant-id", "azure-subscription-id",
 	})
 	if err != nil {
 		logger.Error("failed to configure required flags: %v", err)
@@ -103,7 +164,7 @@ func (o *snapshotAzureAppsOptions) run(args []string) error {
 	envName := args[0]
 	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/azure-apps", global.Host, global.Org, envName)
 
-	webAppsData, err := o.azureStaticCredentials.GetAzureAppsData(logger)
+	webAppsData, err := o.azureStaticCredentials.GetAzureAppsData(o.resourceGroups, o.includeSlots, logger)
 	if err != nil {
 		return err
 	}
@@ -117,8 +178,8 @@ func (o *snapshotAzureAppsOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("%d azure apps were reported to environment %s", len(webAppsData), envName)
 	}
 	return err
@@ -106,9 +106,13 @@ func (suite *SnapshotAzureAppsTestSuite) TestSnapshotAzureAppsCmd() {
 		},
 		{
 			wantError: true,
-			name:      "snapshot azure fails when Azure resource group name is not set",
-			cmd:       fmt.Sprintf(`snapshot azure %s %s --azure-client-id xxx --azure-client-secret xxx --azure-tenant-id xxx --azure-subscription-id xxx`, suite.envName, suite.defaultKosliArguments),
-			golden:    "Error: required flag(s) \"azure-resource-group-name\" not set\n",
+			name:      "snapshot azure fails when both --azure-resource-group-name and --exclude-resource-groups are set",
+			cmd:       fmt.Sprintf(`snapshot azure %s %s %s --exclude-resource-groups xxx`, suite.envName, suite.defaultKosliArguments, suite.defaultAzureArguments),
+			golden:    "Error: only one of --azure-resource-group-name, --exclude-resource-groups is allowed\n",
+		},
+		{
+			name: "snapshot azure succeeds with deployment slots",
+			cmd:  fmt.Sprintf(`snapshot azure %s %s %s --include-slots`, suite.envName, suite.defaultKosliArguments, suite.defaultAzureArguments),
 		},
 	}
 
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/filters"
+	"github.com/kosli-dev/cli/internal/gcp"
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/spf13/cobra"
+)
+
+const snapshotCloudRunShortDesc = `Report a snapshot of the revisions receiving traffic in Google Cloud Run services to Kosli.`
+
+const snapshotCloudRunLongDesc = snapshotCloudRunShortDesc + `
+The services are listed in the projects given with ^--gcp-projects^ and, optionally, only in the regions given
+with ^--gcp-regions^ (all regions by default).
+Skip ^--services^ and ^--services-regex^ to report all services. Or use ^--exclude^ and/or ^--exclude-regex^ to
+report all services excluding some.
+
+Each revision receiving traffic is reported with its service, traffic percentage and traffic tags (e.g. both
+revisions of a service during a gradual rollout), and with the digests of its container images.
+The digest of an image referenced by tag only is the digest Cloud Run resolved the tag to when the revision was
+created, so it does not change when the tag is moved to another image later. Cloud Run reports this digest for the
+ingress container only: sidecar images referenced by tag only are reported as unresolved, without a digest.
+
+To authenticate to Google Cloud, use ^--gcp-credentials-file^ to provide the JSON key file of a service account,
+or skip it to use the application default credentials (e.g. ^gcloud auth application-default login^ or the
+GOOGLE_APPLICATION_CREDENTIALS env variable). The account needs the ^roles/run.viewer^ role in the projects.`
+
+const snapshotCloudRunExample = `
+# report the revisions receiving traffic in all Cloud Run services of a project (application default credentials):
+kosli snapshot cloud-run yourEnvironmentName \
+	--gcp-projects yourGCPProject \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report the revisions receiving traffic in Cloud Run services in several projects and regions:
+kosli snapshot cloud-run yourEnvironmentName \
+	--gcp-projects yourFirstGCPProject,yourSecondGCPProject \
+	--gcp-regions europe-west1,us-central1 \
+	--gcp-credentials-file yourServiceAccountKeyFile.json \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report the revisions receiving traffic in Cloud Run services with names matching a regex pattern:
+kosli snapshot cloud-run yourEnvironmentName \
+	--gcp-projects yourGCPProject \
+	--services-regex "^payments-.*" \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report the revisions receiving traffic in all (excluding some) Cloud Run services of a project:
+kosli snapshot cloud-run yourEnvironmentName \
+	--gcp-projects yourGCPProject \
+	--exclude service1,service2 \
+	--exclude-regex "^sandbox-.*" \
+	--api-token yourAPIToken \
+	--org yourOrgName
+`
+
+type snapshotCloudRunOptions struct {
+	filter         *filters.ResourceFilterOptions
+	projects       []string
+	regions        []string
+	gcpStaticCreds *gcp.GCPStaticCredentials
+}
+
+func newSnapshotCloudRunCmd(out io.Writer) *cobra.Command {
+	o := new(snapshotCloudRunOptions)
+	o.gcpStaticCreds = new(gcp.GCPStaticCredentials)
+	o.filter = new(filters.ResourceFilterOptions)
+	cmd := &cobra.Command{
+		Use:     "cloud-run ENVIRONMENT-NAME",
+		Short:   snapshotCloudRunShortDesc,
+		Long:    snapshotCloudRunLongDesc,
+		Example: snapshotCloudRunExample,
+		Hidden:  true,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			err := RequireGlobalFlags(global, []string{"Org", "ApiToken"})
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+
+			err = MuXRequiredFlags(cmd, []string{"services", "exclude"}, false)
+			if err != nil {
+				return err
+			}
+			err = MuXRequiredFlags(cmd, []string{"services-regex", "exclude"}, false)
+			if err != nil {
+				return err
+			}
+			err = MuXRequiredFlags(cmd, []string{"services", "exclude-regex"}, false)
+			if err != nil {
+				return err
+			}
+			err = MuXRequiredFlags(cmd, []string{"services-regex", "exclude-regex"}, false)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(args)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.projects, "gcp-projects", []string{}, gcpProjectsFlag)
+	cmd.Flags().StringSliceVar(&o.regions, "gcp-regions", []string{}, gcpRegionsFlag)
+	cmd.Flags().StringVar(&o.gcpStaticCreds.CredentialsFile, "gcp-credentials-file", "", gcpCredentialsFileFlag)
+	cmd.Flags().StringSliceVar(&o.filter.IncludeNames, "services", []string{}, cloudRunServicesFlag)
+	cmd.Flags().StringSliceVar(&o.filter.IncludeNamesRegex, "services-regex", []string{}, cloudRunServicesRegexFlag)
+	cmd.Flags().StringSliceVar(&o.filter.ExcludeNames, "exclude", []string{}, cloudRunExcludeServicesFlag)
+	cmd.Flags().StringSliceVar(&o.filter.ExcludeNamesRegex, "exclude-regex", []string{}, cloudRunExcludeServicesRegexFlag)
+	addDryRunFlag(cmd)
+
+	err := RequireFlags(cmd, []string{"gcp-projects"})
+	if err != nil {
+		logger.Error("failed to configure required flags: %v", err)
+	}
+
+	return cmd
+}
+
+func (o *snapshotCloudRunOptions) run(args []string) error {
+	envName := args[0]
+	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/cloud-run", global.Host, global.Org, envName)
+
+	client, err := o.gcpStaticCreds.NewCloudRunClient()
+	if err != nil {
+		return err
+	}
+	revisionsData, err := gcp.GetCloudRunData(client, o.projects, o.regions, o.filter, logger)
+	if err != nil {
+		return err
+	}
+	for _, revision := range revisionsData {
+		for _, container := range revision.Containers {
+			if container.DigestSource == digest.DigestSourceUnresolved {
+				logger.Warning("the digest of container %s (%s) in Cloud Run revision %s is unresolved: %s",
+					container.Name, container.Image, revision.Revision, container.UnresolvedReason)
+			}
+		}
+	}
+
+	payload := &gcp.CloudRunEnvRequest{
+		Artifacts: revisionsData,
+	}
+
+	reqParams := &requests.RequestParams{
+		Method:  http.MethodPut,
+		URL:     url,
+		Payload: payload,
+		DryRun:  global.DryRun,
+		Token:   global.ApiToken,
+	}
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
+		logger.Info("[%d] Cloud Run revisions were reported to environment %s", len(payload.Artifacts), envName)
+	}
+	return err
+}
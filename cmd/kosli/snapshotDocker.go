@@ -1,19 +1,16 @@
 package main
 
 import (
-	"context"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
-	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/containerruntime"
 	"github.com/kosli-dev/cli/internal/requests"
 	"github.com/kosli-dev/cli/internal/server"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const snapshotDockerShortDesc = `Report a snapshot of running containers from docker host to Kosli.  `
@@ -21,15 +18,68 @@ const snapshotDockerShortDesc = `Report a snapshot of running containers from do
 const snapshotDockerLongDesc = snapshotDockerShortDesc + `
 The reported data includes container image digests 
 and creation timestamps. Containers running images which have not
-been pushed to or pulled from a registry will be ignored.`
+been pushed to or pulled from a registry will be ignored.
+
+The containers can be read from docker, podman or containerd with ^--runtime^:
+  - docker: the Docker daemon at ^DOCKER_HOST^ or its default socket.
+  - podman: the Docker-compatible API of the podman socket (the libpod socket serves it too),
+    ^/run/podman/podman.sock^ or ^$XDG_RUNTIME_DIR/podman/podman.sock^ for rootless podman.
+  - containerd: the CRI API of the containerd socket ^/run/containerd/containerd.sock^, which only sees the
+    containers managed through CRI (e.g. by the kubelet), and fails if it reports no running containers.
+    Use ^--containerd-namespace^ to read the containers of a containerd namespace with the containerd API instead,
+    e.g. the containers started with ctr or nerdctl (in the ^default^ namespace unless set otherwise).
+By default (^auto^), docker is used if ^DOCKER_HOST^ is set, otherwise the runtime of the first of these default
+sockets that exists. Use ^--runtime-endpoint^ to use another socket or address.
+
+Use ^--include-services^ to report the containers started by docker compose or docker swarm with their compose
+project and service and/or their swarm stack and service, taken from the container labels. This requires a Kosli
+server which accepts the services of docker artifacts.
+Use ^--selector^ to only report the containers with labels matching a label selector, e.g. to report the
+containers of each compose project of a host to a different Kosli environment. The selector supports
+equality (^key=value^, ^key!=value^), set (^key in (v1,v2)^, ^key notin (v1,v2)^) and existence
+(^key^, ^!key^) requirements, separated by commas.`
 
 const snapshotDockerExample = `
 # report what is running in a docker host:
 kosli snapshot docker yourEnvironmentName \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report what is running in a host with rootless podman:
+kosli snapshot docker yourEnvironmentName \
+	--runtime podman \
+	--runtime-endpoint $XDG_RUNTIME_DIR/podman/podman.sock \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report what is running in a docker compose project of a docker host, with the compose services of the containers:
+kosli snapshot docker yourEnvironmentName \
+	--selector com.docker.compose.project=yourComposeProject \
+	--include-services \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report what is running in a containerd host:
+kosli snapshot docker yourEnvironmentName \
+	--runtime containerd \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report the containers started with nerdctl in a containerd host:
+kosli snapshot docker yourEnvironmentName \
+	--runtime containerd \
+	--containerd-namespace default \
 	--api-token yourAPIToken \
 	--org yourOrgName`
 
-type snapshotDockerOptions struct{}
+type snapshotDockerOptions struct {
+	runtime             string
+	runtimeEndpoint     string
+	containerdNamespace string
+	selector            string
+	labelSelector       labels.Selector
+	includeServices     bool
+}
 
 func newSnapshotDockerCmd(out io.Writer) *cobra.Command {
 	o := new(snapshotDockerOptions)
@@ -45,12 +95,31 @@ func newSnapshotDockerCmd(out io.Writer) *cobra.Command {
 				return ErrorBeforePrintingUsage(cmd, err.Error())
 			}
 
-			return nil
+			o.labelSelector, err = containerruntime.ParseSelector(o.selector)
+			if err != nil {
+				return err
+			}
+
+			if o.containerdNamespace != "" && o.runtime != containerruntime.RuntimeContainerd && o.runtime != containerruntime.RuntimeAuto {
+				return fmt.Errorf("--containerd-namespace can only be used with --runtime containerd")
+			}
+
+			for _, runtime := range containerruntime.Runtimes {
+				if o.runtime == runtime {
+					return nil
+				}
+			}
+			return fmt.Errorf("--runtime must be one of: %s", strings.Join(containerruntime.Runtimes, ", "))
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return o.run(args)
 		},
 	}
+	cmd.Flags().StringVar(&o.runtime, "runtime", containerruntime.RuntimeAuto, containerRuntimeFlag)
+	cmd.Flags().StringVar(&o.runtimeEndpoint, "runtime-endpoint", "", containerRuntimeEndpointFlag)
+	cmd.Flags().StringVar(&o.containerdNamespace, "containerd-namespace", "", containerdNamespaceFlag)
+	cmd.Flags().StringVar(&o.selector, "selector", "", containerSelectorFlag)
+	cmd.Flags().BoolVar(&o.includeServices, "include-services", false, includeServicesFlag)
 	addDryRunFlag(cmd)
 	return cmd
 }
@@ -60,10 +129,15 @@ func (o *snapshotDockerOptions) run(args []string) error {
 
 	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/docker", global.Host, global.Org, envName)
 
-	artifacts, err := CreateDockerArtifactsData()
+	artifacts, err := createContainerArtifactsData(o.runtime, o.runtimeEndpoint, o.containerdNamespace, o.labelSelector)
 	if err != nil {
 		return err
 	}
+	if !o.includeServices {
+		for _, artifact := range artifacts {
+			artifact.Service = nil
+		}
+	}
 
 	payload := &server.ServerEnvRequest{
 		Artifacts: artifacts,
@@ -76,37 +150,26 @@ func (o *snapshotDockerOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("[%d] containers were reported to environment %s", len(payload.Artifacts), envName)
 	}
 	return err
 }
 
+// CreateDockerArtifactsData returns the artifacts data of the running containers of the Docker daemon
 func CreateDockerArtifactsData() ([]*server.ServerData, error) {
-	result := []*server.ServerData{}
-	cli, err := client.NewClientWithOpts(client.FromEnv)
-	if err != nil {
-		return result, err
-	}
+	return createContainerArtifactsData(containerruntime.RuntimeDocker, "", "", labels.Everything())
+}
 
-	containers, err := cli.ContainerList(context.Background(), container.ListOptions{})
+// createContainerArtifactsData returns the artifacts data of the running containers
+// of a container runtime with labels matching selector
+func createContainerArtifactsData(runtime, endpoint, containerdNamespace string, selector labels.Selector) ([]*server.ServerData, error) {
+	rt, err := containerruntime.New(runtime, endpoint, containerdNamespace)
 	if err != nil {
-		return result, err
-	}
-
-	for _, c := range containers {
-		digests := make(map[string]string)
-		digests[c.Image], err = digest.DockerImageSha256(c.Image)
-		if err != nil {
-			if errors.Is(err, digest.ErrRepoDigestUnavailable) {
-				containerName := strings.TrimPrefix(c.Names[0], "/")
-				logger.Info("ignoring container '%s' as it uses an image with no repo digest", containerName)
-				continue
-			}
-			return result, err
-		}
-		result = append(result, &server.ServerData{Digests: digests, CreationTimestamp: c.Created})
+		return []*server.ServerData{}, err
 	}
-	return result, nil
+	defer rt.Close()
+	logger.Debug("reading the running containers from %s", rt.Name())
+	return containerruntime.CreateArtifactsData(rt, selector, logger)
 }
@@ -52,6 +52,38 @@ func (suite *SnapshotDockerTestSuite) TestCreateDockerArtifactsData() {
 	}
 }
 
+func (suite *SnapshotDockerTestSuite) TestSnapshotDockerCmd() {
+	defaultKosliArguments := " --host http://localhost:8001 --org docs-cmd-test-user --api-token secret"
+	tests := []cmdTestCase{
+		{
+			wantError: true,
+			name:      "snapshot docker fails if --runtime is not supported",
+			cmd:       "snapshot docker snapshot-docker-env --runtime cri-o" + defaultKosliArguments,
+			golden:    "Error: --runtime must be one of: auto, docker, podman, containerd\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot docker fails if --containerd-namespace is used with another runtime than containerd",
+			cmd:       "snapshot docker snapshot-docker-env --runtime podman --containerd-namespace default" + defaultKosliArguments,
+			golden:    "Error: --containerd-namespace can only be used with --runtime containerd\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot docker fails if the runtime of --runtime-endpoint cannot be detected",
+			cmd:       "snapshot docker snapshot-docker-env --runtime-endpoint /run/unknown.sock" + defaultKosliArguments,
+			golden:    "Error: cannot detect the container runtime of endpoint /run/unknown.sock, use --runtime to set it\n",
+		},
+		{
+			wantError:   true,
+			name:        "snapshot docker fails if --selector is invalid",
+			cmd:         "snapshot docker snapshot-docker-env --selector env==prod=x" + defaultKosliArguments,
+			goldenRegex: "^Error: invalid label selector \"env==prod=x\": .*\n",
+		},
+	}
+
+	runTestCmd(suite.T(), tests)
+}
+
 func (suite *SnapshotDockerTestSuite) withRunningContainer(imageName string) {
 	containerID, err := docker.RunDockerContainer(imageName)
 	require.NoError(suite.T(), err, fmt.Sprintf("RunDockerContainer for %s", imageName))
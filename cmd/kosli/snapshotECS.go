@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/kosli-dev/cli/internal/aws"
+	"github.com/kosli-dev/cli/internal/digest"
 	"github.com/kosli-dev/cli/internal/filters"
 	"github.com/kosli-dev/cli/internal/requests"
 	"github.com/spf13/cobra"
@@ -14,7 +15,15 @@ import (
 const snapshotECSShortDesc = `Report a snapshot of running containers in one or more AWS ECS cluster(s) to Kosli.  `
 const snapshotECSLongDesc = snapshotECSShortDesc + `
 Skip ^--clusters^ and ^--clusters-regex^ to report all clusters in a given AWS account. Or use ^--exclude^ and/or ^--exclude-regex^ to report all clusters excluding some.
-The reported data includes container image digests and creation timestamps.` + awsAuthDesc
+Use ^--service-name^ to only report the tasks of some services.
+The reported data includes container image digests and creation timestamps, and for each task its service name,
+task definition family and revision, and launch type.
+
+Containers whose image is referenced by tag only and has no digest in the task description are reported
+as unresolved together with the reason. Use ^--resolve-missing-digests^ to look up the digests of these images
+instead: images in ECR are looked up with the ECR API using the AWS credentials, and images in other registries are
+looked up in their registry (use ^--registry-username^ and ^--registry-password^ for private registries).
+Each image is looked up once per snapshot, and each container records whether its digest was observed or resolved.` + awsAuthDesc
 
 const snapshotECSExample = `
 # report what is running in an entire AWS ECS cluster:
@@ -27,14 +36,14 @@ kosli snapshot ecs yourEnvironmentName \
 	--api-token yourAPIToken \
 	--org yourOrgName
 
-# report what is running in a specific AWS ECS service within a cluster:
+# report what is running in specific AWS ECS services within a cluster:
 export AWS_REGION=yourAWSRegion
 export AWS_ACCESS_KEY_ID=yourAWSAccessKeyID
 export AWS_SECRET_ACCESS_KEY=yourAWSSecretAccessKey
 
 kosli snapshot ecs yourEnvironmentName \
 	--clusters yourECSClusterName \
-	--service-name yourECSServiceName \
+	--service-name yourECSServiceName,yourOtherECSServiceName \
 	--api-token yourAPIToken \
 	--org yourOrgName
 
@@ -46,6 +55,13 @@ kosli snapshot ecs yourEnvironmentName \
 	--api-token yourAPIToken \
 	--org yourOrgName
 
+# report what is running in an entire AWS ECS cluster and look up the digests of images referenced by tag only:
+kosli snapshot ecs yourEnvironmentName \
+	--clusters yourECSClusterName \
+	--resolve-missing-digests \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
 # report what is running in all ECS clusters in an AWS account except for clusters with names matching given regex patterns:
 kosli snapshot ecs yourEnvironmentName \
 	--aws-key-id yourAWSAccessKeyID \
@@ -57,9 +73,12 @@ kosli snapshot ecs yourEnvironmentName \
 `
 
 type snapshotECSOptions struct {
-	filter         *filters.ResourceFilterOptions
-	serviceName    string
-	awsStaticCreds *aws.AWSStaticCreds
+	filter                *filters.ResourceFilterOptions
+	services              []string
+	awsStaticCreds        *aws.AWSStaticCreds
+	resolveMissingDigests bool
+	registryUsername      string
+	registryPassword      string
 }
 
 func newSnapshotECSCmd(out io.Writer) *cobra.Command {
@@ -94,6 +113,11 @@ func newSnapshotECSCmd(out io.Writer) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			for _, flag := range []string{"registry-username", "registry-password"} {
+				if cmd.Flags().Changed(flag) && !o.resolveMissingDigests {
+					return fmt.Errorf("--%s is only allowed when --resolve-missing-digests is set", flag)
+				}
+			}
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -107,13 +131,15 @@ func newSnapshotECSCmd(out io.Writer) *cobra.Command {
 	cmd.Flags().StringSliceVar(&o.filter.ExcludeNamesRegex, "exclude-regex", []string{}, ecsExcludeClustersRegexFlag)
 
 	cmd.Flags().StringSliceVarP(&o.filter.IncludeNames, "cluster", "C", []string{}, ecsClusterFlag)
-	cmd.Flags().StringVarP(&o.serviceName, "service-name", "s", "", ecsServiceFlag)
+	cmd.Flags().StringSliceVarP(&o.services, "service-name", "s", []string{}, ecsServiceFlag)
+	cmd.Flags().BoolVar(&o.resolveMissingDigests, "resolve-missing-digests", false, ecsResolveMissingDigestsFlag)
+	cmd.Flags().StringVar(&o.registryUsername, "registry-username", "", registryUsernameFlag)
+	cmd.Flags().StringVar(&o.registryPassword, "registry-password", "", registryPasswordFlag)
 	addAWSAuthFlags(cmd, o.awsStaticCreds)
 	addDryRunFlag(cmd)
 
 	err := DeprecateFlags(cmd, map[string]string{
-		"cluster":      "use --clusters instead",
-		"service-name": "it will be removed in a future release",
+		"cluster": "use --clusters instead",
 	})
 	if err != nil {
 		logger.Error("failed to configure deprecated flags: %v", err)
@@ -126,10 +152,22 @@ func (o *snapshotECSOptions) run(args []string) error {
 	envName := args[0]
 	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/ECS", global.Host, global.Org, envName)
 
-	tasksData, err := o.awsStaticCreds.GetEcsTasksData(o.filter)
+	var digestResolver digest.DigestResolver
+	if o.resolveMissingDigests {
+		digestResolver = o.registryDigest
+	}
+	tasksData, err := o.awsStaticCreds.GetEcsTasksData(o.filter, o.services, digestResolver)
 	if err != nil {
 		return err
 	}
+	for _, task := range tasksData {
+		for _, container := range task.Containers {
+			if container.DigestSource == digest.DigestSourceUnresolved {
+				logger.Warning("the digest of container %s (%s) in task %s is unresolved: %s",
+					container.Name, container.Image, task.TaskArn, container.UnresolvedReason)
+			}
+		}
+	}
 
 	payload := &aws.EcsEnvRequest{
 		Artifacts: tasksData,
@@ -142,9 +180,17 @@ func (o *snapshotECSOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("[%d] containers were reported to environment %s", len(payload.Artifacts), envName)
 	}
 	return err
 }
+
+// registryDigest looks up the digest of an image in ECR, or in its registry for images stored elsewhere
+func (o *snapshotECSOptions) registryDigest(image string) (string, error) {
+	if _, ok := aws.ParseECRImage(image); ok {
+		return o.awsStaticCreds.ECRImageDigest(image)
+	}
+	return digest.OciSha256(image, o.registryUsername, o.registryPassword)
+}
@@ -71,6 +71,12 @@ func (suite *SnapshotECSTestSuite) TestSnapshotECSCmd() {
 			cmd:       fmt.Sprintf(`snapshot ecs %s --clusters sss --exclude-regex sss %s`, suite.envName, suite.defaultKosliArguments),
 			golden:    "Error: only one of --cluster, --clusters, --exclude-regex is allowed\n",
 		},
+		{
+			wantError: true,
+			name:      "snapshot ECS fails if --registry-username is set without --resolve-missing-digests",
+			cmd:       fmt.Sprintf(`snapshot ecs %s --clusters sss --registry-username user %s`, suite.envName, suite.defaultKosliArguments),
+			golden:    "Error: --registry-username is only allowed when --resolve-missing-digests is set\n",
+		},
 		{
 			name: "snapshot ECS works if no filtering flags are used",
 			cmd:  fmt.Sprintf(`snapshot ecs %s %s`, suite.envName, suite.defaultKosliArguments),
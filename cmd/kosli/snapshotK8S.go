@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/kosli-dev/cli/internal/digest"
 	"github.com/kosli-dev/cli/internal/filters"
 	"github.com/kosli-dev/cli/internal/kube"
 	"github.com/kosli-dev/cli/internal/requests"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 const snapshotK8SShortDesc = `Report a snapshot of running pods in a K8S cluster or namespace(s) to Kosli.  `
@@ -19,7 +28,17 @@ const snapshotK8SShortDesc = `Report a snapshot of running pods in a K8S cluster
 const snapshotK8SLongDesc = snapshotK8SShortDesc + `
 Skip ^--namespaces^ and ^--namespaces-regex^ to report all pods in all namespaces in a cluster.
 The reported data includes pod container images digests and creation timestamps. You can customize the scope of reporting
-to include or exclude namespaces.`
+to include or exclude namespaces.
+
+Containers whose image digest cannot be found in the pod status (e.g. containers that have not started yet,
+or whose runtime reports a local image ID) are reported as unresolved together with the reason.
+Use ^--resolve-missing-digests^ to look up the digests of these containers' images in their registries instead.
+
+With ^--watch^, the command runs as a long-running reporter (e.g. as a Deployment inside the cluster).
+It watches pods using shared informers and reports a new snapshot as soon as the set of running
+image digests changes (after waiting for ^--debounce^ without further pod changes, or at most ^--max-delay^
+while pods keep changing), so that short-lived deployments are recorded too. A full snapshot is also reported every ^--resync-interval^ as a safety net.
+The reporter stops gracefully on SIGTERM or SIGINT.`
 
 const snapshotK8SExample = `
 # report what is running in an entire cluster using kubeconfig at $HOME/.kube/config:
@@ -51,58 +70,287 @@ kosli snapshot k8s yourEnvironmentName \
 	--kubeconfig /path/to/kube/config \
 	--api-token yourAPIToken \
 	--org yourOrgName
+
+# continuously report what is running in a given namespace whenever it changes:
+kosli snapshot k8s yourEnvironmentName \
+	--namespaces your-namespace \
+	--watch \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report what is running in a cluster using a specific context of the kubeconfig:
+kosli snapshot k8s yourEnvironmentName \
+	--context yourContextName \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report what is running in several clusters to their environments using a clusters file:
+kosli snapshot k8s \
+	--clusters-file path/to/your/clusters/file \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report what is running in a cluster and look up missing image digests in a private registry:
+kosli snapshot k8s yourEnvironmentName \
+	--resolve-missing-digests \
+	--registry-username yourRegistryUsername \
+	--registry-password yourRegistryPassword \
+	--api-token yourAPIToken \
+	--org yourOrgName
 `
 
 type snapshotK8SOptions struct {
-	kubeconfig string
+	kubeconfig   string
+	context      string
+	clustersFile string
 	// namespaces        []string
 	// excludeNamespaces []string
-	filter *filters.ResourceFilterOptions
+	filter                *filters.ResourceFilterOptions
+	watch                 bool
+	debounce              time.Duration
+	maxDelay              time.Duration
+	resyncInterval        time.Duration
+	resolveMissingDigests bool
+	registryUsername      string
+	registryPassword      string
 }
 
 func newSnapshotK8SCmd(out io.Writer) *cobra.Command {
 	o := new(snapshotK8SOptions)
 	o.filter = new(filters.ResourceFilterOptions)
 	cmd := &cobra.Command{
-		Use:     "k8s ENVIRONMENT-NAME",
+		Use:     "k8s [ENVIRONMENT-NAME]",
 		Aliases: []string{"kubernetes"},
 		Short:   snapshotK8SShortDesc,
 		Long:    snapshotK8SLongDesc,
 		Example: snapshotK8SExample,
-		Args:    cobra.ExactArgs(1),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if o.clustersFile != "" {
+				if len(args) > 0 {
+					return fmt.Errorf("the environment name argument is not allowed with --clusters-file, environments are set in the clusters file")
+				}
+				return nil
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			err := RequireGlobalFlags(global, []string{"Org", "ApiToken"})
 			if err != nil {
 				return ErrorBeforePrintingUsage(cmd, err.Error())
 			}
-			return MuXRequiredFlags(cmd, []string{"namespaces", "exclude-namespaces"}, false)
+			err = MuXRequiredFlags(cmd, []string{"namespaces", "exclude-namespaces"}, false)
+			if err != nil {
+				return err
+			}
+			err = MuXRequiredFlags(cmd, []string{"clusters-file", "context"}, false)
+			if err != nil {
+				return err
+			}
+			err = MuXRequiredFlags(cmd, []string{"clusters-file", "watch"}, false)
+			if err != nil {
+				return err
+			}
+			for _, flag := range []string{"debounce", "max-delay", "resync-interval"} {
+				if cmd.Flags().Changed(flag) && !o.watch {
+					return fmt.Errorf("--%s is only allowed when --watch is set", flag)
+				}
+			}
+			for _, flag := range []string{"registry-username", "registry-password"} {
+				if cmd.Flags().Changed(flag) && !o.resolveMissingDigests {
+					return fmt.Errorf("--%s is only allowed when --resolve-missing-digests is set", flag)
+				}
+			}
+			if o.debounce <= 0 || o.maxDelay <= 0 || o.resyncInterval <= 0 {
+				return fmt.Errorf("--debounce, --max-delay and --resync-interval must be positive durations")
+			}
+			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return o.run(args)
+			return o.run(out, args)
 		},
 	}
 
 	cmd.Flags().StringVarP(&o.kubeconfig, "kubeconfig", "k", defaultKubeConfigPath(), kubeconfigFlag)
+	cmd.Flags().StringVar(&o.context, "context", "", k8sContextFlag)
+	cmd.Flags().StringVar(&o.clustersFile, "clusters-file", "", k8sClustersFileFlag)
 	cmd.Flags().StringSliceVarP(&o.filter.IncludeNames, "namespaces", "n", []string{}, namespacesFlag)
 	cmd.Flags().StringSliceVar(&o.filter.IncludeNamesRegex, "namespaces-regex", []string{}, namespacesRegexFlag)
 	cmd.Flags().StringSliceVarP(&o.filter.ExcludeNames, "exclude-namespaces", "x", []string{}, excludeNamespacesFlag)
 	cmd.Flags().StringSliceVar(&o.filter.ExcludeNamesRegex, "exclude-namespaces-regex", []string{}, excludeNamespacesRegexFlag)
+	cmd.Flags().BoolVar(&o.watch, "watch", false, k8sWatchFlag)
+	cmd.Flags().DurationVar(&o.debounce, "debounce", 10*time.Second, k8sDebounceFlag)
+	cmd.Flags().DurationVar(&o.maxDelay, "max-delay", time.Minute, k8sMaxDelayFlag)
+	cmd.Flags().DurationVar(&o.resyncInterval, "resync-interval", 15*time.Minute, k8sResyncIntervalFlag)
+	cmd.Flags().BoolVar(&o.resolveMissingDigests, "resolve-missing-digests", false, k8sResolveMissingDigestsFlag)
+	cmd.Flags().StringVar(&o.registryUsername, "registry-username", "", registryUsernameFlag)
+	cmd.Flags().StringVar(&o.registryPassword, "registry-password", "", registryPasswordFlag)
 	addDryRunFlag(cmd)
 	return cmd
 }
 
-func (o *snapshotK8SOptions) run(args []string) error {
+func (o *snapshotK8SOptions) run(out io.Writer, args []string) error {
+	if o.clustersFile != "" {
+		return o.runClusters(out)
+	}
+
 	envName := args[0]
-	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/K8S", global.Host, global.Org, envName)
-	clientset, err := kube.NewK8sClientSet(o.kubeconfig)
+	clientset, err := o.newClientSet(o.context)
 	if err != nil {
 		return err
 	}
+
+	if o.watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		watcher := kube.NewPodWatcher(clientset, o.filter, o.debounce, o.maxDelay, o.resyncInterval, logger)
+		watcher.DigestResolver = clientset.DigestResolver
+		return watcher.Run(ctx, func(podsData []*kube.PodData) error {
+			_, err := o.report(envName, podsData)
+			return err
+		})
+	}
+
 	podsData, err := clientset.GetPodsData(o.filter, logger)
 	if err != nil {
 		return err
 	}
+	_, err = o.report(envName, podsData)
+	return err
+}
+
+// clusterSnapshotResult is the outcome of reporting the snapshot of one cluster in a clusters file
+type clusterSnapshotResult struct {
+	context     string
+	environment string
+	pods        int
+	// queued is true when the snapshot was queued in the outbox instead of being reported
+	queued bool
+	err    error
+}
 
+// status returns the status of the snapshot of the cluster in the summary of runClusters
+func (r *clusterSnapshotResult) status() string {
+	switch {
+	case r.err != nil:
+		return fmt.Sprintf("failed: %s", strings.TrimSpace(r.err.Error()))
+	case global.DryRun:
+		return "dry-run"
+	case r.queued:
+		return "queued"
+	}
+	return "reported"
+}
+
+// runClusters reports a snapshot of each cluster in the clusters file concurrently
+// and prints a summary of the results. Failing clusters do not prevent the others from being reported.
+func (o *snapshotK8SOptions) runClusters(out io.Writer) error {
+	cs, err := processClustersSpecFile(o.clustersFile)
+	if err != nil {
+		return err
+	}
+
+	results := make([]*clusterSnapshotResult, len(cs.Clusters))
+	var wg sync.WaitGroup
+	for i, cluster := range cs.Clusters {
+		wg.Add(1)
+		go func(i int, cluster kube.ClusterSpec) {
+			defer wg.Done()
+			result := &clusterSnapshotResult{context: cluster.Context, environment: cluster.Environment}
+			result.pods, result.queued, result.err = o.snapshotCluster(cluster)
+			if result.err != nil {
+				logger.Warning("failed to report cluster %s to environment %s: %v", cluster.Context, cluster.Environment, result.err)
+			}
+			results[i] = result
+		}(i, cluster)
+	}
+	wg.Wait()
+
+	failed := 0
+	rows := []string{}
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+		}
+		rows = append(rows, fmt.Sprintf("%s\t%s\t%d\t%s", result.context, result.environment, result.pods, result.status()))
+	}
+	tabFormattedPrint(out, []string{"CONTEXT", "ENVIRONMENT", "PODS", "STATUS"}, rows)
+
+	if failed > 0 {
+		return fmt.Errorf("[%d] of [%d] clusters failed to be reported", failed, len(results))
+	}
+	return nil
+}
+
+// snapshotCluster reports a snapshot of a single cluster and returns the number of reported pods
+// and whether the snapshot was queued in the outbox
+func (o *snapshotK8SOptions) snapshotCluster(cluster kube.ClusterSpec) (int, bool, error) {
+	filter, err := cluster.Filter(o.filter)
+	if err != nil {
+		return 0, false, err
+	}
+	clientset, err := o.newClientSet(cluster.Context)
+	if err != nil {
+		return 0, false, err
+	}
+	podsData, err := clientset.GetPodsData(filter, logger)
+	if err != nil {
+		return 0, false, err
+	}
+	queued, err := o.report(cluster.Environment, podsData)
+	return len(podsData), queued, err
+}
+
+// newClientSet creates a k8s clientset for a kubeconfig context
+func (o *snapshotK8SOptions) newClientSet(contextName string) (*kube.K8SConnection, error) {
+	clientset, err := kube.NewK8sClientSetForContext(o.kubeconfig, contextName)
+	if err != nil {
+		return nil, err
+	}
+	if o.resolveMissingDigests {
+		clientset.DigestResolver = o.registryDigest
+	}
+	return clientset, nil
+}
+
+func processClustersSpecFile(clustersSpecFile string) (*kube.ClustersSpec, error) {
+	var cs *kube.ClustersSpec
+	v := viper.New()
+	v.SetConfigFile(clustersSpecFile)
+
+	if err := v.ReadInConfig(); err != nil {
+		return cs, fmt.Errorf("failed to parse clusters file [%s] : %v", clustersSpecFile, err)
+	}
+
+	if err := v.UnmarshalExact(&cs); err != nil {
+		return cs, fmt.Errorf("failed to unmarshal clusters file [%s] : %v", clustersSpecFile, err)
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(cs); err != nil {
+		return cs, fmt.Errorf("clusters file [%s] is invalid: %v", clustersSpecFile, err)
+	}
+
+	for _, cluster := range cs.Clusters {
+		if _, err := cluster.Filter(nil); err != nil {
+			return cs, fmt.Errorf("clusters file [%s] is invalid: %v", clustersSpecFile, err)
+		}
+	}
+	return cs, nil
+}
+
+// report sends a K8S environment snapshot with the given pods data to Kosli
+// and returns whether it was queued in the outbox instead of being reported
+func (o *snapshotK8SOptions) report(envName string, podsData []*kube.PodData) (bool, error) {
+	for _, pod := range podsData {
+		for _, container := range pod.Containers {
+			if container.DigestSource == digest.DigestSourceUnresolved {
+				logger.Warning("the digest of container %s (%s) in pod %s/%s is unresolved: %s",
+					container.Name, container.Image, pod.Namespace, pod.PodName, container.UnresolvedReason)
+			}
+		}
+	}
+
+	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/K8S", global.Host, global.Org, envName)
 	payload := &kube.K8sEnvRequest{
 		Artifacts: podsData,
 	}
@@ -114,11 +362,16 @@ func (o *snapshotK8SOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("[%d] pods were reported to environment %s", len(payload.Artifacts), envName)
 	}
-	return err
+	return err == nil && res != nil && res.Queued, err
+}
+
+// registryDigest looks up the digest of an image in its registry
+func (o *snapshotK8SOptions) registryDigest(image string) (string, error) {
+	return digest.OciSha256(image, o.registryUsername, o.registryPassword)
 }
 
 func defaultKubeConfigPath() string {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -36,6 +37,81 @@ func (suite *SnapshotK8STestSuite) TestSnapshotK8SCmd() {
 			cmd:       fmt.Sprintf(`snapshot k8s %s --namespaces default --exclude-namespaces default %s`, suite.envName, suite.defaultKosliArguments),
 			golden:    "Error: only one of --namespaces, --exclude-namespaces is allowed\n",
 		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if --debounce is set without --watch",
+			cmd:       fmt.Sprintf(`snapshot k8s %s --debounce 5s %s`, suite.envName, suite.defaultKosliArguments),
+			golden:    "Error: --debounce is only allowed when --watch is set\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if --max-delay is set without --watch",
+			cmd:       fmt.Sprintf(`snapshot k8s %s --max-delay 30s %s`, suite.envName, suite.defaultKosliArguments),
+			golden:    "Error: --max-delay is only allowed when --watch is set\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if --resync-interval is set without --watch",
+			cmd:       fmt.Sprintf(`snapshot k8s %s --resync-interval 1m %s`, suite.envName, suite.defaultKosliArguments),
+			golden:    "Error: --resync-interval is only allowed when --watch is set\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if --debounce is not a positive duration",
+			cmd:       fmt.Sprintf(`snapshot k8s %s --watch --debounce 0s %s`, suite.envName, suite.defaultKosliArguments),
+			golden:    "Error: --debounce, --max-delay and --resync-interval must be positive durations\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if --registry-username is set without --resolve-missing-digests",
+			cmd:       fmt.Sprintf(`snapshot k8s %s --registry-username user %s`, suite.envName, suite.defaultKosliArguments),
+			golden:    "Error: --registry-username is only allowed when --resolve-missing-digests is set\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if both --clusters-file and --context are set",
+			cmd:       fmt.Sprintf(`snapshot k8s --clusters-file testdata/clusters-files/valid-clustersfile.yml --context foo %s`, suite.defaultKosliArguments),
+			golden:    "Error: only one of --clusters-file, --context is allowed\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if both --clusters-file and --watch are set",
+			cmd:       fmt.Sprintf(`snapshot k8s --clusters-file testdata/clusters-files/valid-clustersfile.yml --watch %s`, suite.defaultKosliArguments),
+			golden:    "Error: only one of --clusters-file, --watch is allowed\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if --clusters-file is set together with an environment name",
+			cmd:       fmt.Sprintf(`snapshot k8s %s --clusters-file testdata/clusters-files/valid-clustersfile.yml %s`, suite.envName, suite.defaultKosliArguments),
+			golden:    "Error: the environment name argument is not allowed with --clusters-file, environments are set in the clusters file\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if the clusters file has invalid keys",
+			cmd:       fmt.Sprintf(`snapshot k8s --clusters-file testdata/clusters-files/invalid-clustersfile.yml %s`, suite.defaultKosliArguments),
+			golden:    "Error: failed to unmarshal clusters file [testdata/clusters-files/invalid-clustersfile.yml] : 1 error(s) decoding:\n\n* 'clusters[0]' has invalid keys: namespace\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if the clusters file has duplicate contexts",
+			cmd:       fmt.Sprintf(`snapshot k8s --clusters-file testdata/clusters-files/duplicate-contexts-clustersfile.yml %s`, suite.defaultKosliArguments),
+			golden:    "Error: clusters file [testdata/clusters-files/duplicate-contexts-clustersfile.yml] is invalid: Key: 'ClustersSpec.Clusters' Error:Field validation for 'Clusters' failed on the 'unique' tag\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S fails if a cluster in the clusters file both includes and excludes namespaces",
+			cmd:       fmt.Sprintf(`snapshot k8s --clusters-file testdata/clusters-files/conflicting-namespaces-clustersfile.yml %s`, suite.defaultKosliArguments),
+			golden:    "Error: clusters file [testdata/clusters-files/conflicting-namespaces-clustersfile.yml] is invalid: cluster prod: only one of namespaces/namespaces_regex or exclude_namespaces/exclude_namespaces_regex is allowed\n",
+		},
+		{
+			wantError: true,
+			name:      "snapshot K8S reports failing clusters in the summary",
+			cmd: fmt.Sprintf(`snapshot k8s --clusters-file testdata/clusters-files/unknown-context-clustersfile.yml --kubeconfig testdata/clusters-files/kubeconfig %s`,
+				suite.defaultKosliArguments),
+			goldenRegex: "CONTEXT\\s+ENVIRONMENT\\s+PODS\\s+STATUS\n" +
+				"does-not-exist\\s+snapshot-k8s-env\\s+0\\s+failed: could not build config for context does-not-exist: context \"does-not-exist\" does not exist\n" +
+				"Error: \\[1\\] of \\[1\\] clusters failed to be reported\n",
+		},
 		{
 			wantError: true,
 			name:      "snapshot K8S fails if 2 args are provided",
@@ -58,3 +134,39 @@ func (suite *SnapshotK8STestSuite) TestSnapshotK8SCmd() {
 func TestSnapshotK8STestSuite(t *testing.T) {
 	suite.Run(t, new(SnapshotK8STestSuite))
 }
+
+func TestClusterSnapshotResultStatus(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		result *clusterSnapshotResult
+		dryRun bool
+		want   string
+	}{
+		{
+			name:   "a reported snapshot",
+			result: &clusterSnapshotResult{},
+			want:   "reported",
+		},
+		{
+			name:   "a snapshot queued in the outbox",
+			result: &clusterSnapshotResult{queued: true},
+			want:   "queued",
+		},
+		{
+			name:   "a dry-run snapshot",
+			result: &clusterSnapshotResult{},
+			dryRun: true,
+			want:   "dry-run",
+		},
+		{
+			name:   "a failed snapshot",
+			result: &clusterSnapshotResult{err: fmt.Errorf("connection refused\n")},
+			want:   "failed: connection refused",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			global = &GlobalOpts{DryRun: tt.dryRun}
+			require.Equal(t, tt.want, tt.result.status())
+		})
+	}
+}
@@ -14,7 +14,14 @@ import (
 const snapshotLambdaShortDesc = `Report a snapshot of artifacts deployed as one or more AWS Lambda functions and their digests to Kosli.`
 
 const snapshotLambdaLongDesc = snapshotLambdaShortDesc + `  
-Skip ^--function-names^ and ^--function-names-regex^ to report all functions in a given AWS account. Or use ^--exclude^ and/or ^--exclude-regex^ to report all functions excluding some.` + awsAuthDesc
+Skip ^--function-names^ and ^--function-names-regex^ to report all functions in a given AWS account. Or use ^--exclude^ and/or ^--exclude-regex^ to report all functions excluding some.
+
+By default, the code of the ^$LATEST^ version of each function is reported. Use ^--include-aliases^ to also report
+the published versions the function aliases route traffic to, each with its aliases and their routing weights
+(e.g. both versions of an alias during a canary deployment).
+
+For functions packaged as container images, the fingerprint is the digest of the resolved container image
+(e.g. in ECR) rather than the code SHA256 of the function.` + awsAuthDesc
 
 const snapshotLambdaExample = `
 # report all Lambda functions running in an AWS account (AWS auth provided in env variables):
@@ -67,6 +74,17 @@ kosli snapshot lambda yourEnvironmentName \
 	--api-token yourAPIToken \
 	--org yourOrgName
 
+# report what is running in the latest version of AWS Lambda functions and in the versions their aliases route traffic to:
+export AWS_REGION=yourAWSRegion
+export AWS_ACCESS_KEY_ID=yourAWSAccessKeyID
+export AWS_SECRET_ACCESS_KEY=yourAWSSecretAccessKey
+
+kosli snapshot lambda yourEnvironmentName \
+	--function-names yourFirstFunctionName,yourSecondFunctionName \
+	--include-aliases \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
 # report what is running in the latest version of an AWS Lambda function (AWS auth provided in flags):
 kosli snapshot lambda yourEnvironmentName \
 	--function-names yourFunctionName \
@@ -79,6 +97,7 @@ kosli snapshot lambda yourEnvironmentName \
 
 type snapshotLambdaOptions struct {
 	functionVersion string
+	includeAliases  bool
 	filter          *filters.ResourceFilterOptions
 	awsStaticCreds  *aws.AWSStaticCreds
 }
@@ -130,6 +149,7 @@ func newSnapshotLambdaCmd(out io.Writer) *cobra.Command {
 	cmd.Flags().StringSliceVar(&o.filter.IncludeNames, "function-names", []string{}, functionNamesFlag)
 	cmd.Flags().StringSliceVar(&o.filter.IncludeNamesRegex, "function-names-regex", []string{}, functionNamesRegexFlag)
 	cmd.Flags().StringVar(&o.functionVersion, "function-version", "", functionVersionFlag)
+	cmd.Flags().BoolVar(&o.includeAliases, "include-aliases", false, includeAliasesFlag)
 	cmd.Flags().StringSliceVar(&o.filter.ExcludeNames, "exclude", []string{}, excludeFlag)
 	cmd.Flags().StringSliceVar(&o.filter.ExcludeNamesRegex, "exclude-regex", []string{}, excludeRegexFlag)
 	addAWSAuthFlags(cmd, o.awsStaticCreds)
@@ -150,7 +170,7 @@ func (o *snapshotLambdaOptions) run(args []string) error {
 	envName := args[0]
 
 	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/lambda", global.Host, global.Org, envName)
-	lambdaData, err := o.awsStaticCreds.GetLambdaPackageData(o.filter)
+	lambdaData, err := o.awsStaticCreds.GetLambdaPackageData(o.filter, o.includeAliases)
 	if err != nil {
 		return err
 	}
@@ -166,8 +186,8 @@ func (o *snapshotLambdaOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("%d lambda functions were reported to environment %s", len(lambdaData), envName)
 	}
 	return err
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/filters"
+	"github.com/kosli-dev/cli/internal/nomad"
+	"github.com/kosli-dev/cli/internal/requests"
+	"github.com/spf13/cobra"
+)
+
+const snapshotNomadShortDesc = `Report a snapshot of the running allocations of HashiCorp Nomad jobs to Kosli.`
+
+const snapshotNomadLongDesc = snapshotNomadShortDesc + `
+The running allocations are listed with the Nomad HTTP API at ^--nomad-address^, in all the namespaces the
+^--nomad-token^ can read. Skip ^--namespaces^ and ^--namespaces-regex^ to report all namespaces, or use
+^--exclude-namespaces^ and/or ^--exclude-namespaces-regex^ to report all namespaces excluding some.
+Jobs can be filtered the same way with ^--jobs^, ^--jobs-regex^, ^--exclude-jobs^ and ^--exclude-jobs-regex^.
+
+Each allocation is reported with its namespace, job, job version, task group, node and start time, and with
+the digests of what its tasks run:
+  - the images of ^docker^ and ^podman^ tasks, when the image is referenced by digest.
+  - the artifacts downloaded by tasks (^artifact^ blocks), when they have a sha256 checksum.
+
+Images referenced by tag only are reported as unresolved together with the reason. Use ^--resolve-missing-digests^
+to look up the digests of these images in their registry instead (use ^--registry-username^ and ^--registry-password^
+for private registries). Each image is looked up once per snapshot.
+
+The token needs the ^read-job^ capability in the namespaces to report.
+
+If the Nomad HTTP API is served over TLS with a private CA, use ^--nomad-ca-cert^ to verify its certificate, and
+^--nomad-client-cert^ and ^--nomad-client-key^ if the Nomad agents verify HTTPS clients.
+Like the Nomad CLI, the Nomad flags default to the NOMAD_ADDR, NOMAD_TOKEN, NOMAD_CACERT, NOMAD_CLIENT_CERT and
+NOMAD_CLIENT_KEY env variables.`
+
+const snapshotNomadExample = `
+# report the running allocations of all jobs in a Nomad cluster:
+kosli snapshot nomad yourEnvironmentName \
+	--nomad-address https://nomad.example.com:4646 \
+	--nomad-token yourNomadToken \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report the running allocations of the jobs in some namespaces, excluding some jobs:
+kosli snapshot nomad yourEnvironmentName \
+	--nomad-address https://nomad.example.com:4646 \
+	--nomad-token yourNomadToken \
+	--namespaces default,payments \
+	--exclude-jobs-regex "^test-.*" \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report the running allocations of a Nomad cluster with TLS and mutual TLS enabled:
+kosli snapshot nomad yourEnvironmentName \
+	--nomad-address https://nomad.example.com:4646 \
+	--nomad-token yourNomadToken \
+	--nomad-ca-cert nomad-ca.pem \
+	--nomad-client-cert cli.pem \
+	--nomad-client-key cli-key.pem \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report the running allocations of a Nomad cluster and look up the digests of images referenced by tag only:
+kosli snapshot nomad yourEnvironmentName \
+	--nomad-address https://nomad.example.com:4646 \
+	--nomad-token yourNomadToken \
+	--resolve-missing-digests \
+	--api-token yourAPIToken \
+	--org yourOrgName
+`
+
+type snapshotNomadOptions struct {
+	address               string
+	token                 string
+	tlsFiles              *nomad.TLSFiles
+	namespaces            *filters.ResourceFilterOptions
+	jobs                  *filters.ResourceFilterOptions
+	resolveMissingDigests bool
+	registryUsername      string
+	registryPassword      string
+}
+
+func newSnapshotNomadCmd(out io.Writer) *cobra.Command {
+	o := new(snapshotNomadOptions)
+	o.namespaces = new(filters.ResourceFilterOptions)
+	o.jobs = new(filters.ResourceFilterOptions)
+	o.tlsFiles = new(nomad.TLSFiles)
+	cmd := &cobra.Command{
+		Use:     "nomad ENVIRONMENT-NAME",
+		Short:   snapshotNomadShortDesc,
+		Long:    snapshotNomadLongDesc,
+		Example: snapshotNomadExample,
+		Hidden:  true,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			err := RequireGlobalFlags(global, []string{"Org", "ApiToken"})
+			if err != nil {
+				return ErrorBeforePrintingUsage(cmd, err.Error())
+			}
+
+			err = setNomadFlagsFromEnv(cmd)
+			if err != nil {
+				return err
+			}
+
+			for _, resource := range []string{"namespaces", "jobs"} {
+				for _, include := range []string{resource, resource + "-regex"} {
+					for _, exclude := range []string{"exclude-" + resource, "exclude-" + resource + "-regex"} {
+						err = MuXRequiredFlags(cmd, []string{include, exclude}, false)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			}
+			for _, flag := range []string{"registry-username", "registry-password"} {
+				if cmd.Flags().Changed(flag) && !o.resolveMissingDigests {
+					return fmt.Errorf("--%s is only allowed when --resolve-missing-digests is set", flag)
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(args)
+		},
+	}
+
+	cmd.Flags().StringVar(&o.address, "nomad-address", nomad.DefaultAddress, nomadAddressFlag)
+	cmd.Flags().StringVar(&o.token, "nomad-token", "", nomadTokenFlag)
+	cmd.Flags().StringVar(&o.tlsFiles.CACert, "nomad-ca-cert", "", nomadCACertFlag)
+	cmd.Flags().StringVar(&o.tlsFiles.ClientCert, "nomad-client-cert", "", nomadClientCertFlag)
+	cmd.Flags().StringVar(&o.tlsFiles.ClientKey, "nomad-client-key", "", nomadClientKeyFlag)
+	cmd.Flags().StringSliceVar(&o.namespaces.IncludeNames, "namespaces", []string{}, nomadNamespacesFlag)
+	cmd.Flags().StringSliceVar(&o.namespaces.IncludeNamesRegex, "namespaces-regex", []string{}, nomadNamespacesRegexFlag)
+	cmd.Flags().StringSliceVar(&o.namespaces.ExcludeNames, "exclude-namespaces", []string{}, nomadExcludeNamespacesFlag)
+	cmd.Flags().StringSliceVar(&o.namespaces.ExcludeNamesRegex, "exclude-namespaces-regex", []string{}, nomadExcludeNamespacesRegexFlag)
+	cmd.Flags().StringSliceVar(&o.jobs.IncludeNames, "jobs", []string{}, nomadJobsFlag)
+	cmd.Flags().StringSliceVar(&o.jobs.IncludeNamesRegex, "jobs-regex", []string{}, nomadJobsRegexFlag)
+	cmd.Flags().StringSliceVar(&o.jobs.ExcludeNames, "exclude-jobs", []string{}, nomadExcludeJobsFlag)
+	cmd.Flags().StringSliceVar(&o.jobs.ExcludeNamesRegex, "exclude-jobs-regex", []string{}, nomadExcludeJobsRegexFlag)
+	cmd.Flags().BoolVar(&o.resolveMissingDigests, "resolve-missing-digests", false, nomadResolveMissingDigestsFlag)
+	cmd.Flags().StringVar(&o.registryUsername, "registry-username", "", registryUsernameFlag)
+	cmd.Flags().StringVar(&o.registryPassword, "registry-password", "", registryPasswordFlag)
+	addDryRunFlag(cmd)
+
+	return cmd
+}
+
+func (o *snapshotNomadOptions) run(args []string) error {
+	envName := args[0]
+	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/nomad", global.Host, global.Org, envName)
+
+	client, err := nomad.NewNomadClient(o.address, o.token, o.tlsFiles)
+	if err != nil {
+		return err
+	}
+	if o.resolveMissingDigests {
+		client.DigestResolver = o.registryDigest
+	}
+	allocationsData, err := client.GetAllocationsData(o.namespaces, o.jobs, logger)
+	if err != nil {
+		return err
+	}
+	for _, alloc := range allocationsData {
+		for _, task := range alloc.Tasks {
+			if task.DigestSource == digest.DigestSourceUnresolved {
+				logger.Warning("the digest of %s %s of task %s in allocation %s is unresolved: %s",
+					task.Kind, task.Artifact, task.Name, alloc.AllocationID, task.UnresolvedReason)
+			}
+		}
+	}
+
+	payload := &nomad.NomadEnvRequest{
+		Artifacts: allocationsData,
+	}
+
+	reqParams := &requests.RequestParams{
+		Method:  http.MethodPut,
+		URL:     url,
+		Payload: payload,
+		DryRun:  global.DryRun,
+		Token:   global.ApiToken,
+	}
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
+		logger.Info("[%d] allocations were reported to environment %s", len(payload.Artifacts), envName)
+	}
+	return err
+}
+
+// registryDigest looks up the digest of an image in its registry
+func (o *snapshotNomadOptions) registryDigest(image string) (string, error) {
+	return digest.OciSha256(image, o.registryUsername, o.registryPassword)
+}
+
+// nomadFlagsEnvVars are the env variables of the Nomad CLI used as defaults of the Nomad flags
+var nomadFlagsEnvVars = map[string]string{
+	"nomad-address":     "NOMAD_ADDR",
+	"nomad-token":       "NOMAD_TOKEN",
+	"nomad-ca-cert":     "NOMAD_CACERT",
+	"nomad-client-cert": "NOMAD_CLIENT_CERT",
+	"nomad-client-key":  "NOMAD_CLIENT_KEY",
+}
+
+// setNomadFlagsFromEnv sets the Nomad flags which are not set from the env variables of the Nomad CLI
+func setNomadFlagsFromEnv(cmd *cobra.Command) error {
+	for flag, envVar := range nomadFlagsEnvVars {
+		value, ok := os.LookupEnv(envVar)
+		if !ok || value == "" || cmd.Flags().Changed(flag) {
+			continue
+		}
+		err := cmd.Flags().Set(flag, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
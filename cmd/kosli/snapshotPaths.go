@@ -18,8 +18,8 @@ const snapshotPathsShortDesc = `Report a snapshot of artifacts running in specif
 
 const pathSpecFileDesc = `Paths files can be in YAML, JSON or TOML formats.
 They specify a list of artifacts to fingerprint. For each artifact, the file specifies a base path to look for the artifact in 
-and (optionally) a list of paths to exclude. Excluded paths are relative to the artifact path(s) and can be literal paths or
-glob patterns.  
+and (optionally) a list of paths to exclude and an ignore file. Excluded paths are relative to the artifact path(s) and can be literal paths or
+glob patterns. The ignore file (^ignore_file^) is in ^.kosli_ignore^ format and can be stored outside the artifact.  
 The supported glob pattern syntax is what is documented here: https://pkg.go.dev/path/filepath#Match , 
 plus the ability to use recursive globs "**"
 
@@ -32,7 +32,8 @@ This is an example YAML paths spec file:
 artifacts:
   artifact_name_a:
     path: dir1
-    exclude: [subdir1, **/log]` +
+    exclude: [subdir1, **/log]
+    ignore_file: path/to/ignore-file` +
 	"\n```"
 
 const snapshotPathsLongDesc = snapshotPathsShortDesc + `
@@ -112,41 +113,49 @@ func (o *snapshotPathsOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("[%d] artifacts were reported to environment %s", len(payload.Artifacts), envName)
 	}
 	return err
 }
 
 func processPathSpecFile(pathsSpecFile string) (*server.PathsSpec, error) {
-	var ps *server.PathsSpec
+	ps := new(server.PathsSpec)
+	err := processSpecFile(pathsSpecFile, "path spec", ps)
+	return ps, err
+}
+
+// processSpecFile loads a YAML, JSON or TOML spec file into spec, a pointer to a struct, and validates it.
+// specName is the name of the kind of spec file used in error messages.
+// opts configure how the spec file is decoded into spec.
+func processSpecFile(specFile, specName string, spec interface{}, opts ...viper.DecoderConfigOption) error {
 	v := viper.New()
-	dir, file := filepath.Split(pathsSpecFile)
+	dir, file := filepath.Split(specFile)
 	file = strings.TrimSuffix(file, filepath.Ext(file))
 
-	// Set the base name of the pathspec file, without the file extension.
+	// Set the base name of the spec file, without the file extension.
 	v.SetConfigName(file)
 
 	// Set the dir path where viper should look for the
-	// pathspec file. By default, we are looking in the current working directory.
+	// spec file. By default, we are looking in the current working directory.
 	if dir == "" {
 		dir = "."
 	}
 	v.AddConfigPath(dir)
 
 	if err := v.ReadInConfig(); err != nil {
-		return ps, fmt.Errorf("failed to parse path spec file [%s] : %v", pathsSpecFile, err)
+		return fmt.Errorf("failed to parse %s file [%s] : %v", specName, specFile, err)
 	}
 
-	if err := v.UnmarshalExact(&ps); err != nil {
-		return ps, fmt.Errorf("failed to unmarshal path spec file [%s] : %v", pathsSpecFile, err)
+	if err := v.UnmarshalExact(spec, opts...); err != nil {
+		return fmt.Errorf("failed to unmarshal %s file [%s] : %v", specName, specFile, err)
 	}
 
 	validate := validator.New(validator.WithRequiredStructEnabled())
-	if err := validate.Struct(ps); err != nil {
-		return ps, fmt.Errorf("path spec file [%s] is invalid: %v", pathsSpecFile, err)
+	if err := validate.Struct(spec); err != nil {
+		return fmt.Errorf("%s file [%s] is invalid: %v", specName, specFile, err)
 	}
 
-	return ps, nil
+	return nil
 }
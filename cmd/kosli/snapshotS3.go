@@ -14,10 +14,40 @@ const snapshotS3ShortDesc = `Report a snapshot of the content of an AWS S3 bucke
 
 const snapshotS3LongDesc = snapshotS3ShortDesc + awsAuthDesc + `
 You can report the entire bucket content, or filter some of the content using ^--include^ and ^--exclude^.
-In all cases, the content is reported as one artifact. If you wish to report separate files/dirs within the same bucket as separate artifacts, you need to run the command twice.
+In both cases, the content is reported as one artifact.
+
+To report separate files/dirs within the same bucket as separate artifacts, use ^--prefixes-file^.
+Each artifact is fingerprinted as a directory containing the objects under its prefix (or as a file if the prefix is
+the key of a single object), so it gets the same fingerprint as the directory it was uploaded from.
+
+` + s3SpecFileDesc + `
+
+The objects are hashed as they are downloaded, without being stored on disk, and the fingerprint is the same as
+the fingerprint of a directory with the bucket content.
+Use ^--use-checksums^ to fingerprint the objects which have a SHA256 checksum (objects uploaded with
+^--checksum-algorithm SHA256^) from their checksum, without downloading them. Objects uploaded in multiple parts
+have a checksum of the checksums of their parts, so they are still downloaded.
 
 ` + kosliIgnoreDesc
 
+const s3SpecFileDesc = `Prefixes files can be in YAML, JSON or TOML formats.
+They specify a list of artifacts to fingerprint. For each artifact, the file specifies the prefix of the artifact
+in the bucket and (optionally) a list of paths to include or a list of paths to exclude. Included and excluded paths are
+relative to the prefix.
+
+This is an example YAML prefixes file:
+` +
+	"```yaml\n" +
+	`version: 1
+artifacts:
+  frontend_a:
+    prefix: apps/frontend-a
+    exclude: [maps]
+  frontend_b:
+    prefix: apps/frontend-b
+    include: [index.html, assets]` +
+	"\n```"
+
 const snapshotS3Example = `
 # report the contents of an entire AWS S3 bucket (AWS auth provided in env variables):
 export AWS_REGION=yourAWSRegion
@@ -59,12 +89,36 @@ kosli snapshot s3 yourEnvironmentName \
 	--exclude file.txt,path/within/bucket \
 	--api-token yourAPIToken \
 	--org yourOrgName
+
+# report several artifacts in an AWS S3 bucket using a prefixes file (AWS auth provided in env variables):
+export AWS_REGION=yourAWSRegion
+export AWS_ACCESS_KEY_ID=yourAWSAccessKeyID
+export AWS_SECRET_ACCESS_KEY=yourAWSSecretAccessKey
+
+kosli snapshot s3 yourEnvironmentName \
+	--bucket yourBucketName \
+	--prefixes-file path/to/your/prefixes/file \
+	--api-token yourAPIToken \
+	--org yourOrgName
+
+# report the contents of an entire AWS S3 bucket using the SHA256 checksums of the objects (AWS auth provided in env variables):
+export AWS_REGION=yourAWSRegion
+export AWS_ACCESS_KEY_ID=yourAWSAccessKeyID
+export AWS_SECRET_ACCESS_KEY=yourAWSSecretAccessKey
+
+kosli snapshot s3 yourEnvironmentName \
+	--bucket yourBucketName \
+	--use-checksums \
+	--api-token yourAPIToken \
+	--org yourOrgName
 `
 
 type snapshotS3Options struct {
 	bucket         string
 	includePaths   []string
 	excludePaths   []string
+	useChecksums   bool
+	prefixesFile   string
 	awsStaticCreds *aws.AWSStaticCreds
 }
 
@@ -89,6 +143,16 @@ func newSnapshotS3Cmd(out io.Writer) *cobra.Command {
 				return err
 			}
 
+			err = MuXRequiredFlags(cmd, []string{"prefixes-file", "include"}, false)
+			if err != nil {
+				return err
+			}
+
+			err = MuXRequiredFlags(cmd, []string{"prefixes-file", "exclude"}, false)
+			if err != nil {
+				return err
+			}
+
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -99,6 +163,8 @@ func newSnapshotS3Cmd(out io.Writer) *cobra.Command {
 	cmd.Flags().StringVar(&o.bucket, "bucket", "", bucketNameFlag)
 	cmd.Flags().StringSliceVarP(&o.includePaths, "include", "i", []string{}, bucketPathsFlag)
 	cmd.Flags().StringSliceVarP(&o.excludePaths, "exclude", "x", []string{}, excludeBucketPathsFlag)
+	cmd.Flags().StringVar(&o.prefixesFile, "prefixes-file", "", s3PrefixesFileFlag)
+	cmd.Flags().BoolVar(&o.useChecksums, "use-checksums", false, useS3ChecksumsFlag)
 	addAWSAuthFlags(cmd, o.awsStaticCreds)
 	addDryRunFlag(cmd)
 
@@ -114,7 +180,19 @@ func (o *snapshotS3Options) run(args []string) error {
 	envName := args[0]
 	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/S3", global.Host, global.Org, envName)
 
-	s3Data, err := o.awsStaticCreds.GetS3Data(o.bucket, o.includePaths, o.excludePaths, logger)
+	var (
+		s3Data []*aws.S3Data
+		err    error
+	)
+	if o.prefixesFile != "" {
+		spec := new(aws.S3Spec)
+		if err := processSpecFile(o.prefixesFile, "prefixes", spec); err != nil {
+			return err
+		}
+		s3Data, err = o.awsStaticCreds.GetS3ArtifactsData(o.bucket, spec, o.useChecksums, logger)
+	} else {
+		s3Data, err = o.awsStaticCreds.GetS3Data(o.bucket, o.includePaths, o.excludePaths, o.useChecksums, logger)
+	}
 	if err != nil {
 		return err
 	}
@@ -129,9 +207,13 @@ func (o *snapshotS3Options) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
-		logger.Info("bucket %s was reported to environment %s", o.bucket, envName)
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
+		if o.prefixesFile != "" {
+			logger.Info("[%d] artifacts in bucket %s were reported to environment %s", len(s3Data), o.bucket, envName)
+		} else {
+			logger.Info("bucket %s was reported to environment %s", o.bucket, envName)
+		}
 	}
 	return err
 }
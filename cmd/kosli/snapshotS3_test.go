@@ -88,6 +88,32 @@ func (suite *SnapshotS3TestSuite) TestSnapshotS3Cmd() {
 			cmd:    fmt.Sprintf(`snapshot s3 %s %s --bucket %s --exclude dummy`, suite.envName, suite.defaultKosliArguments, suite.bucketName),
 			golden: "bucket kosli-cli-public was reported to environment snapshot-s3-env\n",
 		},
+		{
+			wantError: true,
+			name:      "snapshot s3 fails if --prefixes-file and --include are set",
+			cmd:       fmt.Sprintf(`snapshot s3 %s %s --bucket %s --prefixes-file testdata/s3-prefixes-files/valid-prefixesfile.yml --include foo`, suite.envName, suite.defaultKosliArguments, suite.bucketName),
+			golden:    "Error: only one of --prefixes-file, --include is allowed\n",
+		},
+		{
+			wantError:   true,
+			name:        "fails when the prefixes file does not exist",
+			cmd:         fmt.Sprintf(`snapshot s3 %s %s --bucket %s --prefixes-file testdata/s3-prefixes-files/does-not-exist.yml`, suite.envName, suite.defaultKosliArguments, suite.bucketName),
+			goldenRegex: "Error: failed to parse prefixes file \\[testdata\\/s3-prefixes-files\\/does-not-exist\\.yml\\] : Config File \"does-not-exist\" Not Found in \"\\[.*\\/cli\\/cmd\\/kosli\\/testdata\\/s3-prefixes-files\\]\"\n",
+		},
+		{
+			wantError: true,
+			name:      "fails when the prefixes file has an artifact with both include and exclude",
+			cmd:       fmt.Sprintf(`snapshot s3 %s %s --bucket %s --prefixes-file testdata/s3-prefixes-files/invalid-values-prefixesfile.yml`, suite.envName, suite.defaultKosliArguments, suite.bucketName),
+			golden:    "Error: prefixes file [testdata/s3-prefixes-files/invalid-values-prefixesfile.yml] is invalid: Key: 'S3Spec.Artifacts[dummy].Exclude' Error:Field validation for 'Exclude' failed on the 'excluded_with' tag\n",
+		},
+		{
+			name: "can snapshot several artifacts using --prefixes-file",
+			cmd:  fmt.Sprintf(`snapshot s3 %s %s --bucket %s --prefixes-file testdata/s3-prefixes-files/valid-prefixesfile.yml`, suite.envName, suite.defaultKosliArguments, suite.bucketName),
+			additionalConfig: snapshotS3TestConfig{
+				requireAuthToBeSet: true,
+			},
+			golden: "[2] artifacts in bucket kosli-cli-public were reported to environment snapshot-s3-env\n",
+		},
 	}
 
 	for _, t := range tests {
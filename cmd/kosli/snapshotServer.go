@@ -44,6 +44,7 @@ kosli snapshot server yourEnvironmentName \
 type snapshotServerOptions struct {
 	paths        []string
 	excludePaths []string
+	ignoreFile   string
 }
 
 func newSnapshotServerCmd(out io.Writer) *cobra.Command {
@@ -76,6 +77,7 @@ func newSnapshotServerCmd(out io.Writer) *cobra.Command {
 	cmd.Flags().StringSliceVarP(&o.paths, "paths", "p", []string{}, pathsFlag)
 	cmd.Flags().StringSliceVarP(&o.excludePaths, "exclude", "x", []string{}, serverExcludePathsFlag)
 	cmd.Flags().StringSliceVarP(&o.excludePaths, "e", "e", []string{}, serverExcludePathsFlag)
+	cmd.Flags().StringVar(&o.ignoreFile, "ignore-file", "", serverIgnoreFileFlag)
 	addDryRunFlag(cmd)
 
 	err := DeprecateFlags(cmd, map[string]string{
@@ -98,7 +100,7 @@ func (o *snapshotServerOptions) run(args []string) error {
 
 	url := fmt.Sprintf("%s/api/v2/environments/%s/%s/report/server", global.Host, global.Org, envName)
 
-	artifacts, err := server.CreateServerArtifactsData(o.paths, o.excludePaths, logger)
+	artifacts, err := server.CreateServerArtifactsData(o.paths, o.excludePaths, o.ignoreFile, logger)
 	if err != nil {
 		return err
 	}
@@ -113,8 +115,8 @@ func (o *snapshotServerOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		logger.Info("[%d] artifacts were reported to environment %s", len(payload.Artifacts), envName)
 	}
 	return err
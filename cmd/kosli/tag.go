@@ -102,8 +102,8 @@ func (o *tagOptions) run(args []string) error {
 		DryRun:  global.DryRun,
 		Token:   global.ApiToken,
 	}
-	_, err = kosliClient.Do(reqParams)
-	if err == nil && !global.DryRun {
+	res, err := kosliClient.Do(reqParams)
+	if requestReported(res, err) {
 		var addMsg, removedMsg, msg string
 		if len(o.payload.SetTags) > 0 {
 			keys := make([]string, 0, len(o.payload.SetTags))
@@ -2,11 +2,17 @@ package aws
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,16 +20,17 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
-	s3manager "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrTypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/kosli-dev/cli/internal/digest"
 	"github.com/kosli-dev/cli/internal/filters"
 	"github.com/kosli-dev/cli/internal/logger"
-	"github.com/kosli-dev/cli/internal/utils"
 )
 
 // EcsEnvRequest represents the PUT request body to be sent to kosli from ECS
@@ -33,10 +40,45 @@ type EcsEnvRequest struct {
 
 // EcsTaskData represents the harvested ECS task data
 type EcsTaskData struct {
-	TaskArn   string            `json:"taskArn"`
-	Cluster   string            `json:"cluster,omitempty"`
-	Digests   map[string]string `json:"digests"`
-	StartedAt int64             `json:"creationTimestamp"`
+	TaskArn                string              `json:"taskArn"`
+	Cluster                string              `json:"cluster,omitempty"`
+	Service                string              `json:"service,omitempty"`
+	TaskDefinitionFamily   string              `json:"taskDefinitionFamily,omitempty"`
+	TaskDefinitionRevision int32               `json:"taskDefinitionRevision,omitempty"`
+	LaunchType             string              `json:"launchType,omitempty"`
+	CapacityProvider       string              `json:"capacityProvider,omitempty"`
+	Digests                map[string]string   `json:"digests"`
+	Containers             []*EcsContainerData `json:"containers,omitempty"`
+	StartedAt              int64               `json:"creationTimestamp"`
+}
+
+// EcsContainerData represents the harvested data of a single container in an ECS task
+type EcsContainerData struct {
+	Name             string `json:"name"`
+	Image            string `json:"image"`
+	Digest           string `json:"digest"`
+	DigestSource     string `json:"digestSource"`
+	UnresolvedReason string `json:"unresolvedReason,omitempty"`
+}
+
+// UnresolvedImage returns the image of the container if its digest is unresolved
+func (c *EcsContainerData) UnresolvedImage() string {
+	if c.DigestSource != digest.DigestSourceUnresolved {
+		return ""
+	}
+	return c.Image
+}
+
+// SetResolvedDigest records the digest of the container image resolved from its registry
+func (c *EcsContainerData) SetResolvedDigest(sha256 string) {
+	c.Digest = sha256
+	c.DigestSource = digest.DigestSourceResolved
+	c.UnresolvedReason = ""
+}
+
+// AddUnresolvedReason adds to the reason why the digest of the container image is unresolved
+func (c *EcsContainerData) AddUnresolvedReason(reason string) {
+	c.UnresolvedReason = digest.AppendUnresolvedReason(c.UnresolvedReason, reason)
 }
 
 // S3EnvRequest represents the PUT request body to be sent to kosli from a server
@@ -59,8 +101,24 @@ type S3Data struct {
 type LambdaData struct {
 	Digests               map[string]string `json:"digests"`
 	LastModifiedTimestamp int64             `json:"creationTimestamp"`
+	// Version is the version of the function ($LATEST or a published version number), only set when aliases are reported
+	Version string `json:"version,omitempty"`
+	// Aliases are the aliases routing traffic to this version of the function
+	Aliases []*LambdaAliasRoute `json:"aliases,omitempty"`
+	// ImageUri is the resolved image URI of a function packaged as a container image
+	ImageUri string `json:"imageUri,omitempty"`
+}
+
+// LambdaAliasRoute is an alias routing a share of its traffic to a version of a Lambda function
+type LambdaAliasRoute struct {
+	Name string `json:"name"`
+	// Weight is the share of the alias traffic routed to the version, between 0 and 1
+	Weight float64 `json:"weight"`
 }
 
+// lambdaLatestVersion is the version of a Lambda function with its unpublished code
+const lambdaLatestVersion = "$LATEST"
+
 // NewEcsTaskData creates a NewEcsTaskData object from an ECS task
 func NewEcsTaskData(taskArn, cluster string, digests map[string]string, startedAt time.Time) *EcsTaskData {
 	return &EcsTaskData{
@@ -120,6 +178,19 @@ func (staticCreds *AWSStaticCreds) NewLambdaClient() (*lambda.Client, error) {
 	return lambda.NewFromConfig(cfg), nil
 }
 
+// NewECRClient returns a new ECR API client for a region.
+// If region is empty, the region of the AWS config is used.
+func (staticCreds *AWSStaticCreds) NewECRClient(region string) (*ecr.Client, error) {
+	cfg, err := staticCreds.NewAWSConfigFromEnvOrFlags()
+	if err != nil {
+		return nil, err
+	}
+	if region != "" {
+		cfg.Region = region
+	}
+	return ecr.NewFromConfig(cfg), nil
+}
+
 // NewECSClient returns a new ECS API client
 func (staticCreds *AWSStaticCreds) NewECSClient() (*ecs.Client, error) {
 	cfg, err := staticCreds.NewAWSConfigFromEnvOrFlags()
@@ -166,8 +237,10 @@ func getFilteredLambdaFuncs(client *lambda.Client, nextMarker *string, allFuncti
 	return allFunctions, nil
 }
 
-// GetLambdaPackageData returns a digest and metadata of a Lambda function package
-func (staticCreds *AWSStaticCreds) GetLambdaPackageData(filter *filters.ResourceFilterOptions) ([]*LambdaData, error) {
+// GetLambdaPackageData returns a digest and metadata of a Lambda function package.
+// If includeAliases is true, the versions the aliases of each function route traffic to are reported
+// too, with the aliases and their weights.
+func (staticCreds *AWSStaticCreds) GetLambdaPackageData(filter *filters.ResourceFilterOptions, includeAliases bool) ([]*LambdaData, error) {
 	lambdaData := []*LambdaData{}
 	client, err := staticCreds.NewLambdaClient()
 	if err != nil {
@@ -199,7 +272,7 @@ func (staticCreds *AWSStaticCreds) GetLambdaPackageData(filter *filters.Resource
 				return // Error somewhere, terminate
 			default: // Default is a must to avoid blocking
 			}
-			oneLambdaData, err := getAndProcessOneLambdaFunc(client, functionName)
+			functionData, err := getLambdaFuncData(client, functionName, includeAliases)
 			if err != nil {
 				// Non-blocking send of error
 				select {
@@ -211,7 +284,7 @@ func (staticCreds *AWSStaticCreds) GetLambdaPackageData(filter *filters.Resource
 			}
 
 			mutex.Lock()
-			lambdaData = append(lambdaData, oneLambdaData)
+			lambdaData = append(lambdaData, functionData...)
 			mutex.Unlock()
 
 		}(*function.FunctionName)
@@ -227,11 +300,90 @@ func (staticCreds *AWSStaticCreds) GetLambdaPackageData(filter *filters.Resource
 	return lambdaData, nil
 }
 
-// getAndProcessOneLambdaFunc get a lambda function by its name and return a LambdaData object from it
-func getAndProcessOneLambdaFunc(client *lambda.Client, functionName string) (*LambdaData, error) {
+// lambdaAPI is the part of the Lambda API used to collect the data of a function
+type lambdaAPI interface {
+	lambda.ListAliasesAPIClient
+	GetFunction(ctx context.Context, params *lambda.GetFunctionInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error)
+	GetFunctionConfiguration(ctx context.Context, params *lambda.GetFunctionConfigurationInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error)
+}
+
+// getLambdaFuncData returns the LambdaData of the $LATEST version of a function and,
+// if includeAliases is true, of the versions its aliases route traffic to
+func getLambdaFuncData(client lambdaAPI, functionName string, includeAliases bool) ([]*LambdaData, error) {
+	if !includeAliases {
+		data, err := getAndProcessOneLambdaFunc(client, functionName, "")
+		if err != nil {
+			return nil, err
+		}
+		return []*LambdaData{data}, nil
+	}
+
+	routes, err := getLambdaAliasRoutes(client, functionName)
+	if err != nil {
+		return nil, err
+	}
+	versions := []string{lambdaLatestVersion}
+	for version := range routes {
+		if version != lambdaLatestVersion {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions[1:], func(i, j int) bool {
+		a, _ := strconv.Atoi(versions[i+1])
+		b, _ := strconv.Atoi(versions[j+1])
+		return a < b
+	})
+
+	functionData := []*LambdaData{}
+	for _, version := range versions {
+		data, err := getAndProcessOneLambdaFunc(client, functionName, version)
+		if err != nil {
+			return nil, err
+		}
+		data.Version = version
+		data.Aliases = routes[version]
+		functionData = append(functionData, data)
+	}
+	return functionData, nil
+}
+
+// getLambdaAliasRoutes returns the aliases of a function and their weights by the version they route traffic to
+func getLambdaAliasRoutes(client lambda.ListAliasesAPIClient, functionName string) (map[string][]*LambdaAliasRoute, error) {
+	routes := map[string][]*LambdaAliasRoute{}
+	paginator := lambda.NewListAliasesPaginator(client, &lambda.ListAliasesInput{FunctionName: aws.String(functionName)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return routes, err
+		}
+		for _, alias := range page.Aliases {
+			primaryWeight := 1.0
+			if alias.RoutingConfig != nil {
+				for version, weight := range alias.RoutingConfig.AdditionalVersionWeights {
+					routes[version] = append(routes[version], &LambdaAliasRoute{Name: aws.ToString(alias.Name), Weight: weight})
+					primaryWeight -= weight
+				}
+			}
+			version := aws.ToString(alias.FunctionVersion)
+			routes[version] = append(routes[version], &LambdaAliasRoute{Name: aws.ToString(alias.Name), Weight: primaryWeight})
+		}
+	}
+	for _, versionRoutes := range routes {
+		sort.Slice(versionRoutes, func(i, j int) bool { return versionRoutes[i].Name < versionRoutes[j].Name })
+	}
+	return routes, nil
+}
+
+// getAndProcessOneLambdaFunc get a lambda function by its name and return a LambdaData object from it.
+// If qualifier is not empty, the given version or alias of the function is used.
+// For functions packaged as container images, the digest is the digest of the resolved image.
+func getAndProcessOneLambdaFunc(client lambdaAPI, functionName, qualifier string) (*LambdaData, error) {
 	params := &lambda.GetFunctionConfigurationInput{
 		FunctionName: aws.String(functionName),
 	}
+	if qualifier != "" {
+		params.Qualifier = aws.String(qualifier)
+	}
 
 	function, err := client.GetFunctionConfiguration(context.TODO(), params)
 	if err != nil {
@@ -243,6 +395,22 @@ func getAndProcessOneLambdaFunc(client *lambda.Client, functionName string) (*La
 		return lambdaData, err
 	}
 
+	if function.PackageType == types.PackageTypeImage {
+		code, err := client.GetFunction(context.TODO(), &lambda.GetFunctionInput{
+			FunctionName: params.FunctionName,
+			Qualifier:    params.Qualifier,
+		})
+		if err != nil {
+			return lambdaData, err
+		}
+		if code.Code != nil && code.Code.ResolvedImageUri != nil {
+			lambdaData.ImageUri = *code.Code.ResolvedImageUri
+			if _, imageDigest, found := strings.Cut(lambdaData.ImageUri, "@sha256:"); found {
+				lambdaData.Digests[*function.FunctionName] = imageDigest
+			}
+		}
+	}
+
 	return lambdaData, nil
 }
 
@@ -291,30 +459,6 @@ func shouldExcludePath(key string, includedPaths, excludedPaths []string) bool {
 	return false
 }
 
-// containsSingleFile checks if a path contains only a single file
-func containsSingleFile(directoryPath string) (bool, string, error) {
-	files, err := os.ReadDir(directoryPath)
-	if err != nil {
-		return false, "", err
-	}
-
-	if len(files) == 1 {
-		fileInfo := files[0]
-
-		if fileInfo.IsDir() {
-			// If it's a directory, recursively check inside
-			subDir := filepath.Join(directoryPath, fileInfo.Name())
-			return containsSingleFile(subDir)
-		}
-
-		// If it's a file, return information about it
-		path := filepath.Join(directoryPath, fileInfo.Name())
-		return true, path, nil
-	}
-
-	return false, "", nil
-}
-
 func objectInPaths(key string, paths []string) bool {
 	for _, path := range paths {
 		path = strings.TrimLeft(path, "/")
@@ -325,98 +469,249 @@ func objectInPaths(key string, paths []string) bool {
 	return false
 }
 
-// GetS3Data returns a digest and metadata of the S3 bucket content
-func (staticCreds *AWSStaticCreds) GetS3Data(bucket string, includePaths, excludePaths []string, logger *logger.Logger) ([]*S3Data, error) {
-	s3Data := []*S3Data{}
+// maxConcurrentS3Objects is the maximum number of bucket objects hashed concurrently
+const maxConcurrentS3Objects = 16
+
+// s3API is the part of the S3 API used to fingerprint the content of a bucket
+type s3API interface {
+	s3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// s3Tree is the content of a bucket, or of a prefix in a bucket, as a file tree
+type s3Tree struct {
+	client s3API
+	bucket string
+	// keys are the keys of the objects in the tree by their path in the tree
+	keys map[string]string
+	// checksumPaths are the paths of the objects with a SHA256 checksum that can be used instead of their content
+	checksumPaths map[string]bool
+	// lastModified is the time the most recently modified object in the tree was modified
+	lastModified *time.Time
+	logger       *logger.Logger
+}
 
-	tempDirName, err := os.MkdirTemp("", "bucketContent")
+// Open returns the content of an object
+func (t *s3Tree) Open(treePath string) (io.ReadCloser, error) {
+	object, err := t.client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(t.bucket),
+		Key:    aws.String(t.keys[treePath]),
+	})
 	if err != nil {
-		return s3Data, err
+		return nil, err
 	}
-	defer os.RemoveAll(tempDirName)
+	return object.Body, nil
+}
 
-	client, err := staticCreds.NewS3Client()
+// ContentSha256 returns the sha256 digest of the content of an object, streaming the content
+// unless the object has a SHA256 checksum of its full content
+func (t *s3Tree) ContentSha256(treePath string) (string, error) {
+	key := t.keys[treePath]
+	if t.checksumPaths[treePath] {
+		checksum, err := t.objectChecksumSha256(key)
+		if err != nil {
+			return "", err
+		}
+		if checksum != "" {
+			t.logger.Debug("using the SHA256 checksum of %s", key)
+			return checksum, nil
+		}
+	}
+
+	content, err := t.Open(treePath)
 	if err != nil {
-		return s3Data, err
+		return "", err
+	}
+	defer content.Close()
+	hasher := sha256.New()
+	numBytes, err := io.Copy(hasher, content)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from bucket %s: %v", key, t.bucket, err)
 	}
+	t.logger.Debug("hashed %s (%d bytes)", key, numBytes)
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
 
+// objectChecksumSha256 returns the SHA256 checksum of an object in hex, or an empty string if the object
+// has no checksum of its full content (objects uploaded in parts have a checksum of the checksums of their parts)
+func (t *s3Tree) objectChecksumSha256(key string) (string, error) {
+	head, err := t.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket:       aws.String(t.bucket),
+		Key:          aws.String(key),
+		ChecksumMode: s3Types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return "", err
+	}
+	checksum := aws.ToString(head.ChecksumSHA256)
+	if checksum == "" || strings.Contains(checksum, "-") {
+		return "", nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil {
+		return "", fmt.Errorf("invalid SHA256 checksum %s for %s: %v", checksum, key, err)
+	}
+	return hex.EncodeToString(decoded), nil
+}
+
+// fingerprint returns the fingerprint of the tree. If the tree has a single file, it is
+// the fingerprint of that file and the path of the file is returned too.
+func (t *s3Tree) fingerprint() (string, string, error) {
+	paths := make([]string, 0, len(t.keys))
+	for treePath := range t.keys {
+		paths = append(paths, treePath)
+	}
+	if len(paths) == 1 {
+		fingerprint, err := t.ContentSha256(paths[0])
+		return fingerprint, paths[0], err
+	}
+	fingerprint, err := digest.TreeSha256(t, paths, maxConcurrentS3Objects, t.logger)
+	return fingerprint, "", err
+}
+
+// listS3Tree lists the objects of a bucket under a prefix into a file tree.
+// The paths in the tree, and includePaths and excludePaths, are relative to the prefix.
+// A prefix can be a directory in the bucket or the key of a single object.
+// If useChecksums is true, the SHA256 checksums of the objects that have one are used instead of their content.
+func listS3Tree(client s3API, bucket, prefix string, includePaths, excludePaths []string, useChecksums bool, logger *logger.Logger) (*s3Tree, error) {
+	tree := &s3Tree{client: client, bucket: bucket, keys: map[string]string{}, checksumPaths: map[string]bool{}, logger: logger}
+
+	prefix = strings.Trim(prefix, "/")
 	params := &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucket),
 	}
+	if prefix != "" {
+		params.Prefix = aws.String(prefix)
+	}
 
-	downloader := s3manager.NewDownloader(client)
-	var lastModifiedTime *time.Time
 	paginator := s3.NewListObjectsV2Paginator(client, params)
 	for paginator.HasMorePages() {
 		objects, err := paginator.NextPage(context.TODO())
 		if err != nil {
-			return s3Data, err
+			return tree, err
 		}
 
 		for _, object := range objects.Contents {
-			if strings.HasSuffix(*object.Key, "/") { // skip folders
+			key := *object.Key
+			if strings.HasSuffix(key, "/") { // skip folders
 				continue
 			}
-			if shouldExcludePath(*object.Key, includePaths, excludePaths) { // decide if we should skip
+			relPath := key
+			if prefix != "" {
+				if key == prefix {
+					relPath = path.Base(key)
+				} else if strings.HasPrefix(key, prefix+"/") {
+					relPath = strings.TrimPrefix(key, prefix+"/")
+				} else { // a key sharing the beginning of its name with the prefix
+					continue
+				}
+			}
+			if shouldExcludePath(relPath, includePaths, excludePaths) { // decide if we should skip
 				continue
 			}
-			err := downloadFileFromBucket(downloader, tempDirName, *object.Key, bucket, logger)
-			if err != nil {
-				return s3Data, err
+			tree.keys[relPath] = key
+			if useChecksums && slices.Contains(object.ChecksumAlgorithm, s3Types.ChecksumAlgorithmSha256) {
+				tree.checksumPaths[relPath] = true
 			}
 
-			if lastModifiedTime == nil || object.LastModified.After(*lastModifiedTime) {
-				lastModifiedTime = object.LastModified
+			if tree.lastModified == nil || object.LastModified.After(*tree.lastModified) {
+				tree.lastModified = object.LastModified
 			}
 		}
 	}
+	if useChecksums {
+		logger.Debug("%d of %d objects in bucket %s have a SHA256 checksum", len(tree.checksumPaths), len(tree.keys), bucket)
+	}
+	return tree, nil
+}
 
-	if lastModifiedTime == nil {
+// GetS3Data returns a digest and metadata of the S3 bucket content.
+// The objects are hashed as they are downloaded, without being stored. The fingerprint is the same as
+// the fingerprint of a directory with the bucket content. If useChecksums is true, the SHA256 checksums
+// of the objects that have one are used instead of downloading them.
+func (staticCreds *AWSStaticCreds) GetS3Data(bucket string, includePaths, excludePaths []string, useChecksums bool, logger *logger.Logger) ([]*S3Data, error) {
+	client, err := staticCreds.NewS3Client()
+	if err != nil {
+		return []*S3Data{}, err
+	}
+	return getS3Data(client, bucket, includePaths, excludePaths, useChecksums, logger)
+}
+
+func getS3Data(client s3API, bucket string, includePaths, excludePaths []string, useChecksums bool, logger *logger.Logger) ([]*S3Data, error) {
+	s3Data := []*S3Data{}
+	tree, err := listS3Tree(client, bucket, "", includePaths, excludePaths, useChecksums, logger)
+	if err != nil {
+		return s3Data, err
+	}
+	if tree.lastModified == nil {
 		return s3Data, fmt.Errorf("no matching file or dirs in bucket: [%s]", bucket)
 	}
 
-	fileSnapshot, artifactPath, err := containsSingleFile(tempDirName)
+	fingerprint, filePath, err := tree.fingerprint()
 	if err != nil {
 		return s3Data, err
 	}
-	var sha256 string
 	artifactName := bucket
-	if fileSnapshot {
-		sha256, err = digest.FileSha256(artifactPath)
-		if err != nil {
-			return s3Data, err
-		}
-		artifactName = filepath.Base(artifactPath)
-	} else {
-		sha256, err = digest.DirSha256(tempDirName, []string{}, logger)
-		if err != nil {
-			return s3Data, err
-		}
+	if filePath != "" {
+		// a single object is reported as a file named after the object
+		artifactName = path.Base(filePath)
 	}
 
-	s3Data = append(s3Data, &S3Data{Digests: map[string]string{artifactName: sha256}, LastModifiedTimestamp: lastModifiedTime.Unix()})
+	s3Data = append(s3Data, &S3Data{Digests: map[string]string{artifactName: fingerprint}, LastModifiedTimestamp: tree.lastModified.Unix()})
 
 	return s3Data, nil
 }
 
-func downloadFileFromBucket(downloader *s3manager.Downloader, dirName, key, bucket string, logger *logger.Logger) error {
-	file, err := utils.CreateFile(filepath.Join(dirName, key))
+// S3ArtifactSpec represents specification for how to fingerprint an artifact in an S3 bucket
+type S3ArtifactSpec struct {
+	Prefix  string   `mapstructure:"prefix" validate:"required"`
+	Include []string `mapstructure:"include"`
+	Exclude []string `mapstructure:"exclude" validate:"excluded_with=Include"`
+}
+
+// S3Spec represents specification for how to fingerprint a list of artifacts in an S3 bucket
+type S3Spec struct {
+	Version   int                       `mapstructure:"version" validate:"required,oneof=1"`
+	Artifacts map[string]S3ArtifactSpec `mapstructure:"artifacts" validate:"required,dive"`
+}
+
+// GetS3ArtifactsData returns a digest and metadata for each artifact in an S3 bucket specified in spec.
+// Each artifact is fingerprinted as a directory containing the objects under its prefix, or as a
+// file if there is a single one. See GetS3Data for useChecksums.
+func (staticCreds *AWSStaticCreds) GetS3ArtifactsData(bucket string, spec *S3Spec, useChecksums bool, logger *logger.Logger) ([]*S3Data, error) {
+	client, err := staticCreds.NewS3Client()
 	if err != nil {
-		return err
+		return []*S3Data{}, err
 	}
-	defer file.Close()
+	return getS3ArtifactsData(client, bucket, spec, useChecksums, logger)
+}
 
-	numBytes, err := downloader.Download(context.TODO(), file,
-		&s3.GetObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(key),
-		})
-	if err != nil {
-		return err
+func getS3ArtifactsData(client s3API, bucket string, spec *S3Spec, useChecksums bool, logger *logger.Logger) ([]*S3Data, error) {
+	s3Data := []*S3Data{}
+	artifactNames := make([]string, 0, len(spec.Artifacts))
+	for name := range spec.Artifacts {
+		artifactNames = append(artifactNames, name)
 	}
-	logger.Debug("downloaded", file.Name(), numBytes, "bytes")
+	sort.Strings(artifactNames)
 
-	return nil
+	for _, name := range artifactNames {
+		artifact := spec.Artifacts[name]
+		tree, err := listS3Tree(client, bucket, artifact.Prefix, artifact.Include, artifact.Exclude, useChecksums, logger)
+		if err != nil {
+			return s3Data, err
+		}
+		if tree.lastModified == nil {
+			return s3Data, fmt.Errorf("no matching file or dirs for artifact %s under prefix [%s] in bucket: [%s]", name, artifact.Prefix, bucket)
+		}
+		fingerprint, _, err := tree.fingerprint()
+		if err != nil {
+			return s3Data, err
+		}
+		logger.Debug("artifact %s in bucket %s -- fingerprint: %s", name, bucket, fingerprint)
+		s3Data = append(s3Data, &S3Data{Digests: map[string]string{name: fingerprint}, LastModifiedTimestamp: tree.lastModified.Unix()})
+	}
+	return s3Data, nil
 }
 
 // getFilteredECSClusters fetches a filtered set of ECS clusters recursively (50 at a time) and returns a list of ecs Clusters
@@ -461,8 +756,11 @@ func getFilteredECSClusters(client *ecs.Client, nextToken *string, allClusters *
 	return allClusters, nil
 }
 
-// GetEcsTasksData returns a list of tasks data for an ECS cluster or service
-func (staticCreds *AWSStaticCreds) GetEcsTasksData(filter *filters.ResourceFilterOptions) ([]*EcsTaskData, error) {
+// GetEcsTasksData returns a list of tasks data for the ECS clusters matching the filter.
+// If services is not empty, only the tasks of these services are included.
+// If digestResolver is not nil, it is used to look up the digests of the container images
+// that have no digest in the task description. Each image is looked up at most once.
+func (staticCreds *AWSStaticCreds) GetEcsTasksData(filter *filters.ResourceFilterOptions, services []string, digestResolver digest.DigestResolver) ([]*EcsTaskData, error) {
 	allTasksData := []*EcsTaskData{}
 	client, err := staticCreds.NewECSClient()
 	if err != nil {
@@ -473,10 +771,24 @@ func (staticCreds *AWSStaticCreds) GetEcsTasksData(filter *filters.ResourceFilte
 	if err != nil {
 		return allTasksData, err
 	}
+	clusterNames := []string{}
+	for _, cluster := range *filteredClusters {
+		clusterNames = append(clusterNames, aws.ToString(cluster.ClusterName))
+	}
+	return getTasksDataInClusters(client, clusterNames, services, digestResolver)
+}
 
+// getTasksDataInClusters returns the data of the running tasks in the clusters.
+// If services is not empty, only the tasks of these services are included, and it fails
+// when a service is found in none of the clusters.
+func getTasksDataInClusters(client ecsTasksAPI, clusters []string, services []string, digestResolver digest.DigestResolver) ([]*EcsTaskData, error) {
+	allTasksData := []*EcsTaskData{}
 	var (
-		wg    sync.WaitGroup
-		mutex = &sync.Mutex{}
+		wg      sync.WaitGroup
+		mutex   = &sync.Mutex{}
+		digests = digest.NewDigestCache(digestResolver)
+		// the number of clusters each service is missing from
+		missingCount = map[string]int{}
 	)
 
 	// run concurrently
@@ -484,7 +796,7 @@ func (staticCreds *AWSStaticCreds) GetEcsTasksData(filter *filters.ResourceFilte
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel() // Make sure it's called to release resources even if no errors
 
-	for _, cluster := range *filteredClusters {
+	for _, cluster := range clusters {
 		wg.Add(1)
 		go func(cluster string) {
 			defer wg.Done()
@@ -495,7 +807,7 @@ func (staticCreds *AWSStaticCreds) GetEcsTasksData(filter *filters.ResourceFilte
 			default: // Default is must to avoid blocking
 			}
 
-			tasksData, err := getTasksDataInCluster(client, cluster)
+			tasksData, missingServices, err := getTasksDataInCluster(client, cluster, services)
 			if err != nil {
 				// Non-blocking send of error
 				select {
@@ -505,11 +817,17 @@ func (staticCreds *AWSStaticCreds) GetEcsTasksData(filter *filters.ResourceFilte
 				cancel() // send cancel signal to goroutines
 				return
 			}
+			for _, data := range tasksData {
+				digest.ResolveMissingDigests(digests, data.Containers, data.Digests)
+			}
 			mutex.Lock()
 			allTasksData = append(allTasksData, tasksData...)
+			for _, service := range missingServices {
+				missingCount[service]++
+			}
 			mutex.Unlock()
 
-		}(*cluster.ClusterName)
+		}(cluster)
 	}
 
 	wg.Wait()
@@ -518,54 +836,207 @@ func (staticCreds *AWSStaticCreds) GetEcsTasksData(filter *filters.ResourceFilte
 		return allTasksData, <-errs
 	}
 
+	// a mistyped service must not report an empty snapshot, which would clear the environment
+	notFound := []string{}
+	for _, service := range services {
+		if missingCount[service] == len(clusters) {
+			notFound = append(notFound, service)
+		}
+	}
+	if len(notFound) > 0 {
+		return allTasksData, fmt.Errorf("ECS services %v were not found in any of the ECS clusters %v", notFound, clusters)
+	}
+
 	return allTasksData, nil
 }
 
-func getTasksDataInCluster(client *ecs.Client, cluster string) ([]*EcsTaskData, error) {
-	tasksData := []*EcsTaskData{}
-	listInput := &ecs.ListTasksInput{
-		Cluster: aws.String(cluster),
-	}
-	descriptionInput := &ecs.DescribeTasksInput{
-		Cluster: aws.String(cluster),
-	}
+// ecsTasksAPI is the part of the ECS API used to collect the tasks data of a cluster
+type ecsTasksAPI interface {
+	ecs.ListTasksAPIClient
+	DescribeTasks(ctx context.Context, params *ecs.DescribeTasksInput, optFns ...func(*ecs.Options)) (*ecs.DescribeTasksOutput, error)
+}
 
-	list, err := client.ListTasks(context.Background(), listInput)
+// maxDescribeTasks is the maximum number of tasks that can be described in one DescribeTasks call
+const maxDescribeTasks = 100
+
+// getTasksDataInCluster returns the data of the running tasks in a cluster.
+// If services is not empty, only the tasks of these services are included, and the
+// services which do not exist in the cluster are returned.
+func getTasksDataInCluster(client ecsTasksAPI, cluster string, services []string) ([]*EcsTaskData, []string, error) {
+	tasksData := []*EcsTaskData{}
+	taskArns, missingServices, err := listTaskArns(client, cluster, services)
 	if err != nil {
-		return tasksData, err
+		return tasksData, missingServices, err
 	}
-	tasks := list.TaskArns
 
-	if len(tasks) > 0 {
-		descriptionInput.Tasks = tasks
-		result, err := client.DescribeTasks(context.Background(), descriptionInput)
+	for start := 0; start < len(taskArns); start += maxDescribeTasks {
+		end := min(start+maxDescribeTasks, len(taskArns))
+		result, err := client.DescribeTasks(context.Background(), &ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster),
+			Tasks:   taskArns[start:end],
+		})
 		if err != nil {
-			return tasksData, err
+			return tasksData, missingServices, err
 		}
 
 		for _, taskDesc := range result.Tasks {
-			digests := make(map[string]string)
-			if *taskDesc.LastStatus == "RUNNING" {
-				for _, container := range taskDesc.Containers {
-					imageName := container.Image
-					if imageName == nil {
-						// some images like AWS Guard Duty don't get an image name from AWS
-						// so we default to the container name
-						imageName = container.Name
-					}
-					if container.ImageDigest != nil {
-						digests[*imageName] = strings.TrimPrefix(*container.ImageDigest, "sha256:")
-					} else if strings.Contains(*imageName, "@sha256:") {
-						digests[*imageName] = strings.Split(*imageName, "@sha256:")[1]
-					} else {
-						digests[*imageName] = ""
-					}
+			if aws.ToString(taskDesc.LastStatus) == "RUNNING" {
+				tasksData = append(tasksData, newEcsTaskDataFromTask(taskDesc, cluster))
+			}
+		}
+	}
+
+	return tasksData, missingServices, nil
+}
+
+// listTaskArns returns the ARNs of all the tasks in a cluster, or of the tasks of the given services.
+// Services that do not exist in the cluster are skipped and returned.
+func listTaskArns(client ecs.ListTasksAPIClient, cluster string, services []string) ([]string, []string, error) {
+	inputs := []*ecs.ListTasksInput{}
+	if len(services) == 0 {
+		inputs = append(inputs, &ecs.ListTasksInput{Cluster: aws.String(cluster)})
+	}
+	for _, service := range services {
+		inputs = append(inputs, &ecs.ListTasksInput{Cluster: aws.String(cluster), ServiceName: aws.String(service)})
+	}
+
+	taskArns := []string{}
+	missingServices := []string{}
+	for _, input := range inputs {
+		paginator := ecs.NewListTasksPaginator(client, input)
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(context.Background())
+			if err != nil {
+				var notFound *ecsTypes.ServiceNotFoundException
+				if errors.As(err, &notFound) {
+					missingServices = append(missingServices, aws.ToString(input.ServiceName))
+					break
 				}
-				data := NewEcsTaskData(*taskDesc.TaskArn, cluster, digests, *taskDesc.StartedAt)
-				tasksData = append(tasksData, data)
+				return taskArns, missingServices, err
 			}
+			taskArns = append(taskArns, page.TaskArns...)
+		}
+	}
+	return taskArns, missingServices, nil
+}
+
+// newEcsTaskDataFromTask creates an EcsTaskData object from a described ECS task
+func newEcsTaskDataFromTask(taskDesc ecsTypes.Task, cluster string) *EcsTaskData {
+	digests := make(map[string]string)
+	containers := []*EcsContainerData{}
+	for _, container := range taskDesc.Containers {
+		imageName := container.Image
+		if imageName == nil {
+			// some images like AWS Guard Duty don't get an image name from AWS
+			// so we default to the container name
+			imageName = container.Name
+		}
+		containerData := &EcsContainerData{
+			Name:         aws.ToString(container.Name),
+			Image:        aws.ToString(imageName),
+			DigestSource: digest.DigestSourceObserved,
 		}
+		if container.ImageDigest != nil {
+			containerData.Digest = strings.TrimPrefix(*container.ImageDigest, "sha256:")
+		} else if strings.Contains(*imageName, "@sha256:") {
+			containerData.Digest = strings.Split(*imageName, "@sha256:")[1]
+		} else {
+			containerData.DigestSource = digest.DigestSourceUnresolved
+			containerData.UnresolvedReason = "the task description has no digest for the container image"
+		}
+		digests[*imageName] = containerData.Digest
+		containers = append(containers, containerData)
+	}
+
+	startedAt := time.Time{}
+	if taskDesc.StartedAt != nil {
+		startedAt = *taskDesc.StartedAt
 	}
+	data := NewEcsTaskData(aws.ToString(taskDesc.TaskArn), cluster, digests, startedAt)
+	data.Containers = containers
+	// tasks started by a service are in the group service:SERVICE-NAME
+	if service, found := strings.CutPrefix(aws.ToString(taskDesc.Group), "service:"); found {
+		data.Service = service
+	}
+	data.TaskDefinitionFamily, data.TaskDefinitionRevision = parseTaskDefinitionArn(aws.ToString(taskDesc.TaskDefinitionArn))
+	// tasks started with a capacity provider strategy have a capacity provider and no launch type
+	data.LaunchType = string(taskDesc.LaunchType)
+	data.CapacityProvider = aws.ToString(taskDesc.CapacityProviderName)
+	return data
+}
 
-	return tasksData, nil
+// parseTaskDefinitionArn returns the family and revision of a task definition from its ARN
+// (arn:aws:ecs:REGION:ACCOUNT:task-definition/FAMILY:REVISION)
+func parseTaskDefinitionArn(arn string) (string, int32) {
+	_, familyRevision, found := strings.Cut(arn, ":task-definition/")
+	if !found {
+		return "", 0
+	}
+	family, revision, found := strings.Cut(familyRevision, ":")
+	if !found {
+		return family, 0
+	}
+	number, err := strconv.ParseInt(revision, 10, 32)
+	if err != nil {
+		return family, 0
+	}
+	return family, int32(number)
+}
+
+// ecrImageRegex matches image references in ECR private registries:
+// ACCOUNT.dkr.ecr[-fips].REGION.amazonaws.com[.cn]/REPOSITORY[:TAG]
+var ecrImageRegex = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?/([^:@]+)(?::([^@]+))?$`)
+
+// ECRImage is an image reference in an ECR private registry
+type ECRImage struct {
+	RegistryID string
+	Region     string
+	Repository string
+	Tag        string
+}
+
+// ParseECRImage parses an ECR image reference. It returns false if the image is not in an ECR private registry.
+// The tag defaults to latest.
+func ParseECRImage(image string) (*ECRImage, bool) {
+	match := ecrImageRegex.FindStringSubmatch(image)
+	if match == nil {
+		return nil, false
+	}
+	ecrImage := &ECRImage{
+		RegistryID: match[1],
+		Region:     match[2],
+		Repository: match[3],
+		Tag:        match[4],
+	}
+	if ecrImage.Tag == "" {
+		ecrImage.Tag = "latest"
+	}
+	return ecrImage, true
+}
+
+// ECRImageDigest looks up the digest of an image tag in an ECR private registry using BatchGetImage
+func (staticCreds *AWSStaticCreds) ECRImageDigest(image string) (string, error) {
+	ecrImage, ok := ParseECRImage(image)
+	if !ok {
+		return "", fmt.Errorf("%s is not an ECR image", image)
+	}
+	client, err := staticCreds.NewECRClient(ecrImage.Region)
+	if err != nil {
+		return "", err
+	}
+	output, err := client.BatchGetImage(context.Background(), &ecr.BatchGetImageInput{
+		RegistryId:     aws.String(ecrImage.RegistryID),
+		RepositoryName: aws.String(ecrImage.Repository),
+		ImageIds:       []ecrTypes.ImageIdentifier{{ImageTag: aws.String(ecrImage.Tag)}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(output.Images) == 0 || output.Images[0].ImageId == nil || output.Images[0].ImageId.ImageDigest == nil {
+		if len(output.Failures) > 0 {
+			return "", fmt.Errorf("failed to get image %s from ECR: %s", image, aws.ToString(output.Failures[0].FailureReason))
+		}
+		return "", fmt.Errorf("image %s was not found in ECR", image)
+	}
+	return strings.TrimPrefix(*output.Images[0].ImageId.ImageDigest, "sha256:"), nil
 }
@@ -2,9 +2,27 @@ package aws
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kosli-dev/cli/internal/digest"
 	"github.com/kosli-dev/cli/internal/filters"
 	"github.com/kosli-dev/cli/internal/logger"
 	"github.com/kosli-dev/cli/internal/testHelpers"
@@ -363,7 +381,7 @@ func (suite *AWSTestSuite) TestGetLambdaPackageData() {
 	} {
 		suite.Run(t.name, func() {
 			skipOrSetCreds(suite.T(), t.requireEnvVars, t.creds)
-			data, err := t.creds.GetLambdaPackageData(t.filter)
+			data, err := t.creds.GetLambdaPackageData(t.filter, false)
 			require.False(suite.T(), (err != nil) != t.wantErr,
 				"GetLambdaPackageData() error = %v, wantErr %v", err, t.wantErr)
 			if !t.wantErr {
@@ -507,7 +525,7 @@ func (suite *AWSTestSuite) TestGetS3Data() {
 	} {
 		suite.Run(t.name, func() {
 			skipOrSetCreds(suite.T(), t.requireEnvVars, t.creds)
-			data, err := t.creds.GetS3Data(t.bucketName, t.includePaths, t.excludePaths, logger.NewStandardLogger())
+			data, err := t.creds.GetS3Data(t.bucketName, t.includePaths, t.excludePaths, false, logger.NewStandardLogger())
 			require.False(suite.T(), (err != nil) != t.wantErr,
 				"GetS3Data() error = %v, wantErr %v", err, t.wantErr)
 			if !t.wantErr {
@@ -530,6 +548,7 @@ func (suite *AWSTestSuite) TestGetEcsTasksData() {
 		requireEnvVars       bool // indicates that a test case needs real credentials from env vars
 		creds                *AWSStaticCreds
 		filter               *filters.ResourceFilterOptions
+		services             []string
 		minNumberOfArtifacts int
 		wantErr              bool
 	}{
@@ -591,7 +610,7 @@ func (suite *AWSTestSuite) TestGetEcsTasksData() {
 	} {
 		suite.Run(t.name, func() {
 			skipOrSetCreds(suite.T(), t.requireEnvVars, t.creds)
-			data, err := t.creds.GetEcsTasksData(t.filter)
+			data, err := t.creds.GetEcsTasksData(t.filter, t.services, nil)
 			require.False(suite.T(), (err != nil) != t.wantErr,
 				"GetEcsTasksData() error = %v, wantErr %v", err, t.wantErr)
 			if !t.wantErr {
@@ -601,6 +620,635 @@ func (suite *AWSTestSuite) TestGetEcsTasksData() {
 	}
 }
 
+// fakeECSClient serves ListTasks in pages of pageSize tasks and records the DescribeTasks calls
+type fakeECSClient struct {
+	tasks         map[string][]ecsTypes.Task // tasks by service name
+	pageSize      int
+	describeCalls [][]string
+}
+
+func (c *fakeECSClient) ListTasks(ctx context.Context, params *ecs.ListTasksInput, optFns ...func(*ecs.Options)) (*ecs.ListTasksOutput, error) {
+	arns := []string{}
+	for service, tasks := range c.tasks {
+		if params.ServiceName != nil && *params.ServiceName != service {
+			continue
+		}
+		for _, task := range tasks {
+			arns = append(arns, *task.TaskArn)
+		}
+	}
+	// the pages must be served in the same order
+	sort.Strings(arns)
+	if params.ServiceName != nil && len(arns) == 0 {
+		return nil, &ecsTypes.ServiceNotFoundException{Message: aws.String("Service not found.")}
+	}
+
+	start := 0
+	if params.NextToken != nil {
+		fmt.Sscanf(*params.NextToken, "%d", &start)
+	}
+	end := min(start+c.pageSize, len(arns))
+	output := &ecs.ListTasksOutput{TaskArns: arns[start:end]}
+	if end < len(arns) {
+		output.NextToken = aws.String(fmt.Sprintf("%d", end))
+	}
+	return output, nil
+}
+
+func (c *fakeECSClient) DescribeTasks(ctx context.Context, params *ecs.DescribeTasksInput, optFns ...func(*ecs.Options)) (*ecs.DescribeTasksOutput, error) {
+	if len(params.Tasks) > maxDescribeTasks {
+		return nil, fmt.Errorf("too many tasks: %d", len(params.Tasks))
+	}
+	c.describeCalls = append(c.describeCalls, params.Tasks)
+	output := &ecs.DescribeTasksOutput{}
+	for _, arn := range params.Tasks {
+		for _, tasks := range c.tasks {
+			for _, task := range tasks {
+				if *task.TaskArn == arn {
+					output.Tasks = append(output.Tasks, task)
+				}
+			}
+		}
+	}
+	return output, nil
+}
+
+func newFakeECSTasks(service string, count int) []ecsTypes.Task {
+	tasks := []ecsTypes.Task{}
+	for i := 0; i < count; i++ {
+		tasks = append(tasks, ecsTypes.Task{
+			TaskArn:           aws.String(fmt.Sprintf("arn:aws:ecs:eu-central-1:123456789012:task/cluster/%s-%d", service, i)),
+			Group:             aws.String("service:" + service),
+			TaskDefinitionArn: aws.String(fmt.Sprintf("arn:aws:ecs:eu-central-1:123456789012:task-definition/%s-family:7", service)),
+			LaunchType:        ecsTypes.LaunchTypeFargate,
+			LastStatus:        aws.String("RUNNING"),
+			StartedAt:         aws.Time(time.Unix(1700000000, 0)),
+			Containers: []ecsTypes.Container{{
+				Image:       aws.String(service + ":latest"),
+				ImageDigest: aws.String("sha256:e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5"),
+			}},
+		})
+	}
+	return tasks
+}
+
+func (suite *AWSTestSuite) TestGetTasksDataInClusterFollowsPagesAndBatchesDescribes() {
+	client := &fakeECSClient{
+		tasks: map[string][]ecsTypes.Task{
+			"frontend": newFakeECSTasks("frontend", 180),
+			"backend":  newFakeECSTasks("backend", 70),
+		},
+		pageSize: 100,
+	}
+
+	data, missingServices, err := getTasksDataInCluster(client, "cluster", nil)
+	require.NoError(suite.T(), err)
+	require.Empty(suite.T(), missingServices)
+	require.Len(suite.T(), data, 250)
+	require.Len(suite.T(), client.describeCalls, 3)
+
+	services := map[string]int{}
+	for _, task := range data {
+		services[task.Service]++
+		require.Equal(suite.T(), task.Service+"-family", task.TaskDefinitionFamily)
+		require.Equal(suite.T(), int32(7), task.TaskDefinitionRevision)
+		require.Equal(suite.T(), "FARGATE", task.LaunchType)
+		require.Equal(suite.T(), "e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5", task.Digests[task.Service+":latest"])
+	}
+	require.Equal(suite.T(), map[string]int{"frontend": 180, "backend": 70}, services)
+}
+
+func (suite *AWSTestSuite) TestGetTasksDataInClusterForServices() {
+	client := &fakeECSClient{
+		tasks: map[string][]ecsTypes.Task{
+			"frontend": newFakeECSTasks("frontend", 3),
+			"backend":  newFakeECSTasks("backend", 2),
+		},
+		pageSize: 2,
+	}
+
+	data, missingServices, err := getTasksDataInCluster(client, "cluster", []string{"frontend", "not-in-this-cluster"})
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), []string{"not-in-this-cluster"}, missingServices)
+	require.Len(suite.T(), data, 3)
+	for _, task := range data {
+		require.Equal(suite.T(), "frontend", task.Service)
+	}
+}
+
+func (suite *AWSTestSuite) TestGetTasksDataInClustersForServices() {
+	client := &fakeECSClient{
+		tasks: map[string][]ecsTypes.Task{
+			"frontend": newFakeECSTasks("frontend", 3),
+		},
+		pageSize: 100,
+	}
+
+	data, err := getTasksDataInClusters(client, []string{"cluster1", "cluster2"}, []string{"frontend"}, nil)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), data, 6)
+
+	_, err = getTasksDataInClusters(client, []string{"cluster1", "cluster2"}, []string{"frontend", "fronted"}, nil)
+	require.EqualError(suite.T(), err, "ECS services [fronted] were not found in any of the ECS clusters [cluster1 cluster2]")
+
+	_, err = getTasksDataInClusters(client, []string{}, []string{"frontend"}, nil)
+	require.EqualError(suite.T(), err, "ECS services [frontend] were not found in any of the ECS clusters []")
+}
+
+func (suite *AWSTestSuite) TestNewEcsTaskDataFromTask() {
+	task := ecsTypes.Task{
+		TaskArn:              aws.String("arn:aws:ecs:eu-central-1:123456789012:task/cluster/abc"),
+		Group:                aws.String("family:standalone"),
+		TaskDefinitionArn:    aws.String("arn:aws:ecs:eu-central-1:123456789012:task-definition/standalone:12"),
+		CapacityProviderName: aws.String("FARGATE_SPOT"),
+		StartedAt:            aws.Time(time.Unix(1700000000, 0)),
+		Containers: []ecsTypes.Container{
+			{Image: aws.String("nginx@sha256:e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5")},
+			{Name: aws.String("guard-duty-agent")},
+		},
+	}
+	expected := &EcsTaskData{
+		TaskArn:                "arn:aws:ecs:eu-central-1:123456789012:task/cluster/abc",
+		TaskDefinitionFamily:   "standalone",
+		TaskDefinitionRevision: 12,
+		CapacityProvider:       "FARGATE_SPOT",
+		Digests: map[string]string{
+			"nginx@sha256:e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5": "e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5",
+			"guard-duty-agent": "",
+		},
+		Containers: []*EcsContainerData{
+			{
+				Image:        "nginx@sha256:e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5",
+				Digest:       "e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5",
+				DigestSource: digest.DigestSourceObserved,
+			},
+			{
+				Name:             "guard-duty-agent",
+				Image:            "guard-duty-agent",
+				DigestSource:     digest.DigestSourceUnresolved,
+				UnresolvedReason: "the task description has no digest for the container image",
+			},
+		},
+		StartedAt: 1700000000,
+	}
+	require.Equal(suite.T(), expected, newEcsTaskDataFromTask(task, "cluster"))
+}
+
+func (suite *AWSTestSuite) TestResolveMissingDigests() {
+	resolved := "e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5"
+	lookups := map[string]int{}
+	cache := digest.NewDigestCache(func(image string) (string, error) {
+		lookups[image]++
+		if image == "private:1.0" {
+			return "", fmt.Errorf("unauthorized")
+		}
+		return resolved, nil
+	})
+
+	newTask := func() *EcsTaskData {
+		return newEcsTaskDataFromTask(ecsTypes.Task{
+			TaskArn: aws.String("arn:aws:ecs:eu-central-1:123456789012:task/cluster/abc"),
+			Containers: []ecsTypes.Container{
+				{Name: aws.String("app"), Image: aws.String("app:1.0")},
+				{Name: aws.String("private"), Image: aws.String("private:1.0")},
+				{Name: aws.String("sidecar"), Image: aws.String("sidecar:1.0"), ImageDigest: aws.String("sha256:" + resolved)},
+			},
+		}, "cluster")
+	}
+	tasks := []*EcsTaskData{newTask(), newTask()}
+	for _, task := range tasks {
+		digest.ResolveMissingDigests(cache, task.Containers, task.Digests)
+	}
+
+	require.Equal(suite.T(), map[string]int{"app:1.0": 1, "private:1.0": 1}, lookups)
+	for _, task := range tasks {
+		require.Equal(suite.T(), resolved, task.Digests["app:1.0"])
+		require.Equal(suite.T(), digest.DigestSourceResolved, task.Containers[0].DigestSource)
+		require.Empty(suite.T(), task.Containers[0].UnresolvedReason)
+
+		require.Equal(suite.T(), "", task.Digests["private:1.0"])
+		require.Equal(suite.T(), digest.DigestSourceUnresolved, task.Containers[1].DigestSource)
+		require.Equal(suite.T(), "the task description has no digest for the container image; "+
+			"could not resolve the digest from the registry: unauthorized", task.Containers[1].UnresolvedReason)
+
+		require.Equal(suite.T(), digest.DigestSourceObserved, task.Containers[2].DigestSource)
+	}
+}
+
+func (suite *AWSTestSuite) TestParseECRImage() {
+	for _, t := range []struct {
+		name   string
+		image  string
+		want   *ECRImage
+		wantOk bool
+	}{
+		{
+			name:   "ECR image with a tag",
+			image:  "123456789012.dkr.ecr.eu-central-1.amazonaws.com/team/app:1.2.3",
+			want:   &ECRImage{RegistryID: "123456789012", Region: "eu-central-1", Repository: "team/app", Tag: "1.2.3"},
+			wantOk: true,
+		},
+		{
+			name:   "ECR image without a tag defaults to latest",
+			image:  "123456789012.dkr.ecr.us-east-1.amazonaws.com/app",
+			want:   &ECRImage{RegistryID: "123456789012", Region: "us-east-1", Repository: "app", Tag: "latest"},
+			wantOk: true,
+		},
+		{
+			name:   "ECR FIPS image in China",
+			image:  "123456789012.dkr.ecr-fips.cn-north-1.amazonaws.com.cn/app:v1",
+			want:   &ECRImage{RegistryID: "123456789012", Region: "cn-north-1", Repository: "app", Tag: "v1"},
+			wantOk: true,
+		},
+		{
+			name:  "Docker Hub image is not an ECR image",
+			image: "nginx:1.25",
+		},
+		{
+			name:  "ECR public image is not a private ECR image",
+			image: "public.ecr.aws/nginx/nginx:1.25",
+		},
+	} {
+		suite.Run(t.name, func() {
+			got, ok := ParseECRImage(t.image)
+			require.Equal(suite.T(), t.wantOk, ok)
+			require.Equal(suite.T(), t.want, got)
+		})
+	}
+}
+
+// fakeS3Client serves the objects of a bucket in pages of pageSize objects and records the downloaded keys
+type fakeS3Client struct {
+	objects map[string]string
+	// checksums are the SHA256 checksums of the objects uploaded with one, in base64
+	checksums  map[string]string
+	pageSize   int
+	mutex      sync.Mutex
+	downloaded []string
+}
+
+func (c *fakeS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	keys := []string{}
+	for key := range c.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if params.ContinuationToken != nil {
+		fmt.Sscanf(*params.ContinuationToken, "%d", &start)
+	}
+	end := min(start+c.pageSize, len(keys))
+	output := &s3.ListObjectsV2Output{}
+	for _, key := range keys[start:end] {
+		object := s3Types.Object{Key: aws.String(key), LastModified: aws.Time(time.Unix(1700000000, 0))}
+		if _, ok := c.checksums[key]; ok {
+			object.ChecksumAlgorithm = []s3Types.ChecksumAlgorithm{s3Types.ChecksumAlgorithmSha256}
+		}
+		output.Contents = append(output.Contents, object)
+	}
+	if end < len(keys) {
+		output.IsTruncated = true
+		output.NextContinuationToken = aws.String(fmt.Sprintf("%d", end))
+	}
+	return output, nil
+}
+
+func (c *fakeS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	content, ok := c.objects[*params.Key]
+	if !ok {
+		return nil, fmt.Errorf("NoSuchKey: %s", *params.Key)
+	}
+	c.mutex.Lock()
+	c.downloaded = append(c.downloaded, *params.Key)
+	c.mutex.Unlock()
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(content))}, nil
+}
+
+func (c *fakeS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	output := &s3.HeadObjectOutput{}
+	if params.ChecksumMode == s3Types.ChecksumModeEnabled {
+		if checksum, ok := c.checksums[*params.Key]; ok {
+			output.ChecksumSHA256 = aws.String(checksum)
+		}
+	}
+	return output, nil
+}
+
+// dirFingerprint returns the fingerprint of a directory with the given files
+func (suite *AWSTestSuite) dirFingerprint(files map[string]string) string {
+	dir := suite.T().TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), 0777))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
+	}
+	fingerprint, err := digest.DirSha256(dir, []string{}, "", logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	return fingerprint
+}
+
+func (suite *AWSTestSuite) TestGetS3DataStreamsObjects() {
+	objects := map[string]string{
+		"index.html":           "<html></html>",
+		"assets/app.js":        "console.log('app')",
+		"assets/app.js.map":    "{}",
+		"assets.json":          "[]",
+		"assets/img/logo.svg":  "<svg></svg>",
+		"docs/":                "",
+		"docs/guide/intro.md":  "# intro",
+		"docs/.kosli_ignore":   "*.tmp",
+		"docs/guide/draft.tmp": "draft",
+	}
+	checksum := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+	withoutFolders := func(objects map[string]string, keep func(key string) bool) map[string]string {
+		files := map[string]string{}
+		for key, content := range objects {
+			if !strings.HasSuffix(key, "/") && keep(key) {
+				files[key] = content
+			}
+		}
+		return files
+	}
+	all := func(string) bool { return true }
+
+	for _, t := range []struct {
+		name             string
+		checksums        map[string]string
+		includePaths     []string
+		excludePaths     []string
+		useChecksums     bool
+		wantFiles        map[string]string
+		wantArtifactName string
+		wantDownloaded   []string
+	}{
+		{
+			name:      "entire bucket",
+			wantFiles: withoutFolders(objects, all),
+		},
+		{
+			name:         "bucket with excluded paths",
+			excludePaths: []string{"docs"},
+			wantFiles:    withoutFolders(objects, func(key string) bool { return !strings.HasPrefix(key, "docs") }),
+		},
+		{
+			name:             "a single object is reported as a file",
+			includePaths:     []string{"assets/img"},
+			wantArtifactName: "logo.svg",
+		},
+		{
+			name: "checksums are not used by default",
+			checksums: map[string]string{
+				"index.html": checksum(objects["index.html"]),
+			},
+			wantFiles: withoutFolders(objects, all),
+		},
+		{
+			name:         "objects with a full content checksum are not downloaded when checksums are used, nor are ignored objects",
+			useChecksums: true,
+			checksums: map[string]string{
+				"index.html":          checksum(objects["index.html"]),
+				"assets/app.js":       checksum(objects["assets/app.js"]),
+				"assets/img/logo.svg": checksum(objects["assets/img/logo.svg"]) + "-3",
+			},
+			wantFiles:      withoutFolders(objects, all),
+			wantDownloaded: []string{"assets.json", "assets/app.js.map", "assets/img/logo.svg", "docs/.kosli_ignore", "docs/.kosli_ignore", "docs/guide/intro.md"},
+		},
+	} {
+		suite.Run(t.name, func() {
+			client := &fakeS3Client{objects: objects, checksums: t.checksums, pageSize: 3}
+			data, err := getS3Data(client, "bucket", t.includePaths, t.excludePaths, t.useChecksums, logger.NewStandardLogger())
+			require.NoError(suite.T(), err)
+			require.Len(suite.T(), data, 1)
+			require.Equal(suite.T(), int64(1700000000), data[0].LastModifiedTimestamp)
+
+			if t.wantArtifactName != "" {
+				sum := sha256.Sum256([]byte(objects["assets/img/logo.svg"]))
+				require.Equal(suite.T(), map[string]string{t.wantArtifactName: hex.EncodeToString(sum[:])}, data[0].Digests)
+				return
+			}
+			require.Equal(suite.T(), map[string]string{"bucket": suite.dirFingerprint(t.wantFiles)}, data[0].Digests)
+			if t.wantDownloaded != nil {
+				sort.Strings(client.downloaded)
+				require.Equal(suite.T(), t.wantDownloaded, client.downloaded)
+			}
+		})
+	}
+}
+
+func (suite *AWSTestSuite) TestGetS3ArtifactsData() {
+	objects := map[string]string{
+		"apps/frontend-a/index.html":       "<html>a</html>",
+		"apps/frontend-a/assets/app.js":    "a",
+		"apps/frontend-a/maps/app.js.map":  "{}",
+		"apps/frontend-ab/index.html":      "<html>ab</html>",
+		"apps/frontend-b/index.html":       "<html>b</html>",
+		"apps/frontend-b/assets/app.js":    "b",
+		"apps/frontend-b/assets/README.md": "readme",
+		"config.json":                      "{}",
+	}
+	fileFingerprint := sha256.Sum256([]byte(objects["config.json"]))
+	spec := &S3Spec{
+		Version: 1,
+		Artifacts: map[string]S3ArtifactSpec{
+			"frontend-a": {Prefix: "/apps/frontend-a/", Exclude: []string{"maps"}},
+			"frontend-b": {Prefix: "apps/frontend-b", Include: []string{"index.html", "assets/app.js"}},
+			"config":     {Prefix: "config.json"},
+		},
+	}
+
+	client := &fakeS3Client{objects: objects, pageSize: 2}
+	data, err := getS3ArtifactsData(client, "bucket", spec, false, logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), []*S3Data{
+		{
+			Digests:               map[string]string{"config": hex.EncodeToString(fileFingerprint[:])},
+			LastModifiedTimestamp: 1700000000,
+		},
+		{
+			Digests: map[string]string{"frontend-a": suite.dirFingerprint(map[string]string{
+				"index.html":    objects["apps/frontend-a/index.html"],
+				"assets/app.js": objects["apps/frontend-a/assets/app.js"],
+			})},
+			LastModifiedTimestamp: 1700000000,
+		},
+		{
+			Digests: map[string]string{"frontend-b": suite.dirFingerprint(map[string]string{
+				"index.html":    objects["apps/frontend-b/index.html"],
+				"assets/app.js": objects["apps/frontend-b/assets/app.js"],
+			})},
+			LastModifiedTimestamp: 1700000000,
+		},
+	}, data)
+
+	spec.Artifacts["frontend-c"] = S3ArtifactSpec{Prefix: "apps/frontend-c"}
+	_, err = getS3ArtifactsData(client, "bucket", spec, false, logger.NewStandardLogger())
+	require.EqualError(suite.T(), err, "no matching file or dirs for artifact frontend-c under prefix [apps/frontend-c] in bucket: [bucket]")
+}
+
+// fakeLambdaClient serves the configurations of the versions of one function and its aliases
+type fakeLambdaClient struct {
+	functionName string
+	versions     map[string]lambdaTypes.FunctionConfiguration
+	imageUris    map[string]string // resolved image URIs by version
+	aliases      []lambdaTypes.AliasConfiguration
+}
+
+func (c *fakeLambdaClient) version(qualifier *string) string {
+	if qualifier == nil {
+		return lambdaLatestVersion
+	}
+	return *qualifier
+}
+
+func (c *fakeLambdaClient) GetFunctionConfiguration(ctx context.Context, params *lambda.GetFunctionConfigurationInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error) {
+	config, ok := c.versions[c.version(params.Qualifier)]
+	if !ok {
+		return nil, fmt.Errorf("version %s of function %s not found", c.version(params.Qualifier), *params.FunctionName)
+	}
+	return &lambda.GetFunctionConfigurationOutput{
+		FunctionName: aws.String(c.functionName),
+		LastModified: config.LastModified,
+		CodeSha256:   config.CodeSha256,
+		PackageType:  config.PackageType,
+	}, nil
+}
+
+func (c *fakeLambdaClient) GetFunction(ctx context.Context, params *lambda.GetFunctionInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	return &lambda.GetFunctionOutput{
+		Code: &lambdaTypes.FunctionCodeLocation{ResolvedImageUri: aws.String(c.imageUris[c.version(params.Qualifier)])},
+	}, nil
+}
+
+// ListAliases returns one alias per page
+func (c *fakeLambdaClient) ListAliases(ctx context.Context, params *lambda.ListAliasesInput, optFns ...func(*lambda.Options)) (*lambda.ListAliasesOutput, error) {
+	start := 0
+	if params.Marker != nil {
+		fmt.Sscanf(*params.Marker, "%d", &start)
+	}
+	output := &lambda.ListAliasesOutput{}
+	if start < len(c.aliases) {
+		output.Aliases = c.aliases[start : start+1]
+	}
+	if start+1 < len(c.aliases) {
+		output.NextMarker = aws.String(fmt.Sprintf("%d", start+1))
+	}
+	return output, nil
+}
+
+func (suite *AWSTestSuite) TestGetLambdaFuncData() {
+	zipConfig := func(codeSha256 string) lambdaTypes.FunctionConfiguration {
+		return lambdaTypes.FunctionConfiguration{
+			LastModified: aws.String("2023-01-02T10:00:00.000+0000"),
+			CodeSha256:   aws.String(codeSha256),
+			PackageType:  lambdaTypes.PackageTypeZip,
+		}
+	}
+	latest := "lATItSVNwz1sF39qJjgtAxAgRkbzFiYyTzbJhhGIiwg="
+	v1 := "DcDwlmhwOhDYcBl3LZbHhobhnRO01/dglbhE8yZ0qMg="
+	v2 := "tBLxhn8zuWKqFG89VhG0RwZ47cBQ+Ep3q/nh+rqEo5Y="
+	canaryClient := &fakeLambdaClient{
+		functionName: "canary",
+		versions: map[string]lambdaTypes.FunctionConfiguration{
+			lambdaLatestVersion: zipConfig(latest),
+			"1":                 zipConfig(v1),
+			"2":                 zipConfig(v2),
+		},
+		aliases: []lambdaTypes.AliasConfiguration{
+			{
+				Name:            aws.String("prod"),
+				FunctionVersion: aws.String("1"),
+				RoutingConfig: &lambdaTypes.AliasRoutingConfiguration{
+					AdditionalVersionWeights: map[string]float64{"2": 0.25},
+				},
+			},
+			{Name: aws.String("dev"), FunctionVersion: aws.String(lambdaLatestVersion)},
+			{Name: aws.String("beta"), FunctionVersion: aws.String("2")},
+		},
+	}
+	imageDigest := "e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5"
+	imageClient := &fakeLambdaClient{
+		functionName: "image",
+		versions: map[string]lambdaTypes.FunctionConfiguration{
+			lambdaLatestVersion: {
+				LastModified: aws.String("2023-01-02T10:00:00.000+0000"),
+				CodeSha256:   aws.String(imageDigest),
+				PackageType:  lambdaTypes.PackageTypeImage,
+			},
+		},
+		imageUris: map[string]string{
+			lambdaLatestVersion: "123456789012.dkr.ecr.eu-central-1.amazonaws.com/image@sha256:" + imageDigest,
+		},
+	}
+	decode := func(fingerprint string) string {
+		decoded, err := decodeLambdaFingerprint(fingerprint)
+		require.NoError(suite.T(), err)
+		return decoded
+	}
+
+	for _, t := range []struct {
+		name           string
+		client         *fakeLambdaClient
+		includeAliases bool
+		want           []*LambdaData
+	}{
+		{
+			name:   "only the latest version is reported without aliases",
+			client: canaryClient,
+			want: []*LambdaData{
+				{Digests: map[string]string{"canary": decode(latest)}, LastModifiedTimestamp: 1672653600},
+			},
+		},
+		{
+			name:           "the versions the aliases route to are reported with their weights",
+			client:         canaryClient,
+			includeAliases: true,
+			want: []*LambdaData{
+				{
+					Digests:               map[string]string{"canary": decode(latest)},
+					LastModifiedTimestamp: 1672653600,
+					Version:               lambdaLatestVersion,
+					Aliases:               []*LambdaAliasRoute{{Name: "dev", Weight: 1}},
+				},
+				{
+					Digests:               map[string]string{"canary": decode(v1)},
+					LastModifiedTimestamp: 1672653600,
+					Version:               "1",
+					Aliases:               []*LambdaAliasRoute{{Name: "prod", Weight: 0.75}},
+				},
+				{
+					Digests:               map[string]string{"canary": decode(v2)},
+					LastModifiedTimestamp: 1672653600,
+					Version:               "2",
+					Aliases:               []*LambdaAliasRoute{{Name: "beta", Weight: 1}, {Name: "prod", Weight: 0.25}},
+				},
+			},
+		},
+		{
+			name:           "function without aliases reports the latest version",
+			client:         imageClient,
+			includeAliases: true,
+			want: []*LambdaData{
+				{
+					Digests:               map[string]string{"image": imageDigest},
+					LastModifiedTimestamp: 1672653600,
+					Version:               lambdaLatestVersion,
+					ImageUri:              "123456789012.dkr.ecr.eu-central-1.amazonaws.com/image@sha256:" + imageDigest,
+				},
+			},
+		},
+	} {
+		suite.Run(t.name, func() {
+			data, err := getLambdaFuncData(t.client, t.client.functionName, t.includeAliases)
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.want, data)
+		})
+	}
+}
+
 func skipOrSetCreds(T *testing.T, requireEnvVars bool, creds *AWSStaticCreds) {
 	if requireEnvVars {
 		// skips the test case if it requires env vars and they are not set
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,6 +21,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/containers/azcontainerregistry"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appservice/armappservice/v2"
 	smithyTime "github.com/aws/smithy-go/time"
+	"github.com/kosli-dev/cli/internal/filters"
 	"github.com/kosli-dev/cli/internal/logger"
 	"github.com/kosli-dev/cli/internal/server"
 )
@@ -29,7 +31,6 @@ type AzureStaticCredentials struct {
 	ClientId          string
 	ClientSecret      string
 	SubscriptionId    string
-	ResourceGroupName string
 	DownloadLogsAsZip bool
 	DigestsSource     string
 }
@@ -46,28 +47,36 @@ type AppData struct {
 	DigestsSource string            `json:"digests_source"`
 	Digests       map[string]string `json:"digests"`
 	StartedAt     int64             `json:"creationTimestamp"`
+	// Slot is the name of the deployment slot, empty for the production slot
+	Slot string `json:"slot,omitempty"`
 }
 
+// runFromPackageSetting is the app setting pointing to the package an app runs from
+const runFromPackageSetting = "WEBSITE_RUN_FROM_PACKAGE"
+
 // AzureAppsRequest represents the PUT request body to be sent to Kosli from CLI
 type AzureAppsRequest struct {
 	Artifacts []*AppData `json:"artifacts"`
 }
 
-func (staticCreds *AzureStaticCredentials) GetAzureAppsData(logger *logger.Logger) (appsData []*AppData, err error) {
+// GetAzureAppsData returns the data of the running web apps and function apps in the resource groups
+// of the subscription matching the filter. If includeSlots is true, the deployment slots of the apps
+// are reported as separate apps too.
+func (staticCreds *AzureStaticCredentials) GetAzureAppsData(resourceGroups *filters.ResourceFilterOptions, includeSlots bool, logger *logger.Logger) (appsData []*AppData, err error) {
 	azureClient, err := staticCreds.NewAzureClient()
 	if err != nil {
 		return nil, err
 	}
 
-	appsInfo, err := azureClient.GetAppsListForResourceGroup()
+	appsInfo, err := azureClient.GetAppsList(resourceGroups, includeSlots)
 	if err != nil {
 		return nil, err
 	}
 
-	logger.Debug("found %d apps in the resource group %s", len(appsInfo), staticCreds.ResourceGroupName)
+	logger.Debug("found %d apps in the subscription %s", len(appsInfo), staticCreds.SubscriptionId)
 	logger.Debug("Found apps:")
 	for _, app := range appsInfo {
-		logger.Debug("  app Name=%s", *app.Name)
+		logger.Debug("  app Name=%s ResourceGroup=%s", *app.Name, siteResourceGroup(app))
 	}
 
 	// run concurrently
@@ -88,7 +97,7 @@ func (staticCreds *AzureStaticCredentials) GetAzureAppsData(logger *logger.Logge
 			default: // Default is a must to avoid blocking
 			}
 
-			if strings.ToLower(*app.Properties.State) != "running" {
+			if app.Properties == nil || strings.ToLower(stringValue(app.Properties.State)) != "running" {
 				logger.Debug("app %s is not running, skipping from report", *app.Name)
 				return
 			}
@@ -129,15 +138,66 @@ func (staticCreds *AzureStaticCredentials) GetAzureAppsData(logger *logger.Logge
 
 func (azureClient *AzureClient) NewAppData(app *armappservice.Site, logger *logger.Logger) (AppData, error) {
 	// Construct and return AppData for the provided armappservice.Site
+	var (
+		data AppData
+		err  error
+	)
 
 	// get image name from "DOCKER|tookyregistry.azurecr.io/tookyregistry/tooky/sha256:cb29a6"
-	linuxFxVersion := strings.Split(*app.Properties.SiteConfig.LinuxFxVersion, "|")
+	var linuxFxVersion []string
+	if app.Properties.SiteConfig != nil {
+		linuxFxVersion = strings.Split(stringValue(app.Properties.SiteConfig.LinuxFxVersion), "|")
+	}
 	notDocker := len(linuxFxVersion) != 2 || linuxFxVersion[0] != "DOCKER"
 	if notDocker {
-		return azureClient.fingerprintZipService(app, logger)
+		data, err = azureClient.fingerprintZipService(app, logger)
 	} else {
-		return azureClient.fingerprintDockerService(app, logger, linuxFxVersion[1])
+		data, err = azureClient.fingerprintDockerService(app, logger, linuxFxVersion[1])
 	}
+	if err != nil || data.IsEmpty() {
+		return data, err
+	}
+	_, data.Slot = splitSiteName(*app.Name)
+	return data, nil
+}
+
+// isFunctionApp returns true if a site is a function app
+func isFunctionApp(app *armappservice.Site) bool {
+	return strings.Contains(strings.ToLower(stringValue(app.Kind)), "functionapp")
+}
+
+// splitSiteName splits the name of a site into the name of the app and of the deployment slot.
+// The sites of deployment slots are named app/slot.
+func splitSiteName(siteName string) (appName, slot string) {
+	appName, slot, _ = strings.Cut(siteName, "/")
+	return appName, slot
+}
+
+// siteResourceGroup returns the name of the resource group of a site
+func siteResourceGroup(app *armappservice.Site) string {
+	if app.Properties != nil && app.Properties.ResourceGroup != nil {
+		return *app.Properties.ResourceGroup
+	}
+	// the ID is /subscriptions/{subscription}/resourceGroups/{resourceGroup}/providers/...
+	parts := strings.Split(stringValue(app.ID), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// scmHostName returns the host name of the Kudu (SCM) site of an app or deployment slot
+func scmHostName(app *armappservice.Site) string {
+	if app.Properties != nil {
+		for _, state := range app.Properties.HostNameSSLStates {
+			if state.HostType != nil && *state.HostType == armappservice.HostTypeRepository && state.Name != nil {
+				return *state.Name
+			}
+		}
+	}
+	return fmt.Sprintf("%s.scm.azurewebsites.net", strings.ReplaceAll(stringValue(app.Name), "/", "-"))
 }
 
 // getBearerToken gets a bearer token
@@ -177,19 +237,20 @@ func (azureClient *AzureClient) getBearerToken() (string, error) {
 	return accessToken, nil
 }
 
-// downloadAppPackage downloads the zip package of a non-docker web app
-func downloadAppPackage(appName, bearerToken, destination string) error {
-	kuduZipURL := fmt.Sprintf("https://%s.scm.azurewebsites.net/api/zip/site/wwwroot/", appName)
-	req, err := http.NewRequest("GET", kuduZipURL, nil)
+// downloadPackage downloads a zip package of an app from a URL. The bearer token is only sent if it is not empty.
+func downloadPackage(packageURL, appName, bearerToken, destination string) error {
+	req, err := http.NewRequest("GET", packageURL, nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to download package for app [%s]: %v", appName, redactURLError(err))
+	}
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
 	}
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to download package for app [%s]: %v", appName, redactURLError(err))
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
@@ -209,12 +270,72 @@ func downloadAppPackage(appName, bearerToken, destination string) error {
 	return nil
 }
 
+// redactURLError removes the query string from the URL of a *url.Error, as the query string
+// of a package URL can hold the SAS token (sig=...) of a storage blob
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if before, _, found := strings.Cut(urlErr.URL, "?"); found {
+			urlErr.URL = before + "?REDACTED"
+		}
+	}
+	return err
+}
+
+// fingerprintZipService fingerprints the content of a non-docker app or function app. The content is
+// downloaded from the package URL the app runs from if it has one, and from its Kudu site otherwise.
 func (azureClient *AzureClient) fingerprintZipService(app *armappservice.Site, logger *logger.Logger) (AppData, error) {
+	var packageURL string
+	if isFunctionApp(app) {
+		var err error
+		packageURL, err = azureClient.getRunFromPackageURL(app)
+		if err != nil {
+			return AppData{}, err
+		}
+	}
+	if packageURL != "" {
+		logger.Debug("app %s runs from a package URL", *app.Name)
+		return fingerprintPackage(app, packageURL, "", logger)
+	}
+
 	// get bearer token
 	token, err := azureClient.getBearerToken()
 	if err != nil {
 		return AppData{}, err
 	}
+	kuduZipURL := fmt.Sprintf("https://%s/api/zip/site/wwwroot/", scmHostName(app))
+	return fingerprintPackage(app, kuduZipURL, token, logger)
+}
+
+// getRunFromPackageURL returns the URL of the package an app runs from, or an empty string
+// if it does not run from a package URL (WEBSITE_RUN_FROM_PACKAGE is not set or is 1)
+func (azureClient *AzureClient) getRunFromPackageURL(app *armappservice.Site) (string, error) {
+	webAppsClient := azureClient.AppServiceFactory.NewWebAppsClient()
+	appName, slot := splitSiteName(*app.Name)
+	var settings armappservice.StringDictionary
+	if slot == "" {
+		response, err := webAppsClient.ListApplicationSettings(context.Background(), siteResourceGroup(app), appName, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the application settings of app [%s]: %v", *app.Name, err)
+		}
+		settings = response.StringDictionary
+	} else {
+		response, err := webAppsClient.ListApplicationSettingsSlot(context.Background(), siteResourceGroup(app), appName, slot, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the application settings of app [%s]: %v", *app.Name, err)
+		}
+		settings = response.StringDictionary
+	}
+
+	packageURL := stringValue(settings.Properties[runFromPackageSetting])
+	if strings.HasPrefix(packageURL, "https://") || strings.HasPrefix(packageURL, "http://") {
+		return packageURL, nil
+	}
+	return "", nil
+}
+
+// fingerprintPackage downloads the zip package of an app and fingerprints its content
+func fingerprintPackage(app *armappservice.Site, packageURL, bearerToken string, logger *logger.Logger) (AppData, error) {
 	// download package
 	tmpDir, err := os.MkdirTemp("", "*")
 	if err != nil {
@@ -222,8 +343,8 @@ func (azureClient *AzureClient) fingerprintZipService(app *armappservice.Site, l
 	}
 	defer os.RemoveAll(tmpDir)
 
-	packagePath := filepath.Join(tmpDir, *app.Name+".zip")
-	err = downloadAppPackage(*app.Name, token, packagePath)
+	packagePath := filepath.Join(tmpDir, "package.zip")
+	err = downloadPackage(packageURL, *app.Name, bearerToken, packagePath)
 	if err != nil {
 		return AppData{}, err
 	}
@@ -271,7 +392,7 @@ func (azureClient *AzureClient) fingerprintZipService(app *armappservice.Site, l
 	// if deploymentTime != nil {
 	// 	startedAt = deploymentTime.Unix()
 	// }
-	return AppData{*app.Name, *app.Kind, "kosli-cli", artifacts[0].Digests, 0}, nil
+	return AppData{AppName: *app.Name, AppKind: *app.Kind, DigestsSource: "kosli-cli", Digests: artifacts[0].Digests}, nil
 }
 
 // unzip extracts a zip archive to a specified destination directory.
@@ -340,11 +461,12 @@ func (azureClient *AzureClient) fingerprintDockerService(app *armappservice.Site
 		}
 	} else {
 		fingerprintSource = "logs"
-		logs, err := azureClient.GetDockerLogsForApp(*app.Name, logger)
+		logs, err := azureClient.GetDockerLogsForApp(siteResourceGroup(app), *app.Name, logger)
 		if err != nil {
 			return AppData{}, err
 		}
-		fingerprint, startedAt, err = exractImageFingerprintAndStartedTimestampFromLogs(logs, *app.Name)
+		// the containers of deployment slots are named app__slot in the logs
+		fingerprint, startedAt, err = exractImageFingerprintAndStartedTimestampFromLogs(logs, strings.ReplaceAll(*app.Name, "/", "__"))
 		if err != nil {
 			return AppData{}, err
 		}
@@ -352,7 +474,7 @@ func (azureClient *AzureClient) fingerprintDockerService(app *armappservice.Site
 
 	logger.Debug("For app %s found: image=%s, fingerprint=%s, startedAt=%d", *app.Name, imageName, fingerprint, startedAt)
 
-	return AppData{*app.Name, *app.Kind, fingerprintSource, map[string]string{imageName: fingerprint}, startedAt}, nil
+	return AppData{AppName: *app.Name, AppKind: *app.Kind, DigestsSource: fingerprintSource, Digests: map[string]string{imageName: fingerprint}, StartedAt: startedAt}, nil
 }
 
 func (azureClient *AzureClient) GetImageFingerprintFromRegistry(imageName string, logger *logger.Logger) (fingerprint string, err error) {
@@ -414,6 +536,14 @@ func parseImageName(imageName string) (registryUrl, repoName, tag string) {
 	return registryUrl, repoName, tag
 }
 
+// stringValue returns the value of a string pointer, or an empty string if it is nil
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 func (app *AppData) IsEmpty() bool {
 	return app.AppName == "" && len(app.Digests) == 0 && app.StartedAt == 0
 }
@@ -436,11 +566,57 @@ func (staticCreds *AzureStaticCredentials) NewAzureClient() (*AzureClient, error
 	}, nil
 }
 
-func (azureClient *AzureClient) GetAppsListForResourceGroup() ([]*armappservice.Site, error) {
+// GetAppsList returns the apps in the resource groups of the subscription matching the filter.
+// If only resource group names are given, the apps are listed by resource group, otherwise
+// the apps in the whole subscription are listed and filtered by resource group.
+// If includeSlots is true, the deployment slots of the apps are returned too, after their app.
+func (azureClient *AzureClient) GetAppsList(resourceGroups *filters.ResourceFilterOptions, includeSlots bool) ([]*armappservice.Site, error) {
+	var appsInfo []*armappservice.Site
+	if len(resourceGroups.IncludeNames) > 0 && len(resourceGroups.IncludeNamesRegex) == 0 &&
+		len(resourceGroups.ExcludeNames) == 0 && len(resourceGroups.ExcludeNamesRegex) == 0 {
+		for _, resourceGroupName := range resourceGroups.IncludeNames {
+			apps, err := azureClient.GetAppsListForResourceGroup(resourceGroupName)
+			if err != nil {
+				return nil, err
+			}
+			appsInfo = append(appsInfo, apps...)
+		}
+	} else {
+		apps, err := azureClient.GetAppsListForSubscription()
+		if err != nil {
+			return nil, err
+		}
+		for _, app := range apps {
+			include, err := resourceGroups.ShouldInclude(siteResourceGroup(app))
+			if err != nil {
+				return nil, err
+			}
+			if include {
+				appsInfo = append(appsInfo, app)
+			}
+		}
+	}
+
+	if !includeSlots {
+		return appsInfo, nil
+	}
+	var appsAndSlots []*armappservice.Site
+	for _, app := range appsInfo {
+		slots, err := azureClient.GetSlotsListForApp(app)
+		if err != nil {
+			return nil, err
+		}
+		appsAndSlots = append(appsAndSlots, app)
+		appsAndSlots = append(appsAndSlots, slots...)
+	}
+	return appsAndSlots, nil
+}
+
+func (azureClient *AzureClient) GetAppsListForResourceGroup(resourceGroupName string) ([]*armappservice.Site, error) {
 	webAppsClient := azureClient.AppServiceFactory.NewWebAppsClient()
 
 	ctx := context.Background()
-	appsPager := webAppsClient.NewListByResourceGroupPager(azureClient.Credentials.ResourceGroupName, nil)
+	appsPager := webAppsClient.NewListByResourceGroupPager(resourceGroupName, nil)
 
 	var appsInfo []*armappservice.Site
 	for appsPager.More() {
@@ -453,13 +629,60 @@ func (azureClient *AzureClient) GetAppsListForResourceGroup() ([]*armappservice.
 	return appsInfo, nil
 }
 
-func (azureClient *AzureClient) GetDockerLogsForApp(appServiceName string, logger *logger.Logger) (logs []byte, error error) {
+// GetAppsListForSubscription returns the apps in all the resource groups of the subscription
+func (azureClient *AzureClient) GetAppsListForSubscription() ([]*armappservice.Site, error) {
+	webAppsClient := azureClient.AppServiceFactory.NewWebAppsClient()
+
+	ctx := context.Background()
+	appsPager := webAppsClient.NewListPager(nil)
+
+	var appsInfo []*armappservice.Site
+	for appsPager.More() {
+		response, err := appsPager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		appsInfo = append(appsInfo, response.Value...)
+	}
+	return appsInfo, nil
+}
+
+// GetSlotsListForApp returns the deployment slots of an app, other than the production slot
+func (azureClient *AzureClient) GetSlotsListForApp(app *armappservice.Site) ([]*armappservice.Site, error) {
+	webAppsClient := azureClient.AppServiceFactory.NewWebAppsClient()
+
+	ctx := context.Background()
+	slotsPager := webAppsClient.NewListSlotsPager(siteResourceGroup(app), *app.Name, nil)
+
+	var slotsInfo []*armappservice.Site
+	for slotsPager.More() {
+		response, err := slotsPager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the deployment slots of app [%s]: %v", *app.Name, err)
+		}
+		slotsInfo = append(slotsInfo, response.Value...)
+	}
+	return slotsInfo, nil
+}
+
+// GetDockerLogsForApp returns the docker logs of an app or, if appServiceName is app/slot, of a deployment slot
+func (azureClient *AzureClient) GetDockerLogsForApp(resourceGroupName, appServiceName string, logger *logger.Logger) (logs []byte, error error) {
 	appsClient := azureClient.AppServiceFactory.NewWebAppsClient()
 
 	ctx := context.Background()
+	appName, slot := splitSiteName(appServiceName)
+
+	if slot != "" {
+		response, err := appsClient.GetWebSiteContainerLogsSlot(ctx, resourceGroupName, appName, slot, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+		return io.ReadAll(response.Body)
+	}
 
 	if azureClient.Credentials.DownloadLogsAsZip {
-		response, err := appsClient.GetContainerLogsZip(ctx, azureClient.Credentials.ResourceGroupName, appServiceName, nil)
+		response, err := appsClient.GetContainerLogsZip(ctx, resourceGroupName, appServiceName, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -482,7 +705,7 @@ func (azureClient *AzureClient) GetDockerLogsForApp(appServiceName string, logge
 		// TODO: read zip file and return logs
 		return nil, nil
 	} else {
-		response, err := appsClient.GetWebSiteContainerLogs(ctx, azureClient.Credentials.ResourceGroupName, appServiceName, nil)
+		response, err := appsClient.GetWebSiteContainerLogs(ctx, resourceGroupName, appServiceName, nil)
 		if err != nil {
 			return nil, err
 		}
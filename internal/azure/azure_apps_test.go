@@ -2,12 +2,20 @@ package azure
 
 import (
 	"archive/zip"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"regexp"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appservice/armappservice/v2"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appservice/armappservice/v2/fake"
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/filters"
 	"github.com/kosli-dev/cli/internal/logger"
 	"github.com/kosli-dev/cli/internal/testHelpers"
 	"github.com/stretchr/testify/require"
@@ -28,11 +36,10 @@ func (suite *AzureAppsTestSuite) SetupTest() {
 		"INTEGRATION_TEST_AZURE_CLIENT_ID",
 	})
 	suite.staticCreds = AzureStaticCredentials{
-		TenantId:          "e52b5fba-43c2-4eaf-91c1-579dc6fae771",
-		ClientId:          os.Getenv("INTEGRATION_TEST_AZURE_CLIENT_ID"),
-		ClientSecret:      os.Getenv("INTEGRATION_TEST_AZURE_CLIENT_SECRET"),
-		SubscriptionId:    "96cdee58-1fa8-419d-a65a-7233b3465632",
-		ResourceGroupName: "EnvironmentReportingExperiment",
+		TenantId:       "e52b5fba-43c2-4eaf-91c1-579dc6fae771",
+		ClientId:       os.Getenv("INTEGRATION_TEST_AZURE_CLIENT_ID"),
+		ClientSecret:   os.Getenv("INTEGRATION_TEST_AZURE_CLIENT_SECRET"),
+		SubscriptionId: "96cdee58-1fa8-419d-a65a-7233b3465632",
 	}
 	var err error
 	suite.defaultClient, err = suite.staticCreds.NewAzureClient()
@@ -49,7 +56,7 @@ func (suite *AzureAppsTestSuite) TestCanDownloadAppZip() {
 	require.NoError(suite.T(), err)
 	defer os.RemoveAll(tmpDir)
 	dest := filepath.Join(tmpDir, appName+".zip")
-	err = downloadAppPackage(appName, token, dest)
+	err = downloadPackage("https://"+appName+".scm.azurewebsites.net/api/zip/site/wwwroot/", appName, token, dest)
 	require.NoError(suite.T(), err)
 
 	// check download file exists
@@ -92,3 +99,208 @@ func (suite *AzureAppsTestSuite) TestFingerprintZipService() {
 func TestAzureAppsTestSuite(t *testing.T) {
 	suite.Run(t, new(AzureAppsTestSuite))
 }
+
+// AzureAppsListTestSuite tests listing and fingerprinting apps against fake Azure servers
+type AzureAppsListTestSuite struct {
+	suite.Suite
+	client *AzureClient
+}
+
+func newTestSite(resourceGroup, name, kind string) *armappservice.Site {
+	id := "/subscriptions/xxx/resourceGroups/" + resourceGroup + "/providers/Microsoft.Web/sites/" + name
+	return &armappservice.Site{ID: &id, Name: &name, Kind: &kind}
+}
+
+func (suite *AzureAppsListTestSuite) SetupTest() {
+	sites := map[string][]*armappservice.Site{
+		"backend-rg": {
+			newTestSite("backend-rg", "api", "app,linux,container"),
+			newTestSite("backend-rg", "jobs", "functionapp,linux"),
+		},
+		"frontend-rg": {
+			newTestSite("frontend-rg", "web", "app"),
+		},
+		"sandbox-rg": {
+			newTestSite("sandbox-rg", "playground", "app"),
+		},
+	}
+	slots := map[string][]*armappservice.Site{
+		"api": {
+			newTestSite("backend-rg", "api/staging", "app,linux,container"),
+		},
+	}
+
+	server := fake.ServerFactory{
+		WebAppsServer: fake.WebAppsServer{
+			NewListPager: func(options *armappservice.WebAppsClientListOptions) (resp azfake.PagerResponder[armappservice.WebAppsClientListResponse]) {
+				for _, rg := range []string{"backend-rg", "frontend-rg", "sandbox-rg"} {
+					resp.AddPage(http.StatusOK, armappservice.WebAppsClientListResponse{
+						WebAppCollection: armappservice.WebAppCollection{Value: sites[rg]},
+					}, nil)
+				}
+				return
+			},
+			NewListByResourceGroupPager: func(resourceGroupName string, options *armappservice.WebAppsClientListByResourceGroupOptions) (resp azfake.PagerResponder[armappservice.WebAppsClientListByResourceGroupResponse]) {
+				resp.AddPage(http.StatusOK, armappservice.WebAppsClientListByResourceGroupResponse{
+					WebAppCollection: armappservice.WebAppCollection{Value: sites[resourceGroupName]},
+				}, nil)
+				return
+			},
+			NewListSlotsPager: func(resourceGroupName string, name string, options *armappservice.WebAppsClientListSlotsOptions) (resp azfake.PagerResponder[armappservice.WebAppsClientListSlotsResponse]) {
+				resp.AddPage(http.StatusOK, armappservice.WebAppsClientListSlotsResponse{
+					WebAppCollection: armappservice.WebAppCollection{Value: slots[name]},
+				}, nil)
+				return
+			},
+		},
+	}
+	factory, err := armappservice.NewClientFactory("xxx", &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: fake.NewServerFactoryTransport(&server)},
+	})
+	require.NoError(suite.T(), err)
+	suite.client = &AzureClient{AppServiceFactory: factory}
+}
+
+func (suite *AzureAppsListTestSuite) TestGetAppsList() {
+	for _, t := range []struct {
+		name           string
+		resourceGroups *filters.ResourceFilterOptions
+		includeSlots   bool
+		want           []string
+	}{
+		{
+			name:           "all resource groups of the subscription are listed by default",
+			resourceGroups: &filters.ResourceFilterOptions{},
+			want:           []string{"api", "jobs", "web", "playground"},
+		},
+		{
+			name:           "apps are listed from several resource groups",
+			resourceGroups: &filters.ResourceFilterOptions{IncludeNames: []string{"frontend-rg", "backend-rg"}},
+			want:           []string{"web", "api", "jobs"},
+		},
+		{
+			name:           "resource groups can be selected with regex",
+			resourceGroups: &filters.ResourceFilterOptions{IncludeNamesRegex: []string{"^(front|back)end-"}},
+			want:           []string{"api", "jobs", "web"},
+		},
+		{
+			name:           "resource groups can be excluded",
+			resourceGroups: &filters.ResourceFilterOptions{ExcludeNames: []string{"sandbox-rg"}},
+			want:           []string{"api", "jobs", "web"},
+		},
+		{
+			name:           "resource groups can be excluded with regex",
+			resourceGroups: &filters.ResourceFilterOptions{ExcludeNamesRegex: []string{"end-rg$"}},
+			want:           []string{"playground"},
+		},
+		{
+			name:           "deployment slots are listed after their app",
+			resourceGroups: &filters.ResourceFilterOptions{IncludeNames: []string{"backend-rg"}},
+			includeSlots:   true,
+			want:           []string{"api", "api/staging", "jobs"},
+		},
+	} {
+		suite.Run(t.name, func() {
+			apps, err := suite.client.GetAppsList(t.resourceGroups, t.includeSlots)
+			require.NoError(suite.T(), err)
+			names := []string{}
+			for _, app := range apps {
+				names = append(names, *app.Name)
+			}
+			require.Equal(suite.T(), t.want, names)
+		})
+	}
+}
+
+func (suite *AzureAppsListTestSuite) TestSiteHelpers() {
+	slot := newTestSite("backend-rg", "api/staging", "app")
+	appName, slotName := splitSiteName(*slot.Name)
+	require.Equal(suite.T(), "api", appName)
+	require.Equal(suite.T(), "staging", slotName)
+	require.Equal(suite.T(), "backend-rg", siteResourceGroup(slot))
+	require.Equal(suite.T(), "api-staging.scm.azurewebsites.net", scmHostName(slot))
+
+	resourceGroup := "other-rg"
+	scmHost := "api.scm.example.com"
+	repository := armappservice.HostTypeRepository
+	app := newTestSite("backend-rg", "api", "app")
+	app.Properties = &armappservice.SiteProperties{
+		ResourceGroup: &resourceGroup,
+		HostNameSSLStates: []*armappservice.HostNameSSLState{
+			{Name: &scmHost, HostType: &repository},
+		},
+	}
+	require.Equal(suite.T(), "other-rg", siteResourceGroup(app))
+	require.Equal(suite.T(), "api.scm.example.com", scmHostName(app))
+	require.False(suite.T(), isFunctionApp(app))
+	require.True(suite.T(), isFunctionApp(newTestSite("backend-rg", "jobs", "functionapp,linux")))
+}
+
+// A function app running from a package URL has the fingerprint of a directory with the package content
+func (suite *AzureAppsListTestSuite) TestFingerprintPackageFromURL() {
+	contentDir := suite.T().TempDir()
+	files := map[string]string{
+		"host.json":             `{"version": "2.0"}`,
+		"handler/index.js":      "module.exports = async function () {}",
+		"handler/function.json": `{"bindings": []}`,
+	}
+	zipPath := filepath.Join(suite.T().TempDir(), "package.zip")
+	zipFile, err := os.Create(zipPath)
+	require.NoError(suite.T(), err)
+	zipWriter := zip.NewWriter(zipFile)
+	for name, content := range files {
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(filepath.Join(contentDir, name)), 0755))
+		require.NoError(suite.T(), os.WriteFile(filepath.Join(contentDir, name), []byte(content), 0644))
+		w, err := zipWriter.Create(name)
+		require.NoError(suite.T(), err)
+		_, err = w.Write([]byte(content))
+		require.NoError(suite.T(), err)
+	}
+	require.NoError(suite.T(), zipWriter.Close())
+	require.NoError(suite.T(), zipFile.Close())
+
+	packageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(suite.T(), r.Header.Get("Authorization"))
+		http.ServeFile(w, r, zipPath)
+	}))
+	defer packageServer.Close()
+
+	want, err := digest.DirSha256(contentDir, []string{}, "", logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+
+	app := newTestSite("backend-rg", "jobs", "functionapp,linux")
+	appData, err := fingerprintPackage(app, packageServer.URL+"/package.zip", "", logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), map[string]string{"jobs": want}, appData.Digests)
+}
+
+func (suite *AzureAppsListTestSuite) TestDownloadPackage() {
+	packageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("zip content"))
+	}))
+	defer packageServer.Close()
+	dest := filepath.Join(suite.T().TempDir(), "package.zip")
+
+	err := downloadPackage(packageServer.URL+"/package.zip", "api", "secret-token", dest)
+	require.NoError(suite.T(), err)
+	content, err := os.ReadFile(dest)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "zip content", string(content))
+
+	err = downloadPackage(packageServer.URL+"/package.zip", "api", "", dest)
+	require.EqualError(suite.T(), err, "failed to download package for app [api]: 401 Unauthorized")
+
+	// the SAS token in the query string of a package URL is not leaked in the error
+	unreachableURL := "http://127.0.0.1:1/packages/package.zip?sv=2022-11-02&sig=c2VjcmV0LXNpZ25hdHVyZQ%3D%3D"
+	err = downloadPackage(unreachableURL, "jobs", "", dest)
+	require.ErrorContains(suite.T(), err, "failed to download package for app [jobs]: Get \"http://127.0.0.1:1/packages/package.zip?REDACTED\"")
+	require.NotContains(suite.T(), err.Error(), "sig=")
+}
+
+func TestAzureAppsListTestSuite(t *testing.T) {
+	suite.Run(t, new(AzureAppsListTestSuite))
+}
@@ -0,0 +1,137 @@
+package containerruntime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	contentapi "github.com/containerd/containerd/api/services/content/v1"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// containerdNamespaceHeader is the gRPC metadata header which selects the namespace of a containerd request
+	containerdNamespaceHeader = "containerd-namespace"
+	// distributionSourceLabel prefixes the content labels which record the registry a blob was pulled from
+	distributionSourceLabel = "containerd.io/distribution.source."
+	// nerdctlNameLabel is the label with the name of a container started by nerdctl
+	nerdctlNameLabel = "nerdctl/name"
+)
+
+// containerdRuntime is a client of the containerd API which reads the containers of one containerd namespace,
+// e.g. the containers started with ctr or nerdctl which are not visible through CRI
+type containerdRuntime struct {
+	conn       *grpc.ClientConn
+	containers containersapi.ContainersClient
+	images     imagesapi.ImagesClient
+	content    contentapi.ContentClient
+	tasks      tasksapi.TasksClient
+	endpoint   string
+	namespace  string
+}
+
+// newContainerdRuntime returns a client of the containerd API at endpoint for namespace
+func newContainerdRuntime(endpoint, namespace string) (*containerdRuntime, error) {
+	conn, err := grpc.NewClient(endpointAddress(endpoint), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the containerd endpoint %s: %v", endpoint, err)
+	}
+	return &containerdRuntime{
+		conn:       conn,
+		containers: containersapi.NewContainersClient(conn),
+		images:     imagesapi.NewImagesClient(conn),
+		content:    contentapi.NewContentClient(conn),
+		tasks:      tasksapi.NewTasksClient(conn),
+		endpoint:   endpoint,
+		namespace:  namespace,
+	}, nil
+}
+
+func (r *containerdRuntime) Name() string {
+	return RuntimeContainerd
+}
+
+// RunningContainers returns the containers of the namespace with a running task. The repo digest of a container
+// is the digest of its image, if the image was pulled from a registry.
+func (r *containerdRuntime) RunningContainers(ctx context.Context) ([]*RunningContainer, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, containerdNamespaceHeader, r.namespace)
+	response, err := r.tasks.List(ctx, &tasksapi.ListTasksRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tasks of namespace %s of the containerd endpoint %s: %v", r.namespace, r.endpoint, err)
+	}
+
+	result := []*RunningContainer{}
+	for _, process := range response.Tasks {
+		if process.Status != task.Status_RUNNING {
+			continue
+		}
+		containerResponse, err := r.containers.Get(ctx, &containersapi.GetContainerRequest{ID: process.ContainerID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get container %s of namespace %s: %v", process.ContainerID, r.namespace, err)
+		}
+		c := containerResponse.Container
+
+		repoDigests, err := r.repoDigests(ctx, c.Image)
+		if err != nil {
+			return nil, err
+		}
+		name := c.ID
+		if c.Labels[nerdctlNameLabel] != "" {
+			name = c.Labels[nerdctlNameLabel]
+		}
+		var createdAt int64
+		if c.CreatedAt != nil {
+			createdAt = c.CreatedAt.Seconds
+		}
+		result = append(result, &RunningContainer{
+			Name:        name,
+			Image:       c.Image,
+			RepoDigests: repoDigests,
+			Labels:      c.Labels,
+			CreatedAt:   createdAt,
+		})
+	}
+	return result, nil
+}
+
+// repoDigests returns the repo digest of an image, or nothing if the image was not pulled from a registry
+// (e.g. it was built or imported locally), i.e. if its content has no distribution source label
+func (r *containerdRuntime) repoDigests(ctx context.Context, image string) ([]string, error) {
+	imageResponse, err := r.images.Get(ctx, &imagesapi.GetImageRequest{Name: image})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image %s of namespace %s: %v", image, r.namespace, err)
+	}
+	target := imageResponse.Image.Target
+	if target == nil || target.Digest == "" {
+		return nil, nil
+	}
+	infoResponse, err := r.content.Info(ctx, &contentapi.InfoRequest{Digest: target.Digest})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the content of image %s of namespace %s: %v", image, r.namespace, err)
+	}
+	for label := range infoResponse.Info.Labels {
+		if strings.HasPrefix(label, distributionSourceLabel) {
+			return []string{imageRepository(image) + "@" + target.Digest}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *containerdRuntime) Close() error {
+	return r.conn.Close()
+}
+
+// imageRepository returns the repository of an image reference, without its tag or digest
+func imageRepository(image string) string {
+	image = strings.Split(image, "@")[0]
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
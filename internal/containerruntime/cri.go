@@ -0,0 +1,100 @@
+package containerruntime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// criRuntime is a container runtime with a CRI API (containerd)
+type criRuntime struct {
+	conn     *grpc.ClientConn
+	runtime  runtimeapi.RuntimeServiceClient
+	images   runtimeapi.ImageServiceClient
+	endpoint string
+}
+
+// newCRIRuntime returns a client of the CRI API at endpoint
+func newCRIRuntime(endpoint string) (*criRuntime, error) {
+	conn, err := grpc.NewClient(endpointAddress(endpoint), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the CRI endpoint %s: %v", endpoint, err)
+	}
+	return &criRuntime{
+		conn:     conn,
+		runtime:  runtimeapi.NewRuntimeServiceClient(conn),
+		images:   runtimeapi.NewImageServiceClient(conn),
+		endpoint: endpoint,
+	}, nil
+}
+
+func (r *criRuntime) Name() string {
+	return RuntimeContainerd
+}
+
+func (r *criRuntime) RunningContainers(ctx context.Context) ([]*RunningContainer, error) {
+	response, err := r.runtime.ListContainers(ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{
+			State: &runtimeapi.ContainerStateValue{State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+		},
+	})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, fmt.Errorf("the containerd endpoint %s does not serve the CRI API, use --containerd-namespace "+
+			"to read the containers of a containerd namespace", r.endpoint)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the containers of the CRI endpoint %s: %v", r.endpoint, err)
+	}
+	// containers started with ctr or nerdctl are not managed through CRI, so an empty
+	// list more likely means the containers are in a containerd namespace than that none run
+	if len(response.Containers) == 0 {
+		return nil, fmt.Errorf("the CRI API of the containerd endpoint %s reports no running containers. Containers "+
+			"started with ctr or nerdctl are only visible with --containerd-namespace (e.g. default)", r.endpoint)
+	}
+
+	result := []*RunningContainer{}
+	for _, c := range response.Containers {
+		imageStatus, err := r.images.ImageStatus(ctx, &runtimeapi.ImageStatusRequest{
+			Image: &runtimeapi.ImageSpec{Image: c.ImageRef},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the status of image %s: %v", c.ImageRef, err)
+		}
+
+		image := ""
+		if c.Image != nil {
+			image = c.Image.Image
+		}
+		var repoDigests []string
+		if imageStatus.Image != nil {
+			repoDigests = imageStatus.Image.RepoDigests
+			// containers can reference their image by ID
+			if (image == "" || strings.HasPrefix(image, "sha256:")) && len(imageStatus.Image.RepoTags) > 0 {
+				image = imageStatus.Image.RepoTags[0]
+			}
+		}
+		name := c.Id
+		if c.Metadata != nil && c.Metadata.Name != "" {
+			name = c.Metadata.Name
+		}
+		result = append(result, &RunningContainer{
+			Name:        name,
+			Image:       image,
+			RepoDigests: repoDigests,
+			Labels:      c.Labels,
+			// the CRI creation time is in nanoseconds
+			CreatedAt: c.CreatedAt / 1e9,
+		})
+	}
+	return result, nil
+}
+
+func (r *criRuntime) Close() error {
+	return r.conn.Close()
+}
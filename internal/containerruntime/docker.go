@@ -0,0 +1,65 @@
+package containerruntime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// dockerRuntime is a container runtime with a Docker-compatible API (docker or podman)
+type dockerRuntime struct {
+	name   string
+	client *client.Client
+}
+
+// newDockerRuntime returns a client of the Docker-compatible API of a runtime.
+// If endpoint is empty, the docker environment variables (e.g. DOCKER_HOST) are used.
+func newDockerRuntime(name, endpoint string) (*dockerRuntime, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if endpoint != "" {
+		opts = append(opts, client.WithHost(endpointAddress(endpoint)))
+	}
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a %s client: %v", name, err)
+	}
+	return &dockerRuntime{name: name, client: cli}, nil
+}
+
+func (r *dockerRuntime) Name() string {
+	return r.name
+}
+
+func (r *dockerRuntime) RunningContainers(ctx context.Context) ([]*RunningContainer, error) {
+	containers, err := r.client.ContainerList(ctx, container.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*RunningContainer{}
+	for _, c := range containers {
+		imageInspect, _, err := r.client.ImageInspectWithRaw(ctx, c.Image)
+		if err != nil {
+			return nil, err
+		}
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		result = append(result, &RunningContainer{
+			Name:        name,
+			Image:       c.Image,
+			RepoDigests: imageInspect.RepoDigests,
+			Labels:      c.Labels,
+			CreatedAt:   c.Created,
+		})
+	}
+	return result, nil
+}
+
+func (r *dockerRuntime) Close() error {
+	return r.client.Close()
+}
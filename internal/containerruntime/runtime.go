@@ -0,0 +1,212 @@
+package containerruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/logger"
+	"github.com/kosli-dev/cli/internal/server"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// the supported container runtimes
+const (
+	RuntimeAuto       = "auto"
+	RuntimeDocker     = "docker"
+	RuntimePodman     = "podman"
+	RuntimeContainerd = "containerd"
+)
+
+// Runtimes are the names accepted by New
+var Runtimes = []string{RuntimeAuto, RuntimeDocker, RuntimePodman, RuntimeContainerd}
+
+// RunningContainer is a running container and the repo digests of its image
+type RunningContainer struct {
+	Name        string
+	Image       string
+	RepoDigests []string
+	Labels      map[string]string
+	// CreatedAt is the creation time of the container in seconds since the epoch
+	CreatedAt int64
+}
+
+// Runtime is a container runtime which can list its running containers
+type Runtime interface {
+	// Name returns the name of the runtime (docker, podman or containerd)
+	Name() string
+	// RunningContainers returns the running containers
+	RunningContainers(ctx context.Context) ([]*RunningContainer, error)
+	Close() error
+}
+
+// runtimeSocket is the default socket of a container runtime
+type runtimeSocket struct {
+	runtime string
+	path    string
+}
+
+// defaultSockets are the sockets probed, in order, to detect the container runtime
+var defaultSockets = func() []runtimeSocket {
+	sockets := []runtimeSocket{
+		{RuntimeDocker, "/var/run/docker.sock"},
+		{RuntimePodman, "/run/podman/podman.sock"},
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		// rootless podman
+		sockets = append(sockets, runtimeSocket{RuntimePodman, runtimeDir + "/podman/podman.sock"})
+	}
+	return append(sockets, runtimeSocket{RuntimeContainerd, "/run/containerd/containerd.sock"})
+}
+
+// New returns a client of a container runtime. If runtimeName is auto (or empty), the runtime is detected.
+// If endpoint (a unix socket path or a unix:// or tcp:// address) is empty, the default endpoint of the runtime is used.
+// containerd is read through its CRI API, or through the containerd API if containerdNamespace is set.
+func New(runtimeName, endpoint, containerdNamespace string) (Runtime, error) {
+	if runtimeName == "" || runtimeName == RuntimeAuto {
+		var err error
+		runtimeName, endpoint, err = detect(endpoint, defaultSockets())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch runtimeName {
+	case RuntimeDocker:
+		return newDockerRuntime(RuntimeDocker, endpoint)
+	case RuntimePodman:
+		if endpoint == "" {
+			endpoint = defaultPodmanSocket()
+		}
+		return newDockerRuntime(RuntimePodman, endpoint)
+	case RuntimeContainerd:
+		if endpoint == "" {
+			endpoint = "/run/containerd/containerd.sock"
+		}
+		if containerdNamespace != "" {
+			return newContainerdRuntime(endpoint, containerdNamespace)
+		}
+		return newCRIRuntime(endpoint)
+	default:
+		return nil, fmt.Errorf("unsupported container runtime %q, the supported runtimes are: %s", runtimeName, strings.Join(Runtimes, ", "))
+	}
+}
+
+// detect finds the container runtime to use: docker if DOCKER_HOST is set, otherwise the runtime
+// of the first socket that exists. If endpoint is set, only the runtime of that socket is detected.
+func detect(endpoint string, sockets []runtimeSocket) (string, string, error) {
+	if endpoint == "" && os.Getenv("DOCKER_HOST") != "" {
+		return RuntimeDocker, "", nil
+	}
+	for _, socket := range sockets {
+		if endpoint != "" && socketPath(endpoint) != socket.path {
+			continue
+		}
+		info, err := os.Stat(socket.path)
+		if err == nil && info.Mode()&os.ModeSocket != 0 {
+			return socket.runtime, socket.path, nil
+		}
+	}
+	if endpoint != "" {
+		return "", "", fmt.Errorf("cannot detect the container runtime of endpoint %s, use --runtime to set it", endpoint)
+	}
+	paths := []string{}
+	for _, socket := range sockets {
+		paths = append(paths, socket.path)
+	}
+	return "", "", fmt.Errorf("no container runtime socket found in %s, use --runtime and --runtime-endpoint to set it", strings.Join(paths, ", "))
+}
+
+// defaultPodmanSocket returns the socket of rootful podman, or of rootless podman if only that one exists
+func defaultPodmanSocket() string {
+	for _, socket := range defaultSockets() {
+		if socket.runtime != RuntimePodman {
+			continue
+		}
+		if _, err := os.Stat(socket.path); err == nil {
+			return socket.path
+		}
+	}
+	return "/run/podman/podman.sock"
+}
+
+// socketPath returns the path of a unix socket endpoint
+func socketPath(endpoint string) string {
+	return strings.TrimPrefix(endpoint, "unix://")
+}
+
+// endpointAddress returns the address of an endpoint, with the unix:// scheme for socket paths
+func endpointAddress(endpoint string) string {
+	if strings.HasPrefix(endpoint, "/") {
+		return "unix://" + endpoint
+	}
+	return endpoint
+}
+
+// the labels set on containers by docker compose and docker swarm
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	swarmStackLabel     = "com.docker.stack.namespace"
+	swarmServiceLabel   = "com.docker.swarm.service.name"
+)
+
+// ParseSelector parses a label selector (e.g. env=prod,tier!=frontend,app in (api,web)).
+// An empty selector selects all containers.
+func ParseSelector(selector string) (labels.Selector, error) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %v", selector, err)
+	}
+	return parsed, nil
+}
+
+// CreateArtifactsData returns the image digests, creation timestamps and compose/swarm services of the running
+// containers of a runtime which match selector. Containers running images which have no repo digest (i.e. have
+// not been pushed to or pulled from a registry) are ignored.
+func CreateArtifactsData(rt Runtime, selector labels.Selector, logger *logger.Logger) ([]*server.ServerData, error) {
+	result := []*server.ServerData{}
+	containers, err := rt.RunningContainers(context.Background())
+	if err != nil {
+		return result, err
+	}
+
+	for _, c := range containers {
+		if !selector.Matches(labels.Set(c.Labels)) {
+			logger.Debug("ignoring container '%s' as its labels do not match the selector", c.Name)
+			continue
+		}
+		sha256, err := digest.RepoDigestSha256(c.Image, c.RepoDigests)
+		if err != nil {
+			if errors.Is(err, digest.ErrRepoDigestUnavailable) {
+				logger.Info("ignoring container '%s' as it uses an image with no repo digest", c.Name)
+				continue
+			}
+			return result, err
+		}
+		result = append(result, &server.ServerData{
+			Digests:           map[string]string{c.Image: sha256},
+			CreationTimestamp: c.CreatedAt,
+			Service:           containerService(c.Labels),
+		})
+	}
+	return result, nil
+}
+
+// containerService returns the compose and swarm services of a container from its labels,
+// or nil if the container was not started by docker compose or swarm
+func containerService(containerLabels map[string]string) *server.ContainerService {
+	service := &server.ContainerService{
+		ComposeProject: containerLabels[composeProjectLabel],
+		ComposeService: containerLabels[composeServiceLabel],
+		SwarmStack:     containerLabels[swarmStackLabel],
+		SwarmService:   containerLabels[swarmServiceLabel],
+	}
+	if *service == (server.ContainerService{}) {
+		return nil
+	}
+	return service
+}
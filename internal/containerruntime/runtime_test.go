@@ -0,0 +1,485 @@
+package containerruntime
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	contentapi "github.com/containerd/containerd/api/services/content/v1"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/kosli-dev/cli/internal/logger"
+	"github.com/kosli-dev/cli/internal/server"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/labels"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type ContainerRuntimeTestSuite struct {
+	suite.Suite
+}
+
+const (
+	nginxDigest = "6f2f4b3a4d30b0d4a1c1a0c2b0e1dfc9a4fae3e3f1b2e8b1f5a7d1c0e2f4a6b8"
+	redisDigest = "9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a0f9e8d"
+	localImage  = "sha256:0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c"
+)
+
+// the artifacts data expected from the fake runtimes, which run nginx, redis and a local image
+// nginx is a compose service and redis a swarm service
+var expectedArtifactsData = []*server.ServerData{
+	{
+		Digests:           map[string]string{"docker.io/library/nginx:1.27": nginxDigest},
+		CreationTimestamp: 1790848800,
+		Service:           &server.ContainerService{ComposeProject: "shop", ComposeService: "web"},
+	},
+	{
+		Digests:           map[string]string{"docker.io/library/redis:7": redisDigest},
+		CreationTimestamp: 1790935200,
+		Service:           &server.ContainerService{SwarmStack: "cache", SwarmService: "cache_redis"},
+	},
+}
+
+var (
+	nginxLabels = map[string]string{composeProjectLabel: "shop", composeServiceLabel: "web", "env": "prod"}
+	redisLabels = map[string]string{swarmStackLabel: "cache", swarmServiceLabel: "cache_redis", "env": "test"}
+)
+
+// newFakeDockerAPI returns a fake of the Docker-compatible API served by docker and podman
+func newFakeDockerAPI(t *testing.T) *httptest.Server {
+	images := map[string][]string{
+		"docker.io/library/nginx:1.27": {"docker.io/library/nginx@sha256:" + nginxDigest},
+		"docker.io/library/redis:7":    {"docker.io/library/redis@sha256:" + redisDigest},
+		"localhost/dev:latest":         {},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("API-Version", "1.41")
+		path := r.URL.Path
+		var response interface{}
+		switch {
+		case path == "/_ping":
+			return
+		case strings.HasSuffix(path, "/containers/json"):
+			response = []map[string]interface{}{
+				{"Id": "c1", "Names": []string{"/web"}, "Image": "docker.io/library/nginx:1.27", "Created": 1790848800, "Labels": nginxLabels},
+				{"Id": "c2", "Names": []string{"/cache"}, "Image": "docker.io/library/redis:7", "Created": 1790935200, "Labels": redisLabels},
+				{"Id": "c3", "Names": []string{"/dev"}, "Image": "localhost/dev:latest", "Created": 1790935200},
+			}
+		case strings.Contains(path, "/images/") && strings.HasSuffix(path, "/json"):
+			name := strings.TrimSuffix(path[strings.Index(path, "/images/")+len("/images/"):], "/json")
+			repoDigests, ok := images[name]
+			if !ok {
+				http.Error(w, `{"message": "no such image"}`, http.StatusNotFound)
+				return
+			}
+			response = map[string]interface{}{"Id": "sha256:" + name, "RepoDigests": repoDigests}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+}
+
+// fakeCRIServer is a fake of the CRI API served by containerd
+type fakeCRIServer struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	runtimeapi.UnimplementedImageServiceServer
+}
+
+func (s *fakeCRIServer) ListContainers(ctx context.Context, req *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	if req.Filter == nil || req.Filter.State == nil || req.Filter.State.State != runtimeapi.ContainerState_CONTAINER_RUNNING {
+		return &runtimeapi.ListContainersResponse{}, nil
+	}
+	return &runtimeapi.ListContainersResponse{Containers: []*runtimeapi.Container{
+		{Id: "c1", Metadata: &runtimeapi.ContainerMetadata{Name: "web"}, Image: &runtimeapi.ImageSpec{Image: "docker.io/library/nginx:1.27"},
+			ImageRef: "docker.io/library/nginx@sha256:" + nginxDigest, CreatedAt: 1790848800123456789, Labels: nginxLabels},
+		// containers started by the kubelet reference their image by ID
+		{Id: "c2", Metadata: &runtimeapi.ContainerMetadata{Name: "cache"}, Image: &runtimeapi.ImageSpec{Image: "sha256:redis"},
+			ImageRef: "sha256:redis", CreatedAt: 1790935200000000000, Labels: redisLabels},
+		{Id: "c3", Metadata: &runtimeapi.ContainerMetadata{Name: "dev"}, Image: &runtimeapi.ImageSpec{Image: localImage},
+			ImageRef: localImage, CreatedAt: 1790935200000000000},
+	}}, nil
+}
+
+func (s *fakeCRIServer) ImageStatus(ctx context.Context, req *runtimeapi.ImageStatusRequest) (*runtimeapi.ImageStatusResponse, error) {
+	images := map[string]*runtimeapi.Image{
+		"docker.io/library/nginx@sha256:" + nginxDigest: {
+			RepoTags:    []string{"docker.io/library/nginx:1.27"},
+			RepoDigests: []string{"docker.io/library/nginx@sha256:" + nginxDigest},
+		},
+		"sha256:redis": {
+			RepoTags:    []string{"docker.io/library/redis:7"},
+			RepoDigests: []string{"docker.io/library/redis@sha256:" + redisDigest},
+		},
+		localImage: {},
+	}
+	return &runtimeapi.ImageStatusResponse{Image: images[req.Image.Image]}, nil
+}
+
+// newFakeCRIEndpoint serves the fake CRI API on a unix socket and returns the socket path
+func (suite *ContainerRuntimeTestSuite) newFakeCRIEndpoint() string {
+	return suite.newFakeGRPCEndpoint(func(grpcServer *grpc.Server) {
+		fake := &fakeCRIServer{}
+		runtimeapi.RegisterRuntimeServiceServer(grpcServer, fake)
+		runtimeapi.RegisterImageServiceServer(grpcServer, fake)
+	})
+}
+
+// fakeEmptyCRIServer is a fake of the CRI API of a containerd which runs no CRI containers
+type fakeEmptyCRIServer struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+}
+
+func (s *fakeEmptyCRIServer) ListContainers(ctx context.Context, req *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	return &runtimeapi.ListContainersResponse{}, nil
+}
+
+// the fakes of the services of the containerd API, with containers in the default namespace
+// running nginx and redis pulled from docker hub, and a locally built image
+type fakeContainerdTasksServer struct {
+	tasksapi.UnimplementedTasksServer
+}
+
+type fakeContainerdContainersServer struct {
+	containersapi.UnimplementedContainersServer
+}
+
+type fakeContainerdImagesServer struct {
+	imagesapi.UnimplementedImagesServer
+}
+
+type fakeContainerdContentServer struct {
+	contentapi.UnimplementedContentServer
+}
+
+// requireNamespace fails a request which is not for the default namespace
+func requireNamespace(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if namespaces := md.Get(containerdNamespaceHeader); len(namespaces) != 1 || namespaces[0] != "default" {
+		return status.Errorf(codes.NotFound, "namespace %v: not found", namespaces)
+	}
+	return nil
+}
+
+func (s *fakeContainerdTasksServer) List(ctx context.Context, req *tasksapi.ListTasksRequest) (*tasksapi.ListTasksResponse, error) {
+	if err := requireNamespace(ctx); err != nil {
+		return nil, err
+	}
+	return &tasksapi.ListTasksResponse{Tasks: []*task.Process{
+		{ContainerID: "web", Status: task.Status_RUNNING},
+		{ContainerID: "cache", Status: task.Status_RUNNING},
+		{ContainerID: "dev", Status: task.Status_RUNNING},
+		{ContainerID: "job", Status: task.Status_STOPPED},
+	}}, nil
+}
+
+func (s *fakeContainerdContainersServer) Get(ctx context.Context, req *containersapi.GetContainerRequest) (*containersapi.GetContainerResponse, error) {
+	if err := requireNamespace(ctx); err != nil {
+		return nil, err
+	}
+	containers := map[string]*containersapi.Container{
+		"web":   {ID: "web", Image: "docker.io/library/nginx:1.27", CreatedAt: &timestamppb.Timestamp{Seconds: 1790848800, Nanos: 123456789}, Labels: nginxLabels},
+		"cache": {ID: "cache", Image: "docker.io/library/redis:7", CreatedAt: &timestamppb.Timestamp{Seconds: 1790935200}, Labels: redisLabels},
+		"dev":   {ID: "dev", Image: "localhost:5000/dev:latest", CreatedAt: &timestamppb.Timestamp{Seconds: 1790935200}},
+	}
+	c, ok := containers[req.ID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "container %q: not found", req.ID)
+	}
+	return &containersapi.GetContainerResponse{Container: c}, nil
+}
+
+func (s *fakeContainerdContentServer) Info(ctx context.Context, req *contentapi.InfoRequest) (*contentapi.InfoResponse, error) {
+	if err := requireNamespace(ctx); err != nil {
+		return nil, err
+	}
+	info := &contentapi.Info{Digest: req.Digest}
+	if req.Digest != localImage {
+		info.Labels = map[string]string{distributionSourceLabel + "docker.io": "library/nginx,library/redis"}
+	}
+	return &contentapi.InfoResponse{Info: info}, nil
+}
+
+func (s *fakeContainerdImagesServer) Get(ctx context.Context, req *imagesapi.GetImageRequest) (*imagesapi.GetImageResponse, error) {
+	if err := requireNamespace(ctx); err != nil {
+		return nil, err
+	}
+	targets := map[string]string{
+		"docker.io/library/nginx:1.27": "sha256:" + nginxDigest,
+		"docker.io/library/redis:7":    "sha256:" + redisDigest,
+		"localhost:5000/dev:latest":    localImage,
+	}
+	target, ok := targets[req.Name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "image %q: not found", req.Name)
+	}
+	return &imagesapi.GetImageResponse{Image: &imagesapi.Image{Name: req.Name, Target: &types.Descriptor{Digest: target}}}, nil
+}
+
+// newFakeGRPCEndpoint serves the gRPC services registered by register on a unix socket and returns the socket path
+func (suite *ContainerRuntimeTestSuite) newFakeGRPCEndpoint(register func(grpcServer *grpc.Server)) string {
+	// unix socket paths are limited to about 100 characters, which test temp dirs can exceed
+	dir, err := os.MkdirTemp("", "cri")
+	require.NoError(suite.T(), err)
+	socket := filepath.Join(dir, "containerd.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(suite.T(), err)
+
+	grpcServer := grpc.NewServer()
+	register(grpcServer)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	suite.T().Cleanup(func() {
+		grpcServer.Stop()
+		os.RemoveAll(dir)
+	})
+	return socket
+}
+
+func (suite *ContainerRuntimeTestSuite) TestCreateArtifactsDataFromDockerCompatibleAPI() {
+	for _, runtime := range []string{RuntimeDocker, RuntimePodman} {
+		suite.Run(runtime, func() {
+			api := newFakeDockerAPI(suite.T())
+			defer api.Close()
+
+			rt, err := New(runtime, strings.Replace(api.URL, "http://", "tcp://", 1), "")
+			require.NoError(suite.T(), err)
+			defer rt.Close()
+			require.Equal(suite.T(), runtime, rt.Name())
+
+			data, err := CreateArtifactsData(rt, labels.Everything(), logger.NewStandardLogger())
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), expectedArtifactsData, data)
+		})
+	}
+}
+
+func (suite *ContainerRuntimeTestSuite) TestCreateArtifactsDataFromCRI() {
+	rt, err := New(RuntimeContainerd, suite.newFakeCRIEndpoint(), "")
+	require.NoError(suite.T(), err)
+	defer rt.Close()
+
+	data, err := CreateArtifactsData(rt, labels.Everything(), logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), expectedArtifactsData, data)
+}
+
+func (suite *ContainerRuntimeTestSuite) TestCreateArtifactsDataFromContainerdNamespace() {
+	endpoint := suite.newFakeGRPCEndpoint(func(grpcServer *grpc.Server) {
+		tasksapi.RegisterTasksServer(grpcServer, &fakeContainerdTasksServer{})
+		containersapi.RegisterContainersServer(grpcServer, &fakeContainerdContainersServer{})
+		contentapi.RegisterContentServer(grpcServer, &fakeContainerdContentServer{})
+		imagesapi.RegisterImagesServer(grpcServer, &fakeContainerdImagesServer{})
+	})
+
+	rt, err := New(RuntimeContainerd, endpoint, "default")
+	require.NoError(suite.T(), err)
+	defer rt.Close()
+	data, err := CreateArtifactsData(rt, labels.Everything(), logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), expectedArtifactsData, data)
+
+	rt, err = New(RuntimeContainerd, endpoint, "k8s.io")
+	require.NoError(suite.T(), err)
+	defer rt.Close()
+	_, err = CreateArtifactsData(rt, labels.Everything(), logger.NewStandardLogger())
+	require.ErrorContains(suite.T(), err, "failed to list the tasks of namespace k8s.io of the containerd endpoint ")
+}
+
+func (suite *ContainerRuntimeTestSuite) TestCreateArtifactsDataFailsWhenCRIReportsNoContainers() {
+	for _, t := range []struct {
+		name            string
+		register        func(grpcServer *grpc.Server)
+		wantErrContains string
+	}{
+		{
+			name: "CRI reports no running containers",
+			register: func(grpcServer *grpc.Server) {
+				runtimeapi.RegisterRuntimeServiceServer(grpcServer, &fakeEmptyCRIServer{})
+			},
+			wantErrContains: "reports no running containers. Containers started with ctr or nerdctl are only visible with --containerd-namespace",
+		},
+		{
+			name:            "CRI is not served",
+			register:        func(grpcServer *grpc.Server) {},
+			wantErrContains: "does not serve the CRI API, use --containerd-namespace",
+		},
+	} {
+		suite.Run(t.name, func() {
+			rt, err := New(RuntimeContainerd, suite.newFakeGRPCEndpoint(t.register), "")
+			require.NoError(suite.T(), err)
+			defer rt.Close()
+			_, err = CreateArtifactsData(rt, labels.Everything(), logger.NewStandardLogger())
+			require.ErrorContains(suite.T(), err, t.wantErrContains)
+		})
+	}
+}
+
+func (suite *ContainerRuntimeTestSuite) TestImageRepository() {
+	for image, want := range map[string]string{
+		"docker.io/library/nginx:1.27":          "docker.io/library/nginx",
+		"localhost:5000/dev:latest":             "localhost:5000/dev",
+		"localhost:5000/dev":                    "localhost:5000/dev",
+		"ghcr.io/org/app@sha256:" + nginxDigest: "ghcr.io/org/app",
+	} {
+		require.Equal(suite.T(), want, imageRepository(image), image)
+	}
+}
+
+func (suite *ContainerRuntimeTestSuite) TestCreateArtifactsDataWithSelector() {
+	rt, err := New(RuntimeContainerd, suite.newFakeCRIEndpoint(), "")
+	require.NoError(suite.T(), err)
+	defer rt.Close()
+
+	for _, t := range []struct {
+		name     string
+		selector string
+		want     []*server.ServerData
+	}{
+		{
+			name:     "an empty selector selects all containers",
+			selector: "",
+			want:     expectedArtifactsData,
+		},
+		{
+			name:     "an equality selector selects the matching containers",
+			selector: "env=prod",
+			want:     expectedArtifactsData[:1],
+		},
+		{
+			name:     "a compose project selector selects the containers of the project",
+			selector: composeProjectLabel + "=shop",
+			want:     expectedArtifactsData[:1],
+		},
+		{
+			name:     "a set selector selects the matching containers",
+			selector: "env in (test,staging)",
+			want:     expectedArtifactsData[1:],
+		},
+		{
+			name:     "an existence selector selects the containers with the label",
+			selector: swarmServiceLabel,
+			want:     expectedArtifactsData[1:],
+		},
+		{
+			name:     "a selector matching no container selects nothing",
+			selector: "env=prod,!" + composeProjectLabel,
+			want:     []*server.ServerData{},
+		},
+	} {
+		suite.Run(t.name, func() {
+			selector, err := ParseSelector(t.selector)
+			require.NoError(suite.T(), err)
+			data, err := CreateArtifactsData(rt, selector, logger.NewStandardLogger())
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.want, data)
+		})
+	}
+}
+
+func (suite *ContainerRuntimeTestSuite) TestParseSelectorFailsForInvalidSelector() {
+	_, err := ParseSelector("env==prod=x")
+	require.ErrorContains(suite.T(), err, `invalid label selector "env==prod=x": `)
+}
+
+func (suite *ContainerRuntimeTestSuite) TestDetect() {
+	dir, err := os.MkdirTemp("", "sockets")
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(dir)
+	podmanSocket := filepath.Join(dir, "podman.sock")
+	listener, err := net.Listen("unix", podmanSocket)
+	require.NoError(suite.T(), err)
+	defer listener.Close()
+	containerdSocket := filepath.Join(dir, "containerd.sock")
+	listener, err = net.Listen("unix", containerdSocket)
+	require.NoError(suite.T(), err)
+	defer listener.Close()
+	notASocket := filepath.Join(dir, "docker.sock")
+	require.NoError(suite.T(), os.WriteFile(notASocket, []byte{}, 0644))
+
+	sockets := []runtimeSocket{
+		{RuntimeDocker, notASocket},
+		{RuntimeDocker, filepath.Join(dir, "missing.sock")},
+		{RuntimePodman, podmanSocket},
+		{RuntimeContainerd, containerdSocket},
+	}
+
+	for _, t := range []struct {
+		name            string
+		dockerHost      string
+		endpoint        string
+		wantRuntime     string
+		wantEndpoint    string
+		wantErrContains string
+	}{
+		{
+			name:         "the runtime of the first existing socket is detected",
+			wantRuntime:  RuntimePodman,
+			wantEndpoint: podmanSocket,
+		},
+		{
+			name:         "docker is detected when DOCKER_HOST is set",
+			dockerHost:   "tcp://127.0.0.1:2375",
+			wantRuntime:  RuntimeDocker,
+			wantEndpoint: "",
+		},
+		{
+			name:         "the runtime of an endpoint is detected",
+			endpoint:     "unix://" + containerdSocket,
+			wantRuntime:  RuntimeContainerd,
+			wantEndpoint: containerdSocket,
+		},
+		{
+			name:            "an unknown endpoint fails",
+			endpoint:        "/run/unknown.sock",
+			wantErrContains: "cannot detect the container runtime of endpoint /run/unknown.sock, use --runtime to set it",
+		},
+	} {
+		suite.Run(t.name, func() {
+			suite.T().Setenv("DOCKER_HOST", t.dockerHost)
+			runtime, endpoint, err := detect(t.endpoint, sockets)
+			if t.wantErrContains != "" {
+				require.ErrorContains(suite.T(), err, t.wantErrContains)
+				return
+			}
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.wantRuntime, runtime)
+			require.Equal(suite.T(), t.wantEndpoint, endpoint)
+		})
+	}
+
+	_, _, err = detect("", sockets[:2])
+	require.ErrorContains(suite.T(), err, "no container runtime socket found in")
+}
+
+func (suite *ContainerRuntimeTestSuite) TestNewFailsForUnknownRuntime() {
+	_, err := New("cri-o", "", "")
+	require.EqualError(suite.T(), err, `unsupported container runtime "cri-o", the supported runtimes are: auto, docker, podman, containerd`)
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestContainerRuntimeTestSuite(t *testing.T) {
+	suite.Run(t, new(ContainerRuntimeTestSuite))
+}
@@ -0,0 +1,61 @@
+package coverage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+type coberturaReport struct {
+	Packages []struct {
+		Name    string `xml:"name,attr"`
+		Classes []struct {
+			Filename string          `xml:"filename,attr"`
+			Lines    []coberturaLine `xml:"lines>line"`
+		} `xml:"classes>class"`
+	} `xml:"packages>package"`
+}
+
+type coberturaLine struct {
+	Number int   `xml:"number,attr"`
+	Hits   int64 `xml:"hits,attr"`
+	Branch bool  `xml:"branch,attr"`
+	// ConditionCoverage is the coverage of the branches of the line, e.g. 50% (1/2)
+	ConditionCoverage string `xml:"condition-coverage,attr"`
+}
+
+var conditionCoveragePattern = regexp.MustCompile(`\((\d+)/(\d+)\)`)
+
+// parseCobertura parses a Cobertura XML report, e.g. from coverage.py, gcovr or coverlet.
+// The classes of a file, e.g. Java inner classes, are merged.
+func parseCobertura(content []byte, c *collector) error {
+	report := coberturaReport{}
+	if err := xml.Unmarshal(content, &report); err != nil {
+		return err
+	}
+	for _, p := range report.Packages {
+		for _, class := range p.Classes {
+			if class.Filename == "" {
+				return fmt.Errorf("a class of package %q has no filename", p.Name)
+			}
+			// files without lines are valid files
+			c.file(p.Name, class.Filename)
+			for _, l := range class.Lines {
+				key := strconv.Itoa(l.Number)
+				c.addLine(p.Name, class.Filename, key, 1, l.Hits > 0)
+				if !l.Branch {
+					continue
+				}
+				match := conditionCoveragePattern.FindStringSubmatch(l.ConditionCoverage)
+				if match == nil {
+					return fmt.Errorf("invalid condition coverage %q of line %d of %s", l.ConditionCoverage, l.Number, class.Filename)
+				}
+				covered, _ := strconv.Atoi(match[1])
+				valid, _ := strconv.Atoi(match[2])
+				c.addBranches(p.Name, class.Filename, key, valid, covered)
+			}
+		}
+	}
+	return nil
+}
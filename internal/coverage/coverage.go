@@ -0,0 +1,308 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the formats of coverage reports
+const (
+	FormatCobertura = "cobertura"
+	FormatLcov      = "lcov"
+	FormatJacoco    = "jacoco"
+	FormatGo        = "go"
+)
+
+// Formats are the supported formats of coverage reports
+var Formats = []string{FormatCobertura, FormatLcov, FormatJacoco, FormatGo}
+
+// Summary is the line and branch coverage of a report, a package or a file.
+// The lines of Go cover profiles are statements.
+type Summary struct {
+	LinesCovered    int     `json:"lines_covered"`
+	LinesValid      int     `json:"lines_valid"`
+	LineRate        float64 `json:"line_rate"`
+	BranchesCovered int     `json:"branches_covered"`
+	BranchesValid   int     `json:"branches_valid"`
+	// BranchRate is nil when there is no branch data, e.g. in Go cover profiles
+	BranchRate *float64 `json:"branch_rate,omitempty"`
+}
+
+type FileCoverage struct {
+	Path string `json:"path"`
+	Summary
+}
+
+type PackageCoverage struct {
+	Name string `json:"name"`
+	Summary
+	Files []FileCoverage `json:"files"`
+}
+
+// ReportSummary is the coverage of a single report
+type ReportSummary struct {
+	File   string `json:"file"`
+	Format string `json:"format"`
+	Summary
+}
+
+// CoverageData is the coverage of one or more reports. A line or a branch covered in
+// any of the reports is covered.
+type CoverageData struct {
+	SchemaVersion int `json:"schema_version"`
+	Summary
+	Reports  []ReportSummary   `json:"reports"`
+	Packages []PackageCoverage `json:"packages"`
+}
+
+// ValidateFormat checks that format is a supported format, or empty to detect it
+func ValidateFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid coverage report format %q, the supported formats are: %s", format, strings.Join(Formats, ", "))
+}
+
+// ProcessCoverageFiles takes paths to coverage reports and returns their combined coverage
+// per package and per file. The format of each report is detected when format is empty.
+func ProcessCoverageFiles(files []string, format string) (*CoverageData, error) {
+	data := &CoverageData{
+		SchemaVersion: 1,
+		Reports:       []ReportSummary{},
+		Packages:      []PackageCoverage{},
+	}
+	all := newCollector()
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("the provided file path doesn't have a file: %s", err)
+		}
+		reportFormat := format
+		if reportFormat == "" {
+			reportFormat, err = detectFormat(content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse coverage report [%s]: %s", file, err)
+			}
+		}
+
+		report := newCollector()
+		switch reportFormat {
+		case FormatCobertura:
+			err = parseCobertura(content, report)
+		case FormatLcov:
+			err = parseLcov(content, report)
+		case FormatJacoco:
+			err = parseJacoco(content, report)
+		case FormatGo:
+			err = parseGoProfile(content, report)
+		default:
+			err = ValidateFormat(reportFormat)
+		}
+		if err == nil && len(report.files) == 0 {
+			err = fmt.Errorf("the report has no source files")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s coverage report [%s]: %s", reportFormat, file, err)
+		}
+
+		data.Reports = append(data.Reports, ReportSummary{File: file, Format: reportFormat, Summary: report.summary()})
+		all.merge(report)
+	}
+	data.Summary = all.summary()
+	data.Packages = all.packages()
+	return data, nil
+}
+
+var lcovPattern = regexp.MustCompile(`(?m)^(TN|SF):`)
+
+// detectFormat detects the format of a report from its content: the root element of
+// XML reports, the mode line of Go cover profiles and the records of LCOV reports
+func detectFormat(content []byte) (string, error) {
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("mode:")):
+		return FormatGo, nil
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		decoder := xml.NewDecoder(bytes.NewReader(trimmed))
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			if element, ok := token.(xml.StartElement); ok {
+				switch element.Name.Local {
+				case "coverage":
+					return FormatCobertura, nil
+				case "report":
+					return FormatJacoco, nil
+				}
+				break
+			}
+		}
+	case lcovPattern.Match(content):
+		return FormatLcov, nil
+	}
+	return "", fmt.Errorf("unrecognized coverage report, expected a Cobertura, LCOV, JaCoCo or Go cover profile report")
+}
+
+// line is a line, or a block of statements in Go cover profiles
+type line struct {
+	count   int
+	covered bool
+}
+
+type branches struct {
+	valid   int
+	covered int
+}
+
+type fileData struct {
+	pkg      string
+	lines    map[string]line
+	branches map[string]branches
+}
+
+// collector collects the coverage of the lines and branches of files, by a key which
+// identifies them in the files, e.g. their line number
+type collector struct {
+	files map[string]*fileData
+}
+
+func newCollector() *collector {
+	return &collector{files: map[string]*fileData{}}
+}
+
+func (c *collector) file(pkg, file string) *fileData {
+	f, ok := c.files[file]
+	if !ok {
+		if pkg == "" {
+			pkg = path.Dir(file)
+		}
+		f = &fileData{pkg: pkg, lines: map[string]line{}, branches: map[string]branches{}}
+		c.files[file] = f
+	}
+	return f
+}
+
+// addLine adds a line of count lines (or statements), which is covered if it is covered by any report
+func (c *collector) addLine(pkg, file, key string, count int, covered bool) {
+	f := c.file(pkg, file)
+	l := f.lines[key]
+	l.count = count
+	l.covered = l.covered || covered
+	f.lines[key] = l
+}
+
+// addBranches adds the branches of a line, the most covered of which count
+func (c *collector) addBranches(pkg, file, key string, valid, covered int) {
+	if valid == 0 {
+		return
+	}
+	f := c.file(pkg, file)
+	b := f.branches[key]
+	b.valid = max(b.valid, valid)
+	b.covered = min(max(b.covered, covered), b.valid)
+	f.branches[key] = b
+}
+
+func (c *collector) merge(other *collector) {
+	for file, f := range other.files {
+		for key, l := range f.lines {
+			c.addLine(f.pkg, file, key, l.count, l.covered)
+		}
+		for key, b := range f.branches {
+			c.addBranches(f.pkg, file, key, b.valid, b.covered)
+		}
+		// files without lines, e.g. empty files of LCOV reports
+		c.file(f.pkg, file)
+	}
+}
+
+func (f *fileData) summary() Summary {
+	s := Summary{}
+	for _, l := range f.lines {
+		s.LinesValid += l.count
+		if l.covered {
+			s.LinesCovered += l.count
+		}
+	}
+	for _, b := range f.branches {
+		s.BranchesValid += b.valid
+		s.BranchesCovered += b.covered
+	}
+	s.computeRates()
+	return s
+}
+
+func (s *Summary) add(other Summary) {
+	s.LinesCovered += other.LinesCovered
+	s.LinesValid += other.LinesValid
+	s.BranchesCovered += other.BranchesCovered
+	s.BranchesValid += other.BranchesValid
+}
+
+func (s *Summary) computeRates() {
+	s.LineRate = rate(s.LinesCovered, s.LinesValid)
+	s.BranchRate = nil
+	if s.BranchesValid > 0 {
+		branchRate := rate(s.BranchesCovered, s.BranchesValid)
+		s.BranchRate = &branchRate
+	}
+}
+
+// rate returns covered/valid rounded to 4 decimals, or 0 if nothing is valid
+func rate(covered, valid int) float64 {
+	if valid == 0 {
+		return 0
+	}
+	return math.Round(float64(covered)/float64(valid)*10000) / 10000
+}
+
+func (c *collector) summary() Summary {
+	s := Summary{}
+	for _, f := range c.files {
+		s.add(f.summary())
+	}
+	s.computeRates()
+	return s
+}
+
+// packages returns the coverage of the packages and of their files, sorted by name
+func (c *collector) packages() []PackageCoverage {
+	byName := map[string]*PackageCoverage{}
+	for file, f := range c.files {
+		p, ok := byName[f.pkg]
+		if !ok {
+			p = &PackageCoverage{Name: f.pkg, Files: []FileCoverage{}}
+			byName[f.pkg] = p
+		}
+		fileSummary := f.summary()
+		p.Files = append(p.Files, FileCoverage{Path: file, Summary: fileSummary})
+		p.add(fileSummary)
+	}
+
+	packages := []PackageCoverage{}
+	for _, p := range byName {
+		p.computeRates()
+		sort.Slice(p.Files, func(i, j int) bool { return p.Files[i].Path < p.Files[j].Path })
+		packages = append(packages, *p)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+	return packages
+}
@@ -0,0 +1,204 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func branchRate(rate float64) *float64 {
+	return &rate
+}
+
+func TestProcessCoverageFiles(t *testing.T) {
+	tests := []struct {
+		name            string
+		files           []string
+		format          string
+		wantFormats     []string
+		wantSummary     Summary
+		wantPackages    []string
+		wantErrContains string
+	}{
+		{
+			name:            "non-existing file causes an error",
+			files:           []string{"non-existing.xml"},
+			wantErrContains: "the provided file path doesn't have a file",
+		},
+		{
+			name:            "a file which is not a coverage report causes an error",
+			files:           []string{"../sarif/sarif-codeql.json"},
+			wantErrContains: "failed to parse coverage report [../sarif/sarif-codeql.json]: unrecognized coverage report, expected a Cobertura, LCOV, JaCoCo or Go cover profile report",
+		},
+		{
+			name:            "a report which is not in the provided format causes an error",
+			files:           []string{"go-cover.out"},
+			format:          FormatLcov,
+			wantErrContains: "failed to parse lcov coverage report [go-cover.out]: the report has no source files",
+		},
+		{
+			name:        "Cobertura classes of the same file are merged",
+			files:       []string{"cobertura.xml"},
+			wantFormats: []string{FormatCobertura},
+			wantSummary: Summary{
+				LinesCovered: 6, LinesValid: 8, LineRate: 0.75,
+				BranchesCovered: 1, BranchesValid: 4, BranchRate: branchRate(0.25),
+			},
+			wantPackages: []string{"app", "app.api"},
+		},
+		{
+			name:        "JaCoCo lines with a covered instruction are covered",
+			files:       []string{"jacoco.xml"},
+			wantFormats: []string{FormatJacoco},
+			wantSummary: Summary{
+				LinesCovered: 4, LinesValid: 5, LineRate: 0.8,
+				BranchesCovered: 1, BranchesValid: 4, BranchRate: branchRate(0.25),
+			},
+			wantPackages: []string{"com/acme/app", "com/acme/app/model"},
+		},
+		{
+			name:        "LCOV lines and branches are counted and the totals ignored",
+			files:       []string{"lcov.info"},
+			wantFormats: []string{FormatLcov},
+			wantSummary: Summary{
+				LinesCovered: 4, LinesValid: 6, LineRate: 0.6667,
+				BranchesCovered: 1, BranchesValid: 3, BranchRate: branchRate(0.3333),
+			},
+			wantPackages: []string{"src", "src/lib"},
+		},
+		{
+			name:        "Go cover profile statements are counted as lines",
+			files:       []string{"go-cover.out"},
+			format:      FormatGo,
+			wantFormats: []string{FormatGo},
+			wantSummary: Summary{
+				LinesCovered: 4, LinesValid: 8, LineRate: 0.5,
+			},
+			wantPackages: []string{"github.com/acme/app", "github.com/acme/app/pkg/calc"},
+		},
+		{
+			name:        "statements covered by any report are covered",
+			files:       []string{"go-cover.out", "go-cover-integration.out"},
+			wantFormats: []string{FormatGo, FormatGo},
+			wantSummary: Summary{
+				LinesCovered: 7, LinesValid: 8, LineRate: 0.875,
+			},
+			wantPackages: []string{"github.com/acme/app", "github.com/acme/app/pkg/calc"},
+		},
+		{
+			name:        "reports of different formats are combined",
+			files:       []string{"cobertura.xml", "lcov.info"},
+			wantFormats: []string{FormatCobertura, FormatLcov},
+			wantSummary: Summary{
+				LinesCovered: 10, LinesValid: 14, LineRate: 0.7143,
+				BranchesCovered: 2, BranchesValid: 7, BranchRate: branchRate(0.2857),
+			},
+			wantPackages: []string{"app", "app.api", "src", "src/lib"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessCoverageFiles(tt.files, tt.format)
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, 1, got.SchemaVersion)
+			require.Equal(t, tt.wantSummary, got.Summary)
+			require.Len(t, got.Reports, len(tt.files))
+			for i, report := range got.Reports {
+				require.Equal(t, tt.files[i], report.File)
+				require.Equal(t, tt.wantFormats[i], report.Format)
+			}
+			packages := []string{}
+			for _, p := range got.Packages {
+				packages = append(packages, p.Name)
+			}
+			require.Equal(t, tt.wantPackages, packages)
+		})
+	}
+}
+
+func TestProcessCoverageFilesPackages(t *testing.T) {
+	got, err := ProcessCoverageFiles([]string{"cobertura.xml"}, "")
+	require.NoError(t, err)
+	require.Equal(t, PackageCoverage{
+		Name: "app",
+		Summary: Summary{
+			LinesCovered: 5, LinesValid: 6, LineRate: 0.8333,
+			BranchesCovered: 1, BranchesValid: 2, BranchRate: branchRate(0.5),
+		},
+		Files: []FileCoverage{
+			{Path: "app/main.py", Summary: Summary{
+				LinesCovered: 3, LinesValid: 4, LineRate: 0.75,
+				BranchesCovered: 1, BranchesValid: 2, BranchRate: branchRate(0.5),
+			}},
+			{Path: "app/util.py", Summary: Summary{LinesCovered: 2, LinesValid: 2, LineRate: 1}},
+		},
+	}, got.Packages[0])
+
+	got, err = ProcessCoverageFiles([]string{"go-cover.out", "go-cover-integration.out"}, "")
+	require.NoError(t, err)
+	require.Equal(t, []ReportSummary{
+		{File: "go-cover.out", Format: FormatGo, Summary: Summary{LinesCovered: 4, LinesValid: 8, LineRate: 0.5}},
+		{File: "go-cover-integration.out", Format: FormatGo, Summary: Summary{LinesCovered: 3, LinesValid: 5, LineRate: 0.6}},
+	}, got.Reports)
+	require.Equal(t, []FileCoverage{
+		{Path: "github.com/acme/app/pkg/calc/calc.go", Summary: Summary{LinesCovered: 5, LinesValid: 5, LineRate: 1}},
+	}, got.Packages[1].Files)
+}
+
+func TestThresholds(t *testing.T) {
+	withBranches, err := ProcessCoverageFiles([]string{"cobertura.xml"}, "")
+	require.NoError(t, err)
+	withoutBranches, err := ProcessCoverageFiles([]string{"go-cover.out"}, "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		thresholds     Thresholds
+		data           *CoverageData
+		wantViolations []string
+	}{
+		{
+			name:           "coverage at the minimums complies",
+			thresholds:     Thresholds{MinLineCoverage: 75, MinBranchCoverage: 25},
+			data:           withBranches,
+			wantViolations: []string{},
+		},
+		{
+			name:       "coverage below the minimums does not comply",
+			thresholds: Thresholds{MinLineCoverage: 80, MinBranchCoverage: 50.5},
+			data:       withBranches,
+			wantViolations: []string{
+				"the line coverage 75% is below the minimum of 80%",
+				"the branch coverage 25% is below the minimum of 50.5%",
+			},
+		},
+		{
+			name:           "coverage without branches does not comply with a minimum branch coverage",
+			thresholds:     Thresholds{MinBranchCoverage: 10},
+			data:           withoutBranches,
+			wantViolations: []string{"the branch coverage is unknown as the reports have no branches, the minimum is 10%"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluation := tt.thresholds.Evaluate(tt.data)
+			require.Equal(t, len(tt.wantViolations) == 0, evaluation.Compliant)
+			require.Equal(t, tt.wantViolations, evaluation.Violations)
+		})
+	}
+
+	require.True(t, Thresholds{}.IsEmpty())
+	require.NoError(t, Thresholds{MinLineCoverage: 100}.Validate())
+	require.EqualError(t, Thresholds{MinBranchCoverage: 120}.Validate(), "invalid minimum coverage 120 for --min-branch-coverage, it must be between 0 and 100")
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range append(Formats, "") {
+		require.NoError(t, ValidateFormat(format))
+	}
+	require.EqualError(t, ValidateFormat("clover"), `invalid coverage report format "clover", the supported formats are: cobertura, lcov, jacoco, go`)
+}
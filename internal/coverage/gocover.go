@@ -0,0 +1,45 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseGoProfile parses a Go cover profile ('go test -coverprofile'). Its blocks of statements are
+// counted as lines, so the line rate is the statement coverage reported by 'go tool cover'.
+// The packages are the directories of the files, i.e. their import paths.
+func parseGoProfile(content []byte, c *collector) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "mode:") {
+			continue
+		}
+		// e.g. github.com/kosli-dev/cli/internal/utils/utils.go:26.54,28.16 2 1
+		separator := strings.LastIndex(text, ":")
+		if separator < 0 {
+			return fmt.Errorf("invalid block on line %d", lineNumber)
+		}
+		file, fields := text[:separator], strings.Fields(text[separator+1:])
+		if len(fields) != 3 {
+			return fmt.Errorf("invalid block on line %d", lineNumber)
+		}
+		block := fields[0]
+		statements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid number of statements on line %d: %s", lineNumber, err)
+		}
+		count, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid count on line %d: %s", lineNumber, err)
+		}
+		c.addLine("", file, block, statements, count > 0)
+	}
+	return scanner.Err()
+}
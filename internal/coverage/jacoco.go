@@ -0,0 +1,55 @@
+package coverage
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+type jacocoGroup struct {
+	Groups   []jacocoGroup `xml:"group"`
+	Packages []struct {
+		Name        string `xml:"name,attr"`
+		SourceFiles []struct {
+			Name  string `xml:"name,attr"`
+			Lines []struct {
+				Number int `xml:"nr,attr"`
+				// the missed and covered instructions and branches of the line
+				MissedInstructions  int `xml:"mi,attr"`
+				CoveredInstructions int `xml:"ci,attr"`
+				MissedBranches      int `xml:"mb,attr"`
+				CoveredBranches     int `xml:"cb,attr"`
+			} `xml:"line"`
+		} `xml:"sourcefile"`
+	} `xml:"package"`
+}
+
+// parseJacoco parses a JaCoCo XML report. A line is covered when any of its instructions is covered,
+// as in the JaCoCo line counter. The files are named after the directories of their packages.
+func parseJacoco(content []byte, c *collector) error {
+	report := jacocoGroup{}
+	if err := xml.Unmarshal(content, &report); err != nil {
+		return err
+	}
+	report.collect(c)
+	return nil
+}
+
+func (g jacocoGroup) collect(c *collector) {
+	for _, group := range g.Groups {
+		group.collect(c)
+	}
+	for _, p := range g.Packages {
+		for _, sourceFile := range p.SourceFiles {
+			file := sourceFile.Name
+			if p.Name != "" {
+				file = p.Name + "/" + sourceFile.Name
+			}
+			c.file(p.Name, file)
+			for _, l := range sourceFile.Lines {
+				key := strconv.Itoa(l.Number)
+				c.addLine(p.Name, file, key, 1, l.CoveredInstructions > 0)
+				c.addBranches(p.Name, file, key, l.MissedBranches+l.CoveredBranches, l.CoveredBranches)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseLcov parses an LCOV tracefile, e.g. from lcov, c8, istanbul or cargo-llvm-cov.
+// The lines (DA) and branches (BRDA) are parsed, the totals (LF, LH, BRF, BRH) are recomputed from them.
+func parseLcov(content []byte, c *collector) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	file := ""
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		record, value, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		fields := strings.Split(value, ",")
+		switch record {
+		case "SF":
+			file = value
+			c.file("", file)
+		case "end_of_record":
+			file = ""
+		case "DA":
+			if file == "" || len(fields) < 2 {
+				return fmt.Errorf("invalid line record on line %d", lineNumber)
+			}
+			hits, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid line record on line %d: %s", lineNumber, err)
+			}
+			c.addLine("", file, fields[0], 1, hits > 0)
+		case "BRDA":
+			if file == "" || len(fields) != 4 {
+				return fmt.Errorf("invalid branch record on line %d", lineNumber)
+			}
+			// the number of times the branch was taken is - when the line was never executed
+			covered := 0
+			if fields[3] != "-" && fields[3] != "0" {
+				covered = 1
+			}
+			c.addBranches("", file, strings.Join(fields[:3], ","), 1, covered)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,78 @@
+package coverage
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Thresholds are the minimum line and branch coverage, in percent, for coverage to be compliant.
+// A zero minimum is not checked.
+type Thresholds struct {
+	MinLineCoverage   float64 `json:"min_line_coverage,omitempty"`
+	MinBranchCoverage float64 `json:"min_branch_coverage,omitempty"`
+}
+
+// Evaluation is the result of checking coverage against Thresholds
+type Evaluation struct {
+	Thresholds
+	Compliant  bool     `json:"compliant"`
+	Violations []string `json:"violations"`
+}
+
+// IsEmpty returns whether no minimum coverage is set
+func (t Thresholds) IsEmpty() bool {
+	return t.MinLineCoverage == 0 && t.MinBranchCoverage == 0
+}
+
+// Validate checks that the minimum coverages are percentages
+func (t Thresholds) Validate() error {
+	for _, minimum := range []struct {
+		flag  string
+		value float64
+	}{
+		{"--min-line-coverage", t.MinLineCoverage},
+		{"--min-branch-coverage", t.MinBranchCoverage},
+	} {
+		if minimum.value < 0 || minimum.value > 100 {
+			return fmt.Errorf("invalid minimum coverage %s for %s, it must be between 0 and 100", formatPercent(minimum.value), minimum.flag)
+		}
+	}
+	return nil
+}
+
+// Evaluate checks the total coverage of data against the thresholds. Coverage without branch
+// data does not comply with a minimum branch coverage.
+func (t Thresholds) Evaluate(data *CoverageData) *Evaluation {
+	evaluation := &Evaluation{Thresholds: t, Violations: []string{}}
+	if t.MinLineCoverage > 0 {
+		if lineCoverage := percent(data.LinesCovered, data.LinesValid); lineCoverage < t.MinLineCoverage {
+			evaluation.Violations = append(evaluation.Violations, fmt.Sprintf("the line coverage %s%% is below the minimum of %s%%",
+				formatPercent(lineCoverage), formatPercent(t.MinLineCoverage)))
+		}
+	}
+	if t.MinBranchCoverage > 0 {
+		if data.BranchRate == nil {
+			evaluation.Violations = append(evaluation.Violations, fmt.Sprintf("the branch coverage is unknown as the reports have no branches, the minimum is %s%%",
+				formatPercent(t.MinBranchCoverage)))
+		} else if branchCoverage := percent(data.BranchesCovered, data.BranchesValid); branchCoverage < t.MinBranchCoverage {
+			evaluation.Violations = append(evaluation.Violations, fmt.Sprintf("the branch coverage %s%% is below the minimum of %s%%",
+				formatPercent(branchCoverage), formatPercent(t.MinBranchCoverage)))
+		}
+	}
+	evaluation.Compliant = len(evaluation.Violations) == 0
+	return evaluation
+}
+
+// percent returns the unrounded percentage of covered lines or branches
+func percent(covered, valid int) float64 {
+	if valid == 0 {
+		return 0
+	}
+	return float64(covered) / float64(valid) * 100
+}
+
+// formatPercent formats a percentage with at most 2 decimals, e.g. 72.5
+func formatPercent(percent float64) string {
+	return strconv.FormatFloat(math.Round(percent*100)/100, 'f', -1, 64)
+}
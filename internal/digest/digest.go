@@ -1,7 +1,6 @@
 package digest
 
 import (
-	"bufio"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -13,14 +12,13 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 
-	"github.com/containers/image/v5/docker"
-	"github.com/containers/image/v5/types"
 	"github.com/docker/docker/client"
 	"github.com/kosli-dev/cli/internal/logger"
 	"github.com/kosli-dev/cli/internal/requests"
-	"github.com/kosli-dev/cli/internal/utils"
 	"github.com/yargevad/filepathx"
 )
 
@@ -31,7 +29,18 @@ var (
 )
 
 // DirSha256 returns sha256 digest of a directory
-func DirSha256(dirPath string, excludePaths []string, logger *logger.Logger) (string, error) {
+// The digest is calculated over the name digest of each entry in the directory tree (in lexical order)
+// followed, for files, by the digest of their content. File contents are hashed concurrently.
+// excludePaths are literal paths or glob patterns relative to the directory.
+// Paths matching the gitignore patterns in .kosli_ignore files in the directory tree are excluded too and,
+// if ignoreFile is not empty, so are the paths matching the patterns in that file (relative to the directory).
+func DirSha256(dirPath string, excludePaths []string, ignoreFile string, logger *logger.Logger) (string, error) {
+	return dirSha256(dirPath, excludePaths, ignoreFile, runtime.NumCPU(), nil, logger)
+}
+
+// dirSha256 returns sha256 digest of a directory using the given number of workers to hash files.
+// If onEntry is not nil, it is called for each entry of the directory tree (including excluded ones) in walk order.
+func dirSha256(dirPath string, excludePaths []string, ignoreFile string, workers int, onEntry func(*ManifestEntry), logger *logger.Logger) (string, error) {
 	logger.Debug("calculating fingerprint for path [%s] -- excluding paths: %s", dirPath, excludePaths)
 	info, err := os.Stat(dirPath)
 	if err != nil {
@@ -41,129 +50,257 @@ func DirSha256(dirPath string, excludePaths []string, logger *logger.Logger) (st
 		return "", fmt.Errorf("%s is not a directory", dirPath)
 	}
 
-	tmpDir, err := os.MkdirTemp("", "*")
-	if err != nil {
-		return "", err
+	ignore := &ignoreMatcher{}
+	if ignoreFile != "" {
+		if _, err := os.Stat(ignoreFile); err != nil {
+			return "", fmt.Errorf("failed to read ignore file: %v", err)
+		}
+		ignoredPaths, err := ignore.addFile(ignoreFile, nil)
+		if err != nil {
+			return "", err
+		}
+		logger.Debug("  -> ignore file used %s -- excluding paths: %s", ignoreFile, ignoredPaths)
 	}
-	defer os.RemoveAll(tmpDir)
 
-	digestsFile, err := os.Create(filepath.Join(tmpDir, "digests"))
-	if err != nil {
-		return "", err
-	}
-	defer digestsFile.Close()
-	ignoreFilePath := filepath.Join(dirPath, ".kosli_ignore")
-	ignoredPaths, err := excludePathsFromFile(ignoreFilePath)
-	if err != nil {
-		return "", err
-	}
-	if len(ignoredPaths) > 0 {
-		logger.Debug("  -> ignore file used %s -- excluding paths: %s", ignoreFilePath, ignoredPaths)
+	exclusions := make(map[string]string)
+	for _, p := range excludePaths {
+		found, err := filepathx.Glob(filepath.Join(dirPath, p))
+		if err != nil {
+			return "", err
+		}
+		for _, path := range found {
+			exclusions[path] = ExcludedByExcludePaths
+		}
 	}
-	excludePaths = append(excludePaths, ignoredPaths...)
-	err = calculateDirContentSha256(digestsFile, dirPath, tmpDir, excludePaths, logger)
+	return calculateDirContentSha256(dirPath, exclusions, ignore, workers, onEntry, logger)
+}
+
+// OciSha256 gets the digest of a docker/OCI image from its registry.
+// For a multi-arch image, this is the digest of the image index.
+func OciSha256(artifactName string, registryUsername string, registryPassword string) (string, error) {
+	digests, err := OciImageDigests(artifactName, registryUsername, registryPassword, "")
 	if err != nil {
 		return "", err
 	}
+	return digests.Digest, nil
+}
 
-	return FileSha256(digestsFile.Name())
+// dirEntryDigest is an entry of a directory tree on its way to be added to a directory digest
+type dirEntryDigest struct {
+	path          string
+	name          string
+	isDir         bool
+	entryType     string
+	nameSha256    string
+	contentSha256 string
+	// err is set by the worker that hashes the content of a file
+	err error
+	// done is closed when the content digest of a file has been calculated
+	done chan struct{}
+	// walkErr is set when walking the directory tree failed
+	walkErr error
+	// excludedBy is set when the entry is excluded from the digest
+	excludedBy string
 }
 
-// OciSha256 gets the digest of a docker/OCI image from its registry
-func OciSha256(artifactName string, registryUsername string, registryPassword string) (string, error) {
-	imageName := fmt.Sprintf("//%s", artifactName)
-	ctx := context.Background()
-	sysCtx := &types.SystemContext{
-		DockerAuthConfig: &types.DockerAuthConfig{
-			Username: registryUsername,
-			Password: registryPassword,
-		},
-	}
+// calculateDirContentSha256 calculates a sha256 digest for a directory content.
+// exclusions maps the paths to exclude to the reason they are excluded for. Paths matched by
+// the ignore matcher are excluded too, and the matcher is extended with the .kosli_ignore files found in the tree.
+// The tree is walked in lexical order and the file contents are hashed by a pool of workers.
+func calculateDirContentSha256(dirPath string, exclusions map[string]string, ignore *ignoreMatcher, workers int, onEntry func(*ManifestEntry), logger *logger.Logger) (string, error) {
+	walk := func(ctx context.Context, emit func(*dirEntryDigest) error) error {
+		return filepath.WalkDir(dirPath, func(path string, info fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 
-	// Parse image reference
-	ref, err := docker.ParseReference(imageName)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse image reference for %s: %w", imageName, err)
+			// skip the provided top level dir. Otherwise, the name of that dir is included in
+			// the fingerprint calculation (i.e. changing the dir name would change the fingerprint)
+			if path == dirPath {
+				return addIgnoreFile(ignore, dirPath, path, logger)
+			}
+
+			entry := &dirEntryDigest{path: path, name: info.Name(), isDir: info.IsDir(), entryType: manifestEntryType(info.Type())}
+			excludedBy, excluded := exclusions[path]
+			if !excluded {
+				relPath, err := filepath.Rel(dirPath, path)
+				if err != nil {
+					return err
+				}
+				if ignore.match(splitPath(relPath), info.IsDir()) {
+					excludedBy, excluded = ExcludedByIgnoreFile, true
+				}
+			}
+			if excluded {
+				entry.excludedBy = excludedBy
+				if info.IsDir() {
+					logger.Debug("skipping dir %s (and its contents) as it matches excluded paths", path)
+				} else {
+					logger.Debug("skipping %s as it matches excluded paths", path)
+				}
+			}
+			if err := emit(entry); err != nil {
+				return err
+			}
+			if excluded {
+				if info.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if entry.isDir {
+				return addIgnoreFile(ignore, dirPath, path, logger)
+			}
+			return nil
+		})
 	}
 
-	// Compute digest
-	digest, err := docker.GetDigest(ctx, sysCtx, ref)
-	if err != nil {
-		return "", fmt.Errorf("failed to get digest for %s: %w", imageName, err)
+	var onDirEntry func(*dirEntryDigest)
+	if onEntry != nil {
+		onDirEntry = func(entry *dirEntryDigest) {
+			onEntry(newManifestEntry(dirPath, entry))
+		}
 	}
-	return strings.Split(digest.String(), "sha256:")[1], nil
+	return hashEntries(walk, FileSha256, workers, onDirEntry, logger)
 }
 
-// calculateDirContentSha256 calculates a sha256 digest for a directory content
-func calculateDirContentSha256(digestsFile *os.File, dirPath, tmpDir string, excludePaths []string, logger *logger.Logger) error {
-	pathsToExclude := []string{}
-	for _, p := range excludePaths {
-		found, err := filepathx.Glob(filepath.Join(dirPath, p))
-		if err != nil {
-			return err
-		}
-		pathsToExclude = append(pathsToExclude, found...)
+// hashEntries calculates a sha256 digest for the entries of a directory tree.
+// walk must call emit for each entry of the tree in walk order, and stop when emit returns an error.
+// The contents of the files which are not excluded are hashed with contentSha256 by a pool of workers.
+// The digests are streamed into the directory digest in walk order, so the result is the same
+// regardless of the number of workers. The number of entries in flight is bounded.
+// If onEntry is not nil, it is called for each entry (including excluded ones) once it is hashed.
+func hashEntries(walk func(ctx context.Context, emit func(*dirEntryDigest) error) error, contentSha256 func(path string) (string, error),
+	workers int, onEntry func(*dirEntryDigest), logger *logger.Logger) (string, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries := make(chan *dirEntryDigest, workers*64)
+	files := make(chan *dirEntryDigest, workers)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range files {
+				if err := ctx.Err(); err != nil {
+					entry.err = err
+				} else {
+					entry.contentSha256, entry.err = contentSha256(entry.path)
+				}
+				close(entry.done)
+			}
+		}()
 	}
 
-	return filepath.WalkDir(dirPath, func(path string, info fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+	emit := func(entry *dirEntryDigest) error {
+		hashed := entry.excludedBy == "" && !entry.isDir
+		if hashed {
+			entry.done = make(chan struct{})
 		}
-
-		// skip the provided top level dir. Otherwise, the name of that dir is included in
-		// the fingerprint calculation (i.e. changing the dir name would change the fingerprint)
-		if path == dirPath {
-			return nil
+		select {
+		case entries <- entry:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-
-		if utils.Contains(pathsToExclude, path) {
-			if info.IsDir() {
-				logger.Debug("skipping dir %s (and its contents) as it matches excluded paths", path)
-				return fs.SkipDir
+		if hashed {
+			select {
+			case files <- entry:
+			case <-ctx.Done():
+				return ctx.Err()
 			}
-			logger.Debug("skipping %s as it matches excluded paths", path)
-			return nil
-		}
-
-		nameSha256, err := addNameDigest(tmpDir, info.Name(), digestsFile)
-		if err != nil {
-			return err
 		}
+		return nil
+	}
 
-		if info.IsDir() {
-			logger.Debug("dir path: %s -- dirname digest: %v", path, nameSha256)
-		} else {
-			logger.Debug("file path: %s -- filename digest: %s", path, nameSha256)
-			fileContentSha256, err := FileSha256(path)
-			if err != nil {
-				return err
-			}
-			logger.Debug("filename: %s -- content digest: %s", path, fileContentSha256)
-			if _, err := digestsFile.Write([]byte(fileContentSha256)); err != nil {
-				return err
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(entries)
+		defer close(files)
+		err := walk(ctx, emit)
+		if err != nil && ctx.Err() == nil {
+			select {
+			case entries <- &dirEntryDigest{walkErr: err}:
+			case <-ctx.Done():
 			}
 		}
+	}()
 
-		return nil
-	})
-}
-
-// addNameDigest calculates the sha256 digest of the filename and adds it to the digests file
-func addNameDigest(tmpDir string, filename string, digestsFile *os.File) (string, error) {
-	nameFilePath := filepath.Join(tmpDir, "name")
-	err := utils.CreateFileWithContent(nameFilePath, filename)
+	hasher := sha256.New()
+	var err error
+	for entry := range entries {
+		if err = addEntryDigest(hasher, entry, logger); err != nil {
+			break
+		}
+		if onEntry != nil {
+			onEntry(entry)
+		}
+	}
+	cancel()
+	wg.Wait()
 	if err != nil {
 		return "", err
 	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
 
-	nameSha256, err := FileSha256(nameFilePath)
+// addIgnoreFile adds the patterns of the .kosli_ignore file in a directory of the tree, if any, to the ignore matcher
+func addIgnoreFile(ignore *ignoreMatcher, dirPath, path string, logger *logger.Logger) error {
+	relPath, err := filepath.Rel(dirPath, path)
 	if err != nil {
-		return "", err
+		return err
 	}
-	if _, err := digestsFile.Write([]byte(nameSha256)); err != nil {
-		return "", err
+	var domain []string
+	if relPath != "." {
+		domain = splitPath(relPath)
 	}
-	return nameSha256, nil
+	ignoreFilePath := filepath.Join(path, IgnoreFileName)
+	ignoredPaths, err := ignore.addFile(ignoreFilePath, domain)
+	if err != nil {
+		return err
+	}
+	if len(ignoredPaths) > 0 {
+		logger.Debug("  -> ignore file used %s -- excluding paths: %s", ignoreFilePath, ignoredPaths)
+	}
+	return nil
+}
+
+// addEntryDigest writes the name digest of a directory entry and, for files, its content digest to the directory digest
+func addEntryDigest(hasher io.Writer, entry *dirEntryDigest, logger *logger.Logger) error {
+	if entry.walkErr != nil {
+		return entry.walkErr
+	}
+	if entry.excludedBy != "" {
+		return nil
+	}
+
+	nameSha256 := sha256.Sum256([]byte(entry.name))
+	entry.nameSha256 = hex.EncodeToString(nameSha256[:])
+	if _, err := hasher.Write([]byte(entry.nameSha256)); err != nil {
+		return err
+	}
+
+	if entry.isDir {
+		logger.Debug("dir path: %s -- dirname digest: %v", entry.path, entry.nameSha256)
+		return nil
+	}
+
+	logger.Debug("file path: %s -- filename digest: %s", entry.path, entry.nameSha256)
+	<-entry.done
+	if entry.err != nil {
+		return entry.err
+	}
+	logger.Debug("filename: %s -- content digest: %s", entry.path, entry.contentSha256)
+	_, err := hasher.Write([]byte(entry.contentSha256))
+	return err
 }
 
 // FileSha256 returns a sha256 digest of a file.
@@ -197,6 +334,12 @@ func DockerImageSha256(imageID string) (string, error) {
 	return extractImageDigestFromRepoDigest(imageID, repoDigests)
 }
 
+// RepoDigestSha256 returns the sha256 digest of an image from the repo digests
+// reported for it by a container runtime. imageID can be the image name or ID
+func RepoDigestSha256(imageID string, repoDigests []string) (string, error) {
+	return extractImageDigestFromRepoDigest(imageID, repoDigests)
+}
+
 // extractImageDigestFromRepoDigest finds the corresponding digest for an imageName in a list of repoDigests
 // imageID can be image name or ID
 func extractImageDigestFromRepoDigest(imageID string, repoDigests []string) (string, error) {
@@ -296,28 +439,52 @@ func ValidateDigest(sha256ToCheck string) error {
 	return nil
 }
 
-func excludePathsFromFile(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err == nil {
-		defer file.Close()
-		var excludes = []string{}
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := scanner.Text()
-			line = removeComments(line)
-			line = strings.TrimSpace(line)
-			if len(line) > 0 {
-				excludes = append(excludes, line)
-			}
+var (
+	// ErrLocalImageID returned when an image reference is a local image ID rather than a registry digest.
+	ErrLocalImageID = errors.New("the image ID is a local image ID, not a registry digest")
+	// ErrNoImageDigest returned when an image reference does not contain a digest.
+	ErrNoImageDigest = errors.New("the image reference does not contain a digest")
+	// ErrEmptyImageReference returned when an image reference is empty.
+	ErrEmptyImageReference = errors.New("the image reference is empty")
+)
+
+var (
+	transportPrefixRegex = regexp.MustCompile(`^[a-z][a-z0-9+.-]*://`)
+	imageDigestRegex     = regexp.MustCompile(`^(sha256|sha384|sha512):([a-f0-9]+)$`)
+)
+
+// ImageReferenceDigest extracts the sha256 registry digest from an image reference.
+// It accepts references as found in container runtimes, e.g.:
+//   - registry.io/repo/image@sha256:<digest>
+//   - registry.io/repo/image:tag@sha256:<digest>
+//   - docker-pullable://image@sha256:<digest>
+//
+// It returns ErrLocalImageID for bare image IDs (e.g. sha256:<id> or docker://sha256:<id>),
+// ErrNoImageDigest for references without a digest and ErrEmptyImageReference for empty references.
+func ImageReferenceDigest(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", ErrEmptyImageReference
+	}
+	ref = transportPrefixRegex.ReplaceAllString(ref, "")
+
+	i := strings.LastIndex(ref, "@")
+	if i < 0 {
+		if imageDigestRegex.MatchString(ref) {
+			return "", ErrLocalImageID
 		}
-		return excludes, nil
-	} else if errors.Is(err, fs.ErrNotExist) {
-		return []string{}, nil
+		return "", ErrNoImageDigest
 	}
-	return nil, err
-}
 
-func removeComments(line string) string {
-	parts := strings.SplitN(line, "#", 2)
-	return strings.TrimRight(parts[0], " ")
+	matches := imageDigestRegex.FindStringSubmatch(ref[i+1:])
+	if matches == nil {
+		return "", fmt.Errorf("invalid digest %q in image reference %s", ref[i+1:], ref)
+	}
+	if matches[1] != "sha256" {
+		return "", fmt.Errorf("unsupported digest algorithm %q in image reference %s", matches[1], ref)
+	}
+	if err := ValidateDigest(matches[2]); err != nil {
+		return "", fmt.Errorf("invalid sha256 digest in image reference %s", ref)
+	}
+	return matches[2], nil
 }
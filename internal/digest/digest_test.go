@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/kosli-dev/cli/internal/docker"
@@ -518,7 +519,7 @@ func (suite *DigestTestSuite) TestDirSha256() {
 				suite.createNestedDir(topLevelPath, entry.files, entry.dirs)
 			}
 
-			sha256, err := DirSha256(topLevelPath, t.args.excludePaths, logger.NewStandardLogger())
+			sha256, err := DirSha256(topLevelPath, t.args.excludePaths, "", logger.NewStandardLogger())
 			require.NoErrorf(suite.T(), err, "error creating digest for test dir %s", topLevelPath)
 
 			assert.Equal(suite.T(), t.want, sha256, fmt.Sprintf("TestDirSha256: %s , got: %v -- want: %v", t.name, sha256, t.want))
@@ -526,6 +527,88 @@ func (suite *DigestTestSuite) TestDirSha256() {
 	}
 }
 
+// TestDirSha256GoldenCorpus guards the backward compatibility of directory fingerprints.
+// The expected fingerprints were calculated with the original (serial) implementation
+// and must never change, otherwise artifacts reported by different CLI versions would not match.
+func (suite *DigestTestSuite) TestDirSha256GoldenCorpus() {
+	corpus := filepath.Join("testdata", "dir-corpus")
+	for _, t := range []struct {
+		name         string
+		path         string
+		excludePaths []string
+		want         string
+	}{
+		{
+			name: "a project with nested dirs, binary, empty, hidden, unicode-named files and a symlink",
+			path: "project",
+			want: "3d6163f81ba8854d08a67f15969f8159bbf9d3db096d49a54da0bda4b3315860",
+		},
+		{
+			name:         "a project with excluded paths and glob patterns",
+			path:         "project",
+			excludePaths: []string{"src/pkg", "**/*.md", "bin/app"},
+			want:         "f2067e85df9a860cb8b09417cf64a4767eb70941fd25fe9ddda346ab8162ae1a",
+		},
+		{
+			name: "a subdirectory of a project",
+			path: "project/src",
+			want: "2997c2c3fa79d7031366734b3f90164d9da3de42df3e359ca5345dd03ae0ad7a",
+		},
+		{
+			name: "a project with a .kosli_ignore file",
+			path: "with-ignore",
+			want: "3be5618d1a5474e5e686e0809c77029073f5bbc6b0eb9db5be3b12a9cba1fcdc",
+		},
+		{
+			name:         "a project with a .kosli_ignore file and excluded paths",
+			path:         "with-ignore",
+			excludePaths: []string{"build", "src/src.go"},
+			want:         "22519733ad45662e6725fe051590f419133b1d30ef7615c3a9566308a3c8979a",
+		},
+	} {
+		suite.Run(t.name, func() {
+			for _, workers := range []int{1, 4, 64} {
+				sha256, err := dirSha256(filepath.Join(corpus, t.path), t.excludePaths, "", workers, nil, logger.NewStandardLogger())
+				require.NoError(suite.T(), err)
+				assert.Equal(suite.T(), t.want, sha256, fmt.Sprintf("TestDirSha256GoldenCorpus: %s (%d workers)", t.name, workers))
+			}
+		})
+	}
+}
+
+// TestDirSha256LargeTree checks that fingerprints of trees with many files do not depend on
+// the order in which the files are hashed
+func (suite *DigestTestSuite) TestDirSha256LargeTree() {
+	topLevelPath := filepath.Join(suite.tmpDir, "large-tree")
+	for i := 0; i < 2000; i++ {
+		dir := filepath.Join(topLevelPath, fmt.Sprintf("dir-%02d", i%25), fmt.Sprintf("sub-%d", i%3))
+		require.NoError(suite.T(), os.MkdirAll(dir, 0777))
+		content := strings.Repeat(fmt.Sprintf("content of file %d\n", i), i%7)
+		suite.createFileWithContent(filepath.Join(dir, fmt.Sprintf("file-%04d.txt", i)), content)
+	}
+	require.NoError(suite.T(), os.MkdirAll(filepath.Join(topLevelPath, "empty-dir"), 0777))
+	suite.createFileWithContent(filepath.Join(topLevelPath, "large.bin"), strings.Repeat("0123456789abcdef", 1<<16))
+
+	for _, workers := range []int{1, 2, 8, 32} {
+		sha256, err := dirSha256(topLevelPath, []string{"dir-24"}, "", workers, nil, logger.NewStandardLogger())
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), "25effafbc00124869d78436ac76b9d4a79e9e3678b173af2c57d41ed83a71026", sha256, fmt.Sprintf("TestDirSha256LargeTree: %d workers", workers))
+	}
+}
+
+func (suite *DigestTestSuite) TestDirSha256FailsOnUnreadableFile() {
+	topLevelPath := filepath.Join(suite.tmpDir, "tree-with-dangling-symlink")
+	for i := 0; i < 500; i++ {
+		suite.createFileWithContent(filepath.Join(topLevelPath, fmt.Sprintf("file-%03d", i)), fmt.Sprintf("content %d", i))
+	}
+	require.NoError(suite.T(), os.Symlink(filepath.Join(topLevelPath, "does-not-exist"), filepath.Join(topLevelPath, "file-250-link")))
+
+	for _, workers := range []int{1, 8} {
+		_, err := dirSha256(topLevelPath, []string{}, "", workers, nil, logger.NewStandardLogger())
+		require.ErrorContains(suite.T(), err, "file-250-link")
+	}
+}
+
 func (suite *DigestTestSuite) createNestedDir(path string, files []fileEntry, dirs []dirEntry) {
 	for _, f := range files {
 		filePath := filepath.Join(path, f.name)
@@ -584,7 +667,7 @@ func (suite *DigestTestSuite) TestDirSha256Validation() {
 				suite.createFileWithContent(dirPath, "")
 			}
 
-			_, err := DirSha256(dirPath, []string{}, logger.NewStandardLogger())
+			_, err := DirSha256(dirPath, []string{}, "", logger.NewStandardLogger())
 			if t.errExpected {
 				require.Errorf(suite.T(), err, "TestDirSha256Validation: error was expected")
 			}
@@ -871,6 +954,75 @@ func (suite *DigestTestSuite) TestExtractImageDigestFromRepoDigest() {
 	}
 }
 
+func (suite *DigestTestSuite) TestImageReferenceDigest() {
+	sha := "afcc7f1ac1b49db317a7196c902e61c6c3c4607d63599ee1a82d702d249a0ccb"
+	for _, t := range []struct {
+		name         string
+		ref          string
+		want         string
+		wantError    error
+		wantErrorMsg string
+	}{
+		{
+			name: "a repo digest reference returns the digest",
+			ref:  "docker.io/library/nginx@sha256:" + sha,
+			want: sha,
+		},
+		{
+			name: "a repo digest reference with a tag returns the digest",
+			ref:  "registry.example.com:5000/team/app:1.2.3@sha256:" + sha,
+			want: sha,
+		},
+		{
+			name: "a docker-pullable reference returns the digest",
+			ref:  "docker-pullable://nginx@sha256:" + sha,
+			want: sha,
+		},
+		{
+			name:      "an empty reference fails",
+			ref:       "",
+			wantError: ErrEmptyImageReference,
+		},
+		{
+			name:      "a bare image ID fails",
+			ref:       "sha256:" + sha,
+			wantError: ErrLocalImageID,
+		},
+		{
+			name:      "a docker image ID fails",
+			ref:       "docker://sha256:" + sha,
+			wantError: ErrLocalImageID,
+		},
+		{
+			name:      "a tag-only reference fails",
+			ref:       "nginx:latest",
+			wantError: ErrNoImageDigest,
+		},
+		{
+			name:         "a non-sha256 digest fails",
+			ref:          "nginx@sha512:" + sha + sha,
+			wantErrorMsg: "unsupported digest algorithm \"sha512\"",
+		},
+		{
+			name:         "a short sha256 digest fails",
+			ref:          "nginx@sha256:afcc7f1ac1b4",
+			wantErrorMsg: "invalid sha256 digest",
+		},
+	} {
+		suite.Run(t.name, func() {
+			actual, err := ImageReferenceDigest(t.ref)
+			if t.wantError != nil {
+				require.ErrorIs(suite.T(), err, t.wantError)
+			} else if t.wantErrorMsg != "" {
+				require.ErrorContains(suite.T(), err, t.wantErrorMsg)
+			} else {
+				require.NoError(suite.T(), err)
+				require.Equal(suite.T(), t.want, actual)
+			}
+		})
+	}
+}
+
 func (suite *DigestTestSuite) TestGetExcludePathsFromIgnoreFile() {
 	type want struct {
 		expectError  bool
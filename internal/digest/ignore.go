@@ -0,0 +1,108 @@
+package digest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFileName is the name of the files listing the paths to ignore when fingerprinting a directory
+const IgnoreFileName = ".kosli_ignore"
+
+// trailingCommentRegex matches a comment at the end of an ignore file line.
+// Unlike gitignore, trailing comments are supported for backward compatibility,
+// as long as the # is preceded by whitespace.
+var trailingCommentRegex = regexp.MustCompile(`\s+#.*$`)
+
+// ignoreMatcher matches paths against gitignore patterns collected from ignore files.
+// Patterns are kept in increasing order of priority: patterns from an ignore file outside the
+// directory first, then from the top level .kosli_ignore file, then from nested .kosli_ignore files.
+type ignoreMatcher struct {
+	patterns []gitignore.Pattern
+}
+
+// addFile adds the patterns in an ignore file to the matcher.
+// domain is the path (split in its elements) of the directory the patterns are relative to.
+// It returns the patterns found, or an empty list if the file does not exist.
+func (m *ignoreMatcher) addFile(path string, domain []string) ([]string, error) {
+	lines, err := excludePathsFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m.addPatterns(lines, domain)
+	return lines, nil
+}
+
+// addPatterns adds patterns relative to the directory at domain to the matcher
+func (m *ignoreMatcher) addPatterns(lines []string, domain []string) {
+	for _, line := range lines {
+		m.patterns = append(m.patterns, gitignore.ParsePattern(line, domain))
+	}
+}
+
+// match returns true if a path (relative to the directory and split in its elements) is ignored.
+// As with gitignore, the last matching pattern decides, so negated patterns can re-include paths.
+func (m *ignoreMatcher) match(path []string, isDir bool) bool {
+	for i := len(m.patterns) - 1; i >= 0; i-- {
+		if result := m.patterns[i].Match(path, isDir); result != gitignore.NoMatch {
+			return result == gitignore.Exclude
+		}
+	}
+	return false
+}
+
+// excludePathsFromFile returns the patterns in an ignore file, or an empty list if the file does not exist
+func excludePathsFromFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	return readIgnorePatterns(file, path)
+}
+
+// readIgnorePatterns returns the patterns in the content of an ignore file
+func readIgnorePatterns(r io.Reader, path string) ([]string, error) {
+	patterns := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if pattern := parseIgnoreLine(scanner.Text()); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore file %s: %v", path, err)
+	}
+	return patterns, nil
+}
+
+// parseIgnoreLine returns the pattern in a line of an ignore file, or an empty string for blank and comment lines
+func parseIgnoreLine(line string) string {
+	line = strings.TrimRight(line, "\r")
+	line = strings.TrimLeft(line, " \t")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ""
+	}
+	line = trailingCommentRegex.ReplaceAllString(line, "")
+	// a leading \ escapes a pattern that starts with a #
+	if strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	return line
+}
+
+// splitPath splits a slash or OS separated relative path into its elements
+func splitPath(path string) []string {
+	return strings.Split(filepath.ToSlash(path), "/")
+}
@@ -0,0 +1,114 @@
+package digest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kosli-dev/cli/internal/logger"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type IgnoreTestSuite struct {
+	suite.Suite
+}
+
+func (suite *IgnoreTestSuite) TestParseIgnoreLine() {
+	for _, t := range []struct {
+		line string
+		want string
+	}{
+		{line: "logs", want: "logs"},
+		{line: "  logs/  ", want: "logs/  "},
+		{line: "logs\r", want: "logs"},
+		{line: "", want: ""},
+		{line: "   ", want: ""},
+		{line: "# a comment", want: ""},
+		{line: "*.log # a trailing comment", want: "*.log"},
+		{line: "file#1", want: "file#1"},
+		{line: `\#file`, want: "#file"},
+		{line: "!keep.log", want: "!keep.log"},
+	} {
+		suite.Run(t.line, func() {
+			require.Equal(suite.T(), t.want, parseIgnoreLine(t.line))
+		})
+	}
+}
+
+func (suite *IgnoreTestSuite) TestGitignoreSemantics() {
+	dirPath := suite.T().TempDir()
+	files := map[string]string{
+		".kosli_ignore":       "*.log\n!keep.log\nbuild/\n/top-only.txt\n",
+		"a.log":               "a",
+		"keep.log":            "keep",
+		"top-only.txt":        "top",
+		"secret.txt":          "not a secret here",
+		"external.txt":        "external",
+		"build/output.bin":    "output",
+		"sub/.kosli_ignore":   "secret.txt\n!b.log\n",
+		"sub/b.log":           "b",
+		"sub/c.log":           "c",
+		"sub/keep.log":        "keep",
+		"sub/top-only.txt":    "top",
+		"sub/secret.txt":      "secret",
+		"sub/build":           "a file named build",
+		"sub/deeper/d.log":    "d",
+		"sub/deeper/data.txt": "data",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(dirPath, path)
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(fullPath), 0777))
+		require.NoError(suite.T(), os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	externalIgnoreFile := filepath.Join(suite.T().TempDir(), "ignore")
+	require.NoError(suite.T(), os.WriteFile(externalIgnoreFile, []byte("external.txt\nkeep.log\n"), 0644))
+
+	manifest, err := DirManifestSha256(dirPath, []string{}, externalIgnoreFile, logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+
+	actual := map[string]string{}
+	for _, entry := range manifest.Entries {
+		actual[entry.Path] = entry.ExcludedBy
+	}
+	require.Equal(suite.T(), map[string]string{
+		".kosli_ignore": "",
+		// unanchored patterns match at any depth
+		"a.log":     ExcludedByIgnoreFile,
+		"sub/c.log": ExcludedByIgnoreFile,
+		// negated patterns re-include paths, also over patterns of the external ignore file
+		"keep.log":     "",
+		"sub/keep.log": "",
+		// anchored patterns only match relative to the ignore file
+		"top-only.txt":     ExcludedByIgnoreFile,
+		"sub/top-only.txt": "",
+		// directory-only patterns do not match files
+		"build":     ExcludedByIgnoreFile,
+		"sub/build": "",
+		// nested ignore files apply to their own directory only
+		"secret.txt":          "",
+		"sub/secret.txt":      ExcludedByIgnoreFile,
+		"sub/b.log":           "",
+		"sub/.kosli_ignore":   "",
+		"sub":                 "",
+		"sub/deeper":          "",
+		"sub/deeper/d.log":    ExcludedByIgnoreFile,
+		"sub/deeper/data.txt": "",
+		// the external ignore file applies to the directory
+		"external.txt": ExcludedByIgnoreFile,
+	}, actual)
+}
+
+func (suite *IgnoreTestSuite) TestMissingIgnoreFileFails() {
+	_, err := DirSha256(suite.T().TempDir(), []string{}, filepath.Join(suite.T().TempDir(), "does-not-exist"), logger.NewStandardLogger())
+	require.ErrorContains(suite.T(), err, "failed to read ignore file")
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestIgnoreTestSuite(t *testing.T) {
+	suite.Run(t, new(IgnoreTestSuite))
+}
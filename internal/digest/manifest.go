@@ -0,0 +1,187 @@
+package digest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/kosli-dev/cli/internal/logger"
+)
+
+// the reasons an entry can be excluded from a directory fingerprint for
+const (
+	ExcludedByExcludePaths = "exclude"
+	ExcludedByIgnoreFile   = "ignore-file"
+)
+
+// the types of the entries of a directory
+const (
+	EntryTypeDir     = "dir"
+	EntryTypeFile    = "file"
+	EntryTypeSymlink = "symlink"
+	EntryTypeOther   = "other"
+)
+
+// the kinds of changes between two directory manifests
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+	ChangeExcluded = "excluded"
+	ChangeIncluded = "included"
+)
+
+// DirManifest lists the entries that make up the fingerprint of a directory
+type DirManifest struct {
+	Fingerprint string           `json:"fingerprint"`
+	Entries     []*ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry represents an entry of a directory as it is fed into the directory fingerprint.
+// Entries inside excluded directories are not listed.
+type ManifestEntry struct {
+	Path          string `json:"path"`
+	Type          string `json:"type"`
+	NameDigest    string `json:"name_digest,omitempty"`
+	ContentDigest string `json:"content_digest,omitempty"`
+	ExcludedBy    string `json:"excluded_by,omitempty"`
+}
+
+// ManifestChange represents a difference of an entry between two directory manifests
+type ManifestChange struct {
+	Path   string         `json:"path"`
+	Change string         `json:"change"`
+	Old    *ManifestEntry `json:"old,omitempty"`
+	New    *ManifestEntry `json:"new,omitempty"`
+}
+
+// DirManifestSha256 returns the fingerprint of a directory together with the manifest of the entries it is made of
+func DirManifestSha256(dirPath string, excludePaths []string, ignoreFile string, logger *logger.Logger) (*DirManifest, error) {
+	manifest := &DirManifest{Entries: []*ManifestEntry{}}
+	fingerprint, err := dirSha256(dirPath, excludePaths, ignoreFile, runtime.NumCPU(), func(entry *ManifestEntry) {
+		manifest.Entries = append(manifest.Entries, entry)
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Fingerprint = fingerprint
+	return manifest, nil
+}
+
+// LoadDirManifest loads a directory manifest saved in JSON format
+func LoadDirManifest(path string) (*DirManifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &DirManifest{}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("%s is not a valid fingerprint manifest: %v", path, err)
+	}
+	if err := ValidateDigest(manifest.Fingerprint); err != nil {
+		return nil, fmt.Errorf("%s is not a valid fingerprint manifest: %v", path, err)
+	}
+	return manifest, nil
+}
+
+// DiffDirManifests returns the changed entries between two directory manifests, sorted by path.
+// Excluded entries missing from the other manifest, entries excluded in both manifests and entries
+// inside a directory excluded in either manifest are not compared.
+func DiffDirManifests(oldManifest, newManifest *DirManifest) []*ManifestChange {
+	oldEntries := manifestIndex(oldManifest)
+	newEntries := manifestIndex(newManifest)
+
+	paths := []string{}
+	for path := range oldEntries {
+		paths = append(paths, path)
+	}
+	for path := range newEntries {
+		if _, ok := oldEntries[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	changes := []*ManifestChange{}
+	for _, path := range paths {
+		if inExcludedDir(oldEntries, path) || inExcludedDir(newEntries, path) {
+			continue
+		}
+		oldEntry, inOld := oldEntries[path]
+		newEntry, inNew := newEntries[path]
+		change := ""
+		switch {
+		case !inOld:
+			if newEntry.ExcludedBy == "" {
+				change = ChangeAdded
+			}
+		case !inNew:
+			if oldEntry.ExcludedBy == "" {
+				change = ChangeRemoved
+			}
+		case oldEntry.ExcludedBy != "" && newEntry.ExcludedBy != "":
+		case newEntry.ExcludedBy != "":
+			change = ChangeExcluded
+		case oldEntry.ExcludedBy != "":
+			change = ChangeIncluded
+		case oldEntry.Type != newEntry.Type || oldEntry.ContentDigest != newEntry.ContentDigest:
+			change = ChangeModified
+		}
+		if change != "" {
+			changes = append(changes, &ManifestChange{Path: path, Change: change, Old: oldEntry, New: newEntry})
+		}
+	}
+	return changes
+}
+
+// manifestIndex maps the paths of a manifest to their entries
+func manifestIndex(manifest *DirManifest) map[string]*ManifestEntry {
+	index := make(map[string]*ManifestEntry, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		index[entry.Path] = entry
+	}
+	return index
+}
+
+// inExcludedDir returns true if one of the parent directories of a path is excluded in a manifest
+func inExcludedDir(index map[string]*ManifestEntry, path string) bool {
+	for dir := filepath.Dir(filepath.FromSlash(path)); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if entry, ok := index[filepath.ToSlash(dir)]; ok && entry.ExcludedBy != "" && entry.Type == EntryTypeDir {
+			return true
+		}
+	}
+	return false
+}
+
+// newManifestEntry creates a manifest entry from an entry fed into a directory digest
+func newManifestEntry(dirPath string, entry *dirEntryDigest) *ManifestEntry {
+	relPath, err := filepath.Rel(dirPath, entry.path)
+	if err != nil {
+		relPath = entry.path
+	}
+	return &ManifestEntry{
+		Path:          filepath.ToSlash(relPath),
+		Type:          entry.entryType,
+		NameDigest:    entry.nameSha256,
+		ContentDigest: entry.contentSha256,
+		ExcludedBy:    entry.excludedBy,
+	}
+}
+
+// manifestEntryType returns the manifest entry type of a file mode
+func manifestEntryType(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return EntryTypeDir
+	case mode.IsRegular():
+		return EntryTypeFile
+	case mode&fs.ModeSymlink != 0:
+		return EntryTypeSymlink
+	default:
+		return EntryTypeOther
+	}
+}
@@ -0,0 +1,135 @@
+package digest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kosli-dev/cli/internal/logger"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type ManifestTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ManifestTestSuite) TestDirManifestSha256() {
+	dirPath := filepath.Join("testdata", "dir-corpus", "with-ignore")
+	manifest, err := DirManifestSha256(dirPath, []string{"src/src.go"}, "", logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+
+	fingerprint, err := DirSha256(dirPath, []string{"src/src.go"}, "", logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), fingerprint, manifest.Fingerprint)
+
+	type summary struct {
+		path, entryType, excludedBy string
+		hasContentDigest            bool
+	}
+	actual := []summary{}
+	for _, entry := range manifest.Entries {
+		actual = append(actual, summary{entry.Path, entry.Type, entry.ExcludedBy, entry.ContentDigest != ""})
+	}
+	require.Equal(suite.T(), []summary{
+		{".kosli_ignore", EntryTypeFile, "", true},
+		{"build", EntryTypeDir, "", false},
+		{"build/artifact.bin", EntryTypeFile, "", true},
+		{"build/tmp", EntryTypeDir, ExcludedByIgnoreFile, false},
+		{"kept.txt", EntryTypeFile, "", true},
+		{"logs", EntryTypeDir, ExcludedByIgnoreFile, false},
+		{"old.bak", EntryTypeFile, ExcludedByIgnoreFile, false},
+		{"src", EntryTypeDir, "", false},
+		{"src/src.go", EntryTypeFile, ExcludedByExcludePaths, false},
+	}, actual)
+}
+
+func (suite *ManifestTestSuite) TestDiffDirManifests() {
+	file := func(path, digest string) *ManifestEntry {
+		return &ManifestEntry{Path: path, Type: EntryTypeFile, ContentDigest: digest}
+	}
+	dir := func(path string) *ManifestEntry {
+		return &ManifestEntry{Path: path, Type: EntryTypeDir}
+	}
+	excluded := func(entry *ManifestEntry) *ManifestEntry {
+		entry.ExcludedBy = ExcludedByExcludePaths
+		entry.ContentDigest = ""
+		return entry
+	}
+
+	for _, t := range []struct {
+		name        string
+		oldEntries  []*ManifestEntry
+		newEntries  []*ManifestEntry
+		wantChanges map[string]string
+	}{
+		{
+			name:        "identical manifests have no changes",
+			oldEntries:  []*ManifestEntry{dir("a"), file("a/b", "1")},
+			newEntries:  []*ManifestEntry{dir("a"), file("a/b", "1")},
+			wantChanges: map[string]string{},
+		},
+		{
+			name:        "added, removed and modified entries are reported",
+			oldEntries:  []*ManifestEntry{file("a", "1"), file("b", "2"), dir("c")},
+			newEntries:  []*ManifestEntry{file("a", "10"), file("c", "3"), file("d", "4")},
+			wantChanges: map[string]string{"a": ChangeModified, "b": ChangeRemoved, "c": ChangeModified, "d": ChangeAdded},
+		},
+		{
+			name:        "newly excluded and included entries are reported",
+			oldEntries:  []*ManifestEntry{file("a", "1"), excluded(file("b", "2")), excluded(file("c", "3"))},
+			newEntries:  []*ManifestEntry{excluded(file("a", "1")), file("b", "2"), excluded(file("c", "4"))},
+			wantChanges: map[string]string{"a": ChangeExcluded, "b": ChangeIncluded},
+		},
+		{
+			name:        "excluded entries missing from the other manifest are not reported",
+			oldEntries:  []*ManifestEntry{excluded(file("a", "1"))},
+			newEntries:  []*ManifestEntry{excluded(file("b", "2"))},
+			wantChanges: map[string]string{},
+		},
+		{
+			name:        "entries inside an excluded dir are not compared",
+			oldEntries:  []*ManifestEntry{dir("logs"), file("logs/app.log", "1"), dir("logs/old"), file("logs/old/app.log", "2")},
+			newEntries:  []*ManifestEntry{excluded(dir("logs"))},
+			wantChanges: map[string]string{"logs": ChangeExcluded},
+		},
+	} {
+		suite.Run(t.name, func() {
+			changes := DiffDirManifests(&DirManifest{Entries: t.oldEntries}, &DirManifest{Entries: t.newEntries})
+			actual := map[string]string{}
+			for _, change := range changes {
+				actual[change.Path] = change.Change
+			}
+			require.Equal(suite.T(), t.wantChanges, actual)
+		})
+	}
+}
+
+func (suite *ManifestTestSuite) TestLoadDirManifest() {
+	tmpDir := suite.T().TempDir()
+	manifest, err := DirManifestSha256(filepath.Join("testdata", "dir-corpus", "project"), []string{}, "", logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	content, err := json.Marshal(manifest)
+	require.NoError(suite.T(), err)
+	manifestPath := filepath.Join(tmpDir, "manifest.json")
+	require.NoError(suite.T(), os.WriteFile(manifestPath, content, 0600))
+
+	loaded, err := LoadDirManifest(manifestPath)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), manifest, loaded)
+
+	invalidPath := filepath.Join(tmpDir, "invalid.json")
+	require.NoError(suite.T(), os.WriteFile(invalidPath, []byte(`{"entries": []}`), 0600))
+	_, err = LoadDirManifest(invalidPath)
+	require.ErrorContains(suite.T(), err, "is not a valid fingerprint manifest")
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestManifestTestSuite(t *testing.T) {
+	suite.Run(t, new(ManifestTestSuite))
+}
@@ -0,0 +1,230 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/manifest"
+	ociarchive "github.com/containers/image/v5/oci/archive"
+	ocilayout "github.com/containers/image/v5/oci/layout"
+	"github.com/containers/image/v5/types"
+)
+
+// ErrNoOciIndex returned when an image directory or archive is not in the OCI image layout format
+var ErrNoOciIndex = errors.New("index.json not found, the path is not an OCI image layout")
+
+// ImageDigests are the digests of an image
+type ImageDigests struct {
+	// Digest is the digest of the image manifest, or of the image index for a multi-arch image
+	// unless a platform is selected, in which case it is the digest of the platform manifest
+	Digest string
+	// IndexDigest is the digest of the image index, or empty if the image is not multi-arch
+	IndexDigest string
+	// PlatformDigests are the digests of the platform manifests in the image index, by platform (os/arch[/variant])
+	PlatformDigests map[string]string
+}
+
+// Aliases returns the digests of the image other than Digest, in lexical order.
+// For a multi-arch image, these are the digests of the image index and of all its platform manifests.
+func (d *ImageDigests) Aliases() []string {
+	set := map[string]struct{}{}
+	if d.IndexDigest != "" {
+		set[d.IndexDigest] = struct{}{}
+	}
+	for _, platformDigest := range d.PlatformDigests {
+		set[platformDigest] = struct{}{}
+	}
+	delete(set, d.Digest)
+
+	aliases := make([]string, 0, len(set))
+	for alias := range set {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// OciImageDigests gets the digests of a docker/OCI image from its registry.
+// If platform (os/arch[/variant]) is not empty and the image is multi-arch, the digest of
+// the manifest for that platform is selected, otherwise the digest of the image index is.
+func OciImageDigests(artifactName, registryUsername, registryPassword, platform string) (*ImageDigests, error) {
+	imageName := fmt.Sprintf("//%s", artifactName)
+	ref, err := docker.ParseReference(imageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference for %s: %w", imageName, err)
+	}
+	sysCtx := &types.SystemContext{
+		DockerAuthConfig: &types.DockerAuthConfig{
+			Username: registryUsername,
+			Password: registryPassword,
+		},
+	}
+	return imageDigests(ref, sysCtx, imageName, platform)
+}
+
+// OciLayoutSha256 returns the digest of an image stored in an OCI image layout directory,
+// which is the digest a registry reports for the image once it is pushed.
+// artifactName is the path to the directory, optionally followed by ':' and the image reference
+// (the org.opencontainers.image.ref.name annotation in index.json) to select one of several images.
+func OciLayoutSha256(artifactName string) (string, error) {
+	digests, err := OciLayoutImageDigests(artifactName, "")
+	if err != nil {
+		return "", err
+	}
+	return digests.Digest, nil
+}
+
+// OciLayoutImageDigests returns the digests of an image stored in an OCI image layout directory.
+// See OciLayoutSha256 for the format of artifactName and OciImageDigests for platform.
+func OciLayoutImageDigests(artifactName, platform string) (*ImageDigests, error) {
+	ref, err := ocilayout.ParseReference(artifactName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OCI layout reference %s: %w", artifactName, err)
+	}
+	return imageDigests(ref, &types.SystemContext{}, artifactName, platform)
+}
+
+// OciArchiveSha256 returns the digest of an image stored in a (optionally compressed) tarball of
+// an OCI image layout, which is the digest a registry reports for the image once it is pushed.
+// artifactName is the path to the tarball, optionally followed by ':' and the image reference
+// (the org.opencontainers.image.ref.name annotation in index.json) to select one of several images.
+func OciArchiveSha256(artifactName string) (string, error) {
+	digests, err := OciArchiveImageDigests(artifactName, "")
+	if err != nil {
+		return "", err
+	}
+	return digests.Digest, nil
+}
+
+// OciArchiveImageDigests returns the digests of an image stored in a tarball of an OCI image layout.
+// Tarballs created by 'docker save' before docker 25 have no OCI image layout and are rejected: the digest of
+// their manifest.json is of uncompressed layers, so it is never the digest the registry reports for the image.
+// See OciArchiveSha256 for the format of artifactName and OciImageDigests for platform.
+func OciArchiveImageDigests(artifactName, platform string) (*ImageDigests, error) {
+	ref, err := ociarchive.ParseReference(artifactName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OCI archive reference %s: %w", artifactName, err)
+	}
+	digests, err := imageDigests(ref, &types.SystemContext{}, artifactName, platform)
+	if errors.Is(err, ErrNoOciIndex) {
+		return nil, fmt.Errorf("failed to read image %s: %w (e.g. it was created by 'docker save' before docker 25), "+
+			"export the image again with 'docker save' of docker 25 or later or with 'docker buildx build --output type=oci'", artifactName, ErrNoOciIndex)
+	}
+	return digests, err
+}
+
+// imageDigests returns the digests of the top level manifest (or image index) of an image and,
+// for multi-arch images, of its platform manifests
+func imageDigests(ref types.ImageReference, sysCtx *types.SystemContext, artifactName, platform string) (*ImageDigests, error) {
+	if platform != "" {
+		if err := setPlatformChoice(sysCtx, platform); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := context.Background()
+	src, err := ref.NewImageSource(ctx, sysCtx)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && strings.Contains(err.Error(), "index.json") {
+			return nil, fmt.Errorf("failed to read image %s: %w", artifactName, ErrNoOciIndex)
+		}
+		return nil, fmt.Errorf("failed to read image %s: %w", artifactName, err)
+	}
+	defer src.Close()
+
+	manifestBytes, mimeType, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the manifest of image %s: %w", artifactName, err)
+	}
+	topDigest, err := manifest.Digest(manifestBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate the manifest digest of image %s: %w", artifactName, err)
+	}
+	digests := &ImageDigests{Digest: topDigest.Encoded()}
+	if !manifest.MIMETypeIsMultiImage(mimeType) {
+		if platform != "" {
+			if err := checkImagePlatform(ctx, src, sysCtx, artifactName, platform); err != nil {
+				return nil, err
+			}
+		}
+		return digests, nil
+	}
+
+	list, err := manifest.ListFromBlob(manifestBytes, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the image index of image %s: %w", artifactName, err)
+	}
+	digests.IndexDigest = topDigest.Encoded()
+	digests.PlatformDigests = platformDigests(list)
+	if platform != "" {
+		instance, err := list.ChooseInstance(sysCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select platform %s in the image index of image %s: %w", platform, artifactName, err)
+		}
+		digests.Digest = instance.Encoded()
+	}
+	return digests, nil
+}
+
+// checkImagePlatform returns an error if a single-platform image is not for the platform selected in sysCtx
+func checkImagePlatform(ctx context.Context, src types.ImageSource, sysCtx *types.SystemContext, artifactName, platform string) error {
+	img, err := image.FromUnparsedImage(ctx, sysCtx, image.UnparsedInstance(src, nil))
+	if err != nil {
+		return fmt.Errorf("failed to read image %s: %w", artifactName, err)
+	}
+	info, err := img.Inspect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration of image %s: %w", artifactName, err)
+	}
+	imagePlatform := info.Os + "/" + info.Architecture
+	if info.Variant != "" {
+		imagePlatform += "/" + info.Variant
+	}
+	if info.Os != sysCtx.OSChoice || info.Architecture != sysCtx.ArchitectureChoice ||
+		(sysCtx.VariantChoice != "" && info.Variant != sysCtx.VariantChoice) {
+		return fmt.Errorf("image %s is a single-platform image for %s, it has no manifest for platform %s", artifactName, imagePlatform, platform)
+	}
+	return nil
+}
+
+// platformDigests returns the digests of the manifests in an image index by platform.
+// Manifests without a platform, such as build attestations, are skipped.
+func platformDigests(list manifest.List) map[string]string {
+	result := map[string]string{}
+	for _, instanceDigest := range list.Instances() {
+		instance, err := list.Instance(instanceDigest)
+		if err != nil {
+			continue
+		}
+		p := instance.ReadOnly.Platform
+		if p == nil || p.OS == "" || p.OS == "unknown" || p.Architecture == "" || p.Architecture == "unknown" {
+			continue
+		}
+		name := p.OS + "/" + p.Architecture
+		if p.Variant != "" {
+			name += "/" + p.Variant
+		}
+		result[name] = instanceDigest.Encoded()
+	}
+	return result
+}
+
+// setPlatformChoice sets the platform to select in multi-arch images from a string in the format os/arch[/variant]
+func setPlatformChoice(sysCtx *types.SystemContext, platform string) error {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid platform %q, expected os/arch[/variant] (e.g. linux/amd64 or linux/arm64/v8)", platform)
+	}
+	sysCtx.OSChoice = parts[0]
+	sysCtx.ArchitectureChoice = parts[1]
+	if len(parts) == 3 {
+		sysCtx.VariantChoice = parts[2]
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type OciTestSuite struct {
+	suite.Suite
+}
+
+const (
+	ociLayoutDigest   = "60ee031e6e5138163841c02de14960015eba5e31c9a9d6047a913637b7068574"
+	ociLayoutV1Digest = "bf6709a5309f8cce3392e63f14db88b693ac3aeac11dd82d4404a2cbacd7636d"
+	ociLayoutV2Digest = "590f809cf0d2ba12151dadd74bd9b5042e3a231c416e4dddf72513b76d9133d0"
+
+	multiArchIndexDigest = "4618160c5fe217365eb0236d8cc8f5a002cf715fb4e32f6eacd937e81409c685"
+	multiArchAmd64Digest = "f1500da1532d875b3ac366ee57e40c1a2a48183fa5d3bc218aa1b09192fbaa6e"
+	multiArchArm64Digest = "b9e3f255ceb7dca22bd790f0f97524bc38b306eb6d5e2715f4c3bedc36c5de41"
+)
+
+func (suite *OciTestSuite) TestOciLayoutSha256() {
+	for _, t := range []struct {
+		name         string
+		artifactName string
+		want         string
+		wantErr      string
+	}{
+		{
+			name:         "layout with a single image",
+			artifactName: "testdata/oci/oci-layout",
+			want:         ociLayoutDigest,
+		},
+		{
+			name:         "layout with a single image selected by reference",
+			artifactName: "testdata/oci/oci-layout:latest",
+			want:         ociLayoutDigest,
+		},
+		{
+			name:         "layout with several images selected by reference",
+			artifactName: "testdata/oci/oci-layout-multi:v2",
+			want:         ociLayoutV2Digest,
+		},
+		{
+			name:         "layout with several images and no reference fails",
+			artifactName: "testdata/oci/oci-layout-multi",
+			wantErr:      "more than one image in oci, choose an image",
+		},
+		{
+			name:         "unknown reference fails",
+			artifactName: "testdata/oci/oci-layout-multi:v3",
+			wantErr:      `no descriptor found for reference "v3"`,
+		},
+		{
+			name:         "directory which is not an OCI layout fails",
+			artifactName: "testdata/dir-corpus/project",
+			wantErr:      "index.json not found",
+		},
+	} {
+		suite.Run(t.name, func() {
+			got, err := OciLayoutSha256(t.artifactName)
+			if t.wantErr != "" {
+				require.ErrorContains(suite.T(), err, t.wantErr)
+				return
+			}
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.want, got)
+		})
+	}
+}
+
+func (suite *OciTestSuite) TestOciArchiveSha256() {
+	for _, t := range []struct {
+		name         string
+		artifactName string
+		want         string
+		wantErr      string
+	}{
+		{
+			name:         "tarball",
+			artifactName: "testdata/oci/oci-archive.tar",
+			want:         ociLayoutDigest,
+		},
+		{
+			name:         "gzipped tarball selected by reference",
+			artifactName: "testdata/oci/oci-archive.tar.gz:latest",
+			want:         ociLayoutDigest,
+		},
+		{
+			name:         "legacy docker save tarball without an OCI image layout fails",
+			artifactName: "testdata/oci/docker-save-legacy.tar",
+			wantErr:      "failed to read image testdata/oci/docker-save-legacy.tar: index.json not found, the path is not an OCI image layout (e.g. it was created by 'docker save' before docker 25)",
+		},
+		{
+			name:         "missing tarball fails",
+			artifactName: "testdata/oci/non-existing.tar",
+			wantErr:      "failed to read image testdata/oci/non-existing.tar",
+		},
+	} {
+		suite.Run(t.name, func() {
+			got, err := OciArchiveSha256(t.artifactName)
+			if t.wantErr != "" {
+				require.ErrorContains(suite.T(), err, t.wantErr)
+				return
+			}
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.want, got)
+		})
+	}
+}
+
+func (suite *OciTestSuite) TestMultiArchImageDigests() {
+	platforms := map[string]string{
+		"linux/amd64":    multiArchAmd64Digest,
+		"linux/arm64/v8": multiArchArm64Digest,
+	}
+	for _, t := range []struct {
+		name     string
+		platform string
+		want     *ImageDigests
+		wantErr  string
+	}{
+		{
+			name: "the index digest is selected when no platform is given",
+			want: &ImageDigests{
+				Digest:          multiArchIndexDigest,
+				IndexDigest:     multiArchIndexDigest,
+				PlatformDigests: platforms,
+			},
+		},
+		{
+			name:     "the platform manifest digest is selected",
+			platform: "linux/amd64",
+			want: &ImageDigests{
+				Digest:          multiArchAmd64Digest,
+				IndexDigest:     multiArchIndexDigest,
+				PlatformDigests: platforms,
+			},
+		},
+		{
+			name:     "the platform manifest digest is selected without a variant",
+			platform: "linux/arm64",
+			want: &ImageDigests{
+				Digest:          multiArchArm64Digest,
+				IndexDigest:     multiArchIndexDigest,
+				PlatformDigests: platforms,
+			},
+		},
+		{
+			name:     "a platform missing from the index fails",
+			platform: "linux/s390x",
+			wantErr:  "failed to select platform linux/s390x in the image index of image testdata/oci/oci-layout-multiarch",
+		},
+		{
+			name:     "an invalid platform fails",
+			platform: "amd64",
+			wantErr:  `invalid platform "amd64", expected os/arch[/variant]`,
+		},
+	} {
+		suite.Run(t.name, func() {
+			got, err := OciLayoutImageDigests("testdata/oci/oci-layout-multiarch", t.platform)
+			if t.wantErr != "" {
+				require.ErrorContains(suite.T(), err, t.wantErr)
+				return
+			}
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.want, got)
+
+			archiveDigests, err := OciArchiveImageDigests("testdata/oci/oci-archive-multiarch.tar", t.platform)
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.want, archiveDigests)
+		})
+	}
+}
+
+func (suite *OciTestSuite) TestSinglePlatformImageDigests() {
+	for _, t := range []struct {
+		name         string
+		artifactName string
+		platform     string
+		want         string
+		wantErr      string
+	}{
+		{
+			name:         "the manifest digest is selected for the platform of the image",
+			artifactName: "testdata/oci/oci-layout",
+			platform:     "linux/amd64",
+			want:         ociLayoutDigest,
+		},
+		{
+			name:         "another platform fails",
+			artifactName: "testdata/oci/oci-layout",
+			platform:     "linux/arm64",
+			wantErr:      "image testdata/oci/oci-layout is a single-platform image for linux/amd64, it has no manifest for platform linux/arm64",
+		},
+	} {
+		suite.Run(t.name, func() {
+			var got *ImageDigests
+			var err error
+			if strings.HasSuffix(t.artifactName, ".tar") {
+				got, err = OciArchiveImageDigests(t.artifactName, t.platform)
+			} else {
+				got, err = OciLayoutImageDigests(t.artifactName, t.platform)
+			}
+			if t.wantErr != "" {
+				require.EqualError(suite.T(), err, t.wantErr)
+				return
+			}
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), &ImageDigests{Digest: t.want}, got)
+		})
+	}
+}
+
+func (suite *OciTestSuite) TestImageDigestsAliases() {
+	for _, t := range []struct {
+		name    string
+		digests *ImageDigests
+		want    []string
+	}{
+		{
+			name:    "single platform image has no aliases",
+			digests: &ImageDigests{Digest: ociLayoutDigest},
+			want:    []string{},
+		},
+		{
+			name: "index digest has the platform digests as aliases",
+			digests: &ImageDigests{
+				Digest:          multiArchIndexDigest,
+				IndexDigest:     multiArchIndexDigest,
+				PlatformDigests: map[string]string{"linux/amd64": multiArchAmd64Digest, "linux/arm64/v8": multiArchArm64Digest},
+			},
+			want: []string{multiArchArm64Digest, multiArchAmd64Digest},
+		},
+		{
+			name: "platform digest has the index and other platform digests as aliases",
+			digests: &ImageDigests{
+				Digest:          multiArchAmd64Digest,
+				IndexDigest:     multiArchIndexDigest,
+				PlatformDigests: map[string]string{"linux/amd64": multiArchAmd64Digest, "linux/arm64/v8": multiArchArm64Digest},
+			},
+			want: []string{multiArchIndexDigest, multiArchArm64Digest},
+		},
+	} {
+		suite.Run(t.name, func() {
+			require.Equal(suite.T(), t.want, t.digests.Aliases())
+		})
+	}
+}
+
+// The digest of each image in a layout is the digest of its manifest blob,
+// which is what a registry reports once the image is pushed
+func (suite *OciTestSuite) TestOciLayoutDigestMatchesManifestBlob() {
+	for _, d := range []string{ociLayoutV1Digest, ociLayoutV2Digest} {
+		got, err := FileSha256("testdata/oci/oci-layout-multi/blobs/sha256/" + d)
+		require.NoError(suite.T(), err)
+		require.Equal(suite.T(), d, got)
+	}
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestOciTestSuite(t *testing.T) {
+	suite.Run(t, new(OciTestSuite))
+}
@@ -0,0 +1,105 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// where the digest of a running container image comes from
+const (
+	// the digest is observed where the image runs (the container status, task description or task config)
+	DigestSourceObserved = "observed"
+	// the digest is resolved by looking up the image in its registry
+	DigestSourceResolved = "resolved"
+	// the digest could not be found
+	DigestSourceUnresolved = "unresolved"
+)
+
+// DigestResolver looks up the sha256 digest of an image reference in its registry
+type DigestResolver func(image string) (string, error)
+
+// ResolvableImage is a running container image whose digest may have to be looked up in its registry
+type ResolvableImage interface {
+	// UnresolvedImage returns the image reference if the digest of the image is unresolved,
+	// or an empty string otherwise
+	UnresolvedImage() string
+	// SetResolvedDigest records the digest of the image resolved from its registry
+	SetResolvedDigest(sha256 string)
+	// AddUnresolvedReason adds to the reason why the digest of the image is unresolved
+	AddUnresolvedReason(reason string)
+}
+
+// AppendUnresolvedReason returns the reason why the digest of an image is unresolved with reason added to it
+func AppendUnresolvedReason(unresolvedReason, reason string) string {
+	reasons := []string{}
+	for _, r := range []string{unresolvedReason, reason} {
+		if r != "" {
+			reasons = append(reasons, r)
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// DigestCache looks up image digests in their registries at most once per image.
+// Failed lookups are cached too, so that an image that cannot be resolved is not looked up again.
+type DigestCache struct {
+	resolve DigestResolver
+	mutex   sync.Mutex
+	results map[string]*digestResult
+}
+
+type digestResult struct {
+	once   sync.Once
+	digest string
+	err    error
+}
+
+// NewDigestCache returns a DigestCache looking up digests with resolve.
+// If resolve is nil, no digest is looked up.
+func NewDigestCache(resolve DigestResolver) *DigestCache {
+	return &DigestCache{
+		resolve: resolve,
+		results: make(map[string]*digestResult),
+	}
+}
+
+// ResolveMissingDigests looks up the digests of the unresolved images and adds them to digests by image reference.
+// Images whose digest cannot be resolved stay unresolved and the registry error is added to the reason.
+func ResolveMissingDigests[T ResolvableImage](cache *DigestCache, images []T, digests map[string]string) {
+	if cache.resolve == nil {
+		return
+	}
+	for _, image := range images {
+		ref := image.UnresolvedImage()
+		if ref == "" {
+			continue
+		}
+		sha256, err := cache.Lookup(ref)
+		if err != nil {
+			image.AddUnresolvedReason(fmt.Sprintf("could not resolve the digest from the registry: %v", err))
+			continue
+		}
+		image.SetResolvedDigest(sha256)
+		digests[ref] = sha256
+	}
+}
+
+// Lookup returns the digest of an image, looking it up in its registry the first time it is requested
+func (c *DigestCache) Lookup(image string) (string, error) {
+	c.mutex.Lock()
+	result, ok := c.results[image]
+	if !ok {
+		result = &digestResult{}
+		c.results[image] = result
+	}
+	c.mutex.Unlock()
+
+	result.once.Do(func() {
+		result.digest, result.err = c.resolve(image)
+		if result.err == nil {
+			result.err = ValidateDigest(result.digest)
+		}
+	})
+	return result.digest, result.err
+}
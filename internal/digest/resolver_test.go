@@ -0,0 +1,109 @@
+package digest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type ResolverTestSuite struct {
+	suite.Suite
+}
+
+// fakeImage is a running image with the digest fields of a harvested container
+type fakeImage struct {
+	Image            string
+	Digest           string
+	DigestSource     string
+	UnresolvedReason string
+}
+
+func (i *fakeImage) UnresolvedImage() string {
+	if i.DigestSource != DigestSourceUnresolved {
+		return ""
+	}
+	return i.Image
+}
+
+func (i *fakeImage) SetResolvedDigest(sha256 string) {
+	i.Digest = sha256
+	i.DigestSource = DigestSourceResolved
+	i.UnresolvedReason = ""
+}
+
+func (i *fakeImage) AddUnresolvedReason(reason string) {
+	i.UnresolvedReason = AppendUnresolvedReason(i.UnresolvedReason, reason)
+}
+
+func (suite *ResolverTestSuite) TestResolveMissingDigests() {
+	resolved := "e15947432b813e8ffa90165da919953e2ce850bef511a0ad1287d7cb86de84b5"
+	lookups := map[string]int{}
+	cache := NewDigestCache(func(image string) (string, error) {
+		lookups[image]++
+		switch image {
+		case "private:1.0":
+			return "", fmt.Errorf("unauthorized")
+		case "broken:1.0":
+			return "not-a-digest", nil
+		}
+		return resolved, nil
+	})
+
+	newImages := func() []*fakeImage {
+		return []*fakeImage{
+			{Image: "app:1.0", DigestSource: DigestSourceUnresolved, UnresolvedReason: "no digest"},
+			{Image: "private:1.0", DigestSource: DigestSourceUnresolved, UnresolvedReason: "no digest"},
+			{Image: "broken:1.0", DigestSource: DigestSourceUnresolved, UnresolvedReason: "no digest"},
+			{Image: "sidecar:1.0", Digest: resolved, DigestSource: DigestSourceObserved},
+		}
+	}
+	for i := 0; i < 2; i++ {
+		images := newImages()
+		digests := map[string]string{"sidecar:1.0": resolved}
+		ResolveMissingDigests(cache, images, digests)
+
+		require.Equal(suite.T(), map[string]string{"app:1.0": resolved, "sidecar:1.0": resolved}, digests)
+		require.Equal(suite.T(), &fakeImage{Image: "app:1.0", Digest: resolved, DigestSource: DigestSourceResolved}, images[0])
+		require.Equal(suite.T(), DigestSourceUnresolved, images[1].DigestSource)
+		require.Equal(suite.T(), "no digest; could not resolve the digest from the registry: unauthorized", images[1].UnresolvedReason)
+		require.Equal(suite.T(), DigestSourceUnresolved, images[2].DigestSource)
+		require.Contains(suite.T(), images[2].UnresolvedReason, "no digest; could not resolve the digest from the registry: ")
+		require.Equal(suite.T(), DigestSourceObserved, images[3].DigestSource)
+	}
+	// the failed lookups are cached too
+	require.Equal(suite.T(), map[string]int{"app:1.0": 1, "private:1.0": 1, "broken:1.0": 1}, lookups)
+}
+
+func (suite *ResolverTestSuite) TestResolveMissingDigestsWithoutResolver() {
+	images := []*fakeImage{{Image: "app:1.0", DigestSource: DigestSourceUnresolved, UnresolvedReason: "no digest"}}
+	digests := map[string]string{}
+	ResolveMissingDigests(NewDigestCache(nil), images, digests)
+	require.Empty(suite.T(), digests)
+	require.Equal(suite.T(), &fakeImage{Image: "app:1.0", DigestSource: DigestSourceUnresolved, UnresolvedReason: "no digest"}, images[0])
+}
+
+func (suite *ResolverTestSuite) TestAppendUnresolvedReason() {
+	for _, t := range []struct {
+		unresolvedReason string
+		reason           string
+		want             string
+	}{
+		{unresolvedReason: "", reason: "unauthorized", want: "unauthorized"},
+		{unresolvedReason: "no digest", reason: "", want: "no digest"},
+		{unresolvedReason: "no digest", reason: "unauthorized", want: "no digest; unauthorized"},
+		{unresolvedReason: "", reason: "", want: ""},
+	} {
+		require.Equal(suite.T(), t.want, AppendUnresolvedReason(t.unresolvedReason, t.reason))
+	}
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestResolverTestSuite(t *testing.T) {
+	suite.Run(t, new(ResolverTestSuite))
+}
@@ -0,0 +1,4 @@
+package util
+
+// Add adds two ints
+func Add(a, b int) int { return a + b }
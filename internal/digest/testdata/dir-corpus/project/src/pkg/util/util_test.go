@@ -0,0 +1,5 @@
+package util
+
+import "testing"
+
+func TestAdd(t *testing.T) {}
@@ -0,0 +1,167 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kosli-dev/cli/internal/logger"
+)
+
+// FileTree is a tree of files which are not on the local file system, such as the objects in a bucket.
+// Paths are slash separated and relative to the root of the tree. A leading slash and empty elements are ignored.
+type FileTree interface {
+	// Open returns the content of a file
+	Open(path string) (io.ReadCloser, error)
+	// ContentSha256 returns the sha256 digest of the content of a file
+	ContentSha256(path string) (string, error)
+}
+
+// TreeSha256 returns the sha256 digest of the files at paths in a file tree, which is the same
+// digest DirSha256 returns for a directory containing the same files. Directories are implied by the paths.
+// As with DirSha256, paths matching the gitignore patterns in the .kosli_ignore files of the tree are excluded.
+// File contents are hashed concurrently by the given number of workers.
+// The methods of the tree are called with the file paths as given.
+func TreeSha256(tree FileTree, paths []string, workers int, logger *logger.Logger) (string, error) {
+	files, err := sortTreePaths(paths)
+	if err != nil {
+		return "", err
+	}
+	logger.Debug("calculating fingerprint for %d files", len(files))
+
+	// ignoreFiles are the paths of the .kosli_ignore files by their cleaned path
+	ignoreFiles := map[string]string{}
+	for _, file := range files {
+		if file.elems[len(file.elems)-1] == IgnoreFileName {
+			ignoreFiles[path.Join(file.elems...)] = file.path
+		}
+	}
+	ignore := &ignoreMatcher{}
+	addTreeIgnoreFile := func(dir []string) error {
+		ignoreFilePath := path.Join(append(append([]string{}, dir...), IgnoreFileName)...)
+		originalPath, found := ignoreFiles[ignoreFilePath]
+		if !found {
+			return nil
+		}
+		content, err := tree.Open(originalPath)
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+		ignoredPaths, err := readIgnorePatterns(content, ignoreFilePath)
+		if err != nil {
+			return err
+		}
+		ignore.addPatterns(ignoredPaths, dir)
+		logger.Debug("  -> ignore file used %s -- excluding paths: %s", ignoreFilePath, ignoredPaths)
+		return nil
+	}
+
+	walk := func(ctx context.Context, emit func(*dirEntryDigest) error) error {
+		if err := addTreeIgnoreFile(nil); err != nil {
+			return err
+		}
+		// openDir is the directory of the last emitted entry, skipDir the last excluded directory
+		var openDir, skipDir []string
+		for _, treeFile := range files {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			file := treeFile.elems
+			if skipDir != nil && hasPathPrefix(file, skipDir) {
+				continue
+			}
+			dir := file[:len(file)-1]
+			openDir = openDir[:commonPathPrefix(openDir, dir)]
+			excludedDir := false
+			for len(openDir) < len(dir) {
+				dirPath := dir[:len(openDir)+1]
+				entry := &dirEntryDigest{path: path.Join(dirPath...), name: dirPath[len(dirPath)-1], isDir: true, entryType: EntryTypeDir}
+				if ignore.match(dirPath, true) {
+					logger.Debug("skipping dir %s (and its contents) as it matches excluded paths", entry.path)
+					entry.excludedBy = ExcludedByIgnoreFile
+				}
+				if err := emit(entry); err != nil {
+					return err
+				}
+				if entry.excludedBy != "" {
+					skipDir = dirPath
+					excludedDir = true
+					break
+				}
+				openDir = dirPath
+				if err := addTreeIgnoreFile(dirPath); err != nil {
+					return err
+				}
+			}
+			if excludedDir {
+				continue
+			}
+
+			entry := &dirEntryDigest{path: treeFile.path, name: file[len(file)-1], entryType: EntryTypeFile}
+			if ignore.match(file, false) {
+				logger.Debug("skipping %s as it matches excluded paths", entry.path)
+				entry.excludedBy = ExcludedByIgnoreFile
+			}
+			if err := emit(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return hashEntries(walk, tree.ContentSha256, workers, nil, logger)
+}
+
+// treePath is the path of a file in a tree and its elements
+type treePath struct {
+	path  string
+	elems []string
+}
+
+// sortTreePaths splits the paths of the files in a tree into their elements and sorts them in
+// the order filepath.WalkDir visits them, i.e. the entries of each directory in lexical order
+func sortTreePaths(paths []string) ([]treePath, error) {
+	files := make([]treePath, 0, len(paths))
+	for _, p := range paths {
+		files = append(files, treePath{path: p, elems: strings.Split(path.Clean(strings.TrimLeft(p, "/")), "/")})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return comparePaths(files[i].elems, files[j].elems) < 0
+	})
+	for i := 1; i < len(files); i++ {
+		if comparePaths(files[i].elems, files[i-1].elems) == 0 {
+			return nil, fmt.Errorf("duplicate path %s", path.Join(files[i].elems...))
+		}
+		if hasPathPrefix(files[i].elems, files[i-1].elems) {
+			return nil, fmt.Errorf("%s cannot be both a file and a directory", path.Join(files[i-1].elems...))
+		}
+	}
+	return files, nil
+}
+
+// comparePaths compares two paths split in their elements element by element
+func comparePaths(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// commonPathPrefix returns the number of leading elements two paths have in common
+func commonPathPrefix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// hasPathPrefix returns true if a path starts with all the elements of prefix
+func hasPathPrefix(p, prefix []string) bool {
+	return len(p) >= len(prefix) && commonPathPrefix(p, prefix) == len(prefix)
+}
@@ -0,0 +1,169 @@
+package digest
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kosli-dev/cli/internal/logger"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type TreeTestSuite struct {
+	suite.Suite
+}
+
+// localTree is a file tree backed by a local directory
+type localTree struct {
+	dir string
+}
+
+func (t *localTree) Open(path string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(t.dir, filepath.FromSlash(path)))
+}
+
+func (t *localTree) ContentSha256(path string) (string, error) {
+	return FileSha256(filepath.Join(t.dir, filepath.FromSlash(path)))
+}
+
+// paths returns the slash separated paths of the files in the tree, in the order given by unsorted
+func (t *localTree) paths(unsorted bool) []string {
+	paths := []string{}
+	err := filepath.WalkDir(t.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(t.dir, path)
+		paths = append(paths, filepath.ToSlash(relPath))
+		return err
+	})
+	if err != nil {
+		panic(err)
+	}
+	if unsorted {
+		for i, j := 0, len(paths)-1; i < j; i, j = i+1, j-1 {
+			paths[i], paths[j] = paths[j], paths[i]
+		}
+	}
+	return paths
+}
+
+func (suite *TreeTestSuite) writeFiles(dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(suite.T(), os.MkdirAll(filepath.Dir(path), 0777))
+		require.NoError(suite.T(), os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+// TestTreeSha256MatchesDirSha256 checks that a tree of files gets the same fingerprint
+// as a directory with the same files, regardless of the order of the paths
+func (suite *TreeTestSuite) TestTreeSha256MatchesDirSha256() {
+	for _, t := range []struct {
+		name  string
+		files map[string]string
+		dir   string
+	}{
+		{
+			name: "files sorted differently by path and by directory walk",
+			files: map[string]string{
+				"a.txt":         "a",
+				"a/b":           "b",
+				"a/b.c/d":       "d",
+				"a-b/c":         "c",
+				"z":             "z",
+				"nested/x/y/z1": "z1",
+				"nested/x/y/z2": "z2",
+			},
+		},
+		{
+			name: "nested .kosli_ignore files with negated patterns",
+			files: map[string]string{
+				".kosli_ignore":       "*.log\nbuild/\n",
+				"app.log":             "log",
+				"main.go":             "package main",
+				"build/out.bin":       "bin",
+				"src/.kosli_ignore":   "!keep.log\ngenerated\n",
+				"src/keep.log":        "keep",
+				"src/other.log":       "other",
+				"src/generated/x.go":  "x",
+				"src/pkg/generated":   "file named generated",
+				"src/pkg/pkg.go":      "package pkg",
+				"docs/.kosli_ignore":  "# only comments",
+				"docs/with space.md":  "docs",
+				"docs/ünïcode naming": "unicode",
+			},
+		},
+		{
+			name: "a single file",
+			files: map[string]string{
+				"dir/file.txt": "content",
+			},
+		},
+		{
+			name: "golden corpus with .kosli_ignore",
+			dir:  filepath.Join("testdata", "dir-corpus", "with-ignore"),
+		},
+	} {
+		suite.Run(t.name, func() {
+			dir := t.dir
+			if dir == "" {
+				dir = suite.T().TempDir()
+				suite.writeFiles(dir, t.files)
+			}
+			want, err := DirSha256(dir, []string{}, "", logger.NewStandardLogger())
+			require.NoError(suite.T(), err)
+
+			tree := &localTree{dir: dir}
+			for _, workers := range []int{1, 8} {
+				got, err := TreeSha256(tree, tree.paths(true), workers, logger.NewStandardLogger())
+				require.NoError(suite.T(), err)
+				require.Equal(suite.T(), want, got)
+			}
+		})
+	}
+}
+
+func (suite *TreeTestSuite) TestTreeSha256Fails() {
+	for _, t := range []struct {
+		name    string
+		paths   []string
+		wantErr string
+	}{
+		{
+			name:    "a path which is both a file and a directory",
+			paths:   []string{"a/b", "a"},
+			wantErr: "a cannot be both a file and a directory",
+		},
+		{
+			name:    "duplicate paths",
+			paths:   []string{"a/b", "/a//b"},
+			wantErr: "duplicate path a/b",
+		},
+		{
+			name:    "a file that cannot be read",
+			paths:   []string{"a", "does-not-exist"},
+			wantErr: "does-not-exist",
+		},
+	} {
+		suite.Run(t.name, func() {
+			dir := suite.T().TempDir()
+			suite.writeFiles(dir, map[string]string{"a": strings.Repeat("a", 10)})
+			_, err := TreeSha256(&localTree{dir: dir}, t.paths, 4, logger.NewStandardLogger())
+			require.ErrorContains(suite.T(), err, t.wantErr)
+		})
+	}
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestTreeTestSuite(t *testing.T) {
+	suite.Run(t, new(TreeTestSuite))
+}
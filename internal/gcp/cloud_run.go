@@ -0,0 +1,271 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/filters"
+	"github.com/kosli-dev/cli/internal/logger"
+	"google.golang.org/api/option"
+	runv1 "google.golang.org/api/run/v1"
+	run "google.golang.org/api/run/v2"
+)
+
+// allRegions is the location used to list the Cloud Run services in all regions of a project
+const allRegions = "-"
+
+// trafficTypeLatest is the traffic target type of traffic sent to the latest ready revision of a service
+const trafficTypeLatest = "TRAFFIC_TARGET_ALLOCATION_TYPE_LATEST"
+
+// GCPStaticCredentials are the credentials used to access the Google Cloud APIs.
+// If CredentialsFile is empty, the application default credentials are used.
+type GCPStaticCredentials struct {
+	CredentialsFile string
+}
+
+// CloudRunEnvRequest represents the PUT request body to be sent to kosli from Cloud Run
+type CloudRunEnvRequest struct {
+	Artifacts []*CloudRunRevisionData `json:"artifacts"`
+}
+
+// CloudRunRevisionData represents the harvested data of a Cloud Run revision receiving traffic
+type CloudRunRevisionData struct {
+	Project  string `json:"project"`
+	Region   string `json:"region"`
+	Service  string `json:"service"`
+	Revision string `json:"revision"`
+	// TrafficPercent is the percentage of the service traffic sent to the revision
+	TrafficPercent int64 `json:"trafficPercent"`
+	// Tags are the traffic tags of the revision
+	Tags       []string                 `json:"tags,omitempty"`
+	Digests    map[string]string        `json:"digests"`
+	StartedAt  int64                    `json:"creationTimestamp"`
+	Containers []*CloudRunContainerData `json:"containers"`
+}
+
+// CloudRunContainerData represents the harvested data of a single container in a Cloud Run revision
+type CloudRunContainerData struct {
+	Name             string `json:"name"`
+	Image            string `json:"image"`
+	Digest           string `json:"digest"`
+	DigestSource     string `json:"digestSource"`
+	UnresolvedReason string `json:"unresolvedReason,omitempty"`
+}
+
+// CloudRunClient is a client of the Cloud Run Admin APIs. The services are listed with the v2 API
+// and the revisions are read with the v1 API, which reports the digest the image of a revision was
+// resolved to when the revision was created.
+type CloudRunClient struct {
+	Service *run.Service
+	opts    []option.ClientOption
+	// regionalEndpoint returns the endpoint of the v1 API in a region
+	regionalEndpoint func(region string) string
+	// revisionServices are the v1 API clients by region
+	revisionServices map[string]*runv1.APIService
+}
+
+// NewCloudRunClient returns a new Cloud Run Admin API client
+func (staticCreds *GCPStaticCredentials) NewCloudRunClient(opts ...option.ClientOption) (*CloudRunClient, error) {
+	if staticCreds.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(staticCreds.CredentialsFile))
+	}
+	service, err := run.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &CloudRunClient{
+		Service: service,
+		opts:    opts,
+		regionalEndpoint: func(region string) string {
+			// the namespaces methods of the v1 API are only served by the regional endpoints
+			return fmt.Sprintf("https://%s-run.googleapis.com/", region)
+		},
+		revisionServices: map[string]*runv1.APIService{},
+	}, nil
+}
+
+// revisionService returns the v1 API client of a region
+func (client *CloudRunClient) revisionService(ctx context.Context, region string) (*runv1.APIService, error) {
+	if service, ok := client.revisionServices[region]; ok {
+		return service, nil
+	}
+	opts := append(append([]option.ClientOption{}, client.opts...), option.WithEndpoint(client.regionalEndpoint(region)))
+	service, err := runv1.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client.revisionServices[region] = service
+	return service, nil
+}
+
+// GetCloudRunData returns the data of the Cloud Run revisions receiving traffic in the services
+// matching the filter, in the given projects and regions. If regions is empty, all regions are used.
+func GetCloudRunData(client *CloudRunClient, projects, regions []string, filter *filters.ResourceFilterOptions,
+	logger *logger.Logger) ([]*CloudRunRevisionData, error) {
+	if len(regions) == 0 {
+		regions = []string{allRegions}
+	}
+	ctx := context.Background()
+	revisionsData := []*CloudRunRevisionData{}
+	for _, project := range projects {
+		for _, region := range regions {
+			parent := fmt.Sprintf("projects/%s/locations/%s", project, region)
+			services := []*run.GoogleCloudRunV2Service{}
+			err := client.Service.Projects.Locations.Services.List(parent).Pages(ctx, func(response *run.GoogleCloudRunV2ListServicesResponse) error {
+				services = append(services, response.Services...)
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list Cloud Run services in %s: %v", parent, err)
+			}
+
+			for _, svc := range services {
+				include, err := filter.ShouldInclude(lastSegment(svc.Name))
+				if err != nil {
+					return nil, err
+				}
+				if !include {
+					continue
+				}
+				logger.Debug("getting the revisions receiving traffic in Cloud Run service %s", svc.Name)
+				for _, route := range trafficRoutes(svc) {
+					data, err := client.getRevisionData(ctx, svc.Name+"/revisions/"+route.revision)
+					if err != nil {
+						return nil, err
+					}
+					data.TrafficPercent = route.percent
+					data.Tags = route.tags
+					revisionsData = append(revisionsData, data)
+				}
+			}
+		}
+	}
+	return revisionsData, nil
+}
+
+// trafficRoute is the traffic sent to a revision of a service
+type trafficRoute struct {
+	revision string
+	percent  int64
+	tags     []string
+}
+
+// trafficRoutes returns the revisions of a service receiving traffic, in lexical order.
+// A revision can be the target of several traffic statuses (e.g. both as the latest revision and by name).
+func trafficRoutes(svc *run.GoogleCloudRunV2Service) []*trafficRoute {
+	routes := map[string]*trafficRoute{}
+	for _, status := range svc.TrafficStatuses {
+		revision := status.Revision
+		if revision == "" && status.Type == trafficTypeLatest {
+			revision = lastSegment(svc.LatestReadyRevision)
+		}
+		if revision == "" {
+			continue
+		}
+		route, ok := routes[revision]
+		if !ok {
+			route = &trafficRoute{revision: revision}
+			routes[revision] = route
+		}
+		route.percent += status.Percent
+		if status.Tag != "" {
+			route.tags = append(route.tags, status.Tag)
+		}
+	}
+
+	result := []*trafficRoute{}
+	for _, route := range routes {
+		if route.percent > 0 {
+			sort.Strings(route.tags)
+			result = append(result, route)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].revision < result[j].revision
+	})
+	return result
+}
+
+// getRevisionData gets a revision and the digests of its container images.
+// The digest of an image referenced by tag only is the digest Cloud Run resolved the tag to when the revision
+// was created (status.imageDigest of the v1 API), not the digest the tag points to now. Cloud Run only reports
+// it for the ingress container, so sidecar images referenced by tag only are unresolved and are not in Digests.
+func (client *CloudRunClient) getRevisionData(ctx context.Context, revisionName string) (*CloudRunRevisionData, error) {
+	// revision names are projects/{project}/locations/{region}/services/{service}/revisions/{revision}
+	parts := strings.Split(revisionName, "/")
+	if len(parts) != 8 {
+		return nil, fmt.Errorf("unexpected Cloud Run revision name %s", revisionName)
+	}
+	data := &CloudRunRevisionData{
+		Project:    parts[1],
+		Region:     parts[3],
+		Service:    parts[5],
+		Revision:   parts[7],
+		Digests:    make(map[string]string),
+		Containers: []*CloudRunContainerData{},
+	}
+
+	service, err := client.revisionService(ctx, data.Region)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := service.Namespaces.Revisions.Get(fmt.Sprintf("namespaces/%s/revisions/%s", data.Project, data.Revision)).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Cloud Run revision %s: %v", revisionName, err)
+	}
+
+	if revision.Metadata != nil && revision.Metadata.CreationTimestamp != "" {
+		createdAt, err := time.Parse(time.RFC3339Nano, revision.Metadata.CreationTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the creation time of Cloud Run revision %s: %v", revisionName, err)
+		}
+		data.StartedAt = createdAt.Unix()
+	}
+	if revision.Spec == nil {
+		return data, nil
+	}
+
+	resolvedImage := ""
+	if revision.Status != nil {
+		resolvedImage = revision.Status.ImageDigest
+	}
+	resolvedRepository, resolvedDigest, _ := strings.Cut(resolvedImage, "@sha256:")
+	for _, container := range revision.Spec.Containers {
+		containerData := &CloudRunContainerData{
+			Name:         container.Name,
+			Image:        container.Image,
+			DigestSource: digest.DigestSourceObserved,
+		}
+		if _, sha256, found := strings.Cut(container.Image, "@sha256:"); found {
+			containerData.Digest = sha256
+		} else if resolvedDigest != "" && imageRepository(container.Image) == resolvedRepository {
+			containerData.Digest = resolvedDigest
+		} else {
+			containerData.DigestSource = digest.DigestSourceUnresolved
+			containerData.UnresolvedReason = "Cloud Run reports no digest for the image (only the image of the ingress container is resolved)"
+		}
+		if containerData.Digest != "" {
+			data.Digests[container.Image] = containerData.Digest
+		}
+		data.Containers = append(data.Containers, containerData)
+	}
+	return data, nil
+}
+
+// imageRepository returns an image reference without its tag and digest
+func imageRepository(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// lastSegment returns the last segment of a resource name (e.g. the service name of projects/p/locations/l/services/s)
+func lastSegment(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,259 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/filters"
+	"github.com/kosli-dev/cli/internal/logger"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/api/option"
+	runv1 "google.golang.org/api/run/v1"
+	run "google.golang.org/api/run/v2"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type CloudRunTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	client *CloudRunClient
+	// regions are the regions of the v1 API endpoints the revisions are read from
+	regions []string
+}
+
+const (
+	paymentsDigest = "6f2f4b3a4d30b0d4a1c1a0c2b0e1dfc9a4fae3e3f1b2e8b1f5a7d1c0e2f4a6b8"
+	canaryDigest   = "1a3c5e7f9b2d4f6a8c0e2a4c6e8f0b2d4f6a8c0e2a4c6e8f0b2d4f6a8c0e2a4c"
+	webDigest      = "0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c"
+)
+
+// cloudRunFakeServices are the services served by the fake Cloud Run API, by location
+var cloudRunFakeServices = map[string][]*run.GoogleCloudRunV2Service{
+	"projects/shop/locations/europe-west1": {
+		{
+			Name:                "projects/shop/locations/europe-west1/services/payments",
+			LatestReadyRevision: "projects/shop/locations/europe-west1/services/payments/revisions/payments-00002",
+			TrafficStatuses: []*run.GoogleCloudRunV2TrafficTargetStatus{
+				{Type: trafficTypeLatest, Percent: 10, Tag: "canary"},
+				{Type: "TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION", Revision: "payments-00001", Percent: 90},
+				{Type: "TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION", Revision: "payments-00000", Tag: "old"},
+			},
+		},
+	},
+	"projects/shop/locations/us-central1": {
+		{
+			Name: "projects/shop/locations/us-central1/services/web",
+			TrafficStatuses: []*run.GoogleCloudRunV2TrafficTargetStatus{
+				{Type: "TRAFFIC_TARGET_ALLOCATION_TYPE_REVISION", Revision: "web-00007", Percent: 100},
+			},
+		},
+	},
+}
+
+// cloudRunFakeRevisions are the revisions served by the fake Cloud Run v1 API, by name.
+// Both payments revisions run the latest tag, which was moved to the image of payments-00002
+// after payments-00001 was created: each revision has the digest the tag pointed to when it was created.
+var cloudRunFakeRevisions = map[string]*runv1.Revision{
+	"namespaces/shop/revisions/payments-00001": {
+		Metadata: &runv1.ObjectMeta{CreationTimestamp: "2026-10-01T10:00:00.123456Z"},
+		Spec: &runv1.RevisionSpec{Containers: []*runv1.Container{
+			{Name: "app", Image: "europe-docker.pkg.dev/shop/apps/payments:latest"},
+			{Name: "proxy", Image: "docker.io/envoyproxy/envoy:v1.31"},
+		}},
+		Status: &runv1.RevisionStatus{ImageDigest: "europe-docker.pkg.dev/shop/apps/payments@sha256:" + paymentsDigest},
+	},
+	"namespaces/shop/revisions/payments-00002": {
+		Metadata: &runv1.ObjectMeta{CreationTimestamp: "2026-10-02T10:00:00Z"},
+		Spec: &runv1.RevisionSpec{Containers: []*runv1.Container{
+			{Name: "app", Image: "europe-docker.pkg.dev/shop/apps/payments:latest"},
+			{Name: "proxy", Image: "docker.io/envoyproxy/envoy:v1.31"},
+		}},
+		Status: &runv1.RevisionStatus{ImageDigest: "europe-docker.pkg.dev/shop/apps/payments@sha256:" + canaryDigest},
+	},
+	"namespaces/shop/revisions/web-00007": {
+		Metadata: &runv1.ObjectMeta{CreationTimestamp: "2026-09-30T08:30:00Z"},
+		Spec: &runv1.RevisionSpec{Containers: []*runv1.Container{
+			{Image: "gcr.io/shop/web@sha256:" + webDigest},
+		}},
+		Status: &runv1.RevisionStatus{ImageDigest: "gcr.io/shop/web@sha256:" + webDigest},
+	},
+}
+
+func (suite *CloudRunTestSuite) SetupTest() {
+	suite.regions = []string{}
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		if region, name, found := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/apis/serving.knative.dev/v1/"); found {
+			revision, ok := cloudRunFakeRevisions[name]
+			if !ok {
+				http.Error(w, fmt.Sprintf(`{"error": {"code": 404, "message": "%s not found"}}`, name), http.StatusNotFound)
+				return
+			}
+			suite.regions = append(suite.regions, region)
+			response = revision
+		} else if parent, found := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/services"); found {
+			if !strings.HasPrefix(parent, "projects/shop/") {
+				http.Error(w, `{"error": {"code": 403, "message": "permission denied"}}`, http.StatusForbidden)
+				return
+			}
+			services := []*run.GoogleCloudRunV2Service{}
+			for location, locationServices := range cloudRunFakeServices {
+				if location == parent || parent == "projects/shop/locations/-" {
+					services = append(services, locationServices...)
+				}
+			}
+			response = &run.GoogleCloudRunV2ListServicesResponse{Services: services}
+		} else {
+			http.Error(w, fmt.Sprintf(`{"error": {"code": 404, "message": "%s not found"}}`, r.URL.Path), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(suite.T(), json.NewEncoder(w).Encode(response))
+	}))
+
+	var err error
+	creds := &GCPStaticCredentials{}
+	suite.client, err = creds.NewCloudRunClient(option.WithEndpoint(suite.server.URL), option.WithoutAuthentication())
+	require.NoError(suite.T(), err)
+	// the regional endpoints of the v1 API are served under a path prefix
+	suite.client.regionalEndpoint = func(region string) string {
+		return suite.server.URL + "/" + region + "/"
+	}
+}
+
+func (suite *CloudRunTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+// paymentsContainers are the containers of a payments revision whose app image resolved to appDigest.
+// Cloud Run reports no digest for the proxy sidecar, which is referenced by tag only.
+func paymentsContainers(appDigest string) []*CloudRunContainerData {
+	return []*CloudRunContainerData{
+		{Name: "app", Image: "europe-docker.pkg.dev/shop/apps/payments:latest", Digest: appDigest, DigestSource: digest.DigestSourceObserved},
+		{
+			Name:             "proxy",
+			Image:            "docker.io/envoyproxy/envoy:v1.31",
+			DigestSource:     digest.DigestSourceUnresolved,
+			UnresolvedReason: "Cloud Run reports no digest for the image (only the image of the ingress container is resolved)",
+		},
+	}
+}
+
+func (suite *CloudRunTestSuite) TestGetCloudRunData() {
+	payments := []*CloudRunRevisionData{
+		{
+			Project:        "shop",
+			Region:         "europe-west1",
+			Service:        "payments",
+			Revision:       "payments-00001",
+			TrafficPercent: 90,
+			Digests: map[string]string{
+				"europe-docker.pkg.dev/shop/apps/payments:latest": paymentsDigest,
+			},
+			StartedAt:  1790848800,
+			Containers: paymentsContainers(paymentsDigest),
+		},
+		{
+			Project:        "shop",
+			Region:         "europe-west1",
+			Service:        "payments",
+			Revision:       "payments-00002",
+			TrafficPercent: 10,
+			Tags:           []string{"canary"},
+			Digests: map[string]string{
+				"europe-docker.pkg.dev/shop/apps/payments:latest": canaryDigest,
+			},
+			StartedAt:  1790935200,
+			Containers: paymentsContainers(canaryDigest),
+		},
+	}
+	web := &CloudRunRevisionData{
+		Project:        "shop",
+		Region:         "us-central1",
+		Service:        "web",
+		Revision:       "web-00007",
+		TrafficPercent: 100,
+		Digests: map[string]string{
+			"gcr.io/shop/web@sha256:" + webDigest: webDigest,
+		},
+		StartedAt: 1790757000,
+		Containers: []*CloudRunContainerData{
+			{Image: "gcr.io/shop/web@sha256:" + webDigest, Digest: webDigest, DigestSource: digest.DigestSourceObserved},
+		},
+	}
+
+	for _, t := range []struct {
+		name    string
+		regions []string
+		filter  *filters.ResourceFilterOptions
+		want    []*CloudRunRevisionData
+	}{
+		{
+			name:    "revisions receiving traffic are reported in the given regions",
+			regions: []string{"europe-west1", "us-central1"},
+			filter:  &filters.ResourceFilterOptions{},
+			want:    append(payments, web),
+		},
+		{
+			name:   "services can be selected by name",
+			filter: &filters.ResourceFilterOptions{IncludeNames: []string{"web"}},
+			want:   []*CloudRunRevisionData{web},
+		},
+		{
+			name:   "services can be excluded with regex",
+			filter: &filters.ResourceFilterOptions{ExcludeNamesRegex: []string{"^we"}},
+			want:   payments,
+		},
+		{
+			name:    "only the services in the given regions are reported",
+			regions: []string{"europe-west1"},
+			filter:  &filters.ResourceFilterOptions{},
+			want:    payments,
+		},
+	} {
+		suite.Run(t.name, func() {
+			got, err := GetCloudRunData(suite.client, []string{"shop"}, t.regions, t.filter, logger.NewStandardLogger())
+			require.NoError(suite.T(), err)
+			require.ElementsMatch(suite.T(), t.want, got)
+		})
+	}
+}
+
+// The revisions are read from the v1 API endpoint of their region, also when all regions are listed
+func (suite *CloudRunTestSuite) TestGetCloudRunDataReadsRevisionsInTheirRegion() {
+	_, err := GetCloudRunData(suite.client, []string{"shop"}, []string{}, &filters.ResourceFilterOptions{}, logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	require.ElementsMatch(suite.T(), []string{"europe-west1", "europe-west1", "us-central1"}, suite.regions)
+}
+
+func (suite *CloudRunTestSuite) TestGetCloudRunDataFailsForUnknownProject() {
+	_, err := GetCloudRunData(suite.client, []string{"unknown"}, []string{"europe-west1"}, &filters.ResourceFilterOptions{},
+		logger.NewStandardLogger())
+	require.ErrorContains(suite.T(), err, "failed to list Cloud Run services in projects/unknown/locations/europe-west1")
+}
+
+func (suite *CloudRunTestSuite) TestImageRepository() {
+	for image, want := range map[string]string{
+		"europe-docker.pkg.dev/shop/apps/payments:latest": "europe-docker.pkg.dev/shop/apps/payments",
+		"gcr.io/shop/web@sha256:" + webDigest:             "gcr.io/shop/web",
+		"localhost:5000/web:v1@sha256:" + webDigest:       "localhost:5000/web",
+		"localhost:5000/web":                              "localhost:5000/web",
+		"europe-docker.pkg.dev/shop/apps/payments":        "europe-docker.pkg.dev/shop/apps/payments",
+	} {
+		require.Equal(suite.T(), want, imageRepository(image), image)
+	}
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestCloudRunTestSuite(t *testing.T) {
+	suite.Run(t, new(CloudRunTestSuite))
+}
@@ -0,0 +1,43 @@
+package kube
+
+import (
+	"fmt"
+
+	"github.com/kosli-dev/cli/internal/filters"
+)
+
+// ClustersSpec represents a mapping of kubeconfig contexts to the Kosli environments
+// their snapshots are reported to
+type ClustersSpec struct {
+	Version  int           `mapstructure:"version" validate:"required,oneof=1"`
+	Clusters []ClusterSpec `mapstructure:"clusters" validate:"required,min=1,unique=Context,dive"`
+}
+
+// ClusterSpec represents a single cluster (kubeconfig context) and the Kosli environment to report it to
+// the namespace fields are optional and, when none of them is set, the command-level namespace filters are used
+type ClusterSpec struct {
+	Context                string   `mapstructure:"context" validate:"required"`
+	Environment            string   `mapstructure:"environment" validate:"required"`
+	Namespaces             []string `mapstructure:"namespaces"`
+	NamespacesRegex        []string `mapstructure:"namespaces_regex"`
+	ExcludeNamespaces      []string `mapstructure:"exclude_namespaces"`
+	ExcludeNamespacesRegex []string `mapstructure:"exclude_namespaces_regex"`
+}
+
+// Filter returns the namespaces filter of a cluster, or defaultFilter if the cluster does not define one
+func (c *ClusterSpec) Filter(defaultFilter *filters.ResourceFilterOptions) (*filters.ResourceFilterOptions, error) {
+	if len(c.Namespaces) == 0 && len(c.NamespacesRegex) == 0 &&
+		len(c.ExcludeNamespaces) == 0 && len(c.ExcludeNamespacesRegex) == 0 {
+		return defaultFilter, nil
+	}
+	if (len(c.Namespaces) > 0 || len(c.NamespacesRegex) > 0) &&
+		(len(c.ExcludeNamespaces) > 0 || len(c.ExcludeNamespacesRegex) > 0) {
+		return nil, fmt.Errorf("cluster %s: only one of namespaces/namespaces_regex or exclude_namespaces/exclude_namespaces_regex is allowed", c.Context)
+	}
+	return &filters.ResourceFilterOptions{
+		IncludeNames:      c.Namespaces,
+		IncludeNamesRegex: c.NamespacesRegex,
+		ExcludeNames:      c.ExcludeNamespaces,
+		ExcludeNamesRegex: c.ExcludeNamespacesRegex,
+	}, nil
+}
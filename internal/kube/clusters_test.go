@@ -0,0 +1,125 @@
+package kube
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kosli-dev/cli/internal/filters"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type ClustersTestSuite struct {
+	suite.Suite
+}
+
+const twoContextsKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+  - name: cluster-a
+    cluster:
+      server: https://cluster-a.example.com:6443
+  - name: cluster-b
+    cluster:
+      server: https://cluster-b.example.com:6443
+contexts:
+  - name: context-a
+    context:
+      cluster: cluster-a
+      user: user-a
+  - name: context-b
+    context:
+      cluster: cluster-b
+      user: user-b
+current-context: context-a
+users:
+  - name: user-a
+    user:
+      token: token-a
+  - name: user-b
+    user:
+      token: token-b
+`
+
+func (suite *ClustersTestSuite) TestNewK8sClientSetForContext() {
+	kubeconfig := filepath.Join(suite.T().TempDir(), "config")
+	require.NoError(suite.T(), os.WriteFile(kubeconfig, []byte(twoContextsKubeconfig), 0600))
+
+	for _, t := range []struct {
+		name        string
+		contextName string
+		wantHost    string
+		wantError   string
+	}{
+		{
+			name:     "the current context is used when no context is given",
+			wantHost: "cluster-a.example.com:6443",
+		},
+		{
+			name:        "the given context is used",
+			contextName: "context-b",
+			wantHost:    "cluster-b.example.com:6443",
+		},
+		{
+			name:        "an unknown context fails",
+			contextName: "context-c",
+			wantError:   "could not build config for context context-c: context \"context-c\" does not exist ",
+		},
+	} {
+		suite.Run(t.name, func() {
+			clientset, err := NewK8sClientSetForContext(kubeconfig, t.contextName)
+			if t.wantError != "" {
+				require.EqualError(suite.T(), err, t.wantError)
+				return
+			}
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.wantHost, clientset.CoreV1().RESTClient().Get().URL().Host)
+		})
+	}
+}
+
+func (suite *ClustersTestSuite) TestClusterSpecFilter() {
+	defaultFilter := &filters.ResourceFilterOptions{ExcludeNames: []string{"kube-system"}}
+	for _, t := range []struct {
+		name      string
+		cluster   ClusterSpec
+		want      *filters.ResourceFilterOptions
+		wantError bool
+	}{
+		{
+			name:    "a cluster without namespaces uses the default filter",
+			cluster: ClusterSpec{Context: "prod"},
+			want:    defaultFilter,
+		},
+		{
+			name:    "a cluster with namespaces uses its own filter",
+			cluster: ClusterSpec{Context: "prod", Namespaces: []string{"ns1"}, NamespacesRegex: []string{"^team-"}},
+			want:    &filters.ResourceFilterOptions{IncludeNames: []string{"ns1"}, IncludeNamesRegex: []string{"^team-"}},
+		},
+		{
+			name:      "a cluster with both included and excluded namespaces fails",
+			cluster:   ClusterSpec{Context: "prod", NamespacesRegex: []string{"^team-"}, ExcludeNamespaces: []string{"ns1"}},
+			wantError: true,
+		},
+	} {
+		suite.Run(t.name, func() {
+			filter, err := t.cluster.Filter(defaultFilter)
+			if t.wantError {
+				require.Error(suite.T(), err)
+				return
+			}
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.want, filter)
+		})
+	}
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestClustersTestSuite(t *testing.T) {
+	suite.Run(t, new(ClustersTestSuite))
+}
@@ -2,9 +2,11 @@ package kube
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
+	"github.com/kosli-dev/cli/internal/digest"
 	"github.com/kosli-dev/cli/internal/filters"
 	"github.com/kosli-dev/cli/internal/logger"
 	corev1 "k8s.io/api/core/v1"
@@ -27,21 +29,85 @@ type PodData struct {
 	Digests           map[string]string       `json:"digests"`
 	CreationTimestamp int64                   `json:"creationTimestamp"`
 	Owners            []metav1.OwnerReference `json:"owners"`
+	Containers        []*ContainerData        `json:"containers"`
+	Workload          *Workload               `json:"workload,omitempty"`
+}
+
+// the roles a container can have in a pod
+const (
+	ContainerRoleApp       = "app"
+	ContainerRoleInit      = "init"
+	ContainerRoleEphemeral = "ephemeral"
+)
+
+// ContainerData represents the harvested data of a single container in a pod
+type ContainerData struct {
+	Name             string `json:"name"`
+	Image            string `json:"image"`
+	Digest           string `json:"digest"`
+	Role             string `json:"role"`
+	DigestSource     string `json:"digestSource"`
+	UnresolvedReason string `json:"unresolvedReason,omitempty"`
+}
+
+// UnresolvedImage returns the image of the container if its digest is unresolved
+func (c *ContainerData) UnresolvedImage() string {
+	if c.DigestSource != digest.DigestSourceUnresolved {
+		return ""
+	}
+	return c.Image
+}
+
+// SetResolvedDigest records the digest of the container image resolved from its registry
+func (c *ContainerData) SetResolvedDigest(sha256 string) {
+	c.Digest = sha256
+	c.DigestSource = digest.DigestSourceResolved
+	c.UnresolvedReason = ""
+}
+
+// AddUnresolvedReason adds to the reason why the digest of the container image is unresolved
+func (c *ContainerData) AddUnresolvedReason(reason string) {
+	c.UnresolvedReason = digest.AppendUnresolvedReason(c.UnresolvedReason, reason)
 }
 
 type K8SConnection struct {
 	*kubernetes.Clientset
+	// DigestResolver, if set, is used to look up the digests of containers
+	// whose digest cannot be found in the pod status
+	DigestResolver digest.DigestResolver
 }
 
 // NewPodData creates a PodData object from a k8s pod
 func NewPodData(pod *corev1.Pod) *PodData {
 	digests := make(map[string]string)
 
+	containers := []*ContainerData{}
+
 	creationTimestamp := pod.GetObjectMeta().GetCreationTimestamp()
 	owners := pod.GetObjectMeta().GetOwnerReferences()
-	containers := pod.Status.ContainerStatuses
-	for _, cs := range containers {
-		digests[cs.Image] = cs.ImageID[len(cs.ImageID)-64:]
+	statuses := map[string][]corev1.ContainerStatus{
+		ContainerRoleInit:      pod.Status.InitContainerStatuses,
+		ContainerRoleApp:       pod.Status.ContainerStatuses,
+		ContainerRoleEphemeral: pod.Status.EphemeralContainerStatuses,
+	}
+	for _, role := range []string{ContainerRoleInit, ContainerRoleApp, ContainerRoleEphemeral} {
+		for _, cs := range statuses[role] {
+			container := &ContainerData{
+				Name:  cs.Name,
+				Image: cs.Image,
+				Role:  role,
+			}
+			sha256, err := containerDigest(cs)
+			if err != nil {
+				container.DigestSource = digest.DigestSourceUnresolved
+				container.UnresolvedReason = err.Error()
+			} else {
+				container.Digest = sha256
+				container.DigestSource = digest.DigestSourceObserved
+				digests[cs.Image] = sha256
+			}
+			containers = append(containers, container)
+		}
 	}
 
 	return &PodData{
@@ -50,17 +116,49 @@ func NewPodData(pod *corev1.Pod) *PodData {
 		Digests:           digests,
 		CreationTimestamp: creationTimestamp.Unix(),
 		Owners:            owners,
+		Containers:        containers,
+		Workload:          controllerWorkload(pod),
+	}
+}
+
+// containerDigest returns the registry digest of the image a container runs.
+// The digest is taken from the image ID reported by the container runtime and,
+// failing that, from a digest pinned in the image reference of the container.
+func containerDigest(cs corev1.ContainerStatus) (string, error) {
+	sha256, err := digest.ImageReferenceDigest(cs.ImageID)
+	if err == nil {
+		return sha256, nil
+	}
+	if pinned, pinnedErr := digest.ImageReferenceDigest(cs.Image); pinnedErr == nil {
+		return pinned, nil
+	}
+	if errors.Is(err, digest.ErrEmptyImageReference) {
+		return "", fmt.Errorf("the container has no image ID (it has not been started yet)")
 	}
+	return "", fmt.Errorf("could not get a digest from image ID %q: %v", cs.ImageID, err)
 }
 
 // NewK8sClientSet creates a k8s clientset
 // if the kubeconfigPath is empty, it attempts to get an in-cluster client
 func NewK8sClientSet(kubeconfigPath string) (*K8SConnection, error) {
+	return NewK8sClientSetForContext(kubeconfigPath, "")
+}
+
+// NewK8sClientSetForContext creates a k8s clientset for a given context in a kubeconfig file
+// if the context name is empty, the current context of the kubeconfig is used
+// if both the kubeconfigPath and the context name are empty, it attempts to get an in-cluster client
+func NewK8sClientSetForContext(kubeconfigPath, contextName string) (*K8SConnection, error) {
 	var config *rest.Config
 	var err error
-	if kubeconfigPath != "" {
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	if kubeconfigPath != "" || contextName != "" {
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = kubeconfigPath
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
 		if err != nil {
+			if contextName != "" {
+				return nil, fmt.Errorf("could not build config for context %s: %v ", contextName, err)
+			}
 			return nil, fmt.Errorf("could not build config from flags: %v ", err)
 		}
 	} else {
@@ -76,7 +174,7 @@ func NewK8sClientSet(kubeconfigPath string) (*K8SConnection, error) {
 		return nil, err
 	}
 
-	return &K8SConnection{clientset}, nil
+	return &K8SConnection{Clientset: clientset}, nil
 }
 
 // GetPodsData lists pods in the target namespace(s) of a target cluster and creates a list of
@@ -94,7 +192,7 @@ func (clientset *K8SConnection) GetPodsData(filter *filters.ResourceFilterOption
 		if err != nil {
 			return podsData, fmt.Errorf("could not list pods on cluster scope: %v ", err)
 		}
-		return processPods(list), nil
+		return processPods(list, NewWorkloadResolver(clientset), clientset.DigestResolver), nil
 	} else {
 		list := &corev1.PodList{}
 		filteredNamespaces, err := clientset.filterNamespaces(filter)
@@ -143,17 +241,20 @@ func (clientset *K8SConnection) GetPodsData(filter *filters.ResourceFilterOption
 			return podsData, <-errs
 		}
 
-		return processPods(list), nil
+		return processPods(list, NewWorkloadResolver(clientset), clientset.DigestResolver), nil
 	}
 }
 
 // processPods returns podData list for a list of Pods
-func processPods(list *corev1.PodList) []*PodData {
+// the top-level workload of each pod is resolved using the given workload resolver
+// and, if a digest resolver is given, it is used to look up the missing container digests
+func processPods(list *corev1.PodList, resolver *WorkloadResolver, digestResolver digest.DigestResolver) []*PodData {
 	podsData := []*PodData{}
 	var (
 		wg    sync.WaitGroup
 		mutex = &sync.Mutex{}
 	)
+	registry := digest.NewDigestCache(digestResolver)
 	for _, pod := range list.Items {
 		wg.Add(1)
 		go func(pod corev1.Pod) {
@@ -161,6 +262,8 @@ func processPods(list *corev1.PodList) []*PodData {
 			// only report running or failed pods
 			if pod.Status.Phase == corev1.PodRunning || pod.Status.Phase == corev1.PodFailed {
 				data := NewPodData(&pod)
+				data.Workload = resolver.Resolve(&pod)
+				digest.ResolveMissingDigests(registry, data.Containers, data.Digests)
 				mutex.Lock()
 				podsData = append(podsData, data)
 				mutex.Unlock()
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/kosli-dev/cli/internal/digest"
 	"github.com/kosli-dev/cli/internal/filters"
 	"github.com/kosli-dev/cli/internal/logger"
 	"github.com/stretchr/testify/require"
@@ -284,6 +286,161 @@ func (suite *KubeTestSuite) createPod(namespace string, pod *corev1.Pod) {
 	require.NoErrorf(suite.T(), err, "error waiting for pod %s to be running in namespace %s", pod.Name, namespace)
 }
 
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type PodDataTestSuite struct {
+	suite.Suite
+}
+
+func (suite *PodDataTestSuite) TestNewPodData() {
+	nginxDigest := "644a70516a26004c97d0d85c7fe1d0c3a67ea8ab7ddf4aff193d9f301670cf36"
+	busyboxDigest := "2f1cd90e00fe2c991e18272bb35d6a8258eeb27785d121aa4cc1ae4235167cfd"
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web-7d4b9c-abcde",
+			Namespace: "ns1",
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: "web-7d4b9c", Controller: ptr(true)},
+			},
+		},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "init", Image: "busybox:1.36", ImageID: "docker.io/library/busybox@sha256:" + busyboxDigest},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "web", Image: "nginx:1.21.3", ImageID: "docker.io/library/nginx@sha256:" + nginxDigest},
+			},
+			EphemeralContainerStatuses: []corev1.ContainerStatus{
+				{Name: "debugger", Image: "busybox:1.36", ImageID: "docker.io/library/busybox@sha256:" + busyboxDigest},
+				{Name: "not-started", Image: "alpine:3.19"},
+			},
+		},
+	}
+
+	data := NewPodData(pod)
+	require.Equal(suite.T(), map[string]string{
+		"busybox:1.36": busyboxDigest,
+		"nginx:1.21.3": nginxDigest,
+	}, data.Digests)
+	require.Equal(suite.T(), []*ContainerData{
+		{Name: "init", Image: "busybox:1.36", Digest: busyboxDigest, Role: ContainerRoleInit, DigestSource: digest.DigestSourceObserved},
+		{Name: "web", Image: "nginx:1.21.3", Digest: nginxDigest, Role: ContainerRoleApp, DigestSource: digest.DigestSourceObserved},
+		{Name: "debugger", Image: "busybox:1.36", Digest: busyboxDigest, Role: ContainerRoleEphemeral, DigestSource: digest.DigestSourceObserved},
+		{Name: "not-started", Image: "alpine:3.19", Role: ContainerRoleEphemeral, DigestSource: digest.DigestSourceUnresolved,
+			UnresolvedReason: "the container has no image ID (it has not been started yet)"},
+	}, data.Containers)
+	require.Equal(suite.T(), &Workload{Kind: "ReplicaSet", Name: "web-7d4b9c"}, data.Workload)
+}
+
+func (suite *PodDataTestSuite) TestNewPodDataWithoutRepoDigests() {
+	nginxDigest := "644a70516a26004c97d0d85c7fe1d0c3a67ea8ab7ddf4aff193d9f301670cf36"
+	for _, t := range []struct {
+		name       string
+		status     corev1.ContainerStatus
+		wantDigest string
+		wantSource string
+		wantReason string
+	}{
+		{
+			name:       "a docker-pullable image ID is observed",
+			status:     corev1.ContainerStatus{Name: "c", Image: "nginx:1.21.3", ImageID: "docker-pullable://nginx@sha256:" + nginxDigest},
+			wantDigest: nginxDigest,
+			wantSource: digest.DigestSourceObserved,
+		},
+		{
+			name:       "a digest pinned in the image is used when the image ID is a local image ID",
+			status:     corev1.ContainerStatus{Name: "c", Image: "nginx@sha256:" + nginxDigest, ImageID: "docker://sha256:" + nginxDigest},
+			wantDigest: nginxDigest,
+			wantSource: digest.DigestSourceObserved,
+		},
+		{
+			name:       "a local image ID is unresolved",
+			status:     corev1.ContainerStatus{Name: "c", Image: "nginx:1.21.3", ImageID: "docker://sha256:" + nginxDigest},
+			wantSource: digest.DigestSourceUnresolved,
+			wantReason: "could not get a digest from image ID \"docker://sha256:" + nginxDigest + "\": the image ID is a local image ID, not a registry digest",
+		},
+		{
+			name:       "a short image ID is unresolved",
+			status:     corev1.ContainerStatus{Name: "c", Image: "nginx:1.21.3", ImageID: "abc"},
+			wantSource: digest.DigestSourceUnresolved,
+			wantReason: "could not get a digest from image ID \"abc\": the image reference does not contain a digest",
+		},
+		{
+			name:       "a non-sha256 image ID is unresolved",
+			status:     corev1.ContainerStatus{Name: "c", Image: "nginx:1.21.3", ImageID: "nginx@sha512:" + nginxDigest + nginxDigest},
+			wantSource: digest.DigestSourceUnresolved,
+			wantReason: "could not get a digest from image ID \"nginx@sha512:" + nginxDigest + nginxDigest +
+				"\": unsupported digest algorithm \"sha512\" in image reference nginx@sha512:" + nginxDigest + nginxDigest,
+		},
+	} {
+		suite.Run(t.name, func() {
+			pod := &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{t.status}}}
+			data := NewPodData(pod)
+			require.Len(suite.T(), data.Containers, 1)
+			container := data.Containers[0]
+			require.Equal(suite.T(), t.wantDigest, container.Digest)
+			require.Equal(suite.T(), t.wantSource, container.DigestSource)
+			require.Equal(suite.T(), t.wantReason, container.UnresolvedReason)
+			if t.wantDigest == "" {
+				require.Empty(suite.T(), data.Digests)
+			} else {
+				require.Equal(suite.T(), map[string]string{t.status.Image: t.wantDigest}, data.Digests)
+			}
+		})
+	}
+}
+
+func (suite *PodDataTestSuite) TestProcessPodsResolvesMissingDigests() {
+	nginxDigest := "644a70516a26004c97d0d85c7fe1d0c3a67ea8ab7ddf4aff193d9f301670cf36"
+	lookups := 0
+	var mutex sync.Mutex
+	resolver := func(image string) (string, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		lookups++
+		if image == "nginx:1.21.3" {
+			return nginxDigest, nil
+		}
+		return "", fmt.Errorf("manifest unknown")
+	}
+	newPod := func(name, image string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "c", Image: image, ImageID: "docker://sha256:" + nginxDigest}},
+			},
+		}
+	}
+	list := &corev1.PodList{Items: []corev1.Pod{
+		newPod("web-1", "nginx:1.21.3"),
+		newPod("web-2", "nginx:1.21.3"),
+		newPod("other", "private/app:1.0"),
+	}}
+
+	podsData := processPods(list, nil, resolver)
+	require.Len(suite.T(), podsData, 3)
+	require.Equal(suite.T(), 2, lookups, "each image should be looked up once")
+	for _, data := range podsData {
+		container := data.Containers[0]
+		if container.Image == "nginx:1.21.3" {
+			require.Equal(suite.T(), digest.DigestSourceResolved, container.DigestSource)
+			require.Equal(suite.T(), nginxDigest, container.Digest)
+			require.Empty(suite.T(), container.UnresolvedReason)
+			require.Equal(suite.T(), map[string]string{"nginx:1.21.3": nginxDigest}, data.Digests)
+		} else {
+			require.Equal(suite.T(), digest.DigestSourceUnresolved, container.DigestSource)
+			require.Contains(suite.T(), container.UnresolvedReason, "could not resolve the digest from the registry: manifest unknown")
+			require.Empty(suite.T(), data.Digests)
+		}
+	}
+}
+
+func TestPodDataTestSuite(t *testing.T) {
+	suite.Run(t, new(PodDataTestSuite))
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestKubeTestSuite(t *testing.T) {
@@ -0,0 +1,191 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/filters"
+	"github.com/kosli-dev/cli/internal/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PodWatcher watches pods using shared informers and reports the harvested
+// pods data whenever the set of running image digests changes
+type PodWatcher struct {
+	// DigestResolver, if set, is used to look up the digests of containers
+	// whose digest cannot be found in the pod status
+	DigestResolver digest.DigestResolver
+	client         kubernetes.Interface
+	filter         *filters.ResourceFilterOptions
+	debounce       time.Duration
+	maxDelay       time.Duration
+	resync         time.Duration
+	logger         *logger.Logger
+	resolver       *WorkloadResolver
+	informers      []cache.SharedIndexInformer
+}
+
+// ReportFunc is called by the PodWatcher with the current pods data
+type ReportFunc func(podsData []*PodData) error
+
+// NewPodWatcher creates a PodWatcher.
+// debounce is how long to wait after the last pod change event before checking for changes.
+// maxDelay is the longest time to wait after the first pod change event, so that continuous
+// pod changes do not delay reporting until the next resync.
+// resync is how often the pods data is reported regardless of changes.
+func NewPodWatcher(client kubernetes.Interface, filter *filters.ResourceFilterOptions, debounce, maxDelay, resync time.Duration, logger *logger.Logger) *PodWatcher {
+	return &PodWatcher{
+		client:   client,
+		filter:   filter,
+		debounce: debounce,
+		maxDelay: maxDelay,
+		resync:   resync,
+		logger:   logger,
+		resolver: NewWorkloadResolver(client),
+	}
+}
+
+// Run starts the pod informers and blocks until the context is cancelled.
+// Reporting errors are logged and retried on the next change or resync.
+func (w *PodWatcher) Run(ctx context.Context, report ReportFunc) error {
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	}
+
+	factories := w.newInformerFactories()
+	for _, factory := range factories {
+		informer := factory.Core().V1().Pods().Informer()
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return fmt.Errorf("could not watch pods: %v", err)
+		}
+		w.informers = append(w.informers, informer)
+	}
+	for _, factory := range factories {
+		factory.Start(ctx.Done())
+	}
+	for _, factory := range factories {
+		for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+			if !synced {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("could not sync the %v informer cache", informerType)
+			}
+		}
+	}
+	w.logger.Info("watching pods for changes")
+
+	lastReported, ok := w.report(report, "")
+	resyncTicker := time.NewTicker(w.resync)
+	defer resyncTicker.Stop()
+	// the debounce timer is reset by every change, the max delay timer only by the first
+	// change since the last check
+	var debounceTimer, maxDelayTimer <-chan time.Time
+	checkChanges := func() {
+		debounceTimer, maxDelayTimer = nil, nil
+		if !ok || w.digestsKey(w.podsData()) != lastReported {
+			lastReported, ok = w.report(report, lastReported)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("stopped watching pods")
+			return nil
+		case <-changes:
+			debounceTimer = time.After(w.debounce)
+			if maxDelayTimer == nil {
+				maxDelayTimer = time.After(w.maxDelay)
+			}
+		case <-debounceTimer:
+			checkChanges()
+		case <-maxDelayTimer:
+			checkChanges()
+		case <-resyncTicker.C:
+			// every rollout adds ReplicaSets (and every CronJob run adds Jobs) to the cache
+			w.resolver.Reset()
+			lastReported, ok = w.report(report, lastReported)
+		}
+	}
+}
+
+// report calls the report function with the current pods data and returns
+// the digests key of what was reported, or the previous one if reporting failed
+func (w *PodWatcher) report(report ReportFunc, previous string) (string, bool) {
+	podsData := w.podsData()
+	if err := report(podsData); err != nil {
+		w.logger.Warning("failed to report pods data: %v", err)
+		return previous, false
+	}
+	return w.digestsKey(podsData), true
+}
+
+// newInformerFactories creates one shared informer factory per namespace when the
+// namespaces are given by name (which does not require cluster-wide permissions),
+// otherwise a single cluster-wide factory is created and pods are filtered by namespace
+func (w *PodWatcher) newInformerFactories() []informers.SharedInformerFactory {
+	factories := []informers.SharedInformerFactory{}
+	if len(w.filter.IncludeNames) > 0 && len(w.filter.IncludeNamesRegex) == 0 &&
+		len(w.filter.ExcludeNames) == 0 && len(w.filter.ExcludeNamesRegex) == 0 {
+		for _, ns := range w.filter.IncludeNames {
+			factories = append(factories, informers.NewSharedInformerFactoryWithOptions(w.client, 0, informers.WithNamespace(ns)))
+		}
+		return factories
+	}
+	return append(factories, informers.NewSharedInformerFactory(w.client, 0))
+}
+
+// podsData returns the PodData of the pods currently in the informers caches
+func (w *PodWatcher) podsData() []*PodData {
+	list := &corev1.PodList{}
+	for _, informer := range w.informers {
+		for _, obj := range informer.GetStore().List() {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			include, err := w.filter.ShouldInclude(pod.Namespace)
+			if err != nil {
+				w.logger.Warning("could not filter namespace %s: %v", pod.Namespace, err)
+				continue
+			}
+			if include {
+				list.Items = append(list.Items, *pod)
+			}
+		}
+	}
+	return processPods(list, w.resolver, w.DigestResolver)
+}
+
+// digestsKey returns a string that uniquely identifies the set of image digests in a list of PodData
+func (w *PodWatcher) digestsKey(podsData []*PodData) string {
+	set := make(map[string]struct{})
+	for _, pod := range podsData {
+		for image, digest := range pod.Digests {
+			set[image+"@"+digest] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,189 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kosli-dev/cli/internal/filters"
+	"github.com/kosli-dev/cli/internal/logger"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type PodWatcherTestSuite struct {
+	suite.Suite
+}
+
+func (suite *PodWatcherTestSuite) TestRunReportsOnlyWhenDigestsChange() {
+	client := fake.NewClientset(
+		runningPod("ns1", "pod1", "nginx:1.21.3", "644a70516a26004c97d0d85c7fe1d0c3a67ea8ab7ddf4aff193d9f301670cf36"),
+	)
+	reports := make(chan []*PodData, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	watcher := NewPodWatcher(client, &filters.ResourceFilterOptions{}, 50*time.Millisecond, time.Second, time.Hour, logger.NewStandardLogger())
+	go func() {
+		done <- watcher.Run(ctx, func(podsData []*PodData) error {
+			reports <- podsData
+			return nil
+		})
+	}()
+
+	// initial report after the caches are synced
+	require.Len(suite.T(), suite.nextReport(reports), 1)
+
+	// scaling up with the same image does not change the digests
+	suite.createPod(client, runningPod("ns1", "pod2", "nginx:1.21.3", "644a70516a26004c97d0d85c7fe1d0c3a67ea8ab7ddf4aff193d9f301670cf36"))
+	suite.noReport(reports)
+
+	// a new image digest is reported
+	suite.createPod(client, runningPod("ns2", "pod3", "nginx:1.21.0", "2f1cd90e00fe2c991e18272bb35d6a8258eeb27785d121aa4cc1ae4235167cfd"))
+	require.Len(suite.T(), suite.nextReport(reports), 3)
+
+	cancel()
+	require.NoError(suite.T(), <-done)
+}
+
+func (suite *PodWatcherTestSuite) TestRunReportsContinuousChangesAfterMaxDelay() {
+	client := fake.NewClientset(
+		runningPod("ns1", "pod1", "nginx:1.21.3", "644a70516a26004c97d0d85c7fe1d0c3a67ea8ab7ddf4aff193d9f301670cf36"),
+	)
+	reports := make(chan []*PodData, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher := NewPodWatcher(client, &filters.ResourceFilterOptions{}, time.Second, 200*time.Millisecond, time.Hour, logger.NewStandardLogger())
+	go func() {
+		_ = watcher.Run(ctx, func(podsData []*PodData) error {
+			reports <- podsData
+			return nil
+		})
+	}()
+	require.Len(suite.T(), suite.nextReport(reports), 1)
+
+	// pods keep changing more often than the debounce
+	churnCtx, stopChurn := context.WithCancel(ctx)
+	defer stopChurn()
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			select {
+			case <-churnCtx.Done():
+				return
+			case <-ticker.C:
+				_, _ = client.CoreV1().Pods("ns1").Create(context.Background(),
+					runningPod("ns1", fmt.Sprintf("churn-%d", i), "nginx:1.21.0", "2f1cd90e00fe2c991e18272bb35d6a8258eeb27785d121aa4cc1ae4235167cfd"),
+					metav1.CreateOptions{})
+			}
+		}
+	}()
+
+	select {
+	case podsData := <-reports:
+		require.Greater(suite.T(), len(podsData), 1)
+	case <-time.After(800 * time.Millisecond):
+		suite.T().Fatal("the changes were not reported after the max delay")
+	}
+}
+
+func (suite *PodWatcherTestSuite) TestRunRespectsNamespaceFilters() {
+	client := fake.NewClientset(
+		runningPod("ns1", "pod1", "nginx:1.21.3", "644a70516a26004c97d0d85c7fe1d0c3a67ea8ab7ddf4aff193d9f301670cf36"),
+		runningPod("ns2", "pod2", "nginx:1.21.0", "2f1cd90e00fe2c991e18272bb35d6a8258eeb27785d121aa4cc1ae4235167cfd"),
+	)
+
+	for _, t := range []struct {
+		name      string
+		filter    *filters.ResourceFilterOptions
+		wantNames []string
+	}{
+		{
+			name:      "included namespaces are watched",
+			filter:    &filters.ResourceFilterOptions{IncludeNames: []string{"ns2"}},
+			wantNames: []string{"pod2"},
+		},
+		{
+			name:      "excluded namespaces are not reported",
+			filter:    &filters.ResourceFilterOptions{ExcludeNamesRegex: []string{"^ns2$"}},
+			wantNames: []string{"pod1"},
+		},
+	} {
+		suite.Run(t.name, func() {
+			reports := make(chan []*PodData, 10)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			watcher := NewPodWatcher(client, t.filter, 10*time.Millisecond, time.Second, time.Hour, logger.NewStandardLogger())
+			go func() {
+				_ = watcher.Run(ctx, func(podsData []*PodData) error {
+					reports <- podsData
+					return nil
+				})
+			}()
+
+			names := []string{}
+			for _, pod := range suite.nextReport(reports) {
+				names = append(names, pod.PodName)
+			}
+			require.ElementsMatch(suite.T(), t.wantNames, names)
+		})
+	}
+}
+
+func (suite *PodWatcherTestSuite) nextReport(reports chan []*PodData) []*PodData {
+	select {
+	case podsData := <-reports:
+		return podsData
+	case <-time.After(5 * time.Second):
+		suite.T().Fatal("timed out waiting for a report")
+	}
+	return nil
+}
+
+func (suite *PodWatcherTestSuite) noReport(reports chan []*PodData) {
+	select {
+	case podsData := <-reports:
+		suite.T().Fatalf("unexpected report: %v", podsData)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func (suite *PodWatcherTestSuite) createPod(client *fake.Clientset, pod *corev1.Pod) {
+	_, err := client.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+	require.NoError(suite.T(), err)
+}
+
+// runningPod creates a running pod with a single container
+func runningPod(namespace, name, image, digest string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:    "container-0",
+					Image:   image,
+					ImageID: "docker.io/library/nginx@sha256:" + digest,
+				},
+			},
+		},
+	}
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestPodWatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(PodWatcherTestSuite))
+}
@@ -0,0 +1,91 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Workload represents the top-level controller (e.g. Deployment, StatefulSet, DaemonSet or CronJob) running a pod
+type Workload struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// WorkloadResolver resolves the top-level workload of pods by following
+// their ReplicaSet and Job owners. Resolved owners are cached until Reset is called.
+type WorkloadResolver struct {
+	client kubernetes.Interface
+	mutex  sync.Mutex
+	cache  map[string]*Workload
+}
+
+// NewWorkloadResolver creates a WorkloadResolver
+func NewWorkloadResolver(client kubernetes.Interface) *WorkloadResolver {
+	return &WorkloadResolver{
+		client: client,
+		cache:  make(map[string]*Workload),
+	}
+}
+
+// Reset clears the cache of resolved owners. Long-running users (e.g. the PodWatcher)
+// call it regularly so that the owners of old ReplicaSets and Jobs do not pile up.
+func (r *WorkloadResolver) Reset() {
+	r.mutex.Lock()
+	r.cache = make(map[string]*Workload)
+	r.mutex.Unlock()
+}
+
+// controllerWorkload returns the immediate controller of a pod as a Workload
+// or nil if the pod has no controller
+func controllerWorkload(pod *corev1.Pod) *Workload {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return nil
+	}
+	return &Workload{Kind: owner.Kind, Name: owner.Name}
+}
+
+// Resolve returns the top-level workload of a pod.
+// Pods owned by a ReplicaSet resolve to its Deployment and pods owned by a Job resolve to its CronJob.
+// If an owner cannot be looked up (e.g. due to missing permissions), the immediate controller is returned.
+func (r *WorkloadResolver) Resolve(pod *corev1.Pod) *Workload {
+	owner := controllerWorkload(pod)
+	if r == nil || owner == nil || (owner.Kind != "ReplicaSet" && owner.Kind != "Job") {
+		return owner
+	}
+
+	key := fmt.Sprintf("%s/%s/%s", pod.Namespace, owner.Kind, owner.Name)
+	r.mutex.Lock()
+	cached, ok := r.cache[key]
+	r.mutex.Unlock()
+	if ok {
+		return cached
+	}
+
+	var object metav1.Object
+	var err error
+	ctx := context.Background()
+	if owner.Kind == "ReplicaSet" {
+		object, err = r.client.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+	} else {
+		object, err = r.client.BatchV1().Jobs(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		// not cached so that the lookup is retried next time
+		return owner
+	}
+
+	workload := owner
+	if parent := metav1.GetControllerOf(object); parent != nil {
+		workload = &Workload{Kind: parent.Kind, Name: parent.Name}
+	}
+	r.mutex.Lock()
+	r.cache[key] = workload
+	r.mutex.Unlock()
+	return workload
+}
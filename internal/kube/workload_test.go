@@ -0,0 +1,119 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type WorkloadResolverTestSuite struct {
+	suite.Suite
+}
+
+func (suite *WorkloadResolverTestSuite) TestResolve() {
+	client := fake.NewClientset(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-7d4b9c", Namespace: "ns1",
+			OwnerReferences: []metav1.OwnerReference{controllerRef("Deployment", "web")},
+		}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "orphan-rs", Namespace: "ns1"}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name: "backup-28812345", Namespace: "ns1",
+			OwnerReferences: []metav1.OwnerReference{controllerRef("CronJob", "backup")},
+		}},
+	)
+	resolver := NewWorkloadResolver(client)
+
+	for _, t := range []struct {
+		name  string
+		owner *metav1.OwnerReference
+		want  *Workload
+	}{
+		{
+			name: "a bare pod has no workload",
+			want: nil,
+		},
+		{
+			name:  "a ReplicaSet pod resolves to its Deployment",
+			owner: ptr(controllerRef("ReplicaSet", "web-7d4b9c")),
+			want:  &Workload{Kind: "Deployment", Name: "web"},
+		},
+		{
+			name:  "a ReplicaSet pod without a Deployment resolves to the ReplicaSet",
+			owner: ptr(controllerRef("ReplicaSet", "orphan-rs")),
+			want:  &Workload{Kind: "ReplicaSet", Name: "orphan-rs"},
+		},
+		{
+			name:  "a ReplicaSet pod whose ReplicaSet cannot be found resolves to the ReplicaSet",
+			owner: ptr(controllerRef("ReplicaSet", "missing-rs")),
+			want:  &Workload{Kind: "ReplicaSet", Name: "missing-rs"},
+		},
+		{
+			name:  "a Job pod resolves to its CronJob",
+			owner: ptr(controllerRef("Job", "backup-28812345")),
+			want:  &Workload{Kind: "CronJob", Name: "backup"},
+		},
+		{
+			name:  "a StatefulSet pod resolves to the StatefulSet",
+			owner: ptr(controllerRef("StatefulSet", "db")),
+			want:  &Workload{Kind: "StatefulSet", Name: "db"},
+		},
+		{
+			name:  "a DaemonSet pod resolves to the DaemonSet",
+			owner: ptr(controllerRef("DaemonSet", "agent")),
+			want:  &Workload{Kind: "DaemonSet", Name: "agent"},
+		},
+	} {
+		suite.Run(t.name, func() {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns1"}}
+			if t.owner != nil {
+				pod.OwnerReferences = []metav1.OwnerReference{*t.owner}
+			}
+			require.Equal(suite.T(), t.want, resolver.Resolve(pod))
+		})
+	}
+}
+
+func (suite *WorkloadResolverTestSuite) TestReset() {
+	client := fake.NewClientset(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-7d4b9c", Namespace: "ns1",
+			OwnerReferences: []metav1.OwnerReference{controllerRef("Deployment", "web")},
+		}},
+	)
+	resolver := NewWorkloadResolver(client)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "pod", Namespace: "ns1",
+		OwnerReferences: []metav1.OwnerReference{controllerRef("ReplicaSet", "web-7d4b9c")},
+	}}
+
+	require.Equal(suite.T(), &Workload{Kind: "Deployment", Name: "web"}, resolver.Resolve(pod))
+	require.Len(suite.T(), resolver.cache, 1)
+
+	resolver.Reset()
+	require.Len(suite.T(), resolver.cache, 0)
+	require.Equal(suite.T(), &Workload{Kind: "Deployment", Name: "web"}, resolver.Resolve(pod))
+}
+
+func controllerRef(kind, name string) metav1.OwnerReference {
+	return metav1.OwnerReference{Kind: kind, Name: name, Controller: ptr(true)}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestWorkloadResolverTestSuite(t *testing.T) {
+	suite.Run(t, new(WorkloadResolverTestSuite))
+}
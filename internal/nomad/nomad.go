@@ -0,0 +1,413 @@
+package nomad
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/filters"
+	"github.com/kosli-dev/cli/internal/logger"
+)
+
+// DefaultAddress is the address of the Nomad HTTP API of a local agent
+const DefaultAddress = "http://127.0.0.1:4646"
+
+// allocationsPageSize is the number of allocations requested per page
+const allocationsPageSize = 500
+
+// the task drivers running container images
+var imageDrivers = map[string]bool{
+	"docker": true,
+	"podman": true,
+}
+
+// NomadEnvRequest represents the PUT request body to be sent to kosli from Nomad
+type NomadEnvRequest struct {
+	Artifacts []*AllocationData `json:"artifacts"`
+}
+
+// AllocationData represents the harvested data of a running Nomad allocation
+type AllocationData struct {
+	AllocationID string            `json:"allocationId"`
+	Namespace    string            `json:"namespace"`
+	Job          string            `json:"job"`
+	JobVersion   uint64            `json:"jobVersion"`
+	TaskGroup    string            `json:"taskGroup"`
+	Node         string            `json:"node,omitempty"`
+	Digests      map[string]string `json:"digests"`
+	Tasks        []*TaskData       `json:"tasks"`
+	StartedAt    int64             `json:"creationTimestamp"`
+}
+
+// the kinds of artifacts a task can run
+const (
+	TaskArtifactImage = "image"
+	TaskArtifactFile  = "artifact"
+)
+
+// TaskData represents the harvested data of a single artifact (container image or
+// downloaded artifact) of a task in an allocation
+type TaskData struct {
+	Name             string `json:"name"`
+	Driver           string `json:"driver"`
+	Kind             string `json:"kind"`
+	Artifact         string `json:"artifact"`
+	Digest           string `json:"digest"`
+	DigestSource     string `json:"digestSource"`
+	UnresolvedReason string `json:"unresolvedReason,omitempty"`
+}
+
+// UnresolvedImage returns the image of the task if its digest is unresolved
+func (t *TaskData) UnresolvedImage() string {
+	if t.Kind != TaskArtifactImage || t.DigestSource != digest.DigestSourceUnresolved {
+		return ""
+	}
+	return t.Artifact
+}
+
+// SetResolvedDigest records the digest of the task image resolved from its registry
+func (t *TaskData) SetResolvedDigest(sha256 string) {
+	t.Digest = sha256
+	t.DigestSource = digest.DigestSourceResolved
+	t.UnresolvedReason = ""
+}
+
+// AddUnresolvedReason adds to the reason why the digest of the task image is unresolved
+func (t *TaskData) AddUnresolvedReason(reason string) {
+	t.UnresolvedReason = digest.AppendUnresolvedReason(t.UnresolvedReason, reason)
+}
+
+// NomadClient is a client of the Nomad HTTP API
+type NomadClient struct {
+	Address    string
+	Token      string
+	HTTPClient *http.Client
+	// DigestResolver, if set, is used to look up the digests of
+	// images referenced by tag only in the task configs
+	DigestResolver digest.DigestResolver
+}
+
+// TLSFiles are the PEM files used to connect to the Nomad HTTP API over TLS
+type TLSFiles struct {
+	// CACert is the CA certificate used to verify the Nomad server certificate
+	CACert string
+	// ClientCert and ClientKey are the client certificate and key used when the
+	// Nomad agent verifies HTTPS clients
+	ClientCert string
+	ClientKey  string
+}
+
+// NewNomadClient returns a new Nomad HTTP API client.
+// If address is empty, the address of the local agent is used.
+func NewNomadClient(address, token string, tlsFiles *TLSFiles) (*NomadClient, error) {
+	if address == "" {
+		address = DefaultAddress
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsFiles != nil {
+		tlsConfig, err := tlsFiles.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &NomadClient{
+		Address:    strings.TrimSuffix(address, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 60 * time.Second, Transport: transport},
+	}, nil
+}
+
+// tlsConfig returns the TLS config of the TLS files
+func (files *TLSFiles) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if files.CACert != "" {
+		caCert, err := os.ReadFile(files.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the Nomad CA certificate: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no PEM certificate found in the Nomad CA certificate %s", files.CACert)
+		}
+	}
+	if (files.ClientCert == "") != (files.ClientKey == "") {
+		return nil, fmt.Errorf("the Nomad client certificate and client key must be provided together")
+	}
+	if files.ClientCert != "" {
+		clientCert, err := tls.LoadX509KeyPair(files.ClientCert, files.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the Nomad client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+	return tlsConfig, nil
+}
+
+// allocationStub is an allocation as listed by /v1/allocations
+type allocationStub struct {
+	ID           string
+	Namespace    string
+	JobID        string
+	JobVersion   uint64
+	TaskGroup    string
+	NodeName     string
+	ClientStatus string
+	CreateTime   int64
+	TaskStates   map[string]*taskState
+}
+
+type taskState struct {
+	State     string
+	StartedAt time.Time
+}
+
+// allocation is an allocation as returned by /v1/allocation/:id, with the job it runs
+type allocation struct {
+	allocationStub
+	Job *struct {
+		TaskGroups []*struct {
+			Name  string
+			Tasks []*task
+		}
+	}
+}
+
+type task struct {
+	Name      string
+	Driver    string
+	Config    map[string]interface{}
+	Artifacts []*struct {
+		GetterSource  string
+		GetterOptions map[string]string
+	}
+}
+
+// GetAllocationsData returns the data of the running allocations of the jobs matching the
+// jobs filter in the namespaces matching the namespaces filter
+func (c *NomadClient) GetAllocationsData(namespaces, jobs *filters.ResourceFilterOptions, logger *logger.Logger) ([]*AllocationData, error) {
+	stubs, err := c.listRunningAllocations()
+	if err != nil {
+		return nil, err
+	}
+
+	registry := digest.NewDigestCache(c.DigestResolver)
+	allocationsData := []*AllocationData{}
+	for _, stub := range stubs {
+		include, err := namespaces.ShouldInclude(stub.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if include {
+			include, err = jobs.ShouldInclude(stub.JobID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !include {
+			continue
+		}
+
+		logger.Debug("getting allocation %s of job %s in namespace %s", stub.ID, stub.JobID, stub.Namespace)
+		alloc := &allocation{}
+		err = c.get("/v1/allocation/"+url.PathEscape(stub.ID), url.Values{"namespace": {stub.Namespace}}, alloc)
+		if err != nil {
+			return nil, err
+		}
+		data := newAllocationData(alloc)
+		digest.ResolveMissingDigests(registry, data.Tasks, data.Digests)
+		allocationsData = append(allocationsData, data)
+	}
+	return allocationsData, nil
+}
+
+// listRunningAllocations lists the running allocations in all the namespaces the token can read
+func (c *NomadClient) listRunningAllocations() ([]*allocationStub, error) {
+	running := []*allocationStub{}
+	nextToken := ""
+	for {
+		query := url.Values{
+			"namespace": {"*"},
+			"per_page":  {fmt.Sprint(allocationsPageSize)},
+		}
+		if nextToken != "" {
+			query.Set("next_token", nextToken)
+		}
+		page := []*allocationStub{}
+		headers, err := c.getWithHeaders("/v1/allocations", query, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, stub := range page {
+			if stub.ClientStatus == "running" {
+				running = append(running, stub)
+			}
+		}
+		nextToken = headers.Get("X-Nomad-NextToken")
+		if nextToken == "" {
+			return running, nil
+		}
+	}
+}
+
+// newAllocationData creates an AllocationData object from an allocation and the job it runs
+func newAllocationData(alloc *allocation) *AllocationData {
+	data := &AllocationData{
+		AllocationID: alloc.ID,
+		Namespace:    alloc.Namespace,
+		Job:          alloc.JobID,
+		JobVersion:   alloc.JobVersion,
+		TaskGroup:    alloc.TaskGroup,
+		Node:         alloc.NodeName,
+		Digests:      make(map[string]string),
+		Tasks:        []*TaskData{},
+		StartedAt:    allocationStartedAt(&alloc.allocationStub).Unix(),
+	}
+	if alloc.Job == nil {
+		return data
+	}
+
+	for _, group := range alloc.Job.TaskGroups {
+		if group.Name != alloc.TaskGroup {
+			continue
+		}
+		for _, t := range group.Tasks {
+			for _, taskData := range newTasksData(t) {
+				if taskData.DigestSource == digest.DigestSourceObserved {
+					data.Digests[taskData.Artifact] = taskData.Digest
+				}
+				data.Tasks = append(data.Tasks, taskData)
+			}
+		}
+	}
+	return data
+}
+
+// newTasksData returns the data of the container image and the downloaded artifacts of a task
+func newTasksData(t *task) []*TaskData {
+	tasksData := []*TaskData{}
+	if image, ok := t.Config["image"].(string); ok && imageDrivers[t.Driver] {
+		// podman images can be prefixed with their transport
+		image = strings.TrimPrefix(image, "docker://")
+		taskData := &TaskData{
+			Name:         t.Name,
+			Driver:       t.Driver,
+			Kind:         TaskArtifactImage,
+			Artifact:     image,
+			DigestSource: digest.DigestSourceObserved,
+		}
+		if _, sha256, found := strings.Cut(image, "@sha256:"); found {
+			taskData.Digest = sha256
+		} else {
+			taskData.DigestSource = digest.DigestSourceUnresolved
+			taskData.UnresolvedReason = "the task config has no digest for the image"
+		}
+		tasksData = append(tasksData, taskData)
+	}
+
+	for _, artifact := range t.Artifacts {
+		source, checksum := artifactChecksum(artifact.GetterSource, artifact.GetterOptions)
+		taskData := &TaskData{
+			Name:         t.Name,
+			Driver:       t.Driver,
+			Kind:         TaskArtifactFile,
+			Artifact:     source,
+			DigestSource: digest.DigestSourceObserved,
+		}
+		if sha256, found := strings.CutPrefix(checksum, "sha256:"); found && digest.ValidateDigest(sha256) == nil {
+			taskData.Digest = sha256
+		} else {
+			taskData.DigestSource = digest.DigestSourceUnresolved
+			taskData.UnresolvedReason = "the artifact has no sha256 checksum"
+		}
+		tasksData = append(tasksData, taskData)
+	}
+	return tasksData
+}
+
+// artifactChecksum returns the source of an artifact without its checksum and its checksum (type:value),
+// which is either a getter option or a query parameter of the source
+func artifactChecksum(source string, options map[string]string) (string, string) {
+	checksum := options["checksum"]
+	if u, err := url.Parse(source); err == nil {
+		query := u.Query()
+		if query.Has("checksum") {
+			if checksum == "" {
+				checksum = query.Get("checksum")
+			}
+			query.Del("checksum")
+			u.RawQuery = query.Encode()
+			source = u.String()
+		}
+	}
+	return source, strings.ToLower(checksum)
+}
+
+// allocationStartedAt returns the time the first task of an allocation started,
+// or the creation time of the allocation if no task has started
+func allocationStartedAt(alloc *allocationStub) time.Time {
+	startedAt := time.Time{}
+	names := make([]string, 0, len(alloc.TaskStates))
+	for name := range alloc.TaskStates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		state := alloc.TaskStates[name]
+		if state == nil || state.StartedAt.IsZero() {
+			continue
+		}
+		if startedAt.IsZero() || state.StartedAt.Before(startedAt) {
+			startedAt = state.StartedAt
+		}
+	}
+	if startedAt.IsZero() {
+		return time.Unix(0, alloc.CreateTime)
+	}
+	return startedAt
+}
+
+// get sends a GET request to the Nomad HTTP API and decodes the JSON response into result
+func (c *NomadClient) get(path string, query url.Values, result interface{}) error {
+	_, err := c.getWithHeaders(path, query, result)
+	return err
+}
+
+// getWithHeaders is like get and also returns the response headers
+func (c *NomadClient) getWithHeaders(path string, query url.Values, result interface{}) (http.Header, error) {
+	reqURL := fmt.Sprintf("%s%s?%s", c.Address, path, query.Encode())
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Nomad-Token", c.Token)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the Nomad API at %s: %v", c.Address, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s on the Nomad API failed with status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the response of GET %s on the Nomad API: %v", path, err)
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,317 @@
+package nomad
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kosli-dev/cli/internal/digest"
+	"github.com/kosli-dev/cli/internal/filters"
+	"github.com/kosli-dev/cli/internal/logger"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type NomadTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	client   *NomadClient
+	requests []string
+}
+
+const (
+	apiDigest      = "6f2f4b3a4d30b0d4a1c1a0c2b0e1dfc9a4fae3e3f1b2e8b1f5a7d1c0e2f4a6b8"
+	proxyDigest    = "9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a0f9e8d"
+	binaryChecksum = "0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c"
+	reportChecksum = "1a3c5e7f9b2d4f6a8c0e2a4c6e8f0b2d4f6a8c0e2a4c6e8f0b2d4f6a8c0e2a4c"
+)
+
+// nomadStubAllocationPages are the pages of allocations listed by the Nomad API stub
+var nomadStubAllocationPages = []string{
+	`[
+		{"ID": "a1", "Namespace": "default", "JobID": "api", "JobVersion": 3, "TaskGroup": "web", "NodeName": "client-1",
+		 "ClientStatus": "running", "CreateTime": 1790848700000000000,
+		 "TaskStates": {
+			"proxy": {"State": "running", "StartedAt": "2026-10-01T10:00:05Z"},
+			"app": {"State": "running", "StartedAt": "2026-10-01T10:00:00.5Z"}
+		 }},
+		{"ID": "a0", "Namespace": "default", "JobID": "api", "JobVersion": 2, "TaskGroup": "web", "NodeName": "client-1",
+		 "ClientStatus": "complete", "CreateTime": 1790748700000000000}
+	]`,
+	`[
+		{"ID": "b1", "Namespace": "batch", "JobID": "reports", "JobVersion": 0, "TaskGroup": "main", "NodeName": "client-2",
+		 "ClientStatus": "running", "CreateTime": 1790935200000000000,
+		 "TaskStates": {"report": {"State": "pending", "StartedAt": "0001-01-01T00:00:00Z"}}},
+		{"ID": "c1", "Namespace": "sandbox", "JobID": "api", "JobVersion": 0, "TaskGroup": "web", "NodeName": "client-2",
+		 "ClientStatus": "running", "CreateTime": 1790935200000000000}
+	]`,
+}
+
+// nomadStubAllocations are the allocations returned by the Nomad API stub, by ID
+var nomadStubAllocations = map[string]string{
+	"a1": `{"ID": "a1", "Namespace": "default", "JobID": "api", "JobVersion": 3, "TaskGroup": "web", "NodeName": "client-1",
+		"ClientStatus": "running", "CreateTime": 1790848700000000000,
+		"TaskStates": {
+			"proxy": {"State": "running", "StartedAt": "2026-10-01T10:00:05Z"},
+			"app": {"State": "running", "StartedAt": "2026-10-01T10:00:00.5Z"}
+		},
+		"Job": {"TaskGroups": [
+			{"Name": "other", "Tasks": [{"Name": "other", "Driver": "docker", "Config": {"image": "other:latest"}}]},
+			{"Name": "web", "Tasks": [
+				{"Name": "app", "Driver": "docker", "Config": {"image": "registry.example.com/api@sha256:` + apiDigest + `", "ports": ["http"]}},
+				{"Name": "proxy", "Driver": "podman", "Config": {"image": "docker://docker.io/envoyproxy/envoy:v1.31"}}
+			]}
+		]}}`,
+	"b1": `{"ID": "b1", "Namespace": "batch", "JobID": "reports", "JobVersion": 0, "TaskGroup": "main", "NodeName": "client-2",
+		"ClientStatus": "running", "CreateTime": 1790935200000000000,
+		"TaskStates": {"report": {"State": "pending", "StartedAt": "0001-01-01T00:00:00Z"}},
+		"Job": {"TaskGroups": [
+			{"Name": "main", "Tasks": [
+				{"Name": "report", "Driver": "exec", "Config": {"command": "local/report"},
+				 "Artifacts": [
+					{"GetterSource": "https://releases.example.com/report.tar.gz", "GetterOptions": {"checksum": "sha256:` + binaryChecksum + `"}},
+					{"GetterSource": "https://releases.example.com/templates.zip?checksum=sha256:` + reportChecksum + `"},
+					{"GetterSource": "https://releases.example.com/config.json", "GetterOptions": {"checksum": "md5:d41d8cd98f00b204e9800998ecf8427e"}}
+				 ]}
+			]}
+		]}}`,
+	"c1": `{"ID": "c1", "Namespace": "sandbox", "JobID": "api", "JobVersion": 0, "TaskGroup": "web", "NodeName": "client-2",
+		"ClientStatus": "running", "CreateTime": 1790935200000000000,
+		"Job": {"TaskGroups": [
+			{"Name": "web", "Tasks": [{"Name": "app", "Driver": "docker", "Config": {"image": "registry.example.com/api:latest"}}]}
+		]}}`,
+}
+
+func (suite *NomadTestSuite) SetupTest() {
+	suite.requests = []string{}
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.requests = append(suite.requests, r.URL.RequestURI())
+		if r.Header.Get("X-Nomad-Token") != "secret" {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/allocations" {
+			page := 0
+			if r.URL.Query().Get("next_token") == "b1" {
+				page = 1
+			} else {
+				w.Header().Set("X-Nomad-NextToken", "b1")
+			}
+			fmt.Fprint(w, nomadStubAllocationPages[page])
+			return
+		}
+		if alloc, ok := nomadStubAllocations[strings.TrimPrefix(r.URL.Path, "/v1/allocation/")]; ok {
+			fmt.Fprint(w, alloc)
+			return
+		}
+		http.Error(w, "alloc not found", http.StatusNotFound)
+	}))
+	var err error
+	suite.client, err = NewNomadClient(suite.server.URL, "secret", nil)
+	require.NoError(suite.T(), err)
+}
+
+func (suite *NomadTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *NomadTestSuite) TestGetAllocationsData() {
+	apiAllocation := &AllocationData{
+		AllocationID: "a1",
+		Namespace:    "default",
+		Job:          "api",
+		JobVersion:   3,
+		TaskGroup:    "web",
+		Node:         "client-1",
+		Digests: map[string]string{
+			"registry.example.com/api@sha256:" + apiDigest: apiDigest,
+		},
+		Tasks: []*TaskData{
+			{Name: "app", Driver: "docker", Kind: TaskArtifactImage, Artifact: "registry.example.com/api@sha256:" + apiDigest,
+				Digest: apiDigest, DigestSource: digest.DigestSourceObserved},
+			{Name: "proxy", Driver: "podman", Kind: TaskArtifactImage, Artifact: "docker.io/envoyproxy/envoy:v1.31",
+				DigestSource: digest.DigestSourceUnresolved, UnresolvedReason: "the task config has no digest for the image"},
+		},
+		StartedAt: 1790848800,
+	}
+	reportsAllocation := &AllocationData{
+		AllocationID: "b1",
+		Namespace:    "batch",
+		Job:          "reports",
+		TaskGroup:    "main",
+		Node:         "client-2",
+		Digests: map[string]string{
+			"https://releases.example.com/report.tar.gz": binaryChecksum,
+			"https://releases.example.com/templates.zip": reportChecksum,
+		},
+		Tasks: []*TaskData{
+			{Name: "report", Driver: "exec", Kind: TaskArtifactFile, Artifact: "https://releases.example.com/report.tar.gz",
+				Digest: binaryChecksum, DigestSource: digest.DigestSourceObserved},
+			{Name: "report", Driver: "exec", Kind: TaskArtifactFile, Artifact: "https://releases.example.com/templates.zip",
+				Digest: reportChecksum, DigestSource: digest.DigestSourceObserved},
+			{Name: "report", Driver: "exec", Kind: TaskArtifactFile, Artifact: "https://releases.example.com/config.json",
+				DigestSource: digest.DigestSourceUnresolved, UnresolvedReason: "the artifact has no sha256 checksum"},
+		},
+		StartedAt: 1790935200,
+	}
+
+	for _, t := range []struct {
+		name       string
+		namespaces *filters.ResourceFilterOptions
+		jobs       *filters.ResourceFilterOptions
+		want       []*AllocationData
+	}{
+		{
+			name:       "running allocations are reported in namespaces matching the filter",
+			namespaces: &filters.ResourceFilterOptions{IncludeNames: []string{"default", "batch"}},
+			jobs:       &filters.ResourceFilterOptions{},
+			want:       []*AllocationData{apiAllocation, reportsAllocation},
+		},
+		{
+			name:       "allocations can be filtered by job",
+			namespaces: &filters.ResourceFilterOptions{ExcludeNamesRegex: []string{"^sand"}},
+			jobs:       &filters.ResourceFilterOptions{ExcludeNames: []string{"api"}},
+			want:       []*AllocationData{reportsAllocation},
+		},
+	} {
+		suite.Run(t.name, func() {
+			got, err := suite.client.GetAllocationsData(t.namespaces, t.jobs, logger.NewStandardLogger())
+			require.NoError(suite.T(), err)
+			require.Equal(suite.T(), t.want, got)
+		})
+	}
+}
+
+func (suite *NomadTestSuite) TestGetAllocationsDataResolvesMissingDigests() {
+	resolved := []string{}
+	suite.client.DigestResolver = func(image string) (string, error) {
+		resolved = append(resolved, image)
+		if image == "docker.io/envoyproxy/envoy:v1.31" {
+			return proxyDigest, nil
+		}
+		return "", fmt.Errorf("manifest unknown")
+	}
+
+	got, err := suite.client.GetAllocationsData(&filters.ResourceFilterOptions{},
+		&filters.ResourceFilterOptions{IncludeNames: []string{"api"}}, logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), got, 2)
+
+	proxy := got[0].Tasks[1]
+	require.Equal(suite.T(), digest.DigestSourceResolved, proxy.DigestSource)
+	require.Equal(suite.T(), proxyDigest, proxy.Digest)
+	require.Empty(suite.T(), proxy.UnresolvedReason)
+	require.Equal(suite.T(), proxyDigest, got[0].Digests["docker.io/envoyproxy/envoy:v1.31"])
+
+	sandboxApp := got[1].Tasks[0]
+	require.Equal(suite.T(), digest.DigestSourceUnresolved, sandboxApp.DigestSource)
+	require.Equal(suite.T(), "the task config has no digest for the image; could not resolve the digest from the registry: manifest unknown",
+		sandboxApp.UnresolvedReason)
+	require.Empty(suite.T(), got[1].Digests)
+	require.Equal(suite.T(), int64(1790935200), got[1].StartedAt)
+
+	require.Equal(suite.T(), []string{"docker.io/envoyproxy/envoy:v1.31", "registry.example.com/api:latest"}, resolved)
+}
+
+func (suite *NomadTestSuite) TestGetAllocationsDataFailsWithoutPermission() {
+	client, err := NewNomadClient(suite.server.URL, "wrong", nil)
+	require.NoError(suite.T(), err)
+	_, err = client.GetAllocationsData(&filters.ResourceFilterOptions{}, &filters.ResourceFilterOptions{}, logger.NewStandardLogger())
+	require.EqualError(suite.T(), err, "GET /v1/allocations on the Nomad API failed with status 403: Permission denied")
+}
+
+func (suite *NomadTestSuite) TestListRunningAllocationsFollowsPages() {
+	stubs, err := suite.client.listRunningAllocations()
+	require.NoError(suite.T(), err)
+	ids := []string{}
+	for _, stub := range stubs {
+		ids = append(ids, stub.ID)
+	}
+	require.Equal(suite.T(), []string{"a1", "b1", "c1"}, ids)
+	require.Equal(suite.T(), []string{
+		"/v1/allocations?namespace=%2A&per_page=500",
+		"/v1/allocations?namespace=%2A&next_token=b1&per_page=500",
+	}, suite.requests)
+}
+
+// The Nomad API can be served over TLS and require a client certificate
+func (suite *NomadTestSuite) TestNewNomadClientWithTLS() {
+	dir := suite.T().TempDir()
+	clientCert, clientKey, clientPool := suite.writeSelfSignedCert(dir, "client")
+	server := httptest.NewUnstartedServer(suite.server.Config.Handler)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientPool}
+	server.StartTLS()
+	defer server.Close()
+
+	caCert := filepath.Join(dir, "ca.pem")
+	require.NoError(suite.T(), os.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	client, err := NewNomadClient(server.URL, "secret", &TLSFiles{CACert: caCert, ClientCert: clientCert, ClientKey: clientKey})
+	require.NoError(suite.T(), err)
+	stubs, err := client.listRunningAllocations()
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), stubs, 3)
+
+	client, err = NewNomadClient(server.URL, "secret", &TLSFiles{ClientCert: clientCert, ClientKey: clientKey})
+	require.NoError(suite.T(), err)
+	_, err = client.listRunningAllocations()
+	require.ErrorContains(suite.T(), err, "certificate signed by unknown authority")
+
+	_, err = NewNomadClient(server.URL, "secret", &TLSFiles{CACert: caCert, ClientCert: clientCert})
+	require.EqualError(suite.T(), err, "the Nomad client certificate and client key must be provided together")
+
+	_, err = NewNomadClient(server.URL, "secret", &TLSFiles{CACert: clientKey})
+	require.EqualError(suite.T(), err, "no PEM certificate found in the Nomad CA certificate "+clientKey)
+}
+
+// writeSelfSignedCert writes a self-signed certificate and its key as PEM files in dir,
+// and returns their paths and a pool with the certificate
+func (suite *NomadTestSuite) writeSelfSignedCert(dir, name string) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(suite.T(), err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(suite.T(), err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(suite.T(), err)
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	require.NoError(suite.T(), os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(suite.T(), os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(suite.T(), err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestNomadTestSuite(t *testing.T) {
+	suite.Run(t, new(NomadTestSuite))
+}
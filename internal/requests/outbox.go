@@ -0,0 +1,207 @@
+package requests
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const outboxEntryExtension = ".json"
+
+// OutboxEntry represents a serialized request that could not be delivered
+// to the Kosli host and is waiting in the outbox to be replayed
+type OutboxEntry struct {
+	ID        string            `json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Body      []byte            `json:"body"`
+	LastError string            `json:"last_error"`
+}
+
+// Outbox is a local spool directory where failed requests are stored
+// so that they can be sent later in their original order
+type Outbox struct {
+	Dir string
+}
+
+// NewOutbox creates an Outbox backed by the given directory
+func NewOutbox(dir string) *Outbox {
+	return &Outbox{Dir: dir}
+}
+
+// queueable returns true if requests with the given method can be stored in the outbox
+func queueable(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut
+}
+
+// Enqueue serializes a request into the outbox. The request body is captured
+// as sent on the wire, so multipart attachments do not need to exist on disk at replay time.
+// Authorization headers are never stored; credentials are provided again at replay time.
+func (o *Outbox) Enqueue(req *http.Request, cause error) (*OutboxEntry, error) {
+	if err := os.MkdirAll(o.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory %s: %v", o.Dir, err)
+	}
+
+	var body []byte
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body for outbox: %v", err)
+		}
+		defer reader.Close()
+		body, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body for outbox: %v", err)
+		}
+	}
+
+	headers := make(map[string]string)
+	for key := range req.Header {
+		if http.CanonicalHeaderKey(key) == "Authorization" {
+			continue
+		}
+		headers[key] = req.Header.Get(key)
+	}
+
+	id, err := newOutboxEntryID()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &OutboxEntry{
+		ID:        id,
+		CreatedAt: time.Now().UTC(),
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Headers:   headers,
+		Body:      body,
+	}
+	if cause != nil {
+		entry.LastError = cause.Error()
+	}
+
+	if err := o.write(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// List returns the outbox entries in the order they were queued
+func (o *Outbox) List() ([]*OutboxEntry, error) {
+	entries := []*OutboxEntry{}
+	files, err := os.ReadDir(o.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return entries, fmt.Errorf("failed to read outbox directory %s: %v", o.Dir, err)
+	}
+
+	names := []string{}
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), outboxEntryExtension) {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(o.Dir, name))
+		if err != nil {
+			return entries, fmt.Errorf("failed to read outbox entry %s: %v", name, err)
+		}
+		entry := &OutboxEntry{}
+		if err := json.Unmarshal(content, entry); err != nil {
+			return entries, fmt.Errorf("failed to parse outbox entry %s: %v", name, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Remove deletes an entry from the outbox
+func (o *Outbox) Remove(id string) error {
+	err := os.Remove(o.entryPath(id))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove outbox entry %s: %v", id, err)
+	}
+	return nil
+}
+
+// Drop deletes an entry from the outbox without sending it, e.g. an entry which
+// is rejected by Kosli and would otherwise stop every replay
+func (o *Outbox) Drop(id string) error {
+	if _, err := os.Stat(o.entryPath(id)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("outbox entry %s does not exist in the outbox [%s]", id, o.Dir)
+		}
+		return fmt.Errorf("failed to read outbox entry %s: %v", id, err)
+	}
+	return o.Remove(id)
+}
+
+// Purge deletes all entries from the outbox and returns how many were deleted
+func (o *Outbox) Purge() (int, error) {
+	entries, err := o.List()
+	if err != nil {
+		return 0, err
+	}
+	for i, entry := range entries {
+		if err := o.Remove(entry.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// write stores an entry atomically so that a crash never leaves a half-written entry behind
+func (o *Outbox) write(entry *OutboxEntry) error {
+	content, err := json.MarshalIndent(entry, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmpPath := o.entryPath(entry.ID) + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		return fmt.Errorf("failed to write outbox entry %s: %v", entry.ID, err)
+	}
+	if err := os.Rename(tmpPath, o.entryPath(entry.ID)); err != nil {
+		return fmt.Errorf("failed to write outbox entry %s: %v", entry.ID, err)
+	}
+	return nil
+}
+
+func (o *Outbox) entryPath(id string) string {
+	return filepath.Join(o.Dir, id+outboxEntryExtension)
+}
+
+// newOutboxEntryID returns an ID that sorts lexically in queueing order
+func newOutboxEntryID() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate outbox entry id: %v", err)
+	}
+	return fmt.Sprintf("%020d-%s", time.Now().UnixNano(), hex.EncodeToString(suffix)), nil
+}
+
+// newRequest rebuilds an http request from an outbox entry
+func (e *OutboxEntry) newRequest() (*http.Request, error) {
+	req, err := http.NewRequest(e.Method, e.URL, bytes.NewReader(e.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request to %s : %v", e.Method, e.URL, err)
+	}
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
@@ -0,0 +1,220 @@
+package requests
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kosli-dev/cli/internal/logger"
+	"github.com/maxcnunes/httpfake"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type OutboxTestSuite struct {
+	suite.Suite
+	fakeService *httpfake.HTTPFake
+	outboxDir   string
+}
+
+func (suite *OutboxTestSuite) SetupTest() {
+	suite.fakeService = httpfake.New()
+	suite.fakeService.NewHandler().
+		Put("/environments/1").
+		Reply(201).
+		BodyString(`{}`)
+	suite.fakeService.NewHandler().
+		Post("/attestations/1").
+		Reply(201).
+		BodyString(`{}`)
+	suite.fakeService.NewHandler().
+		Post("/attestations/bad").
+		Reply(400).
+		BodyString(`{"message": "invalid attestation"}`)
+
+	var err error
+	suite.outboxDir, err = os.MkdirTemp("", "outbox")
+	require.NoError(suite.T(), err)
+}
+
+func (suite *OutboxTestSuite) TearDownTest() {
+	suite.fakeService.Close()
+	os.RemoveAll(suite.outboxDir)
+}
+
+func (suite *OutboxTestSuite) newClient() *Client {
+	client, err := NewKosliClient("", 0, false, logger.NewStandardLogger())
+	require.NoError(suite.T(), err)
+	client.Outbox = NewOutbox(suite.outboxDir)
+	return client
+}
+
+func (suite *OutboxTestSuite) TestDoQueuesUnreachableRequests() {
+	for _, t := range []struct {
+		name       string
+		params     *RequestParams
+		wantQueued bool
+		wantError  bool
+	}{
+		{
+			name: "PUT request to an unreachable host is queued",
+			params: &RequestParams{
+				Method:  http.MethodPut,
+				URL:     "http://localhost:1/environments/1",
+				Payload: map[string]string{"name": "env"},
+				Token:   "secret",
+			},
+			wantQueued: true,
+		},
+		{
+			name: "multipart POST request to an unreachable host is queued",
+			params: &RequestParams{
+				Method: http.MethodPost,
+				URL:    "http://localhost:1/attestations/1",
+				Form: []FormItem{
+					{Type: "field", FieldName: "data_json", Content: map[string]string{"name": "att"}},
+					{Type: "file", FieldName: "attachment_file", Content: "outbox.go"},
+				},
+			},
+			wantQueued: true,
+		},
+		{
+			name: "GET request to an unreachable host is not queued",
+			params: &RequestParams{
+				Method: http.MethodGet,
+				URL:    "http://localhost:1/environments/1",
+			},
+			wantError: true,
+		},
+		{
+			name: "PUT request to a reachable host is not queued",
+			params: &RequestParams{
+				Method:  http.MethodPut,
+				URL:     suite.fakeService.ResolveURL("/environments/1"),
+				Payload: map[string]string{"name": "env"},
+			},
+		},
+	} {
+		suite.Run(t.name, func() {
+			defer os.RemoveAll(suite.outboxDir)
+			resp, err := suite.newClient().Do(t.params)
+			if t.wantError {
+				require.Error(suite.T(), err)
+			} else {
+				require.NoError(suite.T(), err)
+				require.Equal(suite.T(), t.wantQueued, resp.Queued)
+			}
+
+			entries, err := NewOutbox(suite.outboxDir).List()
+			require.NoError(suite.T(), err)
+			if t.wantQueued {
+				require.Len(suite.T(), entries, 1)
+				require.Equal(suite.T(), t.params.Method, entries[0].Method)
+				require.Equal(suite.T(), t.params.URL, entries[0].URL)
+				require.NotEmpty(suite.T(), entries[0].Body)
+				require.NotEmpty(suite.T(), entries[0].LastError)
+				require.NotContains(suite.T(), entries[0].Headers, "Authorization")
+			} else {
+				require.Len(suite.T(), entries, 0)
+			}
+		})
+	}
+}
+
+func (suite *OutboxTestSuite) TestReplayOutbox() {
+	outbox := NewOutbox(suite.outboxDir)
+	for _, path := range []string{"/environments/1", "/attestations/1"} {
+		req, err := http.NewRequest(http.MethodPost, suite.fakeService.ResolveURL("%s", path), nil)
+		require.NoError(suite.T(), err)
+		if path == "/environments/1" {
+			req.Method = http.MethodPut
+		}
+		_, err = outbox.Enqueue(req, nil)
+		require.NoError(suite.T(), err)
+	}
+
+	entries, err := outbox.List()
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), entries, 2)
+	require.Equal(suite.T(), http.MethodPut, entries[0].Method)
+	require.Equal(suite.T(), http.MethodPost, entries[1].Method)
+
+	client := suite.newClient()
+	sent, err := client.ReplayOutbox("secret", true)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 0, sent)
+
+	sent, err = client.ReplayOutbox("secret", false)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 2, sent)
+
+	entries, err = outbox.List()
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), entries, 0)
+}
+
+func (suite *OutboxTestSuite) TestReplayOutboxStopsAtFirstFailure() {
+	outbox := NewOutbox(suite.outboxDir)
+	for _, path := range []string{"/attestations/1", "/attestations/bad", "/attestations/1"} {
+		req, err := http.NewRequest(http.MethodPost, suite.fakeService.ResolveURL("%s", path), nil)
+		require.NoError(suite.T(), err)
+		_, err = outbox.Enqueue(req, nil)
+		require.NoError(suite.T(), err)
+	}
+
+	sent, err := suite.newClient().ReplayOutbox("secret", false)
+	require.ErrorContains(suite.T(), err, "invalid attestation")
+	require.Equal(suite.T(), 1, sent)
+
+	entries, err := outbox.List()
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), entries, 2)
+	require.Equal(suite.T(), suite.fakeService.ResolveURL("/attestations/bad"), entries[0].URL)
+
+	// dropping the failing entry lets the replay carry on
+	require.NoError(suite.T(), outbox.Drop(entries[0].ID))
+	sent, err = suite.newClient().ReplayOutbox("secret", false)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 1, sent)
+}
+
+func (suite *OutboxTestSuite) TestDrop() {
+	outbox := NewOutbox(suite.outboxDir)
+	req, err := http.NewRequest(http.MethodPut, "http://localhost:1/environments/1", nil)
+	require.NoError(suite.T(), err)
+	entry, err := outbox.Enqueue(req, nil)
+	require.NoError(suite.T(), err)
+
+	require.NoError(suite.T(), outbox.Drop(entry.ID))
+	entries, err := outbox.List()
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), entries, 0)
+
+	require.ErrorContains(suite.T(), outbox.Drop(entry.ID), "does not exist in the outbox")
+}
+
+func (suite *OutboxTestSuite) TestPurge() {
+	outbox := NewOutbox(filepath.Join(suite.outboxDir, "nested"))
+	purged, err := outbox.Purge()
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 0, purged)
+
+	req, err := http.NewRequest(http.MethodPut, "http://localhost:1/environments/1", nil)
+	require.NoError(suite.T(), err)
+	_, err = outbox.Enqueue(req, nil)
+	require.NoError(suite.T(), err)
+
+	purged, err = outbox.Purge()
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 1, purged)
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestOutboxTestSuite(t *testing.T) {
+	suite.Run(t, new(OutboxTestSuite))
+}
@@ -26,8 +26,9 @@ type FormItem struct {
 
 // HTTPResponse is a wrapper of http.Response with ready-extracted string body
 type HTTPResponse struct {
-	Body string
-	Resp *http.Response
+	Body   string
+	Resp   *http.Response
+	Queued bool
 }
 
 type Client struct {
@@ -35,6 +36,7 @@ type Client struct {
 	Debug         bool
 	Logger        *logger.Logger
 	HttpClient    *http.Client
+	Outbox        *Outbox
 }
 
 func NewKosliClient(httpProxyURL string, maxAPIRetries int, debug bool, logger *logger.Logger) (*Client, error) {
@@ -213,46 +215,115 @@ func (c *Client) Do(p *RequestParams) (*HTTPResponse, error) {
 	} else {
 		resp, err := c.HttpClient.Do(req)
 		if err != nil {
+			if c.Outbox != nil && queueable(req.Method) {
+				return c.enqueue(req, err)
+			}
 			// err from retryable client is detailed enough
 			return nil, fmt.Errorf("%v", err)
 		}
+		return c.handleResponse(req, resp)
+	}
+}
 
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response from %s request to %s : %v", req.Method, req.URL, err)
-		}
+// enqueue stores an undelivered request in the outbox and returns a response
+// marked as queued so that the calling command can carry on
+func (c *Client) enqueue(req *http.Request, cause error) (*HTTPResponse, error) {
+	entry, err := c.Outbox.Enqueue(req, cause)
+	if err != nil {
+		return nil, fmt.Errorf("%v. Additionally, storing the request in the outbox failed: %v", cause, err)
+	}
+	c.Logger.Warning("%v", cause)
+	c.Logger.Warning("the %s request to %s was queued in the outbox [%s] as %s. Run 'kosli outbox replay' to send it later.",
+		req.Method, req.URL, c.Outbox.Dir, entry.ID)
+	return &HTTPResponse{
+		Resp:   &http.Response{StatusCode: http.StatusAccepted, Request: req},
+		Queued: true,
+	}, nil
+}
 
-		c.Logger.Debug("request made to %s and got status %d", req.URL, resp.StatusCode)
+// handleResponse reads the response body and converts non-successful responses into errors
+func (c *Client) handleResponse(req *http.Request, resp *http.Response) (*HTTPResponse, error) {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s request to %s : %v", req.Method, req.URL, err)
+	}
 
-		if resp.StatusCode != 200 && resp.StatusCode != 201 {
-			var respBody interface{}
-			err := json.Unmarshal([]byte(body), &respBody)
-			if err != nil {
-				return &HTTPResponse{}, err
-			}
-			cleanedErrorMessage := ""
-			if reflect.ValueOf(respBody).Kind() == reflect.String {
-				cleanedErrorMessage = respBody.(string)
-			} else if reflect.ValueOf(respBody).Kind() == reflect.Map {
-				// Error response from kosli application SW contains a "message"
-				// Error response from the API schema validation contains a "message" and a list of "errors"
-				respBodyMap := respBody.(map[string]interface{})
-				message, ok := respBodyMap["message"]
+	c.Logger.Debug("request made to %s and got status %d", req.URL, resp.StatusCode)
+
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		var respBody interface{}
+		err := json.Unmarshal([]byte(body), &respBody)
+		if err != nil {
+			return &HTTPResponse{}, err
+		}
+		cleanedErrorMessage := ""
+		if reflect.ValueOf(respBody).Kind() == reflect.String {
+			cleanedErrorMessage = respBody.(string)
+		} else if reflect.ValueOf(respBody).Kind() == reflect.Map {
+			// Error response from kosli application SW contains a "message"
+			// Error response from the API schema validation contains a "message" and a list of "errors"
+			respBodyMap := respBody.(map[string]interface{})
+			message, ok := respBodyMap["message"]
+			if ok {
+				errors, ok := respBodyMap["errors"]
 				if ok {
-					errors, ok := respBodyMap["errors"]
-					if ok {
-						cleanedErrorMessage = strings.Split(message.(string), "You have requested")[0] +
-							": " + fmt.Sprintf("%v", errors)
-					} else {
-						cleanedErrorMessage = strings.Split(message.(string), "You have requested")[0]
-					}
+					cleanedErrorMessage = strings.Split(message.(string), "You have requested")[0] +
+						": " + fmt.Sprintf("%v", errors)
 				} else {
-					cleanedErrorMessage = fmt.Sprintf("%s", respBodyMap)
+					cleanedErrorMessage = strings.Split(message.(string), "You have requested")[0]
 				}
+			} else {
+				cleanedErrorMessage = fmt.Sprintf("%s", respBodyMap)
 			}
-			return nil, fmt.Errorf("%s", cleanedErrorMessage)
 		}
-		return &HTTPResponse{string(body), resp}, nil
+		return nil, fmt.Errorf("%s", cleanedErrorMessage)
+	}
+	return &HTTPResponse{string(body), resp, false}, nil
+}
+
+// ReplayOutbox sends the queued outbox entries in their original order using
+// the given API token. It stops at the first entry that fails, so that the
+// order of the audit trail is preserved, and returns the number of entries sent.
+// An entry which keeps failing can be dropped with Outbox.Drop.
+func (c *Client) ReplayOutbox(token string, dryRun bool) (int, error) {
+	if c.Outbox == nil {
+		return 0, fmt.Errorf("no outbox directory is configured")
+	}
+	entries, err := c.Outbox.List()
+	if err != nil {
+		return 0, err
+	}
+
+	if dryRun {
+		c.Logger.Info("############### THIS IS A DRY-RUN  ###############")
+		for _, entry := range entries {
+			c.Logger.Info("the queued %s request %s would have been sent to: %s", entry.Method, entry.ID, entry.URL)
+		}
+		return 0, nil
+	}
+
+	for i, entry := range entries {
+		req, err := entry.newRequest()
+		if err != nil {
+			return i, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
+
+		resp, err := c.HttpClient.Do(req)
+		if err == nil {
+			_, err = c.handleResponse(req, resp)
+		}
+		if err != nil {
+			return i, fmt.Errorf("failed to replay outbox entry %s (%s %s): %v. Run 'kosli outbox drop %s' to delete it if it can never be sent",
+				entry.ID, entry.Method, entry.URL, err, entry.ID)
+		}
+		if err := c.Outbox.Remove(entry.ID); err != nil {
+			return i + 1, err
+		}
+		c.Logger.Debug("outbox entry %s was sent to %s", entry.ID, entry.URL)
 	}
+	return len(entries), nil
 }
@@ -0,0 +1,397 @@
+package sarif
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	gosarif "github.com/owenrumney/go-sarif/v2/sarif"
+)
+
+// the severities of SARIF results, from the highest to the lowest
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityInfo     = "info"
+)
+
+// Severities are the severities of SARIF results, from the highest to the lowest
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo}
+
+// SeverityNone is the threshold which no result reaches
+const SeverityNone = "none"
+
+type Tool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Lines string `json:"lines,omitempty"`
+}
+
+// Finding is a result of a SARIF run
+type Finding struct {
+	RuleID    string     `json:"rule_id"`
+	Severity  string     `json:"severity"`
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	// Properties is the property bag of the result, for tool specific fields
+	Properties map[string]interface{} `json:"-"`
+	// Level is the level of the result in the SARIF file, empty if it is not set
+	Level string `json:"-"`
+	// Rule is the rule of the result from the rules of the tool, nil if it is not found
+	Rule *gosarif.ReportingDescriptor `json:"-"`
+}
+
+type SeverityCounts struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Info     int `json:"info"`
+}
+
+// RuleSummary is the number of findings of a rule in a run
+type RuleSummary struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Severity string `json:"severity"`
+	Count    int    `json:"count"`
+}
+
+// LocationSummary is the number of findings in a file in a run
+type LocationSummary struct {
+	URI   string `json:"uri"`
+	Count int    `json:"count"`
+}
+
+type RunSummary struct {
+	Tool  Tool `json:"tool"`
+	Total int  `json:"total"`
+	// Suppressed is the number of results with a suppression which is not rejected, they are not in Total
+	Suppressed int `json:"suppressed"`
+	// NotFailing is the number of results of another kind than fail (e.g. pass or informational),
+	// they are not in Total
+	NotFailing     int               `json:"not_failing"`
+	SeverityCounts SeverityCounts    `json:"severity_counts"`
+	Rules          []RuleSummary     `json:"rules"`
+	Locations      []LocationSummary `json:"locations"`
+	Findings       []Finding         `json:"findings"`
+}
+
+type SarifData struct {
+	SchemaVersion int           `json:"schema_version"`
+	Runs          []*RunSummary `json:"runs"`
+}
+
+// ValidateSeverity checks that severity is a severity or none
+func ValidateSeverity(severity string) error {
+	if severity == SeverityNone || severityRank(severity) >= 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid severity %q, the allowed severities are: %s, %s", severity, strings.Join(Severities, ", "), SeverityNone)
+}
+
+// AtOrAbove returns whether severity is at or above threshold. Nothing is at or above the none threshold.
+func AtOrAbove(severity, threshold string) bool {
+	if threshold == SeverityNone {
+		return false
+	}
+	rank := severityRank(severity)
+	return rank >= 0 && rank <= severityRank(threshold)
+}
+
+// severityRank returns the position of severity in Severities, or -1 if it is not a severity
+func severityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *SeverityCounts) add(severity string) {
+	switch severity {
+	case SeverityCritical:
+		c.Critical++
+	case SeverityHigh:
+		c.High++
+	case SeverityMedium:
+		c.Medium++
+	case SeverityLow:
+		c.Low++
+	case SeverityInfo:
+		c.Info++
+	}
+}
+
+// ProcessSarifFile takes a path to a SARIF 2.1.0 file
+// and returns a summary of the results of each of its runs
+func ProcessSarifFile(file string) (*SarifData, error) {
+	report, err := gosarif.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	// also accept the 2.1.0 release candidates, e.g. 2.1.0-rtm.5
+	if !strings.HasPrefix(report.Version, string(gosarif.Version210)) {
+		return nil, fmt.Errorf("invalid sarif file: unsupported version %q, only SARIF %s is supported", report.Version, gosarif.Version210)
+	}
+
+	data := &SarifData{
+		SchemaVersion: 1,
+		Runs:          []*RunSummary{},
+	}
+	for _, run := range report.Runs {
+		data.Runs = append(data.Runs, summarizeRun(run))
+	}
+	return data, nil
+}
+
+func summarizeRun(run *gosarif.Run) *RunSummary {
+	summary := &RunSummary{
+		Rules:     []RuleSummary{},
+		Locations: []LocationSummary{},
+		Findings:  []Finding{},
+	}
+	if run.Tool.Driver != nil {
+		summary.Tool.Name = run.Tool.Driver.Name
+		if run.Tool.Driver.Version != nil {
+			summary.Tool.Version = *run.Tool.Driver.Version
+		}
+	}
+
+	rules := map[string]*RuleSummary{}
+	locations := map[string]*LocationSummary{}
+	for _, r := range run.Results {
+		if isSuppressed(r) {
+			summary.Suppressed++
+			continue
+		}
+		if r.Kind != nil && *r.Kind != "fail" {
+			summary.NotFailing++
+			continue
+		}
+		rule := findRule(run, r)
+		finding := createFinding(r, rule)
+		summary.Findings = append(summary.Findings, finding)
+		summary.Total++
+		summary.SeverityCounts.add(finding.Severity)
+
+		ruleSummary, ok := rules[finding.RuleID]
+		if !ok {
+			ruleSummary = &RuleSummary{ID: finding.RuleID, Severity: finding.Severity}
+			if rule != nil && rule.Name != nil {
+				ruleSummary.Name = *rule.Name
+			}
+			rules[finding.RuleID] = ruleSummary
+		}
+		ruleSummary.Count++
+		// a rule can have results of different severities, its summary has the highest one
+		if severityRank(finding.Severity) < severityRank(ruleSummary.Severity) {
+			ruleSummary.Severity = finding.Severity
+		}
+
+		uris := map[string]bool{}
+		for _, l := range finding.Locations {
+			if l.URI == "" || uris[l.URI] {
+				continue
+			}
+			uris[l.URI] = true
+			if _, ok := locations[l.URI]; !ok {
+				locations[l.URI] = &LocationSummary{URI: l.URI}
+			}
+			locations[l.URI].Count++
+		}
+	}
+
+	for _, rule := range rules {
+		summary.Rules = append(summary.Rules, *rule)
+	}
+	sort.Slice(summary.Rules, func(i, j int) bool {
+		if summary.Rules[i].Count != summary.Rules[j].Count {
+			return summary.Rules[i].Count > summary.Rules[j].Count
+		}
+		return summary.Rules[i].ID < summary.Rules[j].ID
+	})
+	for _, location := range locations {
+		summary.Locations = append(summary.Locations, *location)
+	}
+	sort.Slice(summary.Locations, func(i, j int) bool {
+		if summary.Locations[i].Count != summary.Locations[j].Count {
+			return summary.Locations[i].Count > summary.Locations[j].Count
+		}
+		return summary.Locations[i].URI < summary.Locations[j].URI
+	})
+	return summary
+}
+
+// isSuppressed returns whether a result has a suppression which is not rejected,
+// i.e. which is accepted or under review
+func isSuppressed(r *gosarif.Result) bool {
+	for _, suppression := range r.Suppressions {
+		if suppression != nil && (suppression.Status == nil || *suppression.Status != "rejected") {
+			return true
+		}
+	}
+	return false
+}
+
+// findRule returns the rule of a result from the rules of the tool driver, or nil if it is not found
+func findRule(run *gosarif.Run, r *gosarif.Result) *gosarif.ReportingDescriptor {
+	if run.Tool.Driver == nil {
+		return nil
+	}
+	rules := run.Tool.Driver.Rules
+	id := resultRuleID(r)
+	index := r.RuleIndex
+	if index == nil && r.Rule != nil {
+		index = r.Rule.Index
+	}
+	if index != nil && int(*index) < len(rules) && rules[*index] != nil && (id == "" || rules[*index].ID == id) {
+		return rules[*index]
+	}
+	for _, rule := range rules {
+		if rule != nil && rule.ID == id {
+			return rule
+		}
+	}
+	return nil
+}
+
+func resultRuleID(r *gosarif.Result) string {
+	if r.RuleID != nil {
+		return *r.RuleID
+	}
+	if r.Rule != nil && r.Rule.Id != nil {
+		return *r.Rule.Id
+	}
+	return ""
+}
+
+func createFinding(r *gosarif.Result, rule *gosarif.ReportingDescriptor) Finding {
+	locations := []Location{}
+	for _, l := range r.Locations {
+		if l != nil && l.PhysicalLocation != nil {
+			lines := ""
+			if l.PhysicalLocation.Region != nil && l.PhysicalLocation.Region.StartLine != nil {
+				lines = strconv.Itoa(*l.PhysicalLocation.Region.StartLine)
+				if l.PhysicalLocation.Region.EndLine != nil && *l.PhysicalLocation.Region.EndLine != *l.PhysicalLocation.Region.StartLine {
+					lines += fmt.Sprintf("-%d", *l.PhysicalLocation.Region.EndLine)
+				}
+			}
+			uri := ""
+			if l.PhysicalLocation.ArtifactLocation != nil && l.PhysicalLocation.ArtifactLocation.URI != nil {
+				uri = *l.PhysicalLocation.ArtifactLocation.URI
+			}
+
+			locations = append(locations, Location{
+				URI:   uri,
+				Lines: lines,
+			})
+		}
+	}
+	finding := Finding{
+		RuleID:     resultRuleID(r),
+		Severity:   resultSeverity(r, rule),
+		Locations:  locations,
+		Properties: r.Properties,
+		Rule:       rule,
+	}
+	if r.Level != nil {
+		finding.Level = *r.Level
+	}
+	if r.Message.Text != nil {
+		finding.Message = *r.Message.Text
+	}
+	return finding
+}
+
+// resultSeverity returns the severity of a result from, in order:
+//   - the security-severity (CVSS score) property of the result or of its rule, used by e.g. CodeQL and Trivy
+//   - the level of the result
+//   - the problem.severity property of its rule, used by Snyk
+//   - the default level of its rule
+//
+// and defaults to the SARIF default level (warning)
+func resultSeverity(r *gosarif.Result, rule *gosarif.ReportingDescriptor) string {
+	if severity, ok := securitySeverity(r.Properties); ok {
+		return severity
+	}
+	if rule != nil {
+		if severity, ok := securitySeverity(rule.Properties); ok {
+			return severity
+		}
+	}
+	if r.Level != nil {
+		if severity, ok := levelSeverity(*r.Level); ok {
+			return severity
+		}
+	}
+	if rule != nil {
+		if problem, ok := rule.Properties["problem"].(map[string]interface{}); ok {
+			if value, ok := problem["severity"].(string); ok {
+				if severity, ok := levelSeverity(value); ok {
+					return severity
+				}
+			}
+		}
+		if rule.DefaultConfiguration != nil {
+			if severity, ok := levelSeverity(rule.DefaultConfiguration.Level); ok {
+				return severity
+			}
+		}
+	}
+	return SeverityMedium
+}
+
+// securitySeverity maps the security-severity property (a CVSS score) to a severity
+// with the same ranges as GitHub code scanning
+func securitySeverity(properties map[string]interface{}) (string, bool) {
+	var score float64
+	switch value := properties["security-severity"].(type) {
+	case string:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", false
+		}
+		score = parsed
+	case float64:
+		score = value
+	default:
+		return "", false
+	}
+	switch {
+	case score >= 9.0:
+		return SeverityCritical, true
+	case score >= 7.0:
+		return SeverityHigh, true
+	case score >= 4.0:
+		return SeverityMedium, true
+	case score > 0:
+		return SeverityLow, true
+	}
+	return SeverityInfo, true
+}
+
+// levelSeverity maps SARIF levels and the severities used by tools in their properties to a severity
+func levelSeverity(level string) (string, bool) {
+	switch strings.ToLower(level) {
+	case "critical":
+		return SeverityCritical, true
+	case "error", "high":
+		return SeverityHigh, true
+	case "warning", "medium":
+		return SeverityMedium, true
+	case "note", "low":
+		return SeverityLow, true
+	case "info", "none":
+		return SeverityInfo, true
+	}
+	return "", false
+}
@@ -0,0 +1,150 @@
+package sarif
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessSarifFile(t *testing.T) {
+	tests := []struct {
+		name            string
+		file            string
+		want            []*RunSummary
+		wantErrContains string
+	}{
+		{
+			name:            "non-existing file causes an error",
+			file:            "non-existing.json",
+			wantErrContains: "the provided file path doesn't have a file",
+		},
+		{
+			name:            "a non-sarif json file causes an error",
+			file:            "../snyk/snyk.json",
+			wantErrContains: `invalid sarif file: unsupported version ""`,
+		},
+		{
+			name:            "a sarif 1.0.0 file causes an error",
+			file:            "sarif-old-version.json",
+			wantErrContains: `invalid sarif file: unsupported version "1.0.0", only SARIF 2.1.0 is supported`,
+		},
+		{
+			name: "security-severity properties of rules give the severity of CodeQL results",
+			file: "sarif-codeql.json",
+			want: []*RunSummary{
+				{
+					Tool:           Tool{Name: "CodeQL", Version: "2.19.1"},
+					Total:          4,
+					SeverityCounts: SeverityCounts{Critical: 1, High: 2, Low: 1},
+					Rules: []RuleSummary{
+						{ID: "js/xss", Name: "js/xss", Severity: SeverityHigh, Count: 2},
+						{ID: "js/sql-injection", Name: "js/sql-injection", Severity: SeverityCritical, Count: 1},
+						{ID: "js/unused-local-variable", Name: "js/unused-local-variable", Severity: SeverityLow, Count: 1},
+					},
+					Locations: []LocationSummary{
+						{URI: "src/app.js", Count: 2},
+						{URI: "src/util.js", Count: 1},
+						{URI: "src/view.js", Count: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "levels of results and default levels of rules give the severity of Semgrep and Checkov results",
+			file: "sarif-semgrep-checkov.json",
+			want: []*RunSummary{
+				{
+					Tool:           Tool{Name: "Semgrep OSS"},
+					Total:          2,
+					SeverityCounts: SeverityCounts{High: 1, Medium: 1},
+					Rules: []RuleSummary{
+						{ID: "python.lang.security.audit.eval-detected", Name: "python.lang.security.audit.eval-detected", Severity: SeverityHigh, Count: 2},
+					},
+					Locations: []LocationSummary{
+						{URI: "app/main.py", Count: 2},
+					},
+				},
+				{
+					Tool:           Tool{Name: "Checkov", Version: "3.2.0"},
+					Total:          2,
+					SeverityCounts: SeverityCounts{Medium: 1, Info: 1},
+					Rules: []RuleSummary{
+						{ID: "CKV_AWS_20", Name: "S3 Bucket has an ACL defined which allows public READ access.", Severity: SeverityInfo, Count: 1},
+						{ID: "CKV_AWS_999", Severity: SeverityMedium, Count: 1},
+					},
+					Locations: []LocationSummary{
+						{URI: "terraform/s3.tf", Count: 2},
+					},
+				},
+			},
+		},
+		{
+			name: "suppressed results and results of another kind than fail are counted separately",
+			file: "sarif-suppressions-kinds.json",
+			want: []*RunSummary{
+				{
+					Tool:           Tool{Name: "Semgrep OSS"},
+					Total:          3,
+					Suppressed:     2,
+					NotFailing:     2,
+					SeverityCounts: SeverityCounts{High: 1, Medium: 1, Info: 1},
+					Rules: []RuleSummary{
+						{ID: "python.flask.security.audit.debug-enabled", Severity: SeverityHigh, Count: 2},
+						{ID: "python.lang.best-practice.open-never-closed", Severity: SeverityInfo, Count: 1},
+					},
+					Locations: []LocationSummary{
+						{URI: "app/main.py", Count: 2},
+						{URI: "app/api.py", Count: 1},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessSarifFile(tt.file)
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, 1, got.SchemaVersion)
+			require.Len(t, got.Runs, len(tt.want))
+			for i, run := range got.Runs {
+				require.Len(t, run.Findings, run.Total)
+				// the findings are checked in TestProcessSarifFileFindings
+				run.Findings = nil
+				require.Equal(t, tt.want[i], run)
+			}
+		})
+	}
+}
+
+func TestProcessSarifFileFindings(t *testing.T) {
+	got, err := ProcessSarifFile("sarif-codeql.json")
+	require.NoError(t, err)
+	findings := got.Runs[0].Findings
+	for i := range findings {
+		require.Equal(t, findings[i].RuleID, findings[i].Rule.ID)
+		findings[i].Rule = nil
+	}
+	require.Equal(t, Finding{
+		RuleID:    "js/xss",
+		Severity:  SeverityHigh,
+		Message:   "Cross-site scripting vulnerability due to a user-provided value.",
+		Locations: []Location{{URI: "src/app.js", Lines: "12-14"}},
+	}, got.Runs[0].Findings[0])
+	require.Equal(t, Finding{
+		RuleID:    "js/sql-injection",
+		Severity:  SeverityCritical,
+		Message:   "This query depends on a user-provided value.",
+		Locations: []Location{{URI: "src/app.js", Lines: "40"}},
+	}, got.Runs[0].Findings[2])
+}
+
+func TestValidateSeverity(t *testing.T) {
+	for _, severity := range append(Severities, SeverityNone) {
+		require.NoError(t, ValidateSeverity(severity))
+	}
+	require.EqualError(t, ValidateSeverity("error"), `invalid severity "error", the allowed severities are: critical, high, medium, low, info, none`)
+}
@@ -0,0 +1,201 @@
+package sbom
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/kosli-dev/cli/internal/utils"
+)
+
+// cyclonedxSpecVersions are the supported versions of the CycloneDX specification
+var cyclonedxSpecVersions = []string{"1.2", "1.3", "1.4", "1.5", "1.6"}
+
+const cyclonedxXmlNamespace = "http://cyclonedx.org/schema/bom/"
+
+type cyclonedxBom struct {
+	BomFormat   string `json:"bomFormat"`
+	SpecVersion string `json:"specVersion"`
+	Metadata    struct {
+		Component *cyclonedxComponent `json:"component"`
+	} `json:"metadata"`
+	Components []cyclonedxComponent `json:"components"`
+}
+
+type cyclonedxComponent struct {
+	Type     string                   `json:"type"`
+	Name     string                   `json:"name"`
+	Group    string                   `json:"group"`
+	Version  string                   `json:"version"`
+	Purl     string                   `json:"purl"`
+	Licenses []cyclonedxLicenseChoice `json:"licenses"`
+	Hashes   []cyclonedxHash          `json:"hashes"`
+	// Components are the components nested in the component, e.g. the modules of an application
+	Components []cyclonedxComponent `json:"components"`
+}
+
+// cyclonedxLicenseChoice is either a license or an SPDX license expression
+type cyclonedxLicenseChoice struct {
+	License    *cyclonedxLicense `json:"license"`
+	Expression string            `json:"expression"`
+}
+
+type cyclonedxLicense struct {
+	ID   string `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+type cyclonedxHash struct {
+	Alg     string `json:"alg" xml:"alg,attr"`
+	Content string `json:"content" xml:",chardata"`
+}
+
+type cyclonedxXmlBom struct {
+	XMLName  xml.Name
+	Metadata struct {
+		Component *cyclonedxXmlComponent `xml:"component"`
+	} `xml:"metadata"`
+	Components []cyclonedxXmlComponent `xml:"components>component"`
+}
+
+type cyclonedxXmlComponent struct {
+	Type     string `xml:"type,attr"`
+	Name     string `xml:"name"`
+	Group    string `xml:"group"`
+	Version  string `xml:"version"`
+	Purl     string `xml:"purl"`
+	Licenses struct {
+		Licenses    []cyclonedxLicense `xml:"license"`
+		Expressions []string           `xml:"expression"`
+	} `xml:"licenses"`
+	Hashes     []cyclonedxHash         `xml:"hashes>hash"`
+	Components []cyclonedxXmlComponent `xml:"components>component"`
+}
+
+func processCycloneDXJson(content []byte) (*SbomData, error) {
+	bom := cyclonedxBom{}
+	if err := json.Unmarshal(content, &bom); err != nil {
+		return nil, fmt.Errorf("invalid CycloneDX SBOM: %s", err)
+	}
+	if bom.BomFormat != FormatCycloneDX {
+		return nil, fmt.Errorf("invalid CycloneDX SBOM: unsupported bomFormat %q", bom.BomFormat)
+	}
+	return bom.data(EncodingJSON)
+}
+
+func processCycloneDXXml(content []byte) (*SbomData, error) {
+	xmlBom := cyclonedxXmlBom{}
+	if err := xml.Unmarshal(content, &xmlBom); err != nil {
+		return nil, fmt.Errorf("invalid CycloneDX SBOM: %s", err)
+	}
+	if xmlBom.XMLName.Local != "bom" || !strings.HasPrefix(xmlBom.XMLName.Space, cyclonedxXmlNamespace) {
+		return nil, fmt.Errorf("unrecognized SBOM file, only CycloneDX XML documents are supported, got a %q element in the namespace %q",
+			xmlBom.XMLName.Local, xmlBom.XMLName.Space)
+	}
+
+	bom := cyclonedxBom{
+		BomFormat:   FormatCycloneDX,
+		SpecVersion: strings.TrimPrefix(xmlBom.XMLName.Space, cyclonedxXmlNamespace),
+		Components:  []cyclonedxComponent{},
+	}
+	if xmlBom.Metadata.Component != nil {
+		component := xmlBom.Metadata.Component.component()
+		bom.Metadata.Component = &component
+	}
+	for _, c := range xmlBom.Components {
+		bom.Components = append(bom.Components, c.component())
+	}
+	return bom.data(EncodingXML)
+}
+
+// component converts an XML component to its JSON representation
+func (c cyclonedxXmlComponent) component() cyclonedxComponent {
+	component := cyclonedxComponent{
+		Type:    c.Type,
+		Name:    strings.TrimSpace(c.Name),
+		Group:   strings.TrimSpace(c.Group),
+		Version: strings.TrimSpace(c.Version),
+		Purl:    strings.TrimSpace(c.Purl),
+	}
+	for _, l := range c.Licenses.Licenses {
+		license := cyclonedxLicense{ID: strings.TrimSpace(l.ID), Name: strings.TrimSpace(l.Name)}
+		component.Licenses = append(component.Licenses, cyclonedxLicenseChoice{License: &license})
+	}
+	for _, expression := range c.Licenses.Expressions {
+		component.Licenses = append(component.Licenses, cyclonedxLicenseChoice{Expression: strings.TrimSpace(expression)})
+	}
+	for _, h := range c.Hashes {
+		component.Hashes = append(component.Hashes, cyclonedxHash{Alg: h.Alg, Content: strings.TrimSpace(h.Content)})
+	}
+	for _, nested := range c.Components {
+		component.Components = append(component.Components, nested.component())
+	}
+	return component
+}
+
+// data validates the BOM and returns its summary
+func (bom cyclonedxBom) data(encoding string) (*SbomData, error) {
+	if !utils.Contains(cyclonedxSpecVersions, bom.SpecVersion) {
+		return nil, fmt.Errorf("invalid CycloneDX SBOM: unsupported spec version %q, the supported versions are: %s",
+			bom.SpecVersion, strings.Join(cyclonedxSpecVersions, ", "))
+	}
+
+	data := &SbomData{
+		SchemaVersion: 1,
+		Format:        FormatCycloneDX,
+		Encoding:      encoding,
+		SpecVersion:   bom.SpecVersion,
+		Components:    []Component{},
+	}
+	if subject := bom.Metadata.Component; subject != nil {
+		data.Name = subject.Name
+		data.Subject = &Subject{Name: subject.Name, Version: subject.Version}
+		for _, h := range subject.Hashes {
+			if strings.EqualFold(h.Alg, "SHA-256") {
+				data.Subject.addDigest(h.Content)
+			}
+		}
+		data.Subject.addPurlDigest(subject.Purl)
+	}
+
+	if err := addCycloneDXComponents(data, bom.Components, "components"); err != nil {
+		return nil, err
+	}
+	data.summarize()
+	return data, nil
+}
+
+// addCycloneDXComponents validates components and their nested components and adds them to data
+func addCycloneDXComponents(data *SbomData, components []cyclonedxComponent, path string) error {
+	for i, c := range components {
+		componentPath := fmt.Sprintf("%s[%d]", path, i)
+		if c.Type == "" {
+			return fmt.Errorf("invalid CycloneDX SBOM: %s has no type", componentPath)
+		}
+		if c.Name == "" {
+			return fmt.Errorf("invalid CycloneDX SBOM: %s has no name", componentPath)
+		}
+
+		component := Component{Name: c.Name, Version: c.Version, Purl: c.Purl}
+		if c.Group != "" {
+			component.Name = c.Group + "/" + c.Name
+		}
+		for _, l := range c.Licenses {
+			switch {
+			case l.Expression != "":
+				component.Licenses = append(component.Licenses, l.Expression)
+			case l.License != nil && l.License.ID != "":
+				component.Licenses = append(component.Licenses, l.License.ID)
+			case l.License != nil && l.License.Name != "":
+				component.Licenses = append(component.Licenses, l.License.Name)
+			}
+		}
+		data.Components = append(data.Components, component)
+
+		if err := addCycloneDXComponents(data, c.Components, componentPath+".components"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
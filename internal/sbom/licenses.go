@@ -0,0 +1,232 @@
+package sbom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the reasons why a component violates a license policy
+const (
+	ViolationDenied     = "denied"
+	ViolationNotAllowed = "not-allowed"
+	ViolationNoLicense  = "no-license"
+)
+
+// LicensePolicy decides the compliance of the licenses of the components of an SBOM.
+// License IDs are compared case-insensitively.
+type LicensePolicy struct {
+	// Allowed are the only licenses components can have, any license is allowed if empty
+	Allowed []string
+	// Denied are the licenses components cannot have
+	Denied []string
+}
+
+type LicenseViolation struct {
+	Component string `json:"component"`
+	Version   string `json:"version,omitempty"`
+	License   string `json:"license,omitempty"`
+	// Reason is denied, not-allowed or no-license
+	Reason string `json:"reason"`
+}
+
+// LicenseEvaluation is the result of evaluating the components of an SBOM against a LicensePolicy
+type LicenseEvaluation struct {
+	Compliant  bool               `json:"compliant"`
+	Allowed    []string           `json:"allowed_licenses,omitempty"`
+	Denied     []string           `json:"denied_licenses,omitempty"`
+	Violations []LicenseViolation `json:"violations"`
+}
+
+// IsEmpty returns whether the policy has neither allowed nor denied licenses
+func (p *LicensePolicy) IsEmpty() bool {
+	return len(p.Allowed) == 0 && len(p.Denied) == 0
+}
+
+// Evaluate evaluates the licenses of components against the policy. A component complies
+// when it complies with all its licenses. A license expression complies when, after choosing
+// one side of each OR, none of its licenses are denied and all of them are allowed.
+// Components without a license only comply when there are no allowed licenses.
+func (p *LicensePolicy) Evaluate(components []Component) *LicenseEvaluation {
+	evaluation := &LicenseEvaluation{
+		Allowed:    p.Allowed,
+		Denied:     p.Denied,
+		Violations: []LicenseViolation{},
+	}
+	for _, c := range components {
+		if len(c.Licenses) == 0 {
+			if len(p.Allowed) > 0 {
+				evaluation.Violations = append(evaluation.Violations,
+					LicenseViolation{Component: c.Name, Version: c.Version, Reason: ViolationNoLicense})
+			}
+			continue
+		}
+		for _, license := range c.Licenses {
+			if reason := p.check(license); reason != "" {
+				evaluation.Violations = append(evaluation.Violations,
+					LicenseViolation{Component: c.Name, Version: c.Version, License: license, Reason: reason})
+			}
+		}
+	}
+	evaluation.Compliant = len(evaluation.Violations) == 0
+	return evaluation
+}
+
+// Summary returns why the components do not comply with the policy, e.g. "2 components have a denied license"
+func (e *LicenseEvaluation) Summary() []string {
+	counts := map[string]int{}
+	for _, v := range e.Violations {
+		counts[v.Reason]++
+	}
+	summary := []string{}
+	for _, reason := range []struct{ id, description string }{
+		{ViolationDenied, "have a denied license"},
+		{ViolationNotAllowed, "have a license which is not allowed"},
+		{ViolationNoLicense, "have no license"},
+	} {
+		if count := counts[reason.id]; count > 0 {
+			summary = append(summary, fmt.Sprintf("%d components %s", count, reason.description))
+		}
+	}
+	return summary
+}
+
+// check returns why a license or license expression violates the policy,
+// or an empty string if it complies
+func (p *LicensePolicy) check(license string) string {
+	expression, err := parseLicenseExpression(license)
+	if err != nil {
+		// license names, e.g. "Apache License 2.0", are not SPDX expressions
+		expression = &licenseExpression{license: license}
+	}
+	if expression.complies(p.isAllowed) {
+		return ""
+	}
+	if len(p.Denied) > 0 && !expression.complies(func(id string) bool { return !contains(p.Denied, id) }) {
+		return ViolationDenied
+	}
+	return ViolationNotAllowed
+}
+
+func (p *LicensePolicy) isAllowed(license string) bool {
+	if contains(p.Denied, license) {
+		return false
+	}
+	return len(p.Allowed) == 0 || contains(p.Allowed, license)
+}
+
+func contains(licenses []string, license string) bool {
+	for _, l := range licenses {
+		if strings.EqualFold(l, license) {
+			return true
+		}
+	}
+	return false
+}
+
+// licenseExpression is an SPDX license expression: a license, or the AND or
+// the OR of two expressions. License exceptions (WITH) are ignored.
+type licenseExpression struct {
+	license     string
+	operator    string
+	left, right *licenseExpression
+}
+
+// complies returns whether the expression complies when isAllowed decides which licenses comply
+func (e *licenseExpression) complies(isAllowed func(string) bool) bool {
+	switch e.operator {
+	case "AND":
+		return e.left.complies(isAllowed) && e.right.complies(isAllowed)
+	case "OR":
+		return e.left.complies(isAllowed) || e.right.complies(isAllowed)
+	}
+	return isAllowed(e.license)
+}
+
+// parseLicenseExpression parses an SPDX license expression, e.g. "(MIT OR Apache-2.0) AND BSD-3-Clause"
+func parseLicenseExpression(expression string) (*licenseExpression, error) {
+	expression = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)
+	parser := &licenseParser{tokens: strings.Fields(expression)}
+	result, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.position != len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected %q in license expression", parser.tokens[parser.position])
+	}
+	return result, nil
+}
+
+// licenseParser parses license expressions, where AND has precedence over OR
+type licenseParser struct {
+	tokens   []string
+	position int
+}
+
+func (p *licenseParser) next() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+func (p *licenseParser) parseOr() (*licenseExpression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.next(), "OR") {
+		p.position++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &licenseExpression{operator: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *licenseParser) parseAnd() (*licenseExpression, error) {
+	left, err := p.parseLicense()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.next(), "AND") {
+		p.position++
+		right, err := p.parseLicense()
+		if err != nil {
+			return nil, err
+		}
+		left = &licenseExpression{operator: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *licenseParser) parseLicense() (*licenseExpression, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of license expression")
+	case token == "(":
+		p.position++
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ) in license expression")
+		}
+		p.position++
+		return expression, nil
+	case token == ")" || strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR") || strings.EqualFold(token, "WITH"):
+		return nil, fmt.Errorf("unexpected %q in license expression", token)
+	}
+	p.position++
+	if strings.EqualFold(p.next(), "WITH") {
+		// skip the exception, e.g. GPL-2.0-only WITH Classpath-exception-2.0
+		p.position += 2
+		if p.position > len(p.tokens) {
+			return nil, fmt.Errorf("missing exception in license expression")
+		}
+	}
+	return &licenseExpression{license: token}, nil
+}
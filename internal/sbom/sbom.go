@@ -0,0 +1,193 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the formats of SBOM documents
+const (
+	FormatCycloneDX = "CycloneDX"
+	FormatSPDX      = "SPDX"
+)
+
+// the encodings of SBOM documents
+const (
+	EncodingJSON     = "json"
+	EncodingXML      = "xml"
+	EncodingTagValue = "tag-value"
+)
+
+// EcosystemUnknown is the ecosystem of components without a package URL
+const EcosystemUnknown = "unknown"
+
+// Component is a component (CycloneDX) or a package (SPDX) of an SBOM
+type Component struct {
+	Name    string
+	Version string
+	// Purl is the package URL of the component, e.g. pkg:npm/lodash@4.17.21
+	Purl string
+	// Licenses are the licenses of the component, as SPDX license IDs, SPDX license
+	// expressions or license names. A component must comply with all of them.
+	Licenses []string
+}
+
+// Subject is what the SBOM describes, e.g. the container image or the application
+type Subject struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Digests are the hex SHA-256 digests of the subject
+	Digests []string `json:"sha256_digests,omitempty"`
+}
+
+// LicenseSummary is the number of components with a license
+type LicenseSummary struct {
+	License string `json:"license"`
+	Count   int    `json:"count"`
+}
+
+// EcosystemSummary is the number of components of an ecosystem (the type of their package URL)
+type EcosystemSummary struct {
+	Ecosystem string `json:"ecosystem"`
+	Count     int    `json:"count"`
+}
+
+type SbomData struct {
+	SchemaVersion   int                `json:"schema_version"`
+	Format          string             `json:"format"`
+	Encoding        string             `json:"encoding"`
+	SpecVersion     string             `json:"spec_version"`
+	Name            string             `json:"name,omitempty"`
+	Subject         *Subject           `json:"subject,omitempty"`
+	ComponentCount  int                `json:"component_count"`
+	UnlicensedCount int                `json:"unlicensed_count"`
+	Licenses        []LicenseSummary   `json:"licenses"`
+	Ecosystems      []EcosystemSummary `json:"ecosystems"`
+	// Components are not reported, the SBOM document is uploaded instead
+	Components []Component `json:"-"`
+}
+
+// ProcessSbomFile takes a path to a CycloneDX (JSON or XML) or SPDX (JSON or tag-value) document,
+// validates it and returns a summary of its components
+func ProcessSbomFile(file string) (*SbomData, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("the provided file path doesn't have a file: %s", err)
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return processCycloneDXXml(content)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var probe struct {
+			BomFormat   *string `json:"bomFormat"`
+			SpdxVersion *string `json:"spdxVersion"`
+		}
+		if err := json.Unmarshal(content, &probe); err != nil {
+			return nil, err
+		}
+		if probe.BomFormat != nil {
+			return processCycloneDXJson(content)
+		}
+		if probe.SpdxVersion != nil {
+			return processSpdxJson(content)
+		}
+	case spdxTagValuePattern.Match(content):
+		return processSpdxTagValue(content)
+	}
+	return nil, fmt.Errorf("unrecognized SBOM file, expected a CycloneDX (JSON or XML) or SPDX (JSON or tag-value) document")
+}
+
+// HasSubjectDigest returns whether fingerprint is a SHA-256 digest of the subject of the SBOM
+func (d *SbomData) HasSubjectDigest(fingerprint string) bool {
+	if d.Subject == nil {
+		return false
+	}
+	for _, digest := range d.Subject.Digests {
+		if strings.EqualFold(digest, fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// summarize counts the components per license and per ecosystem
+func (d *SbomData) summarize() {
+	d.ComponentCount = len(d.Components)
+	d.Licenses = []LicenseSummary{}
+	d.Ecosystems = []EcosystemSummary{}
+
+	licenses := map[string]int{}
+	ecosystems := map[string]int{}
+	for _, c := range d.Components {
+		if len(c.Licenses) == 0 {
+			d.UnlicensedCount++
+		}
+		for _, license := range c.Licenses {
+			licenses[license]++
+		}
+		ecosystems[ecosystem(c.Purl)]++
+	}
+	for license, count := range licenses {
+		d.Licenses = append(d.Licenses, LicenseSummary{License: license, Count: count})
+	}
+	sort.Slice(d.Licenses, func(i, j int) bool {
+		if d.Licenses[i].Count != d.Licenses[j].Count {
+			return d.Licenses[i].Count > d.Licenses[j].Count
+		}
+		return d.Licenses[i].License < d.Licenses[j].License
+	})
+	for name, count := range ecosystems {
+		d.Ecosystems = append(d.Ecosystems, EcosystemSummary{Ecosystem: name, Count: count})
+	}
+	sort.Slice(d.Ecosystems, func(i, j int) bool {
+		if d.Ecosystems[i].Count != d.Ecosystems[j].Count {
+			return d.Ecosystems[i].Count > d.Ecosystems[j].Count
+		}
+		return d.Ecosystems[i].Ecosystem < d.Ecosystems[j].Ecosystem
+	})
+}
+
+// ecosystem returns the type of a package URL, e.g. npm for pkg:npm/lodash@4.17.21
+func ecosystem(purl string) string {
+	if !strings.HasPrefix(purl, "pkg:") {
+		return EcosystemUnknown
+	}
+	purlType, _, found := strings.Cut(strings.TrimPrefix(purl, "pkg:"), "/")
+	if !found || purlType == "" {
+		return EcosystemUnknown
+	}
+	return strings.ToLower(purlType)
+}
+
+// purlDigestPattern matches the SHA-256 digest version of a package URL, e.g. pkg:oci/app@sha256%3A9a8b...
+var purlDigestPattern = regexp.MustCompile(`(?i)@sha256(?::|%3A)([a-f0-9]{64})(?:[?#]|$)`)
+
+// addDigest adds a hex SHA-256 digest to the subject if it is not there yet
+func (s *Subject) addDigest(digest string) {
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if digest == "" {
+		return
+	}
+	for _, d := range s.Digests {
+		if d == digest {
+			return
+		}
+	}
+	s.Digests = append(s.Digests, digest)
+}
+
+// addPurlDigest adds the SHA-256 digest of the subject if it is the version of its package URL.
+// The version of the subject is not used: tools like syft set it to the image ID, which is not the
+// digest of the image in its registry.
+func (s *Subject) addPurlDigest(purl string) {
+	if match := purlDigestPattern.FindStringSubmatch(purl); match != nil {
+		s.addDigest(match[1])
+	}
+}
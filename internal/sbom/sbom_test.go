@@ -0,0 +1,297 @@
+package sbom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const subjectDigest = "3c0e1bd6b1f5f2e2a4b9b0dc64c6e3f5a5d7c2b9e1f0a3d4c5b6a7980f1e2d3c"
+
+func TestProcessSbomFile(t *testing.T) {
+	tests := []struct {
+		name            string
+		file            string
+		want            *SbomData
+		wantComponents  int
+		wantErrContains string
+	}{
+		{
+			name:            "non-existing file causes an error",
+			file:            "non-existing.json",
+			wantErrContains: "the provided file path doesn't have a file",
+		},
+		{
+			name:            "a file which is not an SBOM causes an error",
+			file:            "../sarif/sarif-codeql.json",
+			wantErrContains: "unrecognized SBOM file, expected a CycloneDX (JSON or XML) or SPDX (JSON or tag-value) document",
+		},
+		{
+			name:            "a CycloneDX component without a name causes an error",
+			file:            "cyclonedx-invalid.json",
+			wantErrContains: "invalid CycloneDX SBOM: components[1] has no name",
+		},
+		{
+			name:            "an SPDX document without a namespace causes an error",
+			file:            "spdx-invalid.json",
+			wantErrContains: "invalid SPDX SBOM: the document has no namespace",
+		},
+		{
+			name: "CycloneDX JSON components and their nested components are summarized",
+			file: "cyclonedx.json",
+			want: &SbomData{
+				SchemaVersion: 1,
+				Format:        FormatCycloneDX,
+				Encoding:      EncodingJSON,
+				SpecVersion:   "1.5",
+				Name:          "ghcr.io/acme/web",
+				Subject: &Subject{
+					Name:    "ghcr.io/acme/web",
+					Version: "sha256:" + subjectDigest,
+					Digests: []string{subjectDigest},
+				},
+				ComponentCount:  6,
+				UnlicensedCount: 1,
+				Licenses: []LicenseSummary{
+					{License: "GPL-2.0-only", Count: 2},
+					{License: "MIT", Count: 2},
+					{License: "BSD-3-Clause OR GPL-2.0-only", Count: 1},
+				},
+				Ecosystems: []EcosystemSummary{
+					{Ecosystem: "npm", Count: 3},
+					{Ecosystem: "apk", Count: 2},
+					{Ecosystem: EcosystemUnknown, Count: 1},
+				},
+			},
+		},
+		{
+			name: "CycloneDX XML components are summarized",
+			file: "cyclonedx.xml",
+			want: &SbomData{
+				SchemaVersion:  1,
+				Format:         FormatCycloneDX,
+				Encoding:       EncodingXML,
+				SpecVersion:    "1.4",
+				Name:           "acme-api",
+				Subject:        &Subject{Name: "acme-api", Version: "2.3.0"},
+				ComponentCount: 3,
+				Licenses: []LicenseSummary{
+					{License: "Apache-2.0", Count: 1},
+					{License: "EPL-1.0 OR LGPL-2.1-only", Count: 1},
+					{License: "GNU Library General Public License v2.1 or later", Count: 1},
+				},
+				Ecosystems: []EcosystemSummary{
+					{Ecosystem: "maven", Count: 3},
+				},
+			},
+		},
+		{
+			name: "SPDX JSON packages are summarized without the package the document describes",
+			file: "spdx.json",
+			want: &SbomData{
+				SchemaVersion: 1,
+				Format:        FormatSPDX,
+				Encoding:      EncodingJSON,
+				SpecVersion:   "SPDX-2.3",
+				Name:          "ghcr.io/acme/worker",
+				Subject: &Subject{
+					Name:    "ghcr.io/acme/worker",
+					Version: "sha256:" + subjectDigest,
+					Digests: []string{subjectDigest},
+				},
+				ComponentCount:  3,
+				UnlicensedCount: 1,
+				Licenses: []LicenseSummary{
+					{License: "Apache-2.0", Count: 1},
+					{License: "LGPL-3.0-or-later WITH openvpn-openssl-exception", Count: 1},
+				},
+				Ecosystems: []EcosystemSummary{
+					{Ecosystem: "pypi", Count: 2},
+					{Ecosystem: EcosystemUnknown, Count: 1},
+				},
+			},
+		},
+		{
+			name: "the image ID version of the subject of a syft SBOM is not a digest of the subject",
+			file: "syft-cyclonedx.json",
+			want: &SbomData{
+				SchemaVersion: 1,
+				Format:        FormatCycloneDX,
+				Encoding:      EncodingJSON,
+				SpecVersion:   "1.6",
+				Name:          "ghcr.io/acme/api",
+				Subject: &Subject{
+					Name:    "ghcr.io/acme/api",
+					Version: "sha256:8d1e5a0b2c4f6e7d9a1b3c5d7e9f0a2b4c6d8e0f1a3b5c7d9e1f2a4b6c8d0e1f",
+				},
+				ComponentCount:  3,
+				UnlicensedCount: 2,
+				Licenses: []LicenseSummary{
+					{License: "MIT", Count: 1},
+				},
+				Ecosystems: []EcosystemSummary{
+					{Ecosystem: "apk", Count: 1},
+					{Ecosystem: "golang", Count: 1},
+					{Ecosystem: EcosystemUnknown, Count: 1},
+				},
+			},
+		},
+		{
+			name: "SPDX tag-value packages are summarized without the files",
+			file: "spdx.spdx",
+			want: &SbomData{
+				SchemaVersion: 1,
+				Format:        FormatSPDX,
+				Encoding:      EncodingTagValue,
+				SpecVersion:   "SPDX-2.2",
+				Name:          "acme-cli",
+				Subject: &Subject{
+					Name:    "acme-cli",
+					Version: "1.8.0",
+					Digests: []string{subjectDigest},
+				},
+				ComponentCount: 2,
+				Licenses: []LicenseSummary{
+					{License: "Apache-2.0", Count: 1},
+					{License: "MPL-2.0", Count: 1},
+				},
+				Ecosystems: []EcosystemSummary{
+					{Ecosystem: "golang", Count: 2},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessSbomFile(tt.file)
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, got.Components, got.ComponentCount)
+			// the components are checked in TestProcessSbomFileComponents
+			got.Components = nil
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProcessSbomFileComponents(t *testing.T) {
+	got, err := ProcessSbomFile("cyclonedx.json")
+	require.NoError(t, err)
+	require.Equal(t, Component{
+		Name:     "@babel/core",
+		Version:  "7.25.2",
+		Purl:     "pkg:npm/%40babel/core@7.25.2",
+		Licenses: []string{"MIT"},
+	}, got.Components[1])
+	require.Equal(t, "busybox-binsh", got.Components[4].Name)
+
+	got, err = ProcessSbomFile("spdx.spdx")
+	require.NoError(t, err)
+	require.Equal(t, Component{
+		Name:     "github.com/hashicorp/go-version",
+		Version:  "v1.7.0",
+		Purl:     "pkg:golang/github.com/hashicorp/go-version@v1.7.0",
+		Licenses: []string{"MPL-2.0"},
+	}, got.Components[1])
+}
+
+func TestHasSubjectDigest(t *testing.T) {
+	got, err := ProcessSbomFile("spdx.json")
+	require.NoError(t, err)
+	require.True(t, got.HasSubjectDigest(subjectDigest))
+	require.False(t, got.HasSubjectDigest("7509e5bda0c762d2bac7f90d758b5b2263fa01ccbc542ab5e3df163be08e6ca9"))
+	require.False(t, (&SbomData{}).HasSubjectDigest(subjectDigest))
+}
+
+func TestLicensePolicyEvaluate(t *testing.T) {
+	data, err := ProcessSbomFile("cyclonedx.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		policy         *LicensePolicy
+		components     []Component
+		wantViolations []LicenseViolation
+		wantSummary    []string
+	}{
+		{
+			name:           "any license complies with an empty policy",
+			policy:         &LicensePolicy{},
+			components:     data.Components,
+			wantViolations: []LicenseViolation{},
+			wantSummary:    []string{},
+		},
+		{
+			name:       "licenses are denied unless an OR expression has an alternative",
+			policy:     &LicensePolicy{Denied: []string{"gpl-2.0-only"}},
+			components: data.Components,
+			wantViolations: []LicenseViolation{
+				{Component: "busybox", Version: "1.36.1-r29", License: "GPL-2.0-only", Reason: ViolationDenied},
+				{Component: "busybox-binsh", Version: "1.36.1-r29", License: "GPL-2.0-only", Reason: ViolationDenied},
+			},
+			wantSummary: []string{"2 components have a denied license"},
+		},
+		{
+			name:       "components without an allowed license or without a license do not comply",
+			policy:     &LicensePolicy{Allowed: []string{"MIT", "BSD-3-Clause"}},
+			components: data.Components,
+			wantViolations: []LicenseViolation{
+				{Component: "busybox", Version: "1.36.1-r29", License: "GPL-2.0-only", Reason: ViolationNotAllowed},
+				{Component: "busybox-binsh", Version: "1.36.1-r29", License: "GPL-2.0-only", Reason: ViolationNotAllowed},
+				{Component: "/app/vendor/legacy.js", Reason: ViolationNoLicense},
+			},
+			wantSummary: []string{"2 components have a license which is not allowed", "1 components have no license"},
+		},
+		{
+			name:   "denied licenses have precedence over allowed licenses",
+			policy: &LicensePolicy{Allowed: []string{"MIT", "BSD-3-Clause"}, Denied: []string{"BSD-3-Clause"}},
+			components: []Component{
+				{Name: "node-forge", Version: "1.3.1", Licenses: []string{"BSD-3-Clause OR GPL-2.0-only"}},
+				{Name: "express", Version: "4.19.2", Licenses: []string{"MIT"}},
+			},
+			wantViolations: []LicenseViolation{
+				{Component: "node-forge", Version: "1.3.1", License: "BSD-3-Clause OR GPL-2.0-only", Reason: ViolationNotAllowed},
+			},
+			wantSummary: []string{"1 components have a license which is not allowed"},
+		},
+		{
+			name:   "AND expressions, exceptions and license names are evaluated",
+			policy: &LicensePolicy{Allowed: []string{"MIT", "Apache-2.0", "GPL-2.0-only"}, Denied: []string{"Apache License 2.0"}},
+			components: []Component{
+				{Name: "a", Licenses: []string{"(MIT OR LGPL-2.1-only) AND Apache-2.0"}},
+				{Name: "b", Licenses: []string{"MIT AND LGPL-2.1-only"}},
+				{Name: "c", Licenses: []string{"GPL-2.0-only WITH Classpath-exception-2.0"}},
+				{Name: "d", Licenses: []string{"Apache License 2.0"}},
+				{Name: "e", Licenses: []string{"MIT", "LGPL-2.1-only"}},
+			},
+			wantViolations: []LicenseViolation{
+				{Component: "b", License: "MIT AND LGPL-2.1-only", Reason: ViolationNotAllowed},
+				{Component: "d", License: "Apache License 2.0", Reason: ViolationDenied},
+				{Component: "e", License: "LGPL-2.1-only", Reason: ViolationNotAllowed},
+			},
+			wantSummary: []string{"1 components have a denied license", "2 components have a license which is not allowed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluation := tt.policy.Evaluate(tt.components)
+			require.Equal(t, len(tt.wantViolations) == 0, evaluation.Compliant)
+			require.Equal(t, tt.wantViolations, evaluation.Violations)
+			require.Equal(t, tt.wantSummary, evaluation.Summary())
+		})
+	}
+}
+
+func TestParseLicenseExpression(t *testing.T) {
+	for _, expression := range []string{"MIT", "(MIT OR Apache-2.0) AND BSD-3-Clause", "GPL-2.0-only WITH Classpath-exception-2.0 OR MIT"} {
+		_, err := parseLicenseExpression(expression)
+		require.NoError(t, err, expression)
+	}
+	for _, expression := range []string{"", "MIT AND", "(MIT OR Apache-2.0", "MIT Apache-2.0", "GPL-2.0-only WITH", "OR MIT"} {
+		_, err := parseLicenseExpression(expression)
+		require.Error(t, err, expression)
+	}
+}
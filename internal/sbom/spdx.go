@@ -0,0 +1,252 @@
+package sbom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kosli-dev/cli/internal/utils"
+)
+
+// spdxVersions are the supported versions of the SPDX specification
+var spdxVersions = []string{"SPDX-2.1", "SPDX-2.2", "SPDX-2.3"}
+
+// spdxTagValuePattern matches the version tag of SPDX tag-value documents
+var spdxTagValuePattern = regexp.MustCompile(`(?m)^SPDXVersion:\s*SPDX-`)
+
+const spdxDocumentID = "SPDXRef-DOCUMENT"
+
+type spdxDocument struct {
+	SpdxVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created string `json:"created"`
+	} `json:"creationInfo"`
+	DocumentDescribes []string           `json:"documentDescribes"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	Checksums        []spdxChecksum    `json:"checksums"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SpdxElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+func processSpdxJson(content []byte) (*SbomData, error) {
+	doc := spdxDocument{}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("invalid SPDX SBOM: %s", err)
+	}
+	return doc.data(EncodingJSON)
+}
+
+// processSpdxTagValue parses the document information, the packages and the relationships
+// of an SPDX tag-value document. The files, snippets and other licensing information are skipped.
+func processSpdxTagValue(content []byte) (*SbomData, error) {
+	doc := spdxDocument{}
+	var pkg *spdxPackage
+	inPackage := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tag, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid SPDX SBOM: line %d is not a tag-value pair", lineNumber)
+		}
+		value = strings.TrimSpace(value)
+
+		// multi-line values, e.g. comments, are wrapped in <text></text>
+		if strings.HasPrefix(value, "<text>") && !strings.Contains(value, "</text>") {
+			for !strings.Contains(scanner.Text(), "</text>") && scanner.Scan() {
+				lineNumber++
+			}
+			continue
+		}
+
+		switch tag {
+		case "PackageName":
+			doc.Packages = append(doc.Packages, spdxPackage{Name: value})
+			pkg = &doc.Packages[len(doc.Packages)-1]
+			inPackage = true
+		case "FileName", "SnippetSPDXID", "LicenseID":
+			inPackage = false
+		case "SPDXVersion":
+			doc.SpdxVersion = value
+		case "DataLicense":
+			doc.DataLicense = value
+		case "DocumentName":
+			doc.Name = value
+		case "DocumentNamespace":
+			doc.DocumentNamespace = value
+		case "Created":
+			doc.CreationInfo.Created = value
+		case "Relationship":
+			fields := strings.Fields(value)
+			if len(fields) == 3 {
+				doc.Relationships = append(doc.Relationships, spdxRelationship{
+					SpdxElementID:      fields[0],
+					RelationshipType:   fields[1],
+					RelatedSpdxElement: fields[2],
+				})
+			}
+		case "SPDXID":
+			if inPackage {
+				pkg.SPDXID = value
+			} else if doc.SPDXID == "" && len(doc.Packages) == 0 {
+				doc.SPDXID = value
+			}
+		}
+
+		if !inPackage {
+			continue
+		}
+		switch tag {
+		case "PackageVersion":
+			pkg.VersionInfo = value
+		case "PackageLicenseConcluded":
+			pkg.LicenseConcluded = value
+		case "PackageLicenseDeclared":
+			pkg.LicenseDeclared = value
+		case "PackageChecksum":
+			algorithm, checksum, _ := strings.Cut(value, ":")
+			pkg.Checksums = append(pkg.Checksums, spdxChecksum{Algorithm: strings.TrimSpace(algorithm), ChecksumValue: strings.TrimSpace(checksum)})
+		case "ExternalRef":
+			fields := strings.Fields(value)
+			if len(fields) == 3 {
+				pkg.ExternalRefs = append(pkg.ExternalRefs, spdxExternalRef{ReferenceCategory: fields[0], ReferenceType: fields[1], ReferenceLocator: fields[2]})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid SPDX SBOM: %s", err)
+	}
+	return doc.data(EncodingTagValue)
+}
+
+// data validates the document and returns its summary. The packages the document
+// describes are its subject, the other packages are its components.
+func (doc spdxDocument) data(encoding string) (*SbomData, error) {
+	if !utils.Contains(spdxVersions, doc.SpdxVersion) {
+		return nil, fmt.Errorf("invalid SPDX SBOM: unsupported version %q, the supported versions are: %s",
+			doc.SpdxVersion, strings.Join(spdxVersions, ", "))
+	}
+	if doc.DataLicense != "CC0-1.0" {
+		return nil, fmt.Errorf("invalid SPDX SBOM: the data license must be CC0-1.0, got %q", doc.DataLicense)
+	}
+	if doc.SPDXID != spdxDocumentID {
+		return nil, fmt.Errorf("invalid SPDX SBOM: the SPDX identifier of the document must be %s, got %q", spdxDocumentID, doc.SPDXID)
+	}
+	if doc.Name == "" {
+		return nil, fmt.Errorf("invalid SPDX SBOM: the document has no name")
+	}
+	if doc.DocumentNamespace == "" {
+		return nil, fmt.Errorf("invalid SPDX SBOM: the document has no namespace")
+	}
+	if doc.CreationInfo.Created == "" {
+		return nil, fmt.Errorf("invalid SPDX SBOM: the document has no creation time")
+	}
+
+	described := doc.DocumentDescribes
+	for _, r := range doc.Relationships {
+		if r.SpdxElementID == spdxDocumentID && r.RelationshipType == "DESCRIBES" {
+			described = append(described, r.RelatedSpdxElement)
+		}
+		if r.RelatedSpdxElement == spdxDocumentID && r.RelationshipType == "DESCRIBED_BY" {
+			described = append(described, r.SpdxElementID)
+		}
+	}
+
+	data := &SbomData{
+		SchemaVersion: 1,
+		Format:        FormatSPDX,
+		Encoding:      encoding,
+		SpecVersion:   doc.SpdxVersion,
+		Name:          doc.Name,
+		Components:    []Component{},
+	}
+	for i, p := range doc.Packages {
+		if p.SPDXID == "" {
+			return nil, fmt.Errorf("invalid SPDX SBOM: packages[%d] has no SPDX identifier", i)
+		}
+		if p.Name == "" {
+			return nil, fmt.Errorf("invalid SPDX SBOM: package %s has no name", p.SPDXID)
+		}
+
+		purl := p.purl()
+		if utils.Contains(described, p.SPDXID) {
+			if data.Subject == nil {
+				data.Subject = &Subject{Name: p.Name, Version: p.VersionInfo}
+			}
+			for _, c := range p.Checksums {
+				if strings.EqualFold(c.Algorithm, "SHA256") {
+					data.Subject.addDigest(c.ChecksumValue)
+				}
+			}
+			data.Subject.addPurlDigest(purl)
+			continue
+		}
+
+		component := Component{Name: p.Name, Version: p.VersionInfo, Purl: purl}
+		if license := p.license(); license != "" {
+			component.Licenses = []string{license}
+		}
+		data.Components = append(data.Components, component)
+	}
+	data.summarize()
+	return data, nil
+}
+
+func (p spdxPackage) purl() string {
+	for _, ref := range p.ExternalRefs {
+		if ref.ReferenceType == "purl" {
+			return ref.ReferenceLocator
+		}
+	}
+	return ""
+}
+
+// license returns the concluded license of the package, or its declared license
+// if no license was concluded. NOASSERTION and NONE are no license.
+func (p spdxPackage) license() string {
+	for _, license := range []string{p.LicenseConcluded, p.LicenseDeclared} {
+		if license != "" && license != "NOASSERTION" && license != "NONE" {
+			return license
+		}
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package scanpolicy
+
+import (
+	"github.com/kosli-dev/cli/internal/sarif"
+	"github.com/kosli-dev/cli/internal/snyk"
+)
+
+// SarifFindings returns the findings of all the runs of SARIF results
+func SarifFindings(data *sarif.SarifData) []Finding {
+	findings := []Finding{}
+	for _, run := range data.Runs {
+		for _, f := range run.Findings {
+			finding := Finding{
+				ID:       f.RuleID,
+				Severity: f.Severity,
+			}
+			for _, l := range f.Locations {
+				if l.URI != "" {
+					finding.Locations = append(finding.Locations, l.URI)
+				}
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// SnykFindings returns the vulnerabilities of all the results of Snyk results,
+// with their identifiers (e.g. CVEs) as aliases
+func SnykFindings(data *snyk.SnykData) []Finding {
+	findings := []Finding{}
+	for _, result := range data.Results {
+		for _, vulnerabilities := range [][]snyk.Vulnerability{result.High, result.Medium, result.Low} {
+			for _, vul := range vulnerabilities {
+				finding := Finding{
+					ID:       vul.ID,
+					Aliases:  vul.Identifiers,
+					Severity: vul.Severity,
+				}
+				for _, l := range vul.Locations {
+					if l.URI != "" {
+						finding.Locations = append(finding.Locations, l.URI)
+					}
+				}
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
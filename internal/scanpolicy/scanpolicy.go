@@ -0,0 +1,242 @@
+package scanpolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kosli-dev/cli/internal/sarif"
+	"github.com/kosli-dev/cli/internal/utils"
+)
+
+// the reasons why a finding is suppressed
+const (
+	SuppressedByIgnoreFile   = "ignore-file"
+	SuppressedByBaselineFile = "baseline-file"
+)
+
+// IgnoreSpec is an ignore file: the rules and vulnerabilities (e.g. CVEs) whose findings
+// do not count for compliance, until they expire
+type IgnoreSpec struct {
+	Version int          `mapstructure:"version" validate:"required,oneof=1"`
+	Ignore  []IgnoreRule `mapstructure:"ignore" validate:"required,dive"`
+}
+
+type IgnoreRule struct {
+	// ID is a rule ID, a vulnerability ID or a vulnerability identifier such as a CVE
+	ID     string `mapstructure:"id" json:"id" validate:"required"`
+	Reason string `mapstructure:"reason" json:"reason" validate:"required"`
+	// Expires is the day from which the rule is not applied anymore, it never expires if not set
+	Expires time.Time `mapstructure:"expires" json:"expires,omitempty"`
+}
+
+// BaselineSpec is a baseline file: the findings which were previously accepted
+type BaselineSpec struct {
+	Version  int               `mapstructure:"version" validate:"required,oneof=1"`
+	Findings []BaselineFinding `mapstructure:"findings" validate:"required,dive"`
+}
+
+type BaselineFinding struct {
+	ID string `mapstructure:"id" validate:"required"`
+	// Location is the file of the finding, the finding is accepted in any file if not set
+	Location string `mapstructure:"location"`
+	Reason   string `mapstructure:"reason"`
+}
+
+// Finding is a finding of a security scan evaluated against a Policy
+type Finding struct {
+	ID string
+	// Aliases are the other IDs of the finding, e.g. its CVEs
+	Aliases   []string
+	Severity  string
+	Locations []string
+}
+
+// Policy decides the compliance of the findings of a security scan
+type Policy struct {
+	// FailOn is the lowest severity which makes a scan non-compliant, none or empty to not use it
+	FailOn string
+	// MaxFindings are the maximum numbers of findings per severity
+	MaxFindings map[string]int
+	Ignore      *IgnoreSpec
+	Baseline    *BaselineSpec
+	// Now is the time at which ignore rules expire, defaults to the current time
+	Now time.Time
+}
+
+type SuppressedFinding struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+	Location string `json:"location,omitempty"`
+	// SuppressedBy is the file which suppresses the finding (ignore-file or baseline-file)
+	SuppressedBy string `json:"suppressed_by"`
+	// MatchedID is the ID of the ignore rule or baseline finding which suppresses the finding
+	MatchedID string     `json:"matched_id"`
+	Reason    string     `json:"reason,omitempty"`
+	Expires   *time.Time `json:"expires,omitempty"`
+}
+
+// Evaluation is the result of evaluating the findings of a scan against a Policy,
+// with the reasons of non-compliance and the findings which were suppressed
+type Evaluation struct {
+	Compliant      bool                `json:"compliant"`
+	FailOn         string              `json:"fail_on,omitempty"`
+	MaxFindings    map[string]int      `json:"max_findings,omitempty"`
+	Counts         map[string]int      `json:"counts"`
+	Violations     []string            `json:"violations"`
+	Suppressed     []SuppressedFinding `json:"suppressed"`
+	ExpiredIgnores []IgnoreRule        `json:"expired_ignores,omitempty"`
+}
+
+// Validate checks the severities of the policy
+func (p *Policy) Validate() error {
+	if p.FailOn != "" {
+		if err := sarif.ValidateSeverity(p.FailOn); err != nil {
+			return fmt.Errorf("%s for --fail-on", err.Error())
+		}
+	}
+	for severity, max := range p.MaxFindings {
+		if err := sarif.ValidateSeverity(severity); err != nil || severity == sarif.SeverityNone {
+			return fmt.Errorf("invalid severity %q for --max-findings, the allowed severities are: %s", severity, strings.Join(sarif.Severities, ", "))
+		}
+		if max < 0 {
+			return fmt.Errorf("invalid maximum number of %s findings %d for --max-findings, it must be 0 or more", severity, max)
+		}
+	}
+	return nil
+}
+
+// HasThresholds returns whether the policy decides compliance
+func (p *Policy) HasThresholds() bool {
+	return (p.FailOn != "" && p.FailOn != sarif.SeverityNone) || len(p.MaxFindings) > 0
+}
+
+// Evaluate evaluates findings against the policy. Findings suppressed by the ignore file or
+// the baseline file do not count for compliance.
+func (p *Policy) Evaluate(findings []Finding) *Evaluation {
+	now := p.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	evaluation := &Evaluation{
+		Compliant:   true,
+		FailOn:      p.FailOn,
+		MaxFindings: p.MaxFindings,
+		Counts:      map[string]int{},
+		Violations:  []string{},
+		Suppressed:  []SuppressedFinding{},
+	}
+	for _, severity := range sarif.Severities {
+		evaluation.Counts[severity] = 0
+	}
+
+	ignoreRules := []IgnoreRule{}
+	if p.Ignore != nil {
+		for _, rule := range p.Ignore.Ignore {
+			if !rule.Expires.IsZero() && !now.Before(rule.Expires) {
+				evaluation.ExpiredIgnores = append(evaluation.ExpiredIgnores, rule)
+				continue
+			}
+			ignoreRules = append(ignoreRules, rule)
+		}
+	}
+
+	for _, finding := range findings {
+		if suppressed := p.suppress(finding, ignoreRules); suppressed != nil {
+			evaluation.Suppressed = append(evaluation.Suppressed, *suppressed)
+			continue
+		}
+		evaluation.Counts[finding.Severity]++
+	}
+
+	if p.FailOn != "" {
+		count := 0
+		for _, severity := range sarif.Severities {
+			if sarif.AtOrAbove(severity, p.FailOn) {
+				count += evaluation.Counts[severity]
+			}
+		}
+		if count > 0 {
+			evaluation.Violations = append(evaluation.Violations,
+				fmt.Sprintf("%d findings have a severity at or above %s", count, p.FailOn))
+		}
+	}
+	severities := []string{}
+	for severity := range p.MaxFindings {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		return severityIndex(severities[i]) < severityIndex(severities[j])
+	})
+	for _, severity := range severities {
+		if count, max := evaluation.Counts[severity], p.MaxFindings[severity]; count > max {
+			evaluation.Violations = append(evaluation.Violations,
+				fmt.Sprintf("%d %s findings exceed the maximum of %d", count, severity, max))
+		}
+	}
+	evaluation.Compliant = len(evaluation.Violations) == 0
+	return evaluation
+}
+
+// suppress returns how a finding is suppressed, by the ignore rules first and then by the baseline,
+// or nil if it is not suppressed
+func (p *Policy) suppress(finding Finding, ignoreRules []IgnoreRule) *SuppressedFinding {
+	location := ""
+	if len(finding.Locations) > 0 {
+		location = finding.Locations[0]
+	}
+	ids := append([]string{finding.ID}, finding.Aliases...)
+
+	for _, rule := range ignoreRules {
+		if utils.Contains(ids, rule.ID) {
+			suppressed := &SuppressedFinding{
+				ID:           finding.ID,
+				Severity:     finding.Severity,
+				Location:     location,
+				SuppressedBy: SuppressedByIgnoreFile,
+				MatchedID:    rule.ID,
+				Reason:       rule.Reason,
+			}
+			if !rule.Expires.IsZero() {
+				expires := rule.Expires
+				suppressed.Expires = &expires
+			}
+			return suppressed
+		}
+	}
+
+	if p.Baseline != nil {
+		for _, accepted := range p.Baseline.Findings {
+			if !utils.Contains(ids, accepted.ID) {
+				continue
+			}
+			if accepted.Location != "" && !utils.Contains(finding.Locations, accepted.Location) {
+				continue
+			}
+			reason := accepted.Reason
+			if reason == "" {
+				reason = "accepted in the baseline"
+			}
+			return &SuppressedFinding{
+				ID:           finding.ID,
+				Severity:     finding.Severity,
+				Location:     location,
+				SuppressedBy: SuppressedByBaselineFile,
+				MatchedID:    accepted.ID,
+				Reason:       reason,
+			}
+		}
+	}
+	return nil
+}
+
+func severityIndex(severity string) int {
+	for i, s := range sarif.Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(sarif.Severities)
+}
@@ -0,0 +1,267 @@
+package scanpolicy
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kosli-dev/cli/internal/sarif"
+	"github.com/kosli-dev/cli/internal/snyk"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Define the suite, and absorb the built-in basic suite
+// functionality from testify - including a T() method which
+// returns the current testing context
+type ScanPolicyTestSuite struct {
+	suite.Suite
+}
+
+var (
+	now        = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	nextMonth  = time.Date(2026, 11, 18, 0, 0, 0, 0, time.UTC)
+	lastMonth  = time.Date(2026, 9, 18, 0, 0, 0, 0, time.UTC)
+	zeroCounts = map[string]int{"critical": 0, "high": 0, "medium": 0, "low": 0, "info": 0}
+)
+
+// the findings of a scan: a critical and two high vulnerabilities and a low code issue
+var findings = []Finding{
+	{ID: "SNYK-JS-MINIMIST-559764", Aliases: []string{"CVE-2021-44906"}, Severity: "critical", Locations: []string{"package-lock.json"}},
+	{ID: "SNYK-JS-LODASH-567746", Aliases: []string{"CVE-2020-8203", "CWE-400"}, Severity: "high", Locations: []string{"package-lock.json"}},
+	{ID: "SNYK-JS-LODASH-567746", Aliases: []string{"CVE-2020-8203", "CWE-400"}, Severity: "high", Locations: []string{"worker/package-lock.json"}},
+	{ID: "js/unused-local-variable", Severity: "low", Locations: []string{"src/util.js"}},
+}
+
+func counts(values map[string]int) map[string]int {
+	result := map[string]int{}
+	for severity, count := range zeroCounts {
+		result[severity] = count
+	}
+	for severity, count := range values {
+		result[severity] = count
+	}
+	return result
+}
+
+func (suite *ScanPolicyTestSuite) TestEvaluate() {
+	for _, t := range []struct {
+		name           string
+		policy         *Policy
+		wantCompliant  bool
+		wantCounts     map[string]int
+		wantViolations []string
+		wantSuppressed []SuppressedFinding
+		wantExpired    []IgnoreRule
+	}{
+		{
+			name:           "findings at or above --fail-on are violations",
+			policy:         &Policy{FailOn: "high"},
+			wantCompliant:  false,
+			wantCounts:     counts(map[string]int{"critical": 1, "high": 2, "low": 1}),
+			wantViolations: []string{"3 findings have a severity at or above high"},
+			wantSuppressed: []SuppressedFinding{},
+		},
+		{
+			name:           "no findings are violations with --fail-on none",
+			policy:         &Policy{FailOn: "none"},
+			wantCompliant:  true,
+			wantCounts:     counts(map[string]int{"critical": 1, "high": 2, "low": 1}),
+			wantViolations: []string{},
+			wantSuppressed: []SuppressedFinding{},
+		},
+		{
+			name:          "findings above the maximum of their severity are violations",
+			policy:        &Policy{MaxFindings: map[string]int{"low": 0, "high": 1, "critical": 1}},
+			wantCompliant: false,
+			wantCounts:    counts(map[string]int{"critical": 1, "high": 2, "low": 1}),
+			wantViolations: []string{
+				"2 high findings exceed the maximum of 1",
+				"1 low findings exceed the maximum of 0",
+			},
+			wantSuppressed: []SuppressedFinding{},
+		},
+		{
+			name: "findings ignored by ID or alias are suppressed until the rules expire",
+			policy: &Policy{
+				FailOn: "high",
+				Ignore: &IgnoreSpec{Version: 1, Ignore: []IgnoreRule{
+					{ID: "CVE-2020-8203", Reason: "the vulnerable function is not used", Expires: nextMonth},
+					{ID: "SNYK-JS-MINIMIST-559764", Reason: "waiting for a fix", Expires: lastMonth},
+				}},
+				Now: now,
+			},
+			wantCompliant:  false,
+			wantCounts:     counts(map[string]int{"critical": 1, "low": 1}),
+			wantViolations: []string{"1 findings have a severity at or above high"},
+			wantSuppressed: []SuppressedFinding{
+				{ID: "SNYK-JS-LODASH-567746", Severity: "high", Location: "package-lock.json", SuppressedBy: SuppressedByIgnoreFile,
+					MatchedID: "CVE-2020-8203", Reason: "the vulnerable function is not used", Expires: &nextMonth},
+				{ID: "SNYK-JS-LODASH-567746", Severity: "high", Location: "worker/package-lock.json", SuppressedBy: SuppressedByIgnoreFile,
+					MatchedID: "CVE-2020-8203", Reason: "the vulnerable function is not used", Expires: &nextMonth},
+			},
+			wantExpired: []IgnoreRule{{ID: "SNYK-JS-MINIMIST-559764", Reason: "waiting for a fix", Expires: lastMonth}},
+		},
+		{
+			name: "findings accepted in the baseline are suppressed in their location",
+			policy: &Policy{
+				FailOn: "high",
+				Baseline: &BaselineSpec{Version: 1, Findings: []BaselineFinding{
+					{ID: "SNYK-JS-LODASH-567746", Location: "package-lock.json", Reason: "accepted in the Q3 review"},
+					{ID: "CVE-2021-44906"},
+				}},
+			},
+			wantCompliant:  false,
+			wantCounts:     counts(map[string]int{"high": 1, "low": 1}),
+			wantViolations: []string{"1 findings have a severity at or above high"},
+			wantSuppressed: []SuppressedFinding{
+				{ID: "SNYK-JS-MINIMIST-559764", Severity: "critical", Location: "package-lock.json", SuppressedBy: SuppressedByBaselineFile,
+					MatchedID: "CVE-2021-44906", Reason: "accepted in the baseline"},
+				{ID: "SNYK-JS-LODASH-567746", Severity: "high", Location: "package-lock.json", SuppressedBy: SuppressedByBaselineFile,
+					MatchedID: "SNYK-JS-LODASH-567746", Reason: "accepted in the Q3 review"},
+			},
+		},
+		{
+			name: "the ignore file has precedence over the baseline file",
+			policy: &Policy{
+				MaxFindings: map[string]int{"critical": 0},
+				Ignore: &IgnoreSpec{Version: 1, Ignore: []IgnoreRule{
+					{ID: "SNYK-JS-MINIMIST-559764", Reason: "not reachable"},
+				}},
+				Baseline: &BaselineSpec{Version: 1, Findings: []BaselineFinding{
+					{ID: "SNYK-JS-MINIMIST-559764", Reason: "accepted"},
+				}},
+			},
+			wantCompliant:  true,
+			wantCounts:     counts(map[string]int{"high": 2, "low": 1}),
+			wantViolations: []string{},
+			wantSuppressed: []SuppressedFinding{
+				{ID: "SNYK-JS-MINIMIST-559764", Severity: "critical", Location: "package-lock.json", SuppressedBy: SuppressedByIgnoreFile,
+					MatchedID: "SNYK-JS-MINIMIST-559764", Reason: "not reachable"},
+			},
+		},
+	} {
+		suite.Run(t.name, func() {
+			evaluation := t.policy.Evaluate(findings)
+			require.Equal(suite.T(), t.wantCompliant, evaluation.Compliant)
+			require.Equal(suite.T(), t.wantCounts, evaluation.Counts)
+			require.Equal(suite.T(), t.wantViolations, evaluation.Violations)
+			require.Equal(suite.T(), t.wantSuppressed, evaluation.Suppressed)
+			require.Equal(suite.T(), t.wantExpired, evaluation.ExpiredIgnores)
+		})
+	}
+}
+
+func (suite *ScanPolicyTestSuite) TestValidate() {
+	for _, t := range []struct {
+		name    string
+		policy  *Policy
+		wantErr string
+	}{
+		{
+			name:   "a policy with valid severities is valid",
+			policy: &Policy{FailOn: "none", MaxFindings: map[string]int{"critical": 0, "info": 10}},
+		},
+		{
+			name:    "an invalid --fail-on severity is invalid",
+			policy:  &Policy{FailOn: "error"},
+			wantErr: `invalid severity "error", the allowed severities are: critical, high, medium, low, info, none for --fail-on`,
+		},
+		{
+			name:    "an invalid --max-findings severity is invalid",
+			policy:  &Policy{MaxFindings: map[string]int{"none": 0}},
+			wantErr: `invalid severity "none" for --max-findings, the allowed severities are: critical, high, medium, low, info`,
+		},
+		{
+			name:    "a negative --max-findings is invalid",
+			policy:  &Policy{MaxFindings: map[string]int{"high": -1}},
+			wantErr: "invalid maximum number of high findings -1 for --max-findings, it must be 0 or more",
+		},
+	} {
+		suite.Run(t.name, func() {
+			err := t.policy.Validate()
+			if t.wantErr != "" {
+				require.EqualError(suite.T(), err, t.wantErr)
+				return
+			}
+			require.NoError(suite.T(), err)
+		})
+	}
+}
+
+func (suite *ScanPolicyTestSuite) TestHasThresholds() {
+	require.False(suite.T(), (&Policy{}).HasThresholds())
+	require.False(suite.T(), (&Policy{FailOn: "none", Ignore: &IgnoreSpec{}}).HasThresholds())
+	require.True(suite.T(), (&Policy{FailOn: "low"}).HasThresholds())
+	require.True(suite.T(), (&Policy{MaxFindings: map[string]int{"high": 0}}).HasThresholds())
+}
+
+func (suite *ScanPolicyTestSuite) TestFindings() {
+	sarifData, err := sarif.ProcessSarifFile("../sarif/sarif-codeql.json")
+	require.NoError(suite.T(), err)
+	sarifFindings := SarifFindings(sarifData)
+	require.Len(suite.T(), sarifFindings, 4)
+	require.Equal(suite.T(), Finding{ID: "js/sql-injection", Severity: "critical", Locations: []string{"src/app.js"}}, sarifFindings[2])
+
+	snykData, err := snyk.ProcessSnykResultFile("../snyk/snyk-all-projects.json")
+	require.NoError(suite.T(), err)
+	snykFindings := SnykFindings(snykData)
+	require.Len(suite.T(), snykFindings, 4)
+	require.Equal(suite.T(), Finding{
+		ID:        "SNYK-JS-LODASH-567746",
+		Aliases:   []string{"CVE-2020-8203", "CWE-400", "GHSA-p6mc-m468-83gw"},
+		Severity:  "high",
+		Locations: []string{"api/package-lock.json"},
+	}, snykFindings[0])
+}
+
+func (suite *ScanPolicyTestSuite) TestEvaluateFailOnThresholds() {
+	sarifData, err := sarif.ProcessSarifFile("../sarif/sarif-codeql.json")
+	require.NoError(suite.T(), err)
+	other, err := sarif.ProcessSarifFile("../sarif/sarif-semgrep-checkov.json")
+	require.NoError(suite.T(), err)
+	sarifData.Runs = append(sarifData.Runs, other.Runs...)
+	snykJsonData, err := snyk.ProcessSnykResultFile("../snyk/snyk-all-projects.json")
+	require.NoError(suite.T(), err)
+	snykSarifData, err := snyk.ProcessSnykResultFile("../snyk/sarif-container.json")
+	require.NoError(suite.T(), err)
+
+	for _, t := range []struct {
+		name      string
+		findings  []Finding
+		threshold string
+		want      int
+	}{
+		{"sarif", SarifFindings(sarifData), "critical", 1},
+		{"sarif", SarifFindings(sarifData), "high", 4},
+		{"sarif", SarifFindings(sarifData), "medium", 6},
+		{"sarif", SarifFindings(sarifData), "low", 7},
+		{"sarif", SarifFindings(sarifData), "info", 8},
+		{"sarif", SarifFindings(sarifData), "none", 0},
+		{"snyk json", SnykFindings(snykJsonData), "critical", 1},
+		{"snyk json", SnykFindings(snykJsonData), "high", 2},
+		{"snyk json", SnykFindings(snykJsonData), "medium", 3},
+		{"snyk json", SnykFindings(snykJsonData), "low", 4},
+		{"snyk json", SnykFindings(snykJsonData), "none", 0},
+		{"snyk sarif", SnykFindings(snykSarifData), "critical", 0},
+		{"snyk sarif", SnykFindings(snykSarifData), "high", 2},
+		{"snyk sarif", SnykFindings(snykSarifData), "medium", 3},
+	} {
+		suite.Run(fmt.Sprintf("%s findings at or above %s", t.name, t.threshold), func() {
+			evaluation := (&Policy{FailOn: t.threshold}).Evaluate(t.findings)
+			if t.want == 0 {
+				require.True(suite.T(), evaluation.Compliant)
+				require.Empty(suite.T(), evaluation.Violations)
+				return
+			}
+			require.False(suite.T(), evaluation.Compliant)
+			require.Equal(suite.T(), []string{fmt.Sprintf("%d findings have a severity at or above %s", t.want, t.threshold)}, evaluation.Violations)
+		})
+	}
+}
+
+// In order for 'go test' to run this suite, we need to create
+// a normal test function and pass our suite to suite.Run
+func TestScanPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(ScanPolicyTestSuite))
+}
@@ -20,12 +20,23 @@ type ServerEnvRequest struct {
 type ServerData struct {
 	Digests           map[string]string `json:"digests"`
 	CreationTimestamp int64             `json:"creationTimestamp"`
+	// Service is the docker compose or swarm service of a container artifact
+	Service *ContainerService `json:"service,omitempty"`
+}
+
+// ContainerService is the docker compose and/or swarm service a container belongs to
+type ContainerService struct {
+	ComposeProject string `json:"composeProject,omitempty"`
+	ComposeService string `json:"composeService,omitempty"`
+	SwarmStack     string `json:"swarmStack,omitempty"`
+	SwarmService   string `json:"swarmService,omitempty"`
 }
 
 // ArtifactPathSpec represents specification for how to fingerprint an artifact
 type ArtifactPathSpec struct {
-	Path    string   `mapstructure:"path" validate:"required"`
-	Exclude []string `mapstructure:"exclude"`
+	Path       string   `mapstructure:"path" validate:"required"`
+	Exclude    []string `mapstructure:"exclude"`
+	IgnoreFile string   `mapstructure:"ignore_file"`
 }
 
 // PathsSpec represents specification for how to fingerprint a list of artifacts
@@ -37,7 +48,8 @@ type PathsSpec struct {
 // CreateServerArtifactsData creates a list of ServerData for server artifacts at given paths
 // and excludePaths can contain Glob patterns
 // if paths have Glob patterns, each path matching the pattern will be treated as an artifact
-func CreateServerArtifactsData(paths, excludePaths []string, logger *logger.Logger) ([]*ServerData, error) {
+// if ignoreFile is not empty, the paths it lists are excluded from each directory artifact
+func CreateServerArtifactsData(paths, excludePaths []string, ignoreFile string, logger *logger.Logger) ([]*ServerData, error) {
 	result := []*ServerData{}
 
 	pathsToInclude := []string{}
@@ -54,7 +66,7 @@ func CreateServerArtifactsData(paths, excludePaths []string, logger *logger.Logg
 	}
 
 	for _, p := range pathsToInclude {
-		data, err := getArtifactDataForPath(p, "", excludePaths, logger)
+		data, err := getArtifactDataForPath(p, "", excludePaths, ignoreFile, logger)
 		if err != nil {
 			return result, err
 		}
@@ -63,10 +75,10 @@ func CreateServerArtifactsData(paths, excludePaths []string, logger *logger.Logg
 	return result, nil
 }
 
-// getArtifactDataForPath calculates the artifact fingerprint for path (while excluding excludePaths)
-// and returns a ServerData object.
+// getArtifactDataForPath calculates the artifact fingerprint for path (while excluding excludePaths
+// and the paths in ignoreFile) and returns a ServerData object.
 // If artifactName is empty, it is defaulted to the absolute path of the artifact path
-func getArtifactDataForPath(path, artifactName string, excludePaths []string, logger *logger.Logger) (*ServerData, error) {
+func getArtifactDataForPath(path, artifactName string, excludePaths []string, ignoreFile string, logger *logger.Logger) (*ServerData, error) {
 	data := &ServerData{}
 	digests := make(map[string]string)
 
@@ -89,7 +101,7 @@ func getArtifactDataForPath(path, artifactName string, excludePaths []string, lo
 		}
 		fingerprint, err = digest.FileSha256(path)
 	} else {
-		fingerprint, err = digest.DirSha256(path, excludePaths, logger)
+		fingerprint, err = digest.DirSha256(path, excludePaths, ignoreFile, logger)
 	}
 
 	if err != nil {
@@ -121,7 +133,7 @@ func CreatePathsArtifactsData(ps *PathsSpec, logger *logger.Logger) ([]*ServerDa
 	result := []*ServerData{}
 	for artifactName, pathSpec := range ps.Artifacts {
 		logger.Debug("fingerprinting artifact [%s] with spec [ Include: %s, Exclude: %s]", artifactName, pathSpec.Path, pathSpec.Exclude)
-		data, err := getArtifactDataForPath(pathSpec.Path, artifactName, pathSpec.Exclude, logger)
+		data, err := getArtifactDataForPath(pathSpec.Path, artifactName, pathSpec.Exclude, pathSpec.IgnoreFile, logger)
 		if err != nil {
 			return result, fmt.Errorf("failed to calculate fingerprint for artifact [%s]: %v", artifactName, err)
 		}
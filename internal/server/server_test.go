@@ -171,7 +171,7 @@ func (suite *ServerTestSuite) TestCreateServerArtifactsData() {
 				t.paths[i] = filepath.Join(suite.tmpDir, path)
 			}
 
-			serverData, err := CreateServerArtifactsData(t.paths, t.excludePaths, logger.NewStandardLogger())
+			serverData, err := CreateServerArtifactsData(t.paths, t.excludePaths, "", logger.NewStandardLogger())
 			require.NoErrorf(suite.T(), err, "error creating server artifact data: %v", err)
 
 			digestsList := []map[string]string{}
@@ -252,7 +252,7 @@ func (suite *ServerTestSuite) TestCreateServerArtifactsDataWithFiles() {
 				suite.createFileWithContent(path, t.args.content)
 			}
 
-			serverData, err := CreateServerArtifactsData(paths, []string{}, logger.NewStandardLogger())
+			serverData, err := CreateServerArtifactsData(paths, []string{}, "", logger.NewStandardLogger())
 			if t.expectError {
 				require.Errorf(suite.T(), err, "was expecting error during creating server artifact data but got none")
 			} else {
@@ -284,7 +284,7 @@ func (suite *ServerTestSuite) TestCreateServerArtifactsDataInvalid() {
 
 	paths := []string{"a/b/c"}
 
-	_, err := CreateServerArtifactsData(paths, []string{}, logger.NewStandardLogger())
+	_, err := CreateServerArtifactsData(paths, []string{}, "", logger.NewStandardLogger())
 	require.Errorf(suite.T(), err, "error was expected")
 }
 
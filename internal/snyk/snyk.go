@@ -2,10 +2,9 @@ package snyk
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
+	"os"
 
-	"github.com/owenrumney/go-sarif/v2/sarif"
+	"github.com/kosli-dev/cli/internal/sarif"
 )
 
 type SnykTool struct {
@@ -18,11 +17,31 @@ type Location struct {
 	Lines string `json:"lines,omitempty"`
 }
 
+// VulnerablePath is a dependency path through which a vulnerable package is used,
+// and the upgrades of the packages of the path which fix the vulnerability
+type VulnerablePath struct {
+	From        []string `json:"from"`
+	UpgradePath []string `json:"upgrade_path,omitempty"`
+}
+
+// Vulnerability is a Snyk finding. The fields after PriorityScore are not sent to Kosli
+// until the snyk attestation endpoint accepts them.
 type Vulnerability struct {
 	ID            string     `json:"id"`
 	Message       string     `json:"message"`
 	Locations     []Location `json:"locations,omitempty"`
 	PriorityScore float64    `json:"priority_score,omitempty"`
+	Severity      string     `json:"-"`
+	// the fields below are only set from Snyk JSON results
+	PackageName    string           `json:"-"`
+	PackageVersion string           `json:"-"`
+	CVSSScore      float64          `json:"-"`
+	Identifiers    []string         `json:"-"`
+	IsUpgradable   bool             `json:"-"`
+	IsPatchable    bool             `json:"-"`
+	Patches        []string         `json:"-"`
+	FixedIn        []string         `json:"-"`
+	Paths          []VulnerablePath `json:"-"`
 }
 
 type SnykResult struct {
@@ -40,55 +59,77 @@ type SnykData struct {
 	Results       []SnykResult `json:"results"`
 }
 
-// ProcessSnykResultFile takes a path to a Snyk scan results file
+// ProcessSnykResultFile takes a path to a Snyk scan results file, in SARIF or JSON format,
 // and returns a processed SnykData object from it
 func ProcessSnykResultFile(file string) (*SnykData, error) {
-	report, err := sarif.Open(file)
+	content, err := os.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
-	if !strings.HasPrefix(report.Schema, "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-") {
-		return nil, fmt.Errorf("invalid sarif file")
+	isJson, err := isSnykJson(content)
+	if err != nil {
+		return nil, err
 	}
+	if isJson {
+		return ProcessSnykJson(content)
+	}
+	return ProcessSnykSarifFile(file)
+}
+
+// ProcessSnykSarifFile takes a path to a Snyk SARIF scan results file
+// and returns a processed SnykData object from it
+func ProcessSnykSarifFile(file string) (*SnykData, error) {
+	sarifData, err := sarif.ProcessSarifFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return FromSarifData(sarifData)
+}
+
+// FromSarifData converts the summary of Snyk SARIF results to SnykData
+func FromSarifData(sarifData *sarif.SarifData) (*SnykData, error) {
 	data := &SnykData{
 		SchemaVersion: 1,
 		Tool:          SnykTool{},
 		Results:       []SnykResult{},
 	}
 
-	if len(report.Runs) > 0 {
-		data.Tool.Name = report.Runs[0].Tool.Driver.Name
-		if report.Runs[0].Tool.Driver.Version != nil {
-			data.Tool.Version = *report.Runs[0].Tool.Driver.Version
-		}
+	if len(sarifData.Runs) > 0 {
+		data.Tool.Name = sarifData.Runs[0].Tool.Name
+		data.Tool.Version = sarifData.Runs[0].Tool.Version
 	}
 
-	for _, run := range report.Runs {
+	for _, run := range sarifData.Runs {
 		result := SnykResult{}
-		for _, r := range run.Results {
-			level := r.Level
-			vulnerability := createVulnerability(r)
-			if level == nil {
-				ruleLevel, err := findLevel(run, vulnerability.ID)
+		for _, finding := range run.Findings {
+			level := finding.Level
+			if level == "" {
+				var err error
+				level, err = findLevel(finding)
 				if err != nil {
 					return nil, err
 				}
-				level = &ruleLevel
 			}
+			vulnerability := createVulnerability(finding)
 			// levels in sarif and JSON snyk output files differ
 			// mapping can be found here: https://docs.snyk.io/snyk-cli/scan-and-maintain-projects-using-the-cli/snyk-cli-for-snyk-code/view-snyk-code-cli-results#severity-levels-in-json-and-sarif-files
-			switch *level {
+			switch level {
 			case "error", "high", "critical":
+				vulnerability.Severity = sarif.SeverityHigh
+				if level == "critical" {
+					vulnerability.Severity = sarif.SeverityCritical
+				}
 				result.HighCount++
 				result.High = append(result.High, vulnerability)
 			case "warning", "medium":
+				vulnerability.Severity = sarif.SeverityMedium
 				result.MediumCount++
 				result.Medium = append(result.Medium, vulnerability)
 			case "info", "low", "note":
+				vulnerability.Severity = sarif.SeverityLow
 				result.LowCount++
 				result.Low = append(result.Low, vulnerability)
 			}
-
 		}
 		data.Results = append(data.Results, result)
 	}
@@ -96,60 +137,34 @@ func ProcessSnykResultFile(file string) (*SnykData, error) {
 	return data, nil
 }
 
-func createVulnerability(r *sarif.Result) Vulnerability {
+func createVulnerability(finding sarif.Finding) Vulnerability {
 	locations := []Location{}
-	for _, l := range r.Locations {
-		if l != nil && l.PhysicalLocation != nil {
-			lines := ""
-			if l.PhysicalLocation.Region != nil && l.PhysicalLocation.Region.StartLine != nil {
-				lines = strconv.Itoa(*l.PhysicalLocation.Region.StartLine)
-				if l.PhysicalLocation.Region.EndLine != nil && *l.PhysicalLocation.Region.EndLine != *l.PhysicalLocation.Region.StartLine {
-					lines += fmt.Sprintf("-%d", *l.PhysicalLocation.Region.EndLine)
-				}
-			}
-			uri := ""
-			if l.PhysicalLocation.ArtifactLocation != nil && l.PhysicalLocation.ArtifactLocation.URI != nil {
-				uri = *l.PhysicalLocation.ArtifactLocation.URI
-			}
-
-			locations = append(locations, Location{
-				URI:   uri,
-				Lines: lines,
-			})
-		}
+	for _, l := range finding.Locations {
+		locations = append(locations, Location{
+			URI:   l.URI,
+			Lines: l.Lines,
+		})
 	}
 	vul := Vulnerability{
+		ID:        finding.RuleID,
+		Message:   finding.Message,
 		Locations: locations,
 	}
-	if r.RuleID != nil {
-		vul.ID = *r.RuleID
-	}
-	if r.Message.Text != nil {
-		vul.Message = *r.Message.Text
-	}
-	if value, exists := r.Properties["priorityScore"]; exists {
-		if value != nil {
-			vul.PriorityScore = value.(float64)
-		}
+	if value, ok := finding.Properties["priorityScore"].(float64); ok {
+		vul.PriorityScore = value
 	}
 	return vul
 }
 
-func findLevel(r *sarif.Run, id string) (string, error) {
-	ruleDesc, err := r.GetRuleById(id)
-	if err != nil {
-		return "", fmt.Errorf("could not find rule ID: %s. %s", id, err)
+// findLevel returns the level of a finding without a level from the problem.severity property of its rule
+func findLevel(finding sarif.Finding) (string, error) {
+	if finding.Rule == nil {
+		return "", fmt.Errorf("could not find rule ID: %s", finding.RuleID)
 	}
-	// defaultConfig := ruleDesc.DefaultConfiguration
-	// if defaultConfig != nil {
-	// 	return defaultConfig.Level, nil
-	// }
-	problem, problem_exists := ruleDesc.Properties["problem"]
-	if problem_exists && problem != nil {
-		severity, severity_exists := problem.(map[string]interface{})["severity"]
-		if severity_exists {
-			return severity.(string), nil
+	if problem, ok := finding.Rule.Properties["problem"].(map[string]interface{}); ok {
+		if severity, ok := problem["severity"].(string); ok {
+			return severity, nil
 		}
 	}
-	return "", fmt.Errorf("could not find level for rule ID: %s", id)
+	return "", fmt.Errorf("could not find level for rule ID: %s", finding.RuleID)
 }
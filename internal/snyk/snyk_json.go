@@ -0,0 +1,207 @@
+package snyk
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kosli-dev/cli/internal/sarif"
+	"github.com/kosli-dev/cli/internal/utils"
+)
+
+// jsonProject is the output of 'snyk test --json' and 'snyk container test --json' for a project.
+// 'snyk test --all-projects --json' outputs an array of them.
+type jsonProject struct {
+	OK                bool                `json:"ok"`
+	Error             string              `json:"error"`
+	Vulnerabilities   []jsonVulnerability `json:"vulnerabilities"`
+	Docker            json.RawMessage     `json:"docker"`
+	ProjectName       string              `json:"projectName"`
+	TargetFile        string              `json:"targetFile"`
+	DisplayTargetFile string              `json:"displayTargetFile"`
+	// Applications are the projects found in the image by 'snyk container test --app-vulns'
+	Applications []jsonProject `json:"applications"`
+}
+
+type jsonVulnerability struct {
+	ID                   string                   `json:"id"`
+	Title                string                   `json:"title"`
+	Severity             string                   `json:"severity"`
+	SeverityWithCritical string                   `json:"severityWithCritical"`
+	CVSSScore            float64                  `json:"cvssScore"`
+	Identifiers          map[string][]interface{} `json:"identifiers"`
+	PackageName          string                   `json:"packageName"`
+	Version              string                   `json:"version"`
+	From                 []string                 `json:"from"`
+	// UpgradePath has an entry per package of From, false if the package does not need an upgrade
+	UpgradePath  []interface{} `json:"upgradePath"`
+	IsUpgradable bool          `json:"isUpgradable"`
+	IsPatchable  bool          `json:"isPatchable"`
+	Patches      []struct {
+		ID string `json:"id"`
+	} `json:"patches"`
+	FixedIn []string `json:"fixedIn"`
+}
+
+// isSnykJson returns whether content is Snyk JSON output rather than SARIF
+func isSnykJson(content []byte) (bool, error) {
+	trimmed := strings.TrimSpace(string(content))
+	if strings.HasPrefix(trimmed, "[") {
+		return true, nil
+	}
+	var probe struct {
+		Version         *string          `json:"version"`
+		Runs            *json.RawMessage `json:"runs"`
+		Vulnerabilities *json.RawMessage `json:"vulnerabilities"`
+		Error           *string          `json:"error"`
+	}
+	if err := json.Unmarshal(content, &probe); err != nil {
+		return false, err
+	}
+	if probe.Version != nil || probe.Runs != nil {
+		return false, nil
+	}
+	if probe.Vulnerabilities != nil || probe.Error != nil {
+		return true, nil
+	}
+	return false, fmt.Errorf("unrecognized Snyk results file, expected the SARIF or JSON output of snyk")
+}
+
+// ProcessSnykJson processes the JSON output of 'snyk test' and 'snyk container test',
+// for a project or for several projects (--all-projects). Each project (and each application
+// of a container image) is a result.
+func ProcessSnykJson(content []byte) (*SnykData, error) {
+	projects := []jsonProject{}
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "[") {
+		if err := json.Unmarshal(content, &projects); err != nil {
+			return nil, err
+		}
+	} else {
+		project := jsonProject{}
+		if err := json.Unmarshal(content, &project); err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	data := &SnykData{
+		SchemaVersion: 1,
+		Tool:          SnykTool{Name: "Snyk Open Source"},
+		Results:       []SnykResult{},
+	}
+	for i, project := range projects {
+		if project.Error != "" {
+			return nil, fmt.Errorf("snyk failed to test %s: %s", project.name(), project.Error)
+		}
+		if i == 0 && len(project.Docker) > 0 && string(project.Docker) != "null" {
+			data.Tool.Name = "Snyk Container"
+		}
+		data.Results = append(data.Results, project.result())
+		for _, application := range project.Applications {
+			data.Results = append(data.Results, application.result())
+		}
+	}
+	return data, nil
+}
+
+func (p jsonProject) name() string {
+	if p.ProjectName != "" {
+		return p.ProjectName
+	}
+	return p.TargetFile
+}
+
+// result groups the vulnerabilities of a project, which snyk reports once per dependency path
+func (p jsonProject) result() SnykResult {
+	targetFile := p.DisplayTargetFile
+	if targetFile == "" {
+		targetFile = p.TargetFile
+	}
+
+	vulnerabilities := []*Vulnerability{}
+	byKey := map[string]*Vulnerability{}
+	for _, v := range p.Vulnerabilities {
+		key := v.ID + " " + v.PackageName + "@" + v.Version
+		vul, ok := byKey[key]
+		if !ok {
+			vul = v.vulnerability(targetFile)
+			byKey[key] = vul
+			vulnerabilities = append(vulnerabilities, vul)
+		}
+		path := VulnerablePath{From: v.From}
+		for _, upgrade := range v.UpgradePath {
+			if pkg, ok := upgrade.(string); ok && pkg != "" {
+				path.UpgradePath = append(path.UpgradePath, pkg)
+			}
+		}
+		vul.Paths = append(vul.Paths, path)
+		vul.IsUpgradable = vul.IsUpgradable || v.IsUpgradable
+		vul.IsPatchable = vul.IsPatchable || v.IsPatchable
+		for _, patch := range v.Patches {
+			if !utils.Contains(vul.Patches, patch.ID) {
+				vul.Patches = append(vul.Patches, patch.ID)
+			}
+		}
+	}
+
+	result := SnykResult{}
+	for _, vul := range vulnerabilities {
+		switch vul.Severity {
+		case sarif.SeverityCritical, sarif.SeverityHigh:
+			result.HighCount++
+			result.High = append(result.High, *vul)
+		case sarif.SeverityMedium:
+			result.MediumCount++
+			result.Medium = append(result.Medium, *vul)
+		case sarif.SeverityLow:
+			result.LowCount++
+			result.Low = append(result.Low, *vul)
+		}
+	}
+	return result
+}
+
+// severity returns the severity of a vulnerability. severity is at most high
+// in the output of older snyk versions, which have the critical severity in severityWithCritical.
+func (v jsonVulnerability) severity() string {
+	if v.SeverityWithCritical != "" {
+		return strings.ToLower(v.SeverityWithCritical)
+	}
+	return strings.ToLower(v.Severity)
+}
+
+func (v jsonVulnerability) vulnerability(targetFile string) *Vulnerability {
+	vul := &Vulnerability{
+		ID:             v.ID,
+		Message:        fmt.Sprintf("%s in %s@%s", v.Title, v.PackageName, v.Version),
+		Severity:       v.severity(),
+		PackageName:    v.PackageName,
+		PackageVersion: v.Version,
+		CVSSScore:      v.CVSSScore,
+		FixedIn:        v.FixedIn,
+		Paths:          []VulnerablePath{},
+	}
+	if targetFile != "" {
+		vul.Locations = []Location{{URI: targetFile}}
+	}
+	// CVE first, then the other identifiers (CWE, GHSA...) in a stable order
+	types := []string{}
+	for identifierType := range v.Identifiers {
+		types = append(types, identifierType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if (types[i] == "CVE") != (types[j] == "CVE") {
+			return types[i] == "CVE"
+		}
+		return types[i] < types[j]
+	})
+	for _, identifierType := range types {
+		for _, identifier := range v.Identifiers[identifierType] {
+			if id, ok := identifier.(string); ok {
+				vul.Identifiers = append(vul.Identifiers, id)
+			}
+		}
+	}
+	return vul
+}
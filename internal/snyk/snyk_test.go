@@ -2,6 +2,8 @@ package snyk
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestProcessSnykResultFile(t *testing.T) {
@@ -22,10 +24,55 @@ func TestProcessSnykResultFile(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name:    "a non-sarif json file causes an error",
-			file:    "snyk.json",
+			name:    "a json file which is neither sarif nor snyk json causes an error",
+			file:    "not-snyk.json",
+			wantErr: true,
+		},
+		{
+			name:    "a snyk json file with an error causes an error",
+			file:    "snyk-error.json",
 			wantErr: true,
 		},
+		{
+			name: "a snyk container test json file is parsed correctly",
+			file: "snyk.json",
+			want: &want{
+				tool:    "Snyk Container",
+				version: "",
+				results: []result{
+					{
+						high_count:   0,
+						medium_count: 0,
+						low_count:    0,
+					},
+					{
+						high_count:   2,
+						medium_count: 1,
+						low_count:    0,
+					},
+				},
+			},
+		},
+		{
+			name: "a snyk test --all-projects json file is parsed correctly",
+			file: "snyk-all-projects.json",
+			want: &want{
+				tool:    "Snyk Open Source",
+				version: "",
+				results: []result{
+					{
+						high_count:   2,
+						medium_count: 0,
+						low_count:    1,
+					},
+					{
+						high_count:   0,
+						medium_count: 1,
+						low_count:    0,
+					},
+				},
+			},
+		},
 		{
 			name:    "non-existing file causes an error",
 			file:    "non-existing.json",
@@ -159,6 +206,10 @@ func TestProcessSnykResultFile(t *testing.T) {
 				t.Errorf("ProcessSnykResultFile() failed, want: Tool: %s (got %s) -- Version: %s (got %s)", tt.want.tool, got.Tool.Name, tt.want.version, got.Tool.Version)
 			}
 
+			if tt.want != nil && len(tt.want.results) != len(got.Results) {
+				t.Errorf("ProcessSnykResultFile() failed, want %d results -- got %d", len(tt.want.results), len(got.Results))
+				return
+			}
 			if tt.want != nil && len(tt.want.results) > 0 {
 				for i, wantResult := range tt.want.results {
 					if wantResult.high_count != got.Results[i].HighCount ||
@@ -171,3 +222,60 @@ func TestProcessSnykResultFile(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessSnykJsonVulnerabilities(t *testing.T) {
+	got, err := ProcessSnykResultFile("snyk-all-projects.json")
+	require.NoError(t, err)
+
+	// the two paths of the lodash vulnerability are grouped
+	require.Equal(t, Vulnerability{
+		ID:             "SNYK-JS-LODASH-567746",
+		Message:        "Prototype Pollution in lodash@4.17.15",
+		Locations:      []Location{{URI: "api/package-lock.json"}},
+		Severity:       "high",
+		PackageName:    "lodash",
+		PackageVersion: "4.17.15",
+		CVSSScore:      7.3,
+		Identifiers:    []string{"CVE-2020-8203", "CWE-400", "GHSA-p6mc-m468-83gw"},
+		IsUpgradable:   true,
+		IsPatchable:    true,
+		Patches:        []string{"patch:SNYK-JS-LODASH-567746:0"},
+		FixedIn:        []string{"4.17.16"},
+		Paths: []VulnerablePath{
+			{From: []string{"shop-api@1.0.0", "lodash@4.17.15"}, UpgradePath: []string{"lodash@4.17.16"}},
+			{From: []string{"shop-api@1.0.0", "express-validator@6.4.0", "lodash@4.17.15"}, UpgradePath: []string{"express-validator@6.5.0", "lodash@4.17.16"}},
+		},
+	}, got.Results[0].High[0])
+
+	// severityWithCritical has precedence over severity
+	require.Equal(t, "critical", got.Results[0].High[1].Severity)
+	require.Equal(t, []VulnerablePath{{From: []string{"shop-api@1.0.0", "mkdirp@0.5.1", "minimist@0.0.8"}}}, got.Results[0].High[1].Paths)
+	require.False(t, got.Results[0].High[1].IsUpgradable)
+
+	// identifiers which are not strings (e.g. NSP ids) are ignored
+	require.Equal(t, []string{"CVE-2017-16137", "CWE-400"}, got.Results[0].Low[0].Identifiers)
+	require.Equal(t, "worker/requirements.txt", got.Results[1].Medium[0].Locations[0].URI)
+}
+
+func TestProcessSnykSarifFileCountsLevels(t *testing.T) {
+	// the counts of the Snyk Code results of the attest snyk command tests, from the levels of the results
+	// and the problem.severity of their rules
+	got, err := ProcessSnykResultFile("../../cmd/kosli/testdata/snyk_sarif.json")
+	require.NoError(t, err)
+	require.Equal(t, SnykTool{Name: "SnykCode", Version: "1.0.0"}, got.Tool)
+	require.Len(t, got.Results, 1)
+	require.Equal(t, 0, got.Results[0].HighCount)
+	require.Equal(t, 9, got.Results[0].MediumCount)
+	require.Equal(t, 25, got.Results[0].LowCount)
+	require.Equal(t, "python/OR", got.Results[0].Medium[0].ID)
+	require.Equal(t, "medium", got.Results[0].Medium[0].Severity)
+	require.Equal(t, "low", got.Results[0].Low[0].Severity)
+}
+
+func TestProcessSnykSarifFileFailsWithoutLevel(t *testing.T) {
+	_, err := ProcessSnykResultFile("sarif-missing-rule.json")
+	require.EqualError(t, err, "could not find rule ID: python/Ssrf")
+
+	_, err = ProcessSnykResultFile("sarif-missing-level.json")
+	require.EqualError(t, err, "could not find level for rule ID: python/Ssrf")
+}